@@ -17,7 +17,11 @@
 
 package stats
 
+// SessionStatsEventTopic is the topic of events published when bytescount middleware reports session statistics
+const SessionStatsEventTopic = "connection-statistics"
+
 // SessionStats represents statistics, generated by bytescount middleware
 type SessionStats struct {
-	BytesSent, BytesReceived uint64
+	BytesSent     uint64 `json:"bytesSent"`
+	BytesReceived uint64 `json:"bytesReceived"`
 }
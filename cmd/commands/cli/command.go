@@ -20,6 +20,7 @@ package cli
 import (
 	"fmt"
 	"log"
+	"net/url"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -66,6 +67,9 @@ const redColor = "\033[31m%s\033[0m"
 const identityDefaultPassphrase = ""
 const statusConnected = "Connected"
 
+// providerAuto is used instead of provider identity to connect to the best provider
+const providerAuto = "auto"
+
 var versionSummary = metadata.VersionAsSummary(metadata.LicenseCopyright(
 	"type 'license warranty'",
 	"type 'license conditions'",
@@ -164,16 +168,31 @@ func (c *cliApp) connect(argsString string) {
 	options := strings.Fields(argsString)
 
 	if len(options) < 2 {
-		info("Please type in the provider identity. Connect <consumer-identity> <provider-identity> [disable-kill-switch]")
+		info("Please type in the provider identity. Connect <consumer-identity> <provider-identity> [proposal=<id>] [service=<type>] [disable-kill-switch] [reconnect-attempts]")
+		info("Or connect to the best provider. Connect <consumer-identity> auto [country=<code>] [service=<type>] [protocol=<protocol>] [disable-kill-switch] [reconnect-attempts]")
 		return
 	}
 
 	consumerID, providerID := options[0], options[1]
+	options = options[2:]
 
-	var disableKill bool
+	autoConnect := providerID == providerAuto
+	var filter endpoints.ProviderFilterOptions
+	var selector tequilapi_client.ProposalSelector
 	var err error
-	if len(options) > 2 {
-		disableKillStr := options[2]
+	if autoConnect {
+		filter, options, err = parseProviderFilter(options)
+	} else {
+		selector, options, err = parseProposalSelector(options)
+	}
+	if err != nil {
+		info(err)
+		return
+	}
+
+	var disableKill bool
+	if len(options) > 0 {
+		disableKillStr := options[0]
 		disableKill, err = strconv.ParseBool(disableKillStr)
 		if err != nil {
 			info("Please use true / false for <disable-kill-switch>")
@@ -181,7 +200,22 @@ func (c *cliApp) connect(argsString string) {
 		}
 	}
 
-	connectOptions := endpoints.ConnectOptions{DisableKillSwitch: disableKill}
+	var reconnectAttempts int
+	if len(options) > 1 {
+		reconnectAttempts, err = strconv.Atoi(options[1])
+		if err != nil || reconnectAttempts < 0 {
+			info("Please use non negative number for <reconnect-attempts>")
+			return
+		}
+	}
+
+	connectOptions := endpoints.ConnectOptions{
+		DisableKillSwitch: disableKill,
+		Reconnect: endpoints.ReconnectOptions{
+			MaxAttempts:    reconnectAttempts,
+			BackoffSeconds: 1,
+		},
+	}
 
 	if consumerID == "new" {
 		id, err := c.tequilapi.NewIdentity(identityDefaultPassphrase)
@@ -193,9 +227,22 @@ func (c *cliApp) connect(argsString string) {
 		success("New identity created:", consumerID)
 	}
 
+	if autoConnect {
+		status("CONNECTING", "from:", consumerID, "to the best provider")
+
+		connectionStatus, err := c.tequilapi.ConnectAuto(consumerID, filter, connectOptions)
+		if err != nil {
+			warn(err)
+			return
+		}
+
+		success("Connected to:", connectionStatus.ProviderID)
+		return
+	}
+
 	status("CONNECTING", "from:", consumerID, "to:", providerID)
 
-	_, err = c.tequilapi.Connect(consumerID, providerID, connectOptions)
+	_, err = c.tequilapi.Connect(consumerID, providerID, selector, connectOptions)
 	if err != nil {
 		warn(err)
 		return
@@ -204,6 +251,48 @@ func (c *cliApp) connect(argsString string) {
 	success("Connected.")
 }
 
+// parseProviderFilter takes leading key=value options as provider filter and returns the remaining options
+func parseProviderFilter(options []string) (endpoints.ProviderFilterOptions, []string, error) {
+	var filter endpoints.ProviderFilterOptions
+	for len(options) > 0 && strings.Contains(options[0], "=") {
+		keyValue := strings.SplitN(options[0], "=", 2)
+		switch keyValue[0] {
+		case "country":
+			filter.Country = keyValue[1]
+		case "service":
+			filter.ServiceType = keyValue[1]
+		case "protocol":
+			filter.Protocol = keyValue[1]
+		default:
+			return filter, nil, fmt.Errorf("unknown provider filter %q, use country, service or protocol", keyValue[0])
+		}
+		options = options[1:]
+	}
+	return filter, options, nil
+}
+
+// parseProposalSelector takes leading key=value options as selector of provider's proposal and returns the remaining options
+func parseProposalSelector(options []string) (tequilapi_client.ProposalSelector, []string, error) {
+	var selector tequilapi_client.ProposalSelector
+	for len(options) > 0 && strings.Contains(options[0], "=") {
+		keyValue := strings.SplitN(options[0], "=", 2)
+		switch keyValue[0] {
+		case "proposal":
+			proposalID, err := strconv.Atoi(keyValue[1])
+			if err != nil || proposalID <= 0 {
+				return selector, nil, fmt.Errorf("proposal id should be a positive number, got %q", keyValue[1])
+			}
+			selector.ProposalID = proposalID
+		case "service":
+			selector.ServiceType = keyValue[1]
+		default:
+			return selector, nil, fmt.Errorf("unknown proposal selector %q, use proposal or service", keyValue[0])
+		}
+		options = options[1:]
+	}
+	return selector, options, nil
+}
+
 func (c *cliApp) unlock(argsString string) {
 	unlockSignature := "Unlock <identity> [passphrase]"
 	if len(argsString) == 0 {
@@ -278,9 +367,26 @@ func (c *cliApp) healthcheck() {
 	info(buildString)
 }
 
-func (c *cliApp) proposals(filter string) {
-	proposals := c.fetchProposals()
-	c.fetchedProposals = proposals
+func (c *cliApp) proposals(argsString string) {
+	query := url.Values{}
+	filter := ""
+	for _, arg := range strings.Fields(argsString) {
+		keyValue := strings.SplitN(arg, "=", 2)
+		if len(keyValue) == 2 {
+			query.Set(keyValue[0], keyValue[1])
+		} else {
+			filter = arg
+		}
+	}
+
+	proposals, err := c.tequilapi.FilteredProposals(query)
+	if err != nil {
+		warn(err)
+		return
+	}
+	if len(query) == 0 {
+		c.fetchedProposals = proposals
+	}
 
 	filterMsg := ""
 	if filter != "" {
@@ -468,6 +574,12 @@ func newAutocompleter(tequilapi *tequilapi_client.Client, proposals []tequilapi_
 				readline.PcItemDynamic(
 					getProposalOptionList(proposals),
 				),
+				readline.PcItem(
+					providerAuto,
+					readline.PcItem("country="),
+					readline.PcItem("service="),
+					readline.PcItem("protocol="),
+				),
 			),
 		),
 		readline.PcItem(
@@ -477,7 +589,19 @@ func newAutocompleter(tequilapi *tequilapi_client.Client, proposals []tequilapi_
 		),
 		readline.PcItem("status"),
 		readline.PcItem("healthcheck"),
-		readline.PcItem("proposals"),
+		readline.PcItem(
+			"proposals",
+			readline.PcItem("serviceType="),
+			readline.PcItem("country="),
+			readline.PcItem("city="),
+			readline.PcItem("asn="),
+			readline.PcItem("protocol="),
+			readline.PcItem("paymentMethod="),
+			readline.PcItem("maxPrice="),
+			readline.PcItem("sortBy="),
+			readline.PcItem("page="),
+			readline.PcItem("pageSize="),
+		),
 		readline.PcItem("ip"),
 		readline.PcItem("disconnect"),
 		readline.PcItem("help"),
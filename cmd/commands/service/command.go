@@ -20,13 +20,21 @@ package service
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/mysteriumnetwork/node/cmd"
 	"github.com/mysteriumnetwork/node/cmd/commands/license"
 	"github.com/mysteriumnetwork/node/core/service"
+	"github.com/mysteriumnetwork/node/identity"
 	"github.com/mysteriumnetwork/node/metadata"
+	"github.com/mysteriumnetwork/node/nat"
+	service_noop "github.com/mysteriumnetwork/node/services/noop"
 	service_openvpn "github.com/mysteriumnetwork/node/services/openvpn"
 	openvpn_service "github.com/mysteriumnetwork/node/services/openvpn/service"
+	service_wireguard "github.com/mysteriumnetwork/node/services/wireguard"
+	wireguard_service "github.com/mysteriumnetwork/node/services/wireguard/service"
+	"github.com/mysteriumnetwork/node/session"
 	"github.com/urfave/cli"
 )
 
@@ -48,6 +56,46 @@ var (
 		Name:  "agreed-terms-and-conditions",
 		Usage: "Agree with terms & conditions",
 	}
+
+	sessionMaxFlag = cli.IntFlag{
+		Name:  "session.max",
+		Usage: "Maximum number of concurrent sessions served by node. Unlimited if 0",
+		Value: 0,
+	}
+	sessionMaxPerConsumerFlag = cli.IntFlag{
+		Name:  "session.max-per-consumer",
+		Usage: "Maximum number of concurrent sessions of single consumer. Unlimited if 0",
+		Value: 0,
+	}
+	sessionAllowFlag = cli.StringSliceFlag{
+		Name:  "session.allow",
+		Usage: "Consumer identity which is allowed to create sessions, can be repeated. All consumers are allowed if not given",
+	}
+	sessionDenyFlag = cli.StringSliceFlag{
+		Name:  "session.deny",
+		Usage: "Consumer identity which is not allowed to create sessions, can be repeated",
+	}
+	trafficAllowLocalFlag = cli.BoolFlag{
+		Name:  "traffic.allow-local",
+		Usage: "Allow consumers to reach private (RFC1918) and link-local networks of the provider",
+	}
+	trafficDenyFlag = cli.StringSliceFlag{
+		Name:  "traffic.deny",
+		Usage: "Destination network (CIDR) which consumers are not allowed to reach, can be repeated",
+	}
+	trafficDenyPortFlag = cli.IntSliceFlag{
+		Name:  "traffic.deny-port",
+		Usage: "Destination TCP and UDP port which consumers are not allowed to reach, can be repeated, i.e. 25",
+	}
+	trafficAllowFlag = cli.StringSliceFlag{
+		Name:  "traffic.allow",
+		Usage: "Destination network (CIDR) which consumers are allowed to reach, can be repeated. All networks are allowed if not given",
+	}
+	sessionMinBalanceFlag = cli.Uint64Flag{
+		Name:  "session.min-balance",
+		Usage: "Minimal consumer balance in the blockchain required to create session. Not checked if 0",
+		Value: 0,
+	}
 )
 
 // NewCommand function creates service command
@@ -56,14 +104,21 @@ func NewCommand(licenseCommandName string) *cli.Command {
 
 	command := &cli.Command{
 		Name:      serviceCommandName,
-		Usage:     "Starts and publishes service on Mysterium Network",
-		ArgsUsage: " ",
+		Usage:     "Starts and publishes services on Mysterium Network",
+		ArgsUsage: "[openvpn[:proto[:port]] | wireguard[:port] | noop]...",
+		Description: "Starts given services, openvpn service is started if none given. Options of services default to flag values, i.e.\n" +
+			"   myst service openvpn:udp openvpn:tcp:443 wireguard:51821 noop",
 		Action: func(ctx *cli.Context) error {
 			if !ctx.Bool(agreedTermsConditionsFlag.Name) {
 				printTermWarning(licenseCommandName)
 				os.Exit(2)
 			}
 
+			servicesOptions, err := parseServices(ctx)
+			if err != nil {
+				return err
+			}
+
 			errorChannel := make(chan error, 3)
 
 			if err := di.Bootstrap(cmd.ParseFlagsNode(ctx)); err != nil {
@@ -71,14 +126,16 @@ func NewCommand(licenseCommandName string) *cli.Command {
 			}
 			go func() { errorChannel <- di.Node.Wait() }()
 
-			if err := di.ServiceManager.Start(parseFlags(ctx)); err != nil {
-				return err
+			for _, serviceOptions := range servicesOptions {
+				if _, err := di.ServiceManager.Start(serviceOptions); err != nil {
+					return err
+				}
 			}
 			go func() { errorChannel <- di.ServiceManager.Wait() }()
 
 			cmd.RegisterSignalCallback(func() { errorChannel <- nil })
 
-			err := <-errorChannel
+			err = <-errorChannel
 			switch err {
 			case service.ErrorLocation:
 				printLocationWarning("myst")
@@ -101,18 +158,118 @@ func registerFlags(flags *[]cli.Flag) {
 	*flags = append(*flags,
 		agreedTermsConditionsFlag,
 		identityFlag, identityPassphraseFlag,
+		sessionMaxFlag, sessionMaxPerConsumerFlag, sessionAllowFlag, sessionDenyFlag, sessionMinBalanceFlag,
+		trafficAllowLocalFlag, trafficDenyFlag, trafficDenyPortFlag, trafficAllowFlag,
 	)
 	openvpn_service.RegisterFlags(flags)
+	wireguard_service.RegisterFlags(flags)
+}
+
+// parseServices function fills in options of every service given in CLI arguments
+func parseServices(ctx *cli.Context) ([]service.Options, error) {
+	args := ctx.Args()
+	if len(args) == 0 {
+		args = []string{service_openvpn.ServiceType}
+	}
+
+	trafficPolicy, err := parseTrafficPolicyFlags(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	servicesOptions := make([]service.Options, len(args))
+	for i, arg := range args {
+		serviceType, transportOptions, err := parseServiceArg(arg, openvpn_service.ParseFlags(ctx), wireguard_service.ParseFlags(ctx))
+		if err != nil {
+			return nil, err
+		}
+		servicesOptions[i] = service.Options{
+			Identity:      ctx.String(identityFlag.Name),
+			Passphrase:    ctx.String(identityPassphraseFlag.Name),
+			Type:          serviceType,
+			Options:       transportOptions,
+			Admission:     parseAdmissionFlags(ctx),
+			TrafficPolicy: trafficPolicy,
+		}
+	}
+	return servicesOptions, nil
+}
+
+// parseServiceArg parses service type and its transport options from "type[:option]..." argument
+func parseServiceArg(
+	arg string,
+	openvpnDefaults openvpn_service.Options,
+	wireguardDefaults wireguard_service.Options,
+) (string, service.TransportOptions, error) {
+	parts := strings.Split(arg, ":")
+	serviceType, params := parts[0], parts[1:]
+
+	switch serviceType {
+	case service_openvpn.ServiceType:
+		if len(params) > 2 {
+			return "", nil, fmt.Errorf("too many options for service: %s", arg)
+		}
+		options := openvpnDefaults
+		if len(params) > 0 {
+			options.OpenvpnProtocol = params[0]
+		}
+		if len(params) > 1 {
+			port, err := strconv.Atoi(params[1])
+			if err != nil {
+				return "", nil, fmt.Errorf("invalid port for service: %s", arg)
+			}
+			options.OpenvpnPort = port
+		}
+		return serviceType, options, nil
+	case service_wireguard.ServiceType:
+		if len(params) > 1 {
+			return "", nil, fmt.Errorf("too many options for service: %s", arg)
+		}
+		options := wireguardDefaults
+		if len(params) > 0 {
+			port, err := strconv.Atoi(params[0])
+			if err != nil {
+				return "", nil, fmt.Errorf("invalid port for service: %s", arg)
+			}
+			options.Port = port
+		}
+		return serviceType, options, nil
+	case service_noop.ServiceType:
+		if len(params) > 0 {
+			return "", nil, fmt.Errorf("too many options for service: %s", arg)
+		}
+		return serviceType, nil, nil
+	default:
+		return "", nil, fmt.Errorf("unsupported service type: %s", serviceType)
+	}
+}
+
+func parseAdmissionFlags(ctx *cli.Context) session.AdmissionPolicy {
+	return session.AdmissionPolicy{
+		MaxSessions:            ctx.Int(sessionMaxFlag.Name),
+		MaxSessionsPerConsumer: ctx.Int(sessionMaxPerConsumerFlag.Name),
+		AllowedConsumers:       parseIdentities(ctx.StringSlice(sessionAllowFlag.Name)),
+		DeniedConsumers:        parseIdentities(ctx.StringSlice(sessionDenyFlag.Name)),
+		MinBalance:             ctx.Uint64(sessionMinBalanceFlag.Name),
+	}
+}
+
+func parseTrafficPolicyFlags(ctx *cli.Context) (nat.Policy, error) {
+	policy := nat.Policy{
+		AllowLocalNetworks: ctx.Bool(trafficAllowLocalFlag.Name),
+		DeniedNetworks:     ctx.StringSlice(trafficDenyFlag.Name),
+		DeniedPorts:        ctx.IntSlice(trafficDenyPortFlag.Name),
+		AllowedNetworks:    ctx.StringSlice(trafficAllowFlag.Name),
+	}
+	return policy, policy.Validate()
 }
 
-// parseFlags function fills in node options from CLI context
-func parseFlags(ctx *cli.Context) service.Options {
-	return service.Options{
-		Identity:   ctx.String(identityFlag.Name),
-		Passphrase: ctx.String(identityPassphraseFlag.Name),
-		Type:       service_openvpn.ServiceType,
-		Options:    openvpn_service.ParseFlags(ctx),
+func parseIdentities(addresses []string) []identity.Identity {
+	identities := make([]identity.Identity, len(addresses))
+	for i, address := range addresses {
+		identities[i] = identity.FromAddress(address)
 	}
+	return identities
 }
 
 func printTermWarning(licenseCommandName string) {
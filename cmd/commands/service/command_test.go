@@ -0,0 +1,64 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package service
+
+import (
+	"errors"
+	"testing"
+
+	service_noop "github.com/mysteriumnetwork/node/services/noop"
+	service_openvpn "github.com/mysteriumnetwork/node/services/openvpn"
+	openvpn_service "github.com/mysteriumnetwork/node/services/openvpn/service"
+	service_wireguard "github.com/mysteriumnetwork/node/services/wireguard"
+	wireguard_service "github.com/mysteriumnetwork/node/services/wireguard/service"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	openvpnDefaults   = openvpn_service.Options{OpenvpnProtocol: "udp", OpenvpnPort: 1194}
+	wireguardDefaults = wireguard_service.Options{Port: 51820}
+)
+
+func TestParseServiceArg(t *testing.T) {
+	tests := []struct {
+		arg             string
+		expectedType    string
+		expectedOptions interface{}
+		expectedErr     error
+	}{
+		{"openvpn", service_openvpn.ServiceType, openvpnDefaults, nil},
+		{"openvpn:tcp", service_openvpn.ServiceType, openvpn_service.Options{OpenvpnProtocol: "tcp", OpenvpnPort: 1194}, nil},
+		{"openvpn:tcp:443", service_openvpn.ServiceType, openvpn_service.Options{OpenvpnProtocol: "tcp", OpenvpnPort: 443}, nil},
+		{"openvpn:tcp:https", "", nil, errors.New("invalid port for service: openvpn:tcp:https")},
+		{"openvpn:tcp:443:1", "", nil, errors.New("too many options for service: openvpn:tcp:443:1")},
+		{"noop", service_noop.ServiceType, nil, nil},
+		{"noop:udp", "", nil, errors.New("too many options for service: noop:udp")},
+		{"wireguard", service_wireguard.ServiceType, wireguardDefaults, nil},
+		{"wireguard:51821", service_wireguard.ServiceType, wireguard_service.Options{Port: 51821}, nil},
+		{"wireguard:udp", "", nil, errors.New("invalid port for service: wireguard:udp")},
+		{"wireguard:51821:1", "", nil, errors.New("too many options for service: wireguard:51821:1")},
+		{"ipsec", "", nil, errors.New("unsupported service type: ipsec")},
+	}
+
+	for _, test := range tests {
+		serviceType, options, err := parseServiceArg(test.arg, openvpnDefaults, wireguardDefaults)
+		assert.Equal(t, test.expectedErr, err, test.arg)
+		assert.Equal(t, test.expectedType, serviceType, test.arg)
+		assert.Equal(t, test.expectedOptions, options, test.arg)
+	}
+}
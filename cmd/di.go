@@ -18,6 +18,7 @@
 package cmd
 
 import (
+	"encoding/json"
 	"path/filepath"
 	"time"
 
@@ -31,19 +32,25 @@ import (
 	nats_dialog "github.com/mysteriumnetwork/node/communication/nats/dialog"
 	nats_discovery "github.com/mysteriumnetwork/node/communication/nats/discovery"
 	"github.com/mysteriumnetwork/node/core/connection"
+	"github.com/mysteriumnetwork/node/core/events"
 	"github.com/mysteriumnetwork/node/core/ip"
 	"github.com/mysteriumnetwork/node/core/location"
 	"github.com/mysteriumnetwork/node/core/node"
+	"github.com/mysteriumnetwork/node/core/promise"
+	"github.com/mysteriumnetwork/node/core/promise/clearing"
 	promise_noop "github.com/mysteriumnetwork/node/core/promise/methods/noop"
 	"github.com/mysteriumnetwork/node/core/service"
 	"github.com/mysteriumnetwork/node/core/storage"
 	"github.com/mysteriumnetwork/node/core/storage/boltdb"
 	"github.com/mysteriumnetwork/node/discovery"
+	"github.com/mysteriumnetwork/node/dns"
+	"github.com/mysteriumnetwork/node/firewall"
 	"github.com/mysteriumnetwork/node/identity"
 	identity_registry "github.com/mysteriumnetwork/node/identity/registry"
 	identity_selector "github.com/mysteriumnetwork/node/identity/selector"
 	"github.com/mysteriumnetwork/node/logconfig"
 	"github.com/mysteriumnetwork/node/metadata"
+	"github.com/mysteriumnetwork/node/nat"
 	"github.com/mysteriumnetwork/node/server"
 	"github.com/mysteriumnetwork/node/server/metrics"
 	"github.com/mysteriumnetwork/node/server/metrics/oracle"
@@ -51,12 +58,24 @@ import (
 	service_noop "github.com/mysteriumnetwork/node/services/noop"
 	service_openvpn "github.com/mysteriumnetwork/node/services/openvpn"
 	openvpn_service "github.com/mysteriumnetwork/node/services/openvpn/service"
+	service_wireguard "github.com/mysteriumnetwork/node/services/wireguard"
+	wireguard_service "github.com/mysteriumnetwork/node/services/wireguard/service"
 	"github.com/mysteriumnetwork/node/session"
 	"github.com/mysteriumnetwork/node/tequilapi"
 	tequilapi_endpoints "github.com/mysteriumnetwork/node/tequilapi/endpoints"
 	"github.com/mysteriumnetwork/node/utils"
 )
 
+// eventBusBufferSize defines how many events are buffered in the channel of subscriber, further events are queued
+const eventBusBufferSize = 100
+
+const (
+	// serviceSessionIdleTTL defines how long a provider session may stay unconnected before it is expired
+	serviceSessionIdleTTL = 5 * time.Minute
+	// serviceSessionExpiryInterval defines how often provider sessions are checked for expiry
+	serviceSessionExpiryInterval = 30 * time.Second
+)
+
 // Dependencies is DI container for top level components which is reusedin several places
 type Dependencies struct {
 	Node *node.Node
@@ -64,9 +83,13 @@ type Dependencies struct {
 	NetworkDefinition    metadata.NetworkDefinition
 	MysteriumClient      server.Client
 	MysteriumMorqaClient metrics.QualityOracle
+	ProposalCache        *server.ProposalCache
 	EtherClient          *ethclient.Client
 
 	Storage              storage.Storage
+	PromiseLedger        *promise.Ledger
+	PromiseIssueHistory  *promise.IssueHistory
+	PromiseClearer       *clearing.Clearer
 	Keystore             *keystore.KeyStore
 	IdentityManager      identity.Manager
 	SignerFactory        identity.SignerFactory
@@ -80,12 +103,20 @@ type Dependencies struct {
 
 	StatsKeeper stats.SessionStatsKeeper
 
+	EventBus *events.Bus
+
 	ConnectionManager  connection.Manager
+	ConnectionPool     *connection.Pool
 	ConnectionRegistry *connection.Registry
+	DNSConfigurator    dns.Configurator
 
 	ServiceManager        *service.Manager
 	ServiceRegistry       *service.Registry
 	ServiceSessionStorage *session.StorageMemory
+	NATService            nat.NATService
+	SubnetAllocator       *ip.SubnetAllocator
+
+	stopSessionExpiry func()
 }
 
 // Bootstrap initiates all container dependencies
@@ -93,6 +124,7 @@ func (di *Dependencies) Bootstrap(nodeOptions node.Options) error {
 	logconfig.Bootstrap()
 	nats_discovery.Bootstrap()
 	service_openvpn.Bootstrap()
+	service_wireguard.Bootstrap()
 
 	log.Infof("Starting Mysterium Node (%s)", metadata.VersionAsString())
 
@@ -112,11 +144,23 @@ func (di *Dependencies) Bootstrap(nodeOptions node.Options) error {
 		return err
 	}
 
+	if err := di.bootstrapNAT(nodeOptions); err != nil {
+		return err
+	}
+
+	di.EventBus = events.NewBus(eventBusBufferSize, stats.SessionStatsEventTopic)
+	di.ServiceSessionStorage = session.NewStorageMemory(di.EventBus)
+	di.stopSessionExpiry = di.ServiceSessionStorage.ExpireIdle(serviceSessionIdleTTL, serviceSessionExpiryInterval)
 	di.bootstrapIdentityComponents(nodeOptions.Directories)
+	if err := di.bootstrapPromiseClearing(nodeOptions.OptionsNetwork); err != nil {
+		return err
+	}
 	di.bootstrapLocationComponents(nodeOptions.Location, nodeOptions.Directories.Config)
-	di.bootstrapNodeComponents(nodeOptions)
 	di.bootstrapServiceComponents(nodeOptions)
+	di.bootstrapNodeComponents(nodeOptions)
+	di.bootstrapDNSConfigurator(nodeOptions.Directories)
 	di.bootstrapServiceOpenvpn(nodeOptions)
+	di.bootstrapServiceWireguard()
 	di.bootstrapServiceNoop(nodeOptions)
 
 	if err := di.Node.Start(); err != nil {
@@ -143,6 +187,18 @@ func (di *Dependencies) Shutdown() (err error) {
 			errs = append(errs, err)
 		}
 	}
+	if di.NATService != nil {
+		di.NATService.Disable()
+	}
+	if di.stopSessionExpiry != nil {
+		di.stopSessionExpiry()
+	}
+	if di.PromiseClearer != nil {
+		di.PromiseClearer.Stop()
+	}
+	if di.ProposalCache != nil {
+		di.ProposalCache.Stop()
+	}
 	if di.Node != nil {
 		if err := di.Node.Kill(); err != nil {
 			errs = append(errs, err)
@@ -158,12 +214,32 @@ func (di *Dependencies) Shutdown() (err error) {
 	return nil
 }
 
+// bootstrapNAT creates NAT service and subnet allocator shared by all services, removes NAT rules left if node crashed previously
+func (di *Dependencies) bootstrapNAT(nodeOptions node.Options) (err error) {
+	di.NATService, err = nat.NewService(nat.Options{
+		Backend:    nodeOptions.NATBackend,
+		RuntimeDir: nodeOptions.Directories.Runtime,
+	})
+	if err != nil {
+		return err
+	}
+
+	di.SubnetAllocator = ip.NewSubnetAllocator()
+
+	if err := di.NATService.ClearStale(); err != nil {
+		log.Warn("Failed to clear stale NAT rules: ", err)
+	}
+	return nil
+}
+
 func (di *Dependencies) bootstrapStorage(path string) error {
 	localStorage, err := boltdb.NewStorage(path)
 	if err != nil {
 		return err
 	}
 	di.Storage = localStorage
+	di.PromiseLedger = promise.NewLedger(localStorage)
+	di.PromiseIssueHistory = promise.NewIssueHistory(localStorage)
 	return nil
 }
 
@@ -173,32 +249,67 @@ func (di *Dependencies) bootstrapNodeComponents(nodeOptions node.Options) {
 		return dialogEstablisher.EstablishDialog(providerID, contact)
 	}
 
-	promiseIssuerFactory := func(issuerID identity.Identity, dialog communication.Dialog) connection.PromiseIssuer {
+	promiseIssuerFactory := func(issuerID identity.Identity, dialog communication.Dialog, statsKeeper stats.SessionStatsKeeper) connection.PromiseIssuer {
 		if nodeOptions.ExperimentPromiseCheck {
 			return &promise_noop.FakePromiseEngine{}
 		}
-		return promise_noop.NewPromiseIssuer(issuerID, dialog, di.SignerFactory(issuerID))
+		return promise_noop.NewPromiseIssuer(issuerID, dialog, di.SignerFactory(issuerID), statsKeeper, di.PromiseIssueHistory)
+	}
+
+	killSwitch := firewall.NewKillSwitch()
+	// rules could be left behind if node was not shut down gracefully while kill switch was enabled
+	if err := killSwitch.Disable(); err != nil {
+		log.Warn("Failed to clean up stale kill switch rules: ", err)
+	}
+
+	di.ProposalCache = server.NewProposalCache(di.MysteriumClient, di.Storage, nodeOptions.ProposalsCacheTTL)
+	if nodeOptions.ProposalsRefreshInterval > 0 {
+		if err := di.ProposalCache.Start(nodeOptions.ProposalsRefreshInterval); err != nil {
+			log.Warn("Failed to start refresh of cached proposals: ", err)
+		}
 	}
 
 	sessionStorage := connection.NewSessionStorage(di.Storage)
 	di.StatsKeeper = stats.NewSessionStatsKeeper(time.Now)
 	di.ConnectionRegistry = connection.NewRegistry()
-	di.ConnectionManager = connection.NewManager(
-		di.MysteriumClient,
-		dialogFactory,
-		promiseIssuerFactory,
-		di.ConnectionRegistry.CreateConnection,
+	newConnectionManager := func(statsKeeper stats.SessionStatsKeeper) connection.Manager {
+		return connection.NewManager(
+			di.ProposalCache,
+			dialogFactory,
+			promiseIssuerFactory,
+			di.ConnectionRegistry.CreateConnection,
+			statsKeeper,
+			sessionStorage,
+			killSwitch,
+			di.EventBus,
+		)
+	}
+	di.ConnectionManager = newConnectionManager(di.StatsKeeper)
+	di.ConnectionPool = connection.NewPool(
+		di.ConnectionManager,
 		di.StatsKeeper,
-		sessionStorage,
+		func() (connection.Manager, stats.SessionStatsKeeper) {
+			statsKeeper := stats.NewSessionStatsKeeper(time.Now)
+			return newConnectionManager(statsKeeper), statsKeeper
+		},
 	)
 
+	providerSelector := connection.NewSelector(di.ProposalCache, di.MysteriumMorqaClient, connection.NewDialogProber(dialogFactory))
+	autoConnector := connection.NewAutoConnector(di.ConnectionManager, providerSelector)
+
 	router := tequilapi.NewAPIRouter()
 	tequilapi_endpoints.AddRouteForStop(router, utils.SoftKiller(di.Shutdown))
 	tequilapi_endpoints.AddRoutesForIdentities(router, di.IdentityManager, di.MysteriumClient, di.SignerFactory)
-	tequilapi_endpoints.AddRoutesForConnection(router, di.ConnectionManager, di.IPResolver, di.StatsKeeper)
+	tequilapi_endpoints.AddRoutesForConnection(router, di.ConnectionManager, di.IPResolver, di.StatsKeeper, autoConnector)
+	tequilapi_endpoints.AddRoutesForConnections(router, di.ConnectionPool)
 	tequilapi_endpoints.AddRoutesForLocation(router, di.ConnectionManager, di.LocationDetector, di.LocationOriginal)
-	tequilapi_endpoints.AddRoutesForProposals(router, di.MysteriumClient, di.MysteriumMorqaClient)
+	tequilapi_endpoints.AddRoutesForProposals(router, di.ProposalCache, di.MysteriumMorqaClient)
 	tequilapi_endpoints.AddRoutesForSession(router, sessionStorage)
+	tequilapi_endpoints.AddRoutesForEvents(router, di.EventBus)
+	tequilapi_endpoints.AddRoutesForPromises(router, di.PromiseLedger)
+	tequilapi_endpoints.AddRoutesForClearing(router, di.PromiseClearer)
+	tequilapi_endpoints.AddRoutesForServiceSessions(router, di.ServiceSessionStorage)
+	tequilapi_endpoints.AddRoutesForServices(router, di.ServiceManager, di.ServiceRegistry)
 	identity_registry.AddIdentityRegistrationEndpoint(router, di.IdentityRegistration, di.IdentityRegistry)
 
 	httpAPIServer := tequilapi.NewServer(nodeOptions.TequilapiAddress, nodeOptions.TequilapiPort, router)
@@ -206,12 +317,34 @@ func (di *Dependencies) bootstrapNodeComponents(nodeOptions node.Options) {
 	di.Node = node.NewNode(di.ConnectionManager, httpAPIServer, di.LocationOriginal)
 }
 
+// bootstrapDNSConfigurator creates DNS configurator shared by connections of all service types
+func (di *Dependencies) bootstrapDNSConfigurator(directories node.OptionsDirectory) {
+	di.DNSConfigurator = dns.NewConfigurator(directories.Runtime)
+	// resolvers could be left behind if node was not shut down gracefully while connected
+	if err := di.DNSConfigurator.Clean(); err != nil {
+		log.Warn("Failed to restore DNS of the system: ", err)
+	}
+}
+
 func (di *Dependencies) bootstrapServiceOpenvpn(nodeOptions node.Options) {
 	createService := func(serviceOptions service.Options) (service.Service, error) {
 		transportOptions := serviceOptions.Options.(openvpn_service.Options)
-		return openvpn_service.NewManager(nodeOptions, transportOptions, di.IPResolver, di.LocationResolver, di.ServiceSessionStorage), nil
+		return openvpn_service.NewManager(
+			nodeOptions,
+			transportOptions,
+			serviceOptions.TrafficPolicy,
+			di.IPResolver,
+			di.LocationResolver,
+			di.ServiceSessionStorage,
+			di.NATService,
+			di.SubnetAllocator,
+			di.SignerFactory,
+		), nil
 	}
 	di.ServiceRegistry.Register(service_openvpn.ServiceType, createService)
+	di.ServiceRegistry.RegisterOptionsParser(service_openvpn.ServiceType, func(request *json.RawMessage) (service.TransportOptions, error) {
+		return openvpn_service.ParseJSONOptions(request)
+	})
 
 	connectionFactory := service_openvpn.NewProcessBasedConnectionFactory(
 		di.MysteriumClient,
@@ -219,17 +352,40 @@ func (di *Dependencies) bootstrapServiceOpenvpn(nodeOptions node.Options) {
 		nodeOptions.Openvpn.BinaryPath(),
 		nodeOptions.Directories.Config,
 		nodeOptions.Directories.Runtime,
-		di.StatsKeeper,
 		di.LocationOriginal,
 		di.SignerFactory,
+		di.EventBus,
+		di.DNSConfigurator,
 	)
 	di.ConnectionRegistry.Register(service_openvpn.ServiceType, connectionFactory.CreateConnection)
 }
 
+func (di *Dependencies) bootstrapServiceWireguard() {
+	di.ServiceRegistry.Register(service_wireguard.ServiceType, func(serviceOptions service.Options) (service.Service, error) {
+		transportOptions := serviceOptions.Options.(wireguard_service.Options)
+		return wireguard_service.NewManager(
+			transportOptions,
+			serviceOptions.TrafficPolicy,
+			di.IPResolver,
+			di.LocationResolver,
+			di.ServiceSessionStorage,
+			di.NATService,
+			di.SubnetAllocator,
+		), nil
+	})
+	di.ServiceRegistry.RegisterOptionsParser(service_wireguard.ServiceType, func(request *json.RawMessage) (service.TransportOptions, error) {
+		return wireguard_service.ParseJSONOptions(request)
+	})
+	di.ConnectionRegistry.Register(service_wireguard.ServiceType, service_wireguard.NewConnectionCreator(di.EventBus, di.DNSConfigurator))
+}
+
 func (di *Dependencies) bootstrapServiceNoop(nodeOptions node.Options) {
 	di.ServiceRegistry.Register(service_noop.ServiceType, func(serviceOptions service.Options) (service.Service, error) {
 		return service_noop.NewManager(), nil
 	})
+	di.ServiceRegistry.RegisterOptionsParser(service_noop.ServiceType, func(request *json.RawMessage) (service.TransportOptions, error) {
+		return nil, nil
+	})
 	di.ConnectionRegistry.Register(service_noop.ServiceType, service_noop.NewConnectionCreator())
 }
 
@@ -242,34 +398,49 @@ func (di *Dependencies) bootstrapServiceComponents(nodeOptions node.Options) {
 		di.SignerFactory,
 	)
 
-	discoveryService := discovery.NewService(di.IdentityRegistry, di.IdentityRegistration, di.MysteriumClient, di.SignerFactory)
+	newDiscovery := func() service.Discovery {
+		return discovery.NewService(di.IdentityRegistry, di.IdentityRegistration, di.MysteriumClient, di.SignerFactory, di.EventBus)
+	}
 
-	newDialogWaiter := func(providerID identity.Identity) communication.DialogWaiter {
+	newDialogWaiter := func(providerID identity.Identity, serviceID int) communication.DialogWaiter {
 		return nats_dialog.NewDialogWaiter(
-			nats_discovery.NewAddressGenerate(di.NetworkDefinition.BrokerAddress, providerID),
+			nats_discovery.NewAddressGenerateForService(di.NetworkDefinition.BrokerAddress, providerID, serviceID),
 			di.SignerFactory(providerID),
 			di.IdentityRegistry,
 		)
 	}
-	newDialogHandler := func(proposal dto_discovery.ServiceProposal, configProvider session.ConfigProvider) communication.DialogHandler {
-		promiseHandler := func(dialog communication.Dialog) session.PromiseProcessor {
-			if nodeOptions.ExperimentPromiseCheck {
-				return &promise_noop.FakePromiseEngine{}
+	newDialogHandler := func(
+		proposal dto_discovery.ServiceProposal,
+		configProvider session.ConfigProvider,
+		admissionPolicy session.AdmissionPolicy,
+	) communication.DialogHandler {
+		promiseHandler := func(dialog communication.Dialog) session.PromiseProcessorFactory {
+			router := promise.NewConsumerRouter(dialog)
+			return func() session.PromiseProcessor {
+				if nodeOptions.ExperimentPromiseCheck {
+					return &promise_noop.FakePromiseProcessor{}
+				}
+				return promise_noop.NewPromiseProcessor(
+					dialog,
+					router,
+					identity.NewBalance(di.EtherClient),
+					di.PromiseLedger,
+					di.ServiceSessionStorage,
+				)
 			}
-			return promise_noop.NewPromiseProcessor(dialog, identity.NewBalance(di.EtherClient), di.Storage)
 		}
-		sessionManagerFactory := newSessionManagerFactory(proposal, configProvider, di.ServiceSessionStorage, promiseHandler)
+		admission := session.NewAdmission(admissionPolicy, identity.NewBalance(di.EtherClient))
+		sessionManagerFactory := newSessionManagerFactory(proposal, configProvider, di.ServiceSessionStorage, admission, promiseHandler)
 		return session.NewDialogHandler(sessionManagerFactory)
 	}
 
 	di.ServiceRegistry = service.NewRegistry()
-	di.ServiceSessionStorage = session.NewStorageMemory()
 	di.ServiceManager = service.NewManager(
 		identityHandler,
 		di.ServiceRegistry.Create,
 		newDialogWaiter,
 		newDialogHandler,
-		discoveryService,
+		newDiscovery,
 	)
 }
 
@@ -277,14 +448,16 @@ func newSessionManagerFactory(
 	proposal dto_discovery.ServiceProposal,
 	configProvider session.ConfigProvider,
 	sessionStorage *session.StorageMemory,
-	promiseHandler func(dialog communication.Dialog) session.PromiseProcessor,
+	admission session.Admission,
+	promiseHandler func(dialog communication.Dialog) session.PromiseProcessorFactory,
 ) session.ManagerFactory {
 	return func(dialog communication.Dialog) session.Manager {
 		return session.NewManager(
 			proposal,
 			session.GenerateUUID,
 			configProvider,
-			sessionStorage.Add,
+			sessionStorage,
+			admission,
 			promiseHandler(dialog),
 		)
 	}
@@ -340,6 +513,20 @@ func (di *Dependencies) bootstrapNetworkComponents(options node.OptionsNetwork)
 	return nil
 }
 
+func (di *Dependencies) bootstrapPromiseClearing(options node.OptionsNetwork) error {
+	transactor, err := clearing.NewContractTransactor(di.EtherClient, di.NetworkDefinition.PaymentsContractAddress, di.Keystore)
+	if err != nil {
+		return err
+	}
+
+	di.PromiseClearer = clearing.NewClearer(di.PromiseLedger, transactor, di.Storage)
+	if options.PromiseClearingInterval > 0 {
+		log.Info("Clearing promises every ", options.PromiseClearingInterval)
+		return di.PromiseClearer.Start(options.PromiseClearingInterval)
+	}
+	return nil
+}
+
 func (di *Dependencies) bootstrapIdentityComponents(directories node.OptionsDirectory) {
 	di.Keystore = identity.NewKeystoreFilesystem(directories.Keystore)
 	di.IdentityManager = identity.NewIdentityManager(di.Keystore)
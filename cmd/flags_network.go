@@ -18,6 +18,8 @@
 package cmd
 
 import (
+	"time"
+
 	"github.com/mysteriumnetwork/node/core/node"
 	"github.com/mysteriumnetwork/node/metadata"
 	"github.com/urfave/cli"
@@ -53,6 +55,16 @@ var (
 		Usage: "Address (IP or domain name) of message broker",
 		Value: metadata.DefaultNetwork.BrokerAddress,
 	}
+	proposalsCacheTTLFlag = cli.DurationFlag{
+		Name:  "discovery.cache-ttl",
+		Usage: "How long proposals fetched from discovery are kept in local cache (0 disables caching)",
+		Value: time.Hour,
+	}
+	proposalsRefreshIntervalFlag = cli.DurationFlag{
+		Name:  "discovery.refresh-interval",
+		Usage: "Interval of background refresh of cached proposals (0 disables it)",
+		Value: 5 * time.Minute,
+	}
 
 	etherRpcFlag = cli.StringFlag{
 		Name:  "ether.client.rpc",
@@ -64,6 +76,11 @@ var (
 		Usage: "Address of payments contract",
 		Value: metadata.DefaultNetwork.PaymentsContractAddress.String(),
 	}
+	promiseClearingIntervalFlag = cli.DurationFlag{
+		Name:  "ether.clearing.interval",
+		Usage: "Interval of automatic clearing of received promises in payments contract (0 disables it)",
+		Value: 0,
+	}
 
 	qualityOracleFlag = cli.StringFlag{
 		Name:  "quality-oracle.address",
@@ -80,7 +97,8 @@ func RegisterFlagsNetwork(flags *[]cli.Flag) {
 		identityCheckFlag,
 		promiseCheckFlag,
 		discoveryAddressFlag, brokerAddressFlag,
-		etherRpcFlag, etherContractPaymentsFlag,
+		proposalsCacheTTLFlag, proposalsRefreshIntervalFlag,
+		etherRpcFlag, etherContractPaymentsFlag, promiseClearingIntervalFlag,
 		qualityOracleFlag,
 	)
 }
@@ -96,9 +114,12 @@ func ParseFlagsNetwork(ctx *cli.Context) node.OptionsNetwork {
 
 		ctx.GlobalString(discoveryAddressFlag.Name),
 		ctx.GlobalString(brokerAddressFlag.Name),
+		ctx.GlobalDuration(proposalsCacheTTLFlag.Name),
+		ctx.GlobalDuration(proposalsRefreshIntervalFlag.Name),
 
 		ctx.GlobalString(etherRpcFlag.Name),
 		ctx.GlobalString(etherContractPaymentsFlag.Name),
+		ctx.GlobalDuration(promiseClearingIntervalFlag.Name),
 
 		ctx.GlobalString(qualityOracleFlag.Name),
 	}
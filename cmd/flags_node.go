@@ -20,6 +20,7 @@ package cmd
 import (
 	openvpn_core "github.com/mysteriumnetwork/go-openvpn/openvpn/core"
 	"github.com/mysteriumnetwork/node/core/node"
+	"github.com/mysteriumnetwork/node/nat"
 	"github.com/urfave/cli"
 )
 
@@ -34,6 +35,11 @@ var (
 		Usage: "Port for listening incoming api requests",
 		Value: 4050,
 	}
+	natBackendFlag = cli.StringFlag{
+		Name:  "nat.backend",
+		Usage: "Packet filtering framework used to forward traffic of services. Options: { auto, iptables, nftables }",
+		Value: nat.BackendAuto,
+	}
 )
 
 // RegisterFlagsNode function register node flags to flag list
@@ -42,7 +48,7 @@ func RegisterFlagsNode(flags *[]cli.Flag) error {
 		return err
 	}
 
-	*flags = append(*flags, tequilapiAddressFlag, tequilapiPortFlag)
+	*flags = append(*flags, tequilapiAddressFlag, tequilapiPortFlag, natBackendFlag)
 
 	RegisterFlagsNetwork(flags)
 	openvpn_core.RegisterFlags(flags)
@@ -61,6 +67,7 @@ func ParseFlagsNode(ctx *cli.Context) node.Options {
 
 		wrapper{nodeOptions: openvpn_core.ParseFlags(ctx)},
 		ParseFlagsLocation(ctx),
+		ctx.GlobalString(natBackendFlag.Name),
 		ParseFlagsNetwork(ctx),
 	}
 }
@@ -18,14 +18,24 @@
 package dialog
 
 import (
+	"net"
+	"net/url"
+	"strconv"
+
+	log "github.com/cihub/seelog"
 	"github.com/mysteriumnetwork/node/communication"
+	"github.com/mysteriumnetwork/node/communication/nats/discovery"
+	"github.com/mysteriumnetwork/node/firewall"
 	"github.com/mysteriumnetwork/node/identity"
 )
 
+const dialogLogPrefix = "[NATS.Dialog] "
+
 type dialog struct {
 	communication.Sender
 	communication.Receiver
-	peerID identity.Identity
+	peerID      identity.Identity
+	peerServers []string
 }
 
 func (dialog *dialog) Close() error {
@@ -35,3 +45,36 @@ func (dialog *dialog) Close() error {
 func (dialog *dialog) PeerID() identity.Identity {
 	return dialog.peerID
 }
+
+// PeerAddresses resolves IPv4 addresses of brokers, through which dialog reaches the peer
+func (dialog *dialog) PeerAddresses() []firewall.ServerAddress {
+	var addresses []firewall.ServerAddress
+	for _, server := range dialog.peerServers {
+		serverURL, err := url.Parse(server)
+		if err != nil {
+			log.Warn(dialogLogPrefix, "Failed to parse broker address ", server, ": ", err)
+			continue
+		}
+
+		port := discovery.BrokerPort
+		if serverURL.Port() != "" {
+			if port, err = strconv.Atoi(serverURL.Port()); err != nil {
+				log.Warn(dialogLogPrefix, "Invalid broker port ", server, ": ", err)
+				continue
+			}
+		}
+
+		ips, err := net.LookupIP(serverURL.Hostname())
+		if err != nil {
+			log.Warn(dialogLogPrefix, "Failed to resolve broker ", server, ": ", err)
+			continue
+		}
+		for _, ip := range ips {
+			if ip.To4() == nil {
+				continue
+			}
+			addresses = append(addresses, firewall.ServerAddress{IP: ip.String(), Port: port, Protocol: "tcp"})
+		}
+	}
+	return addresses
+}
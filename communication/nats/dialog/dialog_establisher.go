@@ -123,8 +123,9 @@ func (establisher *dialogEstablisher) newDialogToPeer(
 
 	subTopic := peerAddress.GetTopic() + "." + establisher.ID.Address
 	return &dialog{
-		peerID:   peerID,
-		Sender:   nats.NewSender(peerAddress.GetConnection(), peerCodec, subTopic),
-		Receiver: nats.NewReceiver(peerAddress.GetConnection(), peerCodec, subTopic),
+		peerID:      peerID,
+		peerServers: peerAddress.GetServers(),
+		Sender:      nats.NewSender(peerAddress.GetConnection(), peerCodec, subTopic),
+		Receiver:    nats.NewReceiver(peerAddress.GetConnection(), peerCodec, subTopic),
 	}
 }
@@ -0,0 +1,40 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package dialog
+
+import (
+	"testing"
+
+	"github.com/mysteriumnetwork/node/firewall"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDialog_PeerAddresses(t *testing.T) {
+	dialog := &dialog{
+		peerServers: []string{"nats://127.0.0.1:4223", "nats://127.0.0.2", "nats://127.0.0.3:invalid"},
+	}
+
+	assert.Equal(
+		t,
+		[]firewall.ServerAddress{
+			{IP: "127.0.0.1", Port: 4223, Protocol: "tcp"},
+			{IP: "127.0.0.2", Port: 4222, Protocol: "tcp"},
+		},
+		dialog.PeerAddresses(),
+	)
+}
@@ -41,6 +41,13 @@ func NewAddressGenerate(brokerIP string, myID identity.Identity) *AddressNATS {
 	return NewAddress(myID.Address, address)
 }
 
+// NewAddressGenerateForService generates NATS address for the service of current node
+func NewAddressGenerateForService(brokerIP string, myID identity.Identity, serviceID int) *AddressNATS {
+	address := fmt.Sprintf("nats://%s:%d", brokerIP, BrokerPort)
+
+	return NewAddress(fmt.Sprintf("%s.%d", myID.Address, serviceID), address)
+}
+
 // NewAddressForContact extracts NATS address from given contact structure
 func NewAddressForContact(contact dto_discovery.Contact) (*AddressNATS, error) {
 	if contact.Type != TypeContactNATSV1 {
@@ -102,6 +109,11 @@ func (address *AddressNATS) GetConnection() nats.Connection {
 	return address.connection
 }
 
+// GetServers returns URLs of brokers, which address connects to
+func (address *AddressNATS) GetServers() []string {
+	return address.servers
+}
+
 // GetTopic returns topic.
 // Address points to this topic in established connection.
 func (address *AddressNATS) GetTopic() string {
@@ -54,6 +54,21 @@ func TestNewAddressGenerate(t *testing.T) {
 	)
 }
 
+func TestNewAddressGenerateForService(t *testing.T) {
+	myID := identity.FromAddress("provider1")
+	brokerIP := "127.0.0.1"
+	address := NewAddressGenerateForService(brokerIP, myID, 2)
+
+	assert.Equal(
+		t,
+		&AddressNATS{
+			servers: []string{"nats://" + brokerIP + ":4222"},
+			topic:   "provider1.2",
+		},
+		address,
+	)
+}
+
 func TestNewAddressForContact(t *testing.T) {
 	address, err := NewAddressForContact(dto_discovery.Contact{
 		Type: "nats/v1",
@@ -18,6 +18,9 @@
 package connection
 
 import (
+	"time"
+
+	"github.com/mysteriumnetwork/node/client/stats"
 	"github.com/mysteriumnetwork/node/identity"
 	dto_discovery "github.com/mysteriumnetwork/node/service_discovery/dto"
 	"github.com/mysteriumnetwork/node/session"
@@ -25,8 +28,45 @@ import (
 
 // ConnectParams holds plugin specific params
 type ConnectParams struct {
+	// ProposalID selects proposal of the provider to connect to, provider's first proposal is used when it is not set
+	ProposalID int
+	// ServiceType limits proposals of the provider to the ones of given service type
+	ServiceType string
 	// kill switch option restricting communication only through VPN
 	DisableKillSwitch bool
+	// Reconnect defines how connection is re-established after it drops unexpectedly
+	Reconnect ReconnectParams
+	// DNS are resolvers used instead of resolvers pushed by provider
+	DNS []string
+	// SplitTunnel limits traffic routed through VPN, all traffic is routed when it is empty
+	SplitTunnel SplitTunnelParams
+}
+
+// SplitTunnelParams defines which traffic is routed through VPN
+type SplitTunnelParams struct {
+	// IncludeCIDRs are the only networks routed through VPN
+	IncludeCIDRs []string
+	// ExcludeCIDRs are networks, which traffic bypasses VPN
+	ExcludeCIDRs []string
+	// IncludeDomains are the only domains routed through VPN, they are resolved periodically while connected
+	IncludeDomains []string
+	// ExcludeDomains are domains, which traffic bypasses VPN, they are resolved periodically while connected
+	ExcludeDomains []string
+}
+
+// Enabled tells if only part of traffic is routed through VPN
+func (params SplitTunnelParams) Enabled() bool {
+	return len(params.IncludeCIDRs)+len(params.ExcludeCIDRs)+len(params.IncludeDomains)+len(params.ExcludeDomains) > 0
+}
+
+// ReconnectParams holds automatic reconnection policy
+type ReconnectParams struct {
+	// MaxAttempts is the number of reconnection attempts, zero disables reconnection
+	MaxAttempts int
+	// Backoff is the delay before first reconnection attempt, it doubles after each failed attempt
+	Backoff time.Duration
+	// NewSession requests new session from provider instead of reusing the dropped one
+	NewSession bool
 }
 
 // ConnectOptions represents the params we need to ensure a successful connection
@@ -36,4 +76,8 @@ type ConnectOptions struct {
 	Proposal      dto_discovery.ServiceProposal
 	SessionID     session.ID
 	SessionConfig []byte
+	// StatsKeeper collects statistics of this connection
+	StatsKeeper stats.SessionStatsKeeper
+	// Params are preferences of consumer for this connection
+	Params ConnectParams
 }
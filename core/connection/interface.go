@@ -18,9 +18,12 @@
 package connection
 
 import (
+	"github.com/mysteriumnetwork/node/client/stats"
 	"github.com/mysteriumnetwork/node/communication"
+	"github.com/mysteriumnetwork/node/firewall"
 	"github.com/mysteriumnetwork/node/identity"
 	"github.com/mysteriumnetwork/node/service_discovery/dto"
+	"github.com/mysteriumnetwork/node/session"
 )
 
 // DialogCreator creates new dialog between consumer and provider, using given contact information
@@ -33,18 +36,35 @@ type Connection interface {
 	Stop()
 }
 
+// ServerAddressProvider is implemented by connections which know the address of remote VPN server.
+// Kill switch keeps this address reachable while all other traffic outside the tunnel is dropped.
+type ServerAddressProvider interface {
+	ServerAddress() firewall.ServerAddress
+}
+
+// PeerAddressProvider is implemented by dialogs which know network addresses used to reach the provider.
+// Kill switch keeps these addresses reachable while connection to the provider is re-established.
+type PeerAddressProvider interface {
+	PeerAddresses() []firewall.ServerAddress
+}
+
+// DNSProvider is implemented by connections which take over DNS of the system while connected
+type DNSProvider interface {
+	DNS() []string
+}
+
 // StateChannel is the channel we receive state change events on
 type StateChannel chan State
 
-// PromiseIssuer issues promises from consumer to provider.
+// PromiseIssuer issues promises from consumer to provider for the session.
 // Consumer signs those promises.
 type PromiseIssuer interface {
-	Start(proposal dto.ServiceProposal) error
+	Start(proposal dto.ServiceProposal, sessionID session.ID) error
 	Stop() error
 }
 
 // PromiseIssuerCreator creates new PromiseIssuer given context
-type PromiseIssuerCreator func(issuerID identity.Identity, dialog communication.Dialog) PromiseIssuer
+type PromiseIssuerCreator func(issuerID identity.Identity, dialog communication.Dialog, statsKeeper stats.SessionStatsKeeper) PromiseIssuer
 
 // Manager interface provides methods to manage connection
 type Manager interface {
@@ -20,12 +20,14 @@ package connection
 import (
 	"context"
 	"errors"
+	"fmt"
 	"sync"
 	"time"
 
 	log "github.com/cihub/seelog"
 	"github.com/mysteriumnetwork/node/client/stats"
 	"github.com/mysteriumnetwork/node/communication"
+	"github.com/mysteriumnetwork/node/core/events"
 	"github.com/mysteriumnetwork/node/firewall"
 	"github.com/mysteriumnetwork/node/identity"
 	"github.com/mysteriumnetwork/node/server"
@@ -33,7 +35,12 @@ import (
 	"github.com/mysteriumnetwork/node/session"
 )
 
-const managerLogPrefix = "[connection-manager] "
+const (
+	managerLogPrefix = "[connection-manager] "
+
+	// maxReconnectBackoff limits the delay between reconnection attempts, which doubles after each failed attempt
+	maxReconnectBackoff = 5 * time.Minute
+)
 
 var (
 	// ErrNoConnection error indicates that action applied to manager expects active connection (i.e. disconnect)
@@ -46,6 +53,8 @@ var (
 	ErrConnectionFailed = errors.New("connection has failed")
 	// ErrUnsupportedServiceType indicates that target proposal contains unsupported service type
 	ErrUnsupportedServiceType = errors.New("unsupported service type in proposal")
+	// ErrKillSwitchWithSplitTunnel indicates that kill switch would block traffic which split tunnelling routes outside VPN
+	ErrKillSwitchWithSplitTunnel = errors.New("kill switch can not be combined with split tunnelling")
 )
 
 // ConnectionCreator creates new vpn client by given session,
@@ -64,11 +73,22 @@ type connectionManager struct {
 	newConnection    ConnectionCreator
 	statsKeeper      stats.SessionStatsKeeper
 	sessionStorage   sessionStorageSave
+	killSwitch       firewall.KillSwitch
+	eventPublisher   events.Publisher
 	//these are populated by Connect at runtime
 	ctx             context.Context
+	cancelCtx       context.CancelFunc
 	mutex           sync.RWMutex
 	status          ConnectionStatus
 	cleanConnection func()
+	// dnsProvider reports resolvers of the system taken over by current connection
+	dnsProvider DNSProvider
+	// kill switch is kept enabled after unexpected connection exit, until Disconnect is called
+	killSwitchEnabled bool
+	killSwitchAddress firewall.ServerAddress
+	// providerAddresses are let through kill switch while connection is re-established
+	providerAddresses []firewall.ServerAddress
+	killSwitchLock    sync.Mutex
 }
 
 // NewManager creates connection manager with given dependencies
@@ -79,6 +99,8 @@ func NewManager(
 	connectionCreator ConnectionCreator,
 	statsKeeper stats.SessionStatsKeeper,
 	sessionStorage sessionStorageSave,
+	killSwitch firewall.KillSwitch,
+	eventPublisher events.Publisher,
 ) *connectionManager {
 	return &connectionManager{
 		statsKeeper:      statsKeeper,
@@ -89,6 +111,8 @@ func NewManager(
 		status:           statusNotConnected(),
 		cleanConnection:  warnOnClean,
 		sessionStorage:   sessionStorage,
+		killSwitch:       killSwitch,
+		eventPublisher:   eventPublisher,
 	}
 }
 
@@ -96,15 +120,24 @@ func (manager *connectionManager) Connect(consumerID, providerID identity.Identi
 	if manager.status.State != NotConnected {
 		return ErrAlreadyExists
 	}
+	if !params.DisableKillSwitch && params.SplitTunnel.Enabled() {
+		return ErrKillSwitchWithSplitTunnel
+	}
+
+	// kill switch left by previously crashed connection would block dialog with the provider
+	if err = manager.disableKillSwitch(); err != nil {
+		return err
+	}
 
 	manager.mutex.Lock()
-	manager.ctx, manager.cleanConnection = context.WithCancel(context.Background())
-	manager.status = statusConnecting()
+	manager.ctx, manager.cancelCtx = context.WithCancel(context.Background())
+	manager.cleanConnection = manager.cancelCtx
+	manager.setStatus(statusConnecting())
 	manager.mutex.Unlock()
 	defer func() {
 		if err != nil {
 			manager.mutex.Lock()
-			manager.status = statusNotConnected()
+			manager.setStatus(statusNotConnected())
 			manager.mutex.Unlock()
 		}
 	}()
@@ -121,14 +154,12 @@ func (manager *connectionManager) startConnection(consumerID, providerID identit
 	cancelCtx := manager.cleanConnection
 	manager.mutex.Unlock()
 
-	var cancel []func()
+	releaseConnection := func() {}
 	defer func() {
 		manager.cleanConnection = func() {
-			manager.status = statusDisconnecting()
+			manager.setStatus(statusDisconnecting())
 			cancelCtx()
-			for _, f := range cancel {
-				f()
-			}
+			releaseConnection()
 		}
 		if err != nil {
 			log.Info(managerLogPrefix, "Cancelling connection initiation")
@@ -136,68 +167,202 @@ func (manager *connectionManager) startConnection(consumerID, providerID identit
 		}
 	}()
 
-	proposal, err := manager.findProposalByProviderID(providerID)
+	proposal, err := manager.findProposal(providerID, params)
 	if err != nil {
 		return err
 	}
 
-	dialog, err := manager.newDialog(consumerID, providerID, proposal.ProviderContacts[0])
+	connectOptions := ConnectOptions{
+		ConsumerID:  consumerID,
+		ProviderID:  providerID,
+		Proposal:    proposal,
+		StatsKeeper: manager.statsKeeper,
+		Params:      params,
+	}
+	established, err := manager.establishConnection(connectOptions, params)
 	if err != nil {
 		return err
 	}
-	cancel = append(cancel, func() { dialog.Close() })
+	releaseConnection = established.release
+
+	go manager.consumeConnectionStates(established, params)
+	return nil
+}
 
-	sessionID, sessionConfig, err := session.RequestSessionCreate(dialog, proposal.ID)
+// establishedConnection is a connection which reached connected state, together with function releasing its resources
+type establishedConnection struct {
+	options      ConnectOptions
+	stateChannel StateChannel
+	release      func()
+}
+
+// establishConnection creates dialog, session, promise issuer and connection to provider and waits until connection is up.
+// New session is requested from provider only if options does not contain one already.
+// All created resources are released if connection is not established.
+func (manager *connectionManager) establishConnection(options ConnectOptions, params ConnectParams) (established establishedConnection, err error) {
+	var cancel []func()
+	release := func() {
+		for _, f := range cancel {
+			f()
+		}
+	}
+	defer func() {
+		if err != nil {
+			release()
+		}
+	}()
+
+	dialog, err := manager.newDialog(options.ConsumerID, options.ProviderID, options.Proposal.ProviderContacts[0])
 	if err != nil {
-		return err
+		return
 	}
+	cancel = append(cancel, func() { dialog.Close() })
 
-	promiseIssuer := manager.newPromiseIssuer(consumerID, dialog)
-	err = promiseIssuer.Start(proposal)
+	newSession := options.SessionID == ""
+	if newSession {
+		options.SessionID, options.SessionConfig, err = session.RequestSessionCreate(dialog, options.Proposal.ID)
+		if err != nil {
+			return
+		}
+		// session of the previous connection is kept, as following reconnection attempts may still use it
+		defer func() {
+			if err != nil {
+				destroySession(dialog, options.SessionID)
+			}
+		}()
+	}
+
+	promiseIssuer := manager.newPromiseIssuer(options.ConsumerID, dialog, options.StatsKeeper)
+	err = promiseIssuer.Start(options.Proposal, options.SessionID)
 	if err != nil {
-		return err
+		return
 	}
 	cancel = append(cancel, func() { promiseIssuer.Stop() })
 
 	stateChannel := make(chan State, 10)
 
-	connectOptions := ConnectOptions{
-		SessionID:     sessionID,
-		SessionConfig: sessionConfig,
-		ConsumerID:    consumerID,
-		ProviderID:    providerID,
-		Proposal:      proposal,
-	}
-
-	connection, err := manager.newConnection(connectOptions, stateChannel)
+	connection, err := manager.newConnection(options, stateChannel)
 	if err != nil {
-		return err
+		return
 	}
+	manager.setDNSProvider(connection)
 
-	err = manager.saveSession(connectOptions)
-	if err != nil {
-		return err
+	if newSession {
+		err = manager.saveSession(options)
+		if err != nil {
+			return
+		}
 	}
 
 	if err = connection.Start(); err != nil {
-		return err
+		return
 	}
 	cancel = append(cancel, connection.Stop)
 
-	err = manager.waitForConnectedState(stateChannel, sessionID)
+	err = manager.waitForConnectedState(stateChannel, options.SessionID)
 	if err != nil {
-		return err
+		return
 	}
 
 	if !params.DisableKillSwitch {
-		// TODO: Implement fw based kill switch for respective OS
-		// we may need to wait for tun device to bet setup
-		firewall.NewKillSwitch().Enable()
+		if err = manager.enableKillSwitch(connection, dialog); err != nil {
+			return
+		}
+		cancel = append(cancel, manager.releaseKillSwitch)
 	}
 
 	go connectionWaiter(connection, dialog, promiseIssuer)
-	go manager.consumeConnectionStates(stateChannel, sessionID)
-	return nil
+	releaseWithSession := func() {
+		destroySession(dialog, options.SessionID)
+		release()
+	}
+	return establishedConnection{options: options, stateChannel: stateChannel, release: releaseWithSession}, nil
+}
+
+// destroySession tells provider, that session will not be used anymore
+func destroySession(dialog communication.Dialog, sessionID session.ID) {
+	if err := session.RequestSessionDestroy(dialog, sessionID); err != nil {
+		log.Warn(managerLogPrefix, "Failed to destroy session: ", err)
+	}
+}
+
+// nextReconnectBackoff doubles delay before next reconnection attempt, keeping it below maxReconnectBackoff
+func nextReconnectBackoff(backoff time.Duration) time.Duration {
+	if backoff *= 2; backoff > maxReconnectBackoff {
+		return maxReconnectBackoff
+	}
+	return backoff
+}
+
+// reconnect re-establishes connection which exited unexpectedly, following reconnect policy from params.
+// Returns true if connection was re-established and its states are consumed by a new goroutine.
+func (manager *connectionManager) reconnect(options ConnectOptions, params ConnectParams) bool {
+	policy := params.Reconnect
+	manager.mutex.Lock()
+	ctx, cancelCtx := manager.ctx, manager.cancelCtx
+	if policy.MaxAttempts <= 0 || ctx.Err() != nil {
+		manager.mutex.Unlock()
+		return false
+	}
+	manager.setStatus(statusReconnecting())
+	// resources of exited connection are already released by connection waiter
+	manager.cleanConnection = func() {
+		manager.setStatus(statusDisconnecting())
+		cancelCtx()
+		manager.releaseKillSwitch()
+	}
+	manager.mutex.Unlock()
+
+	// kill switch stays engaged, only provider is let through to re-establish the connection
+	manager.allowProviderThroughKillSwitch()
+
+	backoff := policy.Backoff
+	for attempt := 1; attempt <= policy.MaxAttempts; attempt++ {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(backoff):
+		}
+		backoff = nextReconnectBackoff(backoff)
+
+		log.Info(managerLogPrefix, "Reconnecting, attempt ", attempt, " of ", policy.MaxAttempts)
+		if policy.NewSession {
+			options.SessionID, options.SessionConfig = "", nil
+		}
+		established, err := manager.establishConnection(options, params)
+		if err != nil && options.SessionID != "" && ctx.Err() == nil {
+			// provider may have already cleaned up the session of exited connection
+			log.Warn(managerLogPrefix, "Failed to reuse session ", options.SessionID, ", requesting a new one: ", err)
+			options.SessionID, options.SessionConfig = "", nil
+			established, err = manager.establishConnection(options, params)
+		}
+		if err != nil {
+			log.Warn(managerLogPrefix, "Reconnection attempt failed: ", err)
+			continue
+		}
+
+		manager.mutex.Lock()
+		if ctx.Err() != nil {
+			manager.mutex.Unlock()
+			established.release()
+			return false
+		}
+		manager.cleanConnection = func() {
+			manager.setStatus(statusDisconnecting())
+			cancelCtx()
+			established.release()
+		}
+		manager.mutex.Unlock()
+
+		go manager.consumeConnectionStates(established, params)
+		return true
+	}
+
+	log.Warn(managerLogPrefix, "Reconnection attempts exhausted, giving up")
+	if ctx.Err() == nil {
+		manager.restrictKillSwitchToServer()
+	}
+	return false
 }
 
 func (manager *connectionManager) Status() ConnectionStatus {
@@ -212,28 +377,133 @@ func (manager *connectionManager) Disconnect() error {
 	defer manager.mutex.RUnlock()
 
 	if manager.status.State == NotConnected {
+		if manager.KillSwitchEnabled() {
+			// connection exited by itself, but traffic is still blocked until user disconnects
+			return manager.disableKillSwitch()
+		}
 		return ErrNoConnection
 	}
 	manager.cleanConnection()
 	return nil
 }
 
+func (manager *connectionManager) enableKillSwitch(connection Connection, dialog communication.Dialog) error {
+	addressProvider, ok := connection.(ServerAddressProvider)
+	if !ok {
+		log.Warn(managerLogPrefix, "Connection does not expose VPN server address, kill switch is not enabled")
+		return nil
+	}
+
+	// provider addresses are resolved now, as name resolution is blocked once connection exits
+	var providerAddresses []firewall.ServerAddress
+	if peerAddressProvider, ok := dialog.(PeerAddressProvider); ok {
+		providerAddresses = peerAddressProvider.PeerAddresses()
+	}
+
+	return manager.engageKillSwitch(addressProvider.ServerAddress(), providerAddresses)
+}
+
+func (manager *connectionManager) engageKillSwitch(serverAddress firewall.ServerAddress, providerAddresses []firewall.ServerAddress) error {
+	manager.killSwitchLock.Lock()
+	defer manager.killSwitchLock.Unlock()
+
+	if err := manager.killSwitch.Enable(serverAddress); err != nil {
+		return err
+	}
+	manager.killSwitchEnabled = true
+	manager.killSwitchAddress = serverAddress
+	manager.providerAddresses = providerAddresses
+	return nil
+}
+
+// allowProviderThroughKillSwitch keeps kill switch enabled, but lets through traffic to the provider,
+// so that dialog with the provider can be re-established
+func (manager *connectionManager) allowProviderThroughKillSwitch() {
+	manager.killSwitchLock.Lock()
+	defer manager.killSwitchLock.Unlock()
+
+	if !manager.killSwitchEnabled {
+		return
+	}
+	servers := append([]firewall.ServerAddress{manager.killSwitchAddress}, manager.providerAddresses...)
+	if err := manager.killSwitch.Enable(servers...); err != nil {
+		log.Error(managerLogPrefix, "Failed to let provider through kill switch: ", err)
+	}
+}
+
+// restrictKillSwitchToServer leaves only VPN server reachable through kill switch
+func (manager *connectionManager) restrictKillSwitchToServer() {
+	manager.killSwitchLock.Lock()
+	defer manager.killSwitchLock.Unlock()
+
+	if !manager.killSwitchEnabled {
+		return
+	}
+	if err := manager.killSwitch.Enable(manager.killSwitchAddress); err != nil {
+		log.Error(managerLogPrefix, "Failed to enable kill switch: ", err)
+	}
+}
+
+func (manager *connectionManager) disableKillSwitch() error {
+	manager.killSwitchLock.Lock()
+	defer manager.killSwitchLock.Unlock()
+
+	if !manager.killSwitchEnabled {
+		return nil
+	}
+	if err := manager.killSwitch.Disable(); err != nil {
+		return err
+	}
+	manager.killSwitchEnabled = false
+	return nil
+}
+
+func (manager *connectionManager) releaseKillSwitch() {
+	if err := manager.disableKillSwitch(); err != nil {
+		log.Error(managerLogPrefix, "Failed to disable kill switch: ", err)
+	}
+}
+
+// KillSwitchEnabled tells if traffic is restricted by kill switch of the connection
+func (manager *connectionManager) KillSwitchEnabled() bool {
+	manager.killSwitchLock.Lock()
+	defer manager.killSwitchLock.Unlock()
+
+	return manager.killSwitchEnabled
+}
+
 func warnOnClean() {
 	log.Warn(managerLogPrefix, "Trying to close when there is nothing to close. Possible bug or race condition")
 }
 
 // TODO this can be extracted as dependency later when node selection criteria will be clear
-func (manager *connectionManager) findProposalByProviderID(providerID identity.Identity) (proposal dto.ServiceProposal, err error) {
-	proposals, err := manager.mysteriumClient.FindProposals(providerID.Address)
+// findProposal returns provider's proposal selected by params, or the first proposal of provider if none is selected
+func (manager *connectionManager) findProposal(providerID identity.Identity, params ConnectParams) (proposal dto.ServiceProposal, err error) {
+	proposals, err := manager.mysteriumClient.FindProposals(server.ProposalsFilter{
+		ProviderID:  providerID.Address,
+		ServiceType: params.ServiceType,
+	})
 	if err != nil {
 		return
 	}
 	if len(proposals) == 0 {
-		err = errors.New("provider has no service proposals")
+		if params.ServiceType != "" {
+			err = fmt.Errorf("provider has no %s service proposals", params.ServiceType)
+		} else {
+			err = errors.New("provider has no service proposals")
+		}
 		return
 	}
+	if params.ProposalID == 0 {
+		return proposals[0], nil
+	}
 
-	proposal = proposals[0]
+	for _, proposal := range proposals {
+		if proposal.ID == params.ProposalID {
+			return proposal, nil
+		}
+	}
+	err = fmt.Errorf("provider has no service proposal with ID %d", params.ProposalID)
 	return
 }
 
@@ -270,15 +540,19 @@ func (manager *connectionManager) waitForConnectedState(stateChannel <-chan Stat
 	}
 }
 
-func (manager *connectionManager) consumeConnectionStates(stateChannel <-chan State, sessionID session.ID) {
-	for state := range stateChannel {
-		manager.onStateChanged(state, sessionID)
+func (manager *connectionManager) consumeConnectionStates(established establishedConnection, params ConnectParams) {
+	for state := range established.stateChannel {
+		manager.onStateChanged(state, established.options.SessionID)
+	}
+
+	if manager.reconnect(established.options, params) {
+		return
 	}
 
 	manager.mutex.Lock()
 	defer manager.mutex.Unlock()
 
-	manager.status = statusNotConnected()
+	manager.setStatus(statusNotConnected())
 	log.Debug(managerLogPrefix, "State updater stopCalled")
 }
 
@@ -289,11 +563,35 @@ func (manager *connectionManager) onStateChanged(state State, sessionID session.
 	switch state {
 	case Connected:
 		manager.statsKeeper.MarkSessionStart()
-		manager.status = statusConnected(sessionID)
+		manager.setStatus(statusConnected(sessionID, manager.connectionDNS()))
 	case Disconnecting:
 		manager.statsKeeper.MarkSessionEnd()
 	case Reconnecting:
-		manager.status = statusReconnecting()
+		manager.setStatus(statusReconnecting())
+	}
+}
+
+func (manager *connectionManager) setDNSProvider(connection Connection) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	manager.dnsProvider, _ = connection.(DNSProvider)
+}
+
+// connectionDNS returns resolvers taken over by current connection, caller must hold the mutex
+func (manager *connectionManager) connectionDNS() []string {
+	if manager.dnsProvider == nil {
+		return nil
+	}
+	return manager.dnsProvider.DNS()
+}
+
+// setStatus changes connection status and publishes event about the change, caller must hold the mutex
+func (manager *connectionManager) setStatus(status ConnectionStatus) {
+	changed := manager.status.State != status.State || manager.status.SessionID != status.SessionID
+	manager.status = status
+	if changed {
+		manager.eventPublisher.Publish(StateEventTopic, StateEvent{State: status.State, SessionID: status.SessionID})
 	}
 }
 
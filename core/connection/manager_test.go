@@ -23,10 +23,14 @@ import (
 	"testing"
 	"time"
 
+	"github.com/mysteriumnetwork/node/client/stats"
 	"github.com/mysteriumnetwork/node/communication"
+	"github.com/mysteriumnetwork/node/core/events"
+	"github.com/mysteriumnetwork/node/firewall"
 	"github.com/mysteriumnetwork/node/identity"
 	"github.com/mysteriumnetwork/node/server"
 	"github.com/mysteriumnetwork/node/service_discovery/dto"
+	"github.com/mysteriumnetwork/node/session"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 )
@@ -40,6 +44,8 @@ type testContext struct {
 	fakeDialog            *fakeDialog
 	fakePromiseIssuer     *fakePromiseIssuer
 	fakeSessionRepository *fakeSessionRepository
+	fakeKillSwitch        *fakeKillSwitch
+	fakeEventPublisher    *events.PublisherFake
 	sync.RWMutex
 }
 
@@ -71,7 +77,7 @@ func (tc *testContext) SetupTest() {
 	}
 
 	tc.fakePromiseIssuer = &fakePromiseIssuer{}
-	promiseIssuerFactory := func(_ identity.Identity, _ communication.Dialog) PromiseIssuer {
+	promiseIssuerFactory := func(_ identity.Identity, _ communication.Dialog, _ stats.SessionStatsKeeper) PromiseIssuer {
 		return tc.fakePromiseIssuer
 	}
 
@@ -102,6 +108,10 @@ func (tc *testContext) SetupTest() {
 
 	tc.fakeSessionRepository = &fakeSessionRepository{}
 
+	tc.fakeKillSwitch = &fakeKillSwitch{}
+
+	tc.fakeEventPublisher = events.NewPublisherFake()
+
 	tc.connManager = NewManager(
 		tc.fakeDiscoveryClient,
 		dialogCreator,
@@ -109,6 +119,8 @@ func (tc *testContext) SetupTest() {
 		tc.fakeConnectionFactory.CreateConnection,
 		tc.fakeStatsKeeper,
 		tc.fakeSessionRepository,
+		tc.fakeKillSwitch,
+		tc.fakeEventPublisher,
 	)
 }
 
@@ -123,6 +135,39 @@ func (tc *testContext) TestWithUnknownProviderConnectionIsNotMade() {
 	assert.Equal(tc.T(), statusNotConnected(), tc.connManager.Status())
 }
 
+func (tc *testContext) TestConnectionIsMadeToRequestedProposal() {
+	requestedProposal := activeProposal
+	requestedProposal.ID = 2
+	requestedProposal.ServiceType = "other-service"
+	tc.fakeDiscoveryClient.RegisterProposal(requestedProposal, nil)
+
+	assert.NoError(tc.T(), tc.connManager.Connect(myID, activeProviderID, ConnectParams{ProposalID: 2}))
+	assert.Equal(tc.T(), requestedProposal.ID, tc.fakeConnectionFactory.lastOptions.Proposal.ID)
+	assert.Equal(tc.T(), requestedProposal.ServiceType, tc.fakeConnectionFactory.lastOptions.Proposal.ServiceType)
+}
+
+func (tc *testContext) TestConnectionIsMadeToProposalOfRequestedServiceType() {
+	requestedProposal := activeProposal
+	requestedProposal.ID = 2
+	requestedProposal.ServiceType = "other-service"
+	tc.fakeDiscoveryClient.RegisterProposal(requestedProposal, nil)
+
+	assert.NoError(tc.T(), tc.connManager.Connect(myID, activeProviderID, ConnectParams{ServiceType: "other-service"}))
+	assert.Equal(tc.T(), requestedProposal.ID, tc.fakeConnectionFactory.lastOptions.Proposal.ID)
+}
+
+func (tc *testContext) TestWithUnknownServiceTypeConnectionIsNotMade() {
+	err := tc.connManager.Connect(myID, activeProviderID, ConnectParams{ServiceType: "unknown-service"})
+	assert.EqualError(tc.T(), err, "provider has no unknown-service service proposals")
+	assert.Equal(tc.T(), statusNotConnected(), tc.connManager.Status())
+}
+
+func (tc *testContext) TestWithUnknownProposalConnectionIsNotMade() {
+	err := tc.connManager.Connect(myID, activeProviderID, ConnectParams{ProposalID: 7})
+	assert.EqualError(tc.T(), err, "provider has no service proposal with ID 7")
+	assert.Equal(tc.T(), statusNotConnected(), tc.connManager.Status())
+}
+
 func (tc *testContext) TestOnConnectErrorStatusIsNotConnected() {
 	tc.fakeConnectionFactory.vpnClientCreationError = errors.New("fatal connection error")
 
@@ -131,10 +176,26 @@ func (tc *testContext) TestOnConnectErrorStatusIsNotConnected() {
 	assert.True(tc.T(), tc.fakeDialog.closed)
 }
 
+func (tc *testContext) TestOnConnectErrorSessionIsDestroyed() {
+	tc.fakeConnectionFactory.vpnClientCreationError = errors.New("fatal connection error")
+
+	assert.Error(tc.T(), tc.connManager.Connect(myID, activeProviderID, ConnectParams{}))
+	assert.Equal(tc.T(), []session.ID{"vpn-connection-id"}, tc.fakeDialog.sessionsDestroyed())
+}
+
+func (tc *testContext) TestOnDisconnectSessionIsDestroyed() {
+	assert.NoError(tc.T(), tc.connManager.Connect(myID, activeProviderID, ConnectParams{}))
+	assert.Len(tc.T(), tc.fakeDialog.sessionsDestroyed(), 0)
+
+	assert.NoError(tc.T(), tc.connManager.Disconnect())
+	waitABit()
+	assert.Equal(tc.T(), []session.ID{"vpn-connection-id"}, tc.fakeDialog.sessionsDestroyed())
+}
+
 func (tc *testContext) TestWhenManagerMadeConnectionStatusReturnsConnectedStateAndSessionId() {
 	err := tc.connManager.Connect(myID, activeProviderID, ConnectParams{})
 	assert.NoError(tc.T(), err)
-	assert.Equal(tc.T(), statusConnected("vpn-connection-id"), tc.connManager.Status())
+	assert.Equal(tc.T(), statusConnected("vpn-connection-id", fakeDNS), tc.connManager.Status())
 	assert.True(tc.T(), tc.fakeStatsKeeper.sessionStartMarked)
 }
 
@@ -155,7 +216,7 @@ func (tc *testContext) TestStatusReportsDisconnectingThenNotConnected() {
 	tc.fakeConnectionFactory.fakeVpnClient.onStopReportStates = []fakeState{}
 	err := tc.connManager.Connect(myID, activeProviderID, ConnectParams{})
 	assert.NoError(tc.T(), err)
-	assert.Equal(tc.T(), statusConnected("vpn-connection-id"), tc.connManager.Status())
+	assert.Equal(tc.T(), statusConnected("vpn-connection-id", fakeDNS), tc.connManager.Status())
 
 	assert.NoError(tc.T(), tc.connManager.Disconnect())
 	assert.Equal(tc.T(), statusDisconnecting(), tc.connManager.Status())
@@ -184,7 +245,7 @@ func (tc *testContext) TestReconnectingStatusIsReportedWhenOpenVpnGoesIntoReconn
 
 func (tc *testContext) TestDoubleDisconnectResultsInError() {
 	assert.NoError(tc.T(), tc.connManager.Connect(myID, activeProviderID, ConnectParams{}))
-	assert.Equal(tc.T(), statusConnected("vpn-connection-id"), tc.connManager.Status())
+	assert.Equal(tc.T(), statusConnected("vpn-connection-id", fakeDNS), tc.connManager.Status())
 	assert.NoError(tc.T(), tc.connManager.Disconnect())
 	waitABit()
 	assert.Equal(tc.T(), statusNotConnected(), tc.connManager.Status())
@@ -193,13 +254,13 @@ func (tc *testContext) TestDoubleDisconnectResultsInError() {
 
 func (tc *testContext) TestTwoConnectDisconnectCyclesReturnNoError() {
 	assert.NoError(tc.T(), tc.connManager.Connect(myID, activeProviderID, ConnectParams{}))
-	assert.Equal(tc.T(), statusConnected("vpn-connection-id"), tc.connManager.Status())
+	assert.Equal(tc.T(), statusConnected("vpn-connection-id", fakeDNS), tc.connManager.Status())
 	assert.NoError(tc.T(), tc.connManager.Disconnect())
 	waitABit()
 	assert.Equal(tc.T(), statusNotConnected(), tc.connManager.Status())
 
 	assert.NoError(tc.T(), tc.connManager.Connect(myID, activeProviderID, ConnectParams{}))
-	assert.Equal(tc.T(), statusConnected("vpn-connection-id"), tc.connManager.Status())
+	assert.Equal(tc.T(), statusConnected("vpn-connection-id", fakeDNS), tc.connManager.Status())
 	assert.NoError(tc.T(), tc.connManager.Disconnect())
 	waitABit()
 	assert.Equal(tc.T(), statusNotConnected(), tc.connManager.Status())
@@ -213,7 +274,7 @@ func (tc *testContext) TestConnectFailsIfOpenvpnFactoryReturnsError() {
 
 func (tc *testContext) TestStatusIsConnectedWhenConnectCommandReturnsWithoutError() {
 	tc.connManager.Connect(myID, activeProviderID, ConnectParams{})
-	assert.Equal(tc.T(), statusConnected("vpn-connection-id"), tc.connManager.Status())
+	assert.Equal(tc.T(), statusConnected("vpn-connection-id", fakeDNS), tc.connManager.Status())
 }
 
 func (tc *testContext) TestConnectingInProgressCanBeCanceled() {
@@ -266,6 +327,146 @@ func (tc *testContext) Test_PromiseIssuer_OnConnectErrorIsStopped() {
 	assert.True(tc.T(), tc.fakePromiseIssuer.stopCalled)
 }
 
+func (tc *testContext) Test_KillSwitch_IsEnabledForServerAddressWhenConnected() {
+	assert.NoError(tc.T(), tc.connManager.Connect(myID, activeProviderID, ConnectParams{}))
+	assert.True(tc.T(), tc.fakeKillSwitch.isEnabled())
+	assert.Equal(tc.T(), []firewall.ServerAddress{fakeServerAddress}, tc.fakeKillSwitch.allowedServers())
+
+	assert.NoError(tc.T(), tc.connManager.Disconnect())
+	waitABit()
+	assert.False(tc.T(), tc.fakeKillSwitch.isEnabled())
+}
+
+func (tc *testContext) Test_KillSwitch_IsNotEnabledWhenDisabledByParams() {
+	assert.NoError(tc.T(), tc.connManager.Connect(myID, activeProviderID, ConnectParams{DisableKillSwitch: true}))
+	assert.False(tc.T(), tc.fakeKillSwitch.isEnabled())
+}
+
+func (tc *testContext) Test_KillSwitch_CanNotBeCombinedWithSplitTunnel() {
+	params := ConnectParams{SplitTunnel: SplitTunnelParams{ExcludeCIDRs: []string{"192.168.0.0/16"}}}
+	assert.Equal(tc.T(), ErrKillSwitchWithSplitTunnel, tc.connManager.Connect(myID, activeProviderID, params))
+	assert.Equal(tc.T(), statusNotConnected(), tc.connManager.Status())
+	assert.False(tc.T(), tc.fakeKillSwitch.isEnabled())
+}
+
+func (tc *testContext) Test_SplitTunnel_ConnectsWhenKillSwitchIsDisabled() {
+	params := ConnectParams{
+		DisableKillSwitch: true,
+		SplitTunnel:       SplitTunnelParams{ExcludeCIDRs: []string{"192.168.0.0/16"}},
+	}
+	assert.NoError(tc.T(), tc.connManager.Connect(myID, activeProviderID, params))
+	assert.False(tc.T(), tc.fakeKillSwitch.isEnabled())
+}
+
+func (tc *testContext) Test_KillSwitch_StaysEnabledAfterConnectionExitUntilDisconnect() {
+	assert.NoError(tc.T(), tc.connManager.Connect(myID, activeProviderID, ConnectParams{}))
+
+	tc.fakeConnectionFactory.fakeVpnClient.reportState(processExited)
+	waitABit()
+	assert.Equal(tc.T(), statusNotConnected(), tc.connManager.Status())
+	assert.True(tc.T(), tc.fakeKillSwitch.isEnabled())
+
+	assert.NoError(tc.T(), tc.connManager.Disconnect())
+	assert.False(tc.T(), tc.fakeKillSwitch.isEnabled())
+	assert.Equal(tc.T(), ErrNoConnection, tc.connManager.Disconnect())
+}
+
+func (tc *testContext) Test_Reconnect_ConnectionIsReestablishedAfterUnexpectedExit() {
+	params := ConnectParams{Reconnect: ReconnectParams{MaxAttempts: 2}}
+	assert.NoError(tc.T(), tc.connManager.Connect(myID, activeProviderID, params))
+
+	tc.fakeConnectionFactory.fakeVpnClient.reportState(processExited)
+	waitABit()
+	assert.Equal(tc.T(), statusConnected("vpn-connection-id", fakeDNS), tc.connManager.Status())
+	assert.True(tc.T(), tc.fakeKillSwitch.isEnabled())
+	assert.Equal(tc.T(), 1, tc.fakeDialog.requestsMade())
+
+	assert.NoError(tc.T(), tc.connManager.Disconnect())
+	waitABit()
+	assert.Equal(tc.T(), statusNotConnected(), tc.connManager.Status())
+	assert.False(tc.T(), tc.fakeKillSwitch.isEnabled())
+}
+
+func (tc *testContext) Test_Reconnect_NewSessionIsRequestedWhenConfigured() {
+	params := ConnectParams{Reconnect: ReconnectParams{MaxAttempts: 1, NewSession: true}}
+	assert.NoError(tc.T(), tc.connManager.Connect(myID, activeProviderID, params))
+
+	tc.fakeConnectionFactory.fakeVpnClient.reportState(processExited)
+	waitABit()
+	assert.Equal(tc.T(), statusConnected("vpn-connection-id", fakeDNS), tc.connManager.Status())
+	assert.Equal(tc.T(), 2, tc.fakeDialog.requestsMade())
+}
+
+func (tc *testContext) Test_Reconnect_NewSessionIsRequestedWhenSessionReuseIsRejected() {
+	params := ConnectParams{Reconnect: ReconnectParams{MaxAttempts: 1}}
+	assert.NoError(tc.T(), tc.connManager.Connect(myID, activeProviderID, params))
+
+	tc.fakeConnectionFactory.rejectedSessionCreations = 1
+	tc.fakeConnectionFactory.fakeVpnClient.reportState(processExited)
+	waitABit()
+	assert.Equal(tc.T(), statusConnected("vpn-connection-id", fakeDNS), tc.connManager.Status())
+	assert.Equal(tc.T(), 2, tc.fakeDialog.requestsMade())
+}
+
+func (tc *testContext) Test_Reconnect_FallsBackToNotConnectedWhenAttemptsAreExhausted() {
+	params := ConnectParams{Reconnect: ReconnectParams{MaxAttempts: 3}}
+	assert.NoError(tc.T(), tc.connManager.Connect(myID, activeProviderID, params))
+
+	tc.fakeConnectionFactory.vpnClientCreationError = errors.New("provider is gone")
+	tc.fakeConnectionFactory.fakeVpnClient.reportState(processExited)
+	waitABit()
+	assert.Equal(tc.T(), statusNotConnected(), tc.connManager.Status())
+	assert.True(tc.T(), tc.fakeKillSwitch.isEnabled())
+	assert.Equal(tc.T(), []firewall.ServerAddress{fakeServerAddress}, tc.fakeKillSwitch.allowedServers())
+}
+
+func (tc *testContext) Test_Reconnect_StatusIsReconnectingUntilDisconnected() {
+	params := ConnectParams{Reconnect: ReconnectParams{MaxAttempts: 1, Backoff: time.Minute}}
+	assert.NoError(tc.T(), tc.connManager.Connect(myID, activeProviderID, params))
+
+	tc.fakeConnectionFactory.fakeVpnClient.reportState(processExited)
+	waitABit()
+	assert.Equal(tc.T(), statusReconnecting(), tc.connManager.Status())
+	assert.True(tc.T(), tc.fakeKillSwitch.isEnabled())
+	assert.Equal(
+		tc.T(),
+		[]firewall.ServerAddress{fakeServerAddress, fakeBrokerAddress},
+		tc.fakeKillSwitch.allowedServers(),
+	)
+
+	assert.NoError(tc.T(), tc.connManager.Disconnect())
+	waitABit()
+	assert.Equal(tc.T(), statusNotConnected(), tc.connManager.Status())
+	assert.False(tc.T(), tc.fakeKillSwitch.isEnabled())
+}
+
+func (tc *testContext) Test_Reconnect_IsNotAttemptedAfterDisconnect() {
+	params := ConnectParams{Reconnect: ReconnectParams{MaxAttempts: 1, NewSession: true}}
+	assert.NoError(tc.T(), tc.connManager.Connect(myID, activeProviderID, params))
+
+	assert.NoError(tc.T(), tc.connManager.Disconnect())
+	waitABit()
+	assert.Equal(tc.T(), statusNotConnected(), tc.connManager.Status())
+	assert.Equal(tc.T(), 1, tc.fakeDialog.requestsMade())
+}
+
+func (tc *testContext) Test_Events_StatusChangesArePublished() {
+	assert.NoError(tc.T(), tc.connManager.Connect(myID, activeProviderID, ConnectParams{}))
+	assert.NoError(tc.T(), tc.connManager.Disconnect())
+	waitABit()
+
+	assert.Equal(
+		tc.T(),
+		[]events.Event{
+			{Topic: StateEventTopic, Payload: StateEvent{State: Connecting}},
+			{Topic: StateEventTopic, Payload: StateEvent{State: Connected, SessionID: "vpn-connection-id"}},
+			{Topic: StateEventTopic, Payload: StateEvent{State: Disconnecting}},
+			{Topic: StateEventTopic, Payload: StateEvent{State: NotConnected}},
+		},
+		tc.fakeEventPublisher.Published(),
+	)
+}
+
 func TestConnectionManagerSuite(t *testing.T) {
 	suite.Run(t, new(testContext))
 }
@@ -283,3 +484,10 @@ func (fs *fakeSessionRepository) Save(Session) error { return nil }
 type fakeServiceDefinition struct{}
 
 func (fs *fakeServiceDefinition) GetLocation() dto.Location { return dto.Location{} }
+
+func TestNextReconnectBackoff_DoublesUpToMaximum(t *testing.T) {
+	assert.Equal(t, 4*time.Second, nextReconnectBackoff(2*time.Second))
+	assert.Equal(t, 4*time.Minute, nextReconnectBackoff(2*time.Minute))
+	assert.Equal(t, maxReconnectBackoff, nextReconnectBackoff(3*time.Minute))
+	assert.Equal(t, maxReconnectBackoff, nextReconnectBackoff(maxReconnectBackoff))
+}
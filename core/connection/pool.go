@@ -0,0 +1,192 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package connection
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mysteriumnetwork/node/client/stats"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/session"
+)
+
+var (
+	// ErrConnectionNotFound indicates that there is no connection with requested session id
+	ErrConnectionNotFound = errors.New("connection not found")
+	// ErrAdditionalFullTunnel indicates that additional connection would take over all traffic from primary connection
+	ErrAdditionalFullTunnel = errors.New("additional connection has to route only included networks or domains")
+	// ErrAdditionalWithKillSwitch indicates that kill switch of primary connection would block additional connection
+	ErrAdditionalWithKillSwitch = errors.New("additional connections are blocked by kill switch of primary connection")
+)
+
+// killSwitchReporter is implemented by managers, which restrict traffic with kill switch
+type killSwitchReporter interface {
+	KillSwitchEnabled() bool
+}
+
+// ManagerFactory creates connection manager for additional connection, together with stats keeper it reports to
+type ManagerFactory func() (Manager, stats.SessionStatsKeeper)
+
+// ConnectionInfo holds status and statistics of single connection
+type ConnectionInfo struct {
+	Status     ConnectionStatus
+	Statistics stats.SessionStats
+	Duration   time.Duration
+	// Primary is true for connection managed through singular connection API
+	Primary bool
+}
+
+type pooledConnection struct {
+	manager     Manager
+	statsKeeper stats.SessionStatsKeeper
+}
+
+func (connection pooledConnection) info(primary bool) ConnectionInfo {
+	return ConnectionInfo{
+		Status:     connection.manager.Status(),
+		Statistics: connection.statsKeeper.Retrieve(),
+		Duration:   connection.statsKeeper.GetSessionDuration(),
+		Primary:    primary,
+	}
+}
+
+// Pool keeps track of concurrent connections keyed by session id.
+// Primary connection is created through its own manager, additional connections are created by pool.
+// Kill switch is node wide, so it is supported for primary connection only.
+// Additional connections route only included networks, so they do not take over routing of primary connection.
+type Pool struct {
+	primary    pooledConnection
+	additional map[session.ID]pooledConnection
+	newManager ManagerFactory
+
+	lock sync.Mutex
+}
+
+// NewPool creates connection pool for given primary connection manager
+func NewPool(primary Manager, primaryStatsKeeper stats.SessionStatsKeeper, newManager ManagerFactory) *Pool {
+	return &Pool{
+		primary:    pooledConnection{manager: primary, statsKeeper: primaryStatsKeeper},
+		additional: make(map[session.ID]pooledConnection),
+		newManager: newManager,
+	}
+}
+
+// Connect creates additional connection from given consumer to provider and returns its session id.
+// Connection is refused while kill switch of primary connection is enabled, as it would be blocked.
+func (pool *Pool) Connect(consumerID, providerID identity.Identity, params ConnectParams) (session.ID, error) {
+	if len(params.SplitTunnel.IncludeCIDRs)+len(params.SplitTunnel.IncludeDomains) == 0 {
+		return "", ErrAdditionalFullTunnel
+	}
+	if reporter, ok := pool.primary.manager.(killSwitchReporter); ok && reporter.KillSwitchEnabled() {
+		return "", ErrAdditionalWithKillSwitch
+	}
+
+	manager, statsKeeper := pool.newManager()
+
+	params.DisableKillSwitch = true
+	if err := manager.Connect(consumerID, providerID, params); err != nil {
+		return "", err
+	}
+
+	sessionID := manager.Status().SessionID
+	if sessionID == "" {
+		manager.Disconnect()
+		return "", ErrConnectionFailed
+	}
+
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	pool.additional[sessionID] = pooledConnection{manager: manager, statsKeeper: statsKeeper}
+	return sessionID, nil
+}
+
+// List returns all active connections, primary one goes first
+func (pool *Pool) List() []ConnectionInfo {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	pool.forgetExited()
+
+	var connections []ConnectionInfo
+	if primary := pool.primary.info(true); primary.Status.State != NotConnected {
+		connections = append(connections, primary)
+	}
+
+	ids := make([]string, 0, len(pool.additional))
+	for id := range pool.additional {
+		ids = append(ids, string(id))
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		connections = append(connections, pool.additional[session.ID(id)].info(false))
+	}
+	return connections
+}
+
+// Get returns connection with given session id
+func (pool *Pool) Get(sessionID session.ID) (ConnectionInfo, error) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	connection, primary, err := pool.find(sessionID)
+	if err != nil {
+		return ConnectionInfo{}, err
+	}
+	return connection.info(primary), nil
+}
+
+// Disconnect closes connection with given session id
+func (pool *Pool) Disconnect(sessionID session.ID) error {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	connection, primary, err := pool.find(sessionID)
+	if err != nil {
+		return err
+	}
+	if !primary {
+		delete(pool.additional, sessionID)
+	}
+	return connection.manager.Disconnect()
+}
+
+func (pool *Pool) find(sessionID session.ID) (connection pooledConnection, primary bool, err error) {
+	pool.forgetExited()
+
+	if sessionID != "" && pool.primary.manager.Status().SessionID == sessionID {
+		return pool.primary, true, nil
+	}
+	connection, found := pool.additional[sessionID]
+	if !found {
+		return connection, false, ErrConnectionNotFound
+	}
+	return connection, false, nil
+}
+
+// forgetExited removes additional connections, which exited by themselves
+func (pool *Pool) forgetExited() {
+	for id, connection := range pool.additional {
+		if connection.manager.Status().State == NotConnected {
+			delete(pool.additional, id)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package connection
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mysteriumnetwork/node/client/stats"
+	"github.com/stretchr/testify/assert"
+)
+
+var includeOnlyParams = ConnectParams{SplitTunnel: SplitTunnelParams{IncludeCIDRs: []string{"10.0.0.0/8"}}}
+
+func newTestPool(primary *fakeManager, additional ...*fakeManager) *Pool {
+	created := 0
+	return NewPool(primary, &fakeSessionStatsKeeper{}, func() (Manager, stats.SessionStatsKeeper) {
+		manager := additional[created]
+		created++
+		return manager, &fakeSessionStatsKeeper{}
+	})
+}
+
+func TestPool_ConnectRegistersAdditionalConnectionWithoutKillSwitch(t *testing.T) {
+	additional := &fakeManager{onConnectStatus: statusConnected("additional", nil)}
+	pool := newTestPool(&fakeManager{status: statusNotConnected()}, additional)
+
+	sessionID, err := pool.Connect(myID, activeProviderID, includeOnlyParams)
+	assert.NoError(t, err)
+	assert.Equal(t, "additional", string(sessionID))
+	assert.True(t, additional.requestedParams.DisableKillSwitch)
+
+	info, err := pool.Get("additional")
+	assert.NoError(t, err)
+	assert.Equal(t, statusConnected("additional", nil), info.Status)
+	assert.False(t, info.Primary)
+}
+
+func TestPool_ConnectRefusesAdditionalFullTunnel(t *testing.T) {
+	additional := &fakeManager{onConnectStatus: statusConnected("additional", nil)}
+	pool := newTestPool(&fakeManager{status: statusNotConnected()}, additional)
+
+	_, err := pool.Connect(myID, activeProviderID, ConnectParams{SplitTunnel: SplitTunnelParams{ExcludeCIDRs: []string{"10.0.0.0/8"}}})
+	assert.Equal(t, ErrAdditionalFullTunnel, err)
+	assert.Equal(t, ConnectParams{}, additional.requestedParams)
+}
+
+func TestPool_ConnectRefusesAdditionalConnectionWhileKillSwitchIsEnabled(t *testing.T) {
+	primary := newConnectedFakeManager("primary")
+	primary.killSwitch = true
+	additional := &fakeManager{onConnectStatus: statusConnected("additional", nil)}
+	pool := newTestPool(primary, additional)
+
+	_, err := pool.Connect(myID, activeProviderID, includeOnlyParams)
+	assert.Equal(t, ErrAdditionalWithKillSwitch, err)
+	assert.Equal(t, ConnectParams{}, additional.requestedParams)
+}
+
+func TestPool_ConnectReturnsManagerError(t *testing.T) {
+	connectError := errors.New("boom")
+	pool := newTestPool(&fakeManager{status: statusNotConnected()}, &fakeManager{onConnectError: connectError})
+
+	_, err := pool.Connect(myID, activeProviderID, includeOnlyParams)
+	assert.Equal(t, connectError, err)
+	assert.Len(t, pool.List(), 0)
+}
+
+func TestPool_GetFindsPrimaryConnectionBySessionID(t *testing.T) {
+	pool := newTestPool(newConnectedFakeManager("primary"))
+
+	info, err := pool.Get("primary")
+	assert.NoError(t, err)
+	assert.Equal(t, statusConnected("primary", nil), info.Status)
+	assert.True(t, info.Primary)
+
+	_, err = pool.Get("unknown")
+	assert.Equal(t, ErrConnectionNotFound, err)
+}
+
+func TestPool_ListReturnsPrimaryFirstAndForgetsExitedConnections(t *testing.T) {
+	exiting := newConnectedFakeManager("b")
+	pool := newTestPool(newConnectedFakeManager("primary"), newConnectedFakeManager("c"), exiting)
+	pool.Connect(myID, activeProviderID, includeOnlyParams)
+	pool.Connect(myID, activeProviderID, includeOnlyParams)
+
+	list := pool.List()
+	assert.Len(t, list, 3)
+	assert.Equal(t, statusConnected("primary", nil), list[0].Status)
+	assert.Equal(t, statusConnected("b", nil), list[1].Status)
+	assert.Equal(t, statusConnected("c", nil), list[2].Status)
+
+	exiting.status = statusNotConnected()
+	list = pool.List()
+	assert.Len(t, list, 2)
+	_, err := pool.Get("b")
+	assert.Equal(t, ErrConnectionNotFound, err)
+}
+
+func TestPool_DisconnectClosesRequestedConnection(t *testing.T) {
+	primary := newConnectedFakeManager("primary")
+	additional := newConnectedFakeManager("additional")
+	pool := newTestPool(primary, additional)
+	pool.Connect(myID, activeProviderID, includeOnlyParams)
+
+	assert.NoError(t, pool.Disconnect("additional"))
+	assert.True(t, additional.disconnectCalled)
+	assert.False(t, primary.disconnectCalled)
+	assert.Equal(t, ErrConnectionNotFound, pool.Disconnect("additional"))
+
+	assert.NoError(t, pool.Disconnect("primary"))
+	assert.True(t, primary.disconnectCalled)
+}
@@ -0,0 +1,253 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package connection
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/cihub/seelog"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/server"
+	"github.com/mysteriumnetwork/node/server/metrics"
+	"github.com/mysteriumnetwork/node/service_discovery/dto"
+)
+
+const selectorLogPrefix = "[connection-selector] "
+
+const (
+	// maxProbes limits number of providers probed before connecting, the best ones by quality are probed
+	maxProbes = 10
+	// probeConcurrency limits number of providers probed at the same time
+	probeConcurrency = 5
+	// probeTimeout is how long provider may take to answer the probe
+	probeTimeout = 5 * time.Second
+	// qualityPenalty is added to latency of provider, which consumers never connected to successfully
+	qualityPenalty = time.Second
+	// unknownQuality is assumed for providers without connection statistics
+	unknownQuality = 0.5
+)
+
+// ErrNoProviders indicates that no reachable provider matches the filter
+var ErrNoProviders = errors.New("no reachable provider matches the filter")
+
+// ProposalFilter describes proposals suitable for connection, empty fields match any value
+type ProposalFilter struct {
+	Country     string
+	ServiceType string
+	Protocol    string
+}
+
+// Matches tells if proposal satisfies the filter
+func (filter ProposalFilter) Matches(proposal dto.ServiceProposal) bool {
+	if filter.ServiceType != "" && filter.ServiceType != proposal.ServiceType {
+		return false
+	}
+	if proposal.ServiceDefinition == nil {
+		return filter.Country == "" && filter.Protocol == ""
+	}
+	if filter.Country != "" && !strings.EqualFold(filter.Country, proposal.ServiceDefinition.GetLocation().Country) {
+		return false
+	}
+	if filter.Protocol != "" {
+		definition, ok := proposal.ServiceDefinition.(protocolDefinition)
+		if !ok || !strings.EqualFold(filter.Protocol, definition.GetProtocol()) {
+			return false
+		}
+	}
+	return true
+}
+
+// protocolDefinition is implemented by service definitions, which announce transport protocol
+type protocolDefinition interface {
+	GetProtocol() string
+}
+
+// Prober checks that provider of the proposal is reachable
+type Prober func(consumerID identity.Identity, proposal dto.ServiceProposal) error
+
+// NewDialogProber returns prober, which establishes dialog with provider and closes it right away
+func NewDialogProber(dialogCreator DialogCreator) Prober {
+	return func(consumerID identity.Identity, proposal dto.ServiceProposal) error {
+		if len(proposal.ProviderContacts) == 0 {
+			return errors.New("provider has no contacts")
+		}
+		dialog, err := dialogCreator(consumerID, identity.FromAddress(proposal.ProviderID), proposal.ProviderContacts[0])
+		if err != nil {
+			return err
+		}
+		return dialog.Close()
+	}
+}
+
+// Selector ranks providers by latency of probes and quality reported by quality oracle
+type Selector struct {
+	mysteriumClient server.Client
+	qualityOracle   metrics.QualityOracle
+	probe           Prober
+	probeTimeout    time.Duration
+}
+
+// NewSelector creates provider selector
+func NewSelector(mysteriumClient server.Client, qualityOracle metrics.QualityOracle, prober Prober) *Selector {
+	return &Selector{
+		mysteriumClient: mysteriumClient,
+		qualityOracle:   qualityOracle,
+		probe:           prober,
+		probeTimeout:    probeTimeout,
+	}
+}
+
+type candidate struct {
+	proposal dto.ServiceProposal
+	quality  float64
+	latency  time.Duration
+}
+
+func (c candidate) score() time.Duration {
+	return c.latency + time.Duration((1-c.quality)*float64(qualityPenalty))
+}
+
+// Rank returns reachable providers matching the filter, the best one first
+func (selector *Selector) Rank(consumerID identity.Identity, filter ProposalFilter) ([]dto.ServiceProposal, error) {
+	proposals, err := selector.mysteriumClient.FindProposals(server.ProposalsFilter{ServiceType: filter.ServiceType})
+	if err != nil {
+		return nil, err
+	}
+
+	connectCounts := metrics.ParseConnectCounts(selector.qualityOracle.ProposalsMetrics())
+	candidates := make([]candidate, 0)
+	for _, proposal := range proposals {
+		if !filter.Matches(proposal) || len(proposal.ProviderContacts) == 0 {
+			continue
+		}
+		quality, ok := connectCounts[metrics.ProposalKey{ProviderID: proposal.ProviderID, ServiceType: proposal.ServiceType}].SuccessRate()
+		if !ok {
+			quality = unknownQuality
+		}
+		candidates = append(candidates, candidate{proposal: proposal, quality: quality})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].quality > candidates[j].quality })
+	if len(candidates) > maxProbes {
+		candidates = candidates[:maxProbes]
+	}
+
+	reachable := selector.probeAll(consumerID, candidates)
+	if len(reachable) == 0 {
+		return nil, ErrNoProviders
+	}
+	sort.SliceStable(reachable, func(i, j int) bool { return reachable[i].score() < reachable[j].score() })
+
+	ranked := make([]dto.ServiceProposal, len(reachable))
+	for i, reachableCandidate := range reachable {
+		ranked[i] = reachableCandidate.proposal
+		log.Debug(selectorLogPrefix, "Provider ", reachableCandidate.proposal.ProviderID, " latency: ", reachableCandidate.latency, ", quality: ", reachableCandidate.quality)
+	}
+	return ranked, nil
+}
+
+// probeAll probes candidates concurrently and returns reachable ones with measured latency
+func (selector *Selector) probeAll(consumerID identity.Identity, candidates []candidate) []candidate {
+	var lock sync.Mutex
+	var wait sync.WaitGroup
+	reachable := make([]candidate, 0, len(candidates))
+	slots := make(chan struct{}, probeConcurrency)
+
+	for _, probed := range candidates {
+		wait.Add(1)
+		slots <- struct{}{}
+		go func(probed candidate) {
+			defer func() {
+				<-slots
+				wait.Done()
+			}()
+
+			latency, err := selector.measure(consumerID, probed.proposal)
+			if err != nil {
+				log.Info(selectorLogPrefix, "Provider ", probed.proposal.ProviderID, " is not reachable: ", err)
+				return
+			}
+			probed.latency = latency
+
+			lock.Lock()
+			defer lock.Unlock()
+			reachable = append(reachable, probed)
+		}(probed)
+	}
+	wait.Wait()
+
+	return reachable
+}
+
+func (selector *Selector) measure(consumerID identity.Identity, proposal dto.ServiceProposal) (time.Duration, error) {
+	started := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- selector.probe(consumerID, proposal)
+	}()
+
+	select {
+	case err := <-done:
+		return time.Since(started), err
+	case <-time.After(selector.probeTimeout):
+		return 0, errors.New("probe timed out")
+	}
+}
+
+// ProviderRanker ranks providers matching the filter
+type ProviderRanker interface {
+	Rank(consumerID identity.Identity, filter ProposalFilter) ([]dto.ServiceProposal, error)
+}
+
+// AutoConnector connects to the best provider matching the filter
+type AutoConnector struct {
+	manager Manager
+	ranker  ProviderRanker
+}
+
+// NewAutoConnector creates connector, which establishes connections through given manager
+func NewAutoConnector(manager Manager, ranker ProviderRanker) *AutoConnector {
+	return &AutoConnector{manager: manager, ranker: ranker}
+}
+
+// Connect tries ranked providers in order until connection is established, and returns provider connected to
+func (connector *AutoConnector) Connect(consumerID identity.Identity, filter ProposalFilter, params ConnectParams) (identity.Identity, error) {
+	proposals, err := connector.ranker.Rank(consumerID, filter)
+	if err != nil {
+		return identity.Identity{}, err
+	}
+
+	for _, proposal := range proposals {
+		providerID := identity.FromAddress(proposal.ProviderID)
+		// the same proposal, which matched the filter and was probed, is connected to
+		params.ProposalID = proposal.ID
+		err = connector.manager.Connect(consumerID, providerID, params)
+		switch err {
+		case nil:
+			return providerID, nil
+		case ErrAlreadyExists, ErrConnectionCancelled:
+			return identity.Identity{}, err
+		}
+		log.Warn(selectorLogPrefix, "Failed to connect to provider ", proposal.ProviderID, ", trying next one: ", err)
+	}
+	return identity.Identity{}, err
+}
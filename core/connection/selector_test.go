@@ -0,0 +1,262 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package connection
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/server"
+	"github.com/mysteriumnetwork/node/service_discovery/dto"
+	"github.com/stretchr/testify/assert"
+)
+
+var selectorConsumerID = identity.FromAddress("consumer")
+
+type selectorServiceDefinition struct {
+	country  string
+	protocol string
+}
+
+func (definition selectorServiceDefinition) GetLocation() dto.Location {
+	return dto.Location{Country: definition.country}
+}
+
+func (definition selectorServiceDefinition) GetProtocol() string {
+	return definition.protocol
+}
+
+type qualityOracleFake struct {
+	metrics []json.RawMessage
+}
+
+func (oracle *qualityOracleFake) ProposalsMetrics() []json.RawMessage {
+	return oracle.metrics
+}
+
+type proberFake struct {
+	lock      sync.Mutex
+	latencies map[string]time.Duration
+	failures  map[string]error
+	probed    []string
+}
+
+func (prober *proberFake) probe(consumerID identity.Identity, proposal dto.ServiceProposal) error {
+	prober.lock.Lock()
+	prober.probed = append(prober.probed, proposal.ProviderID)
+	latency := prober.latencies[proposal.ProviderID]
+	err := prober.failures[proposal.ProviderID]
+	prober.lock.Unlock()
+
+	time.Sleep(latency)
+	return err
+}
+
+func selectorProposal(providerID, serviceType, country, protocol string) dto.ServiceProposal {
+	return dto.ServiceProposal{
+		ProviderID:        providerID,
+		ServiceType:       serviceType,
+		ServiceDefinition: selectorServiceDefinition{country: country, protocol: protocol},
+		ProviderContacts:  []dto.Contact{{Type: "fake"}},
+	}
+}
+
+func newSelectorClient(proposals ...dto.ServiceProposal) server.Client {
+	client := server.NewClientFake()
+	for _, proposal := range proposals {
+		client.RegisterProposal(proposal, nil)
+	}
+	return client
+}
+
+func providerIDs(proposals []dto.ServiceProposal) []string {
+	ids := make([]string, len(proposals))
+	for i, proposal := range proposals {
+		ids[i] = proposal.ProviderID
+	}
+	return ids
+}
+
+func TestProposalFilter_Matches(t *testing.T) {
+	proposal := selectorProposal("provider", "openvpn", "LT", "udp")
+
+	assert.True(t, ProposalFilter{}.Matches(proposal))
+	assert.True(t, ProposalFilter{Country: "lt", ServiceType: "openvpn", Protocol: "UDP"}.Matches(proposal))
+	assert.False(t, ProposalFilter{Country: "DE"}.Matches(proposal))
+	assert.False(t, ProposalFilter{ServiceType: "wireguard"}.Matches(proposal))
+	assert.False(t, ProposalFilter{Protocol: "tcp"}.Matches(proposal))
+
+	proposal.ServiceDefinition = &fakeServiceDefinition{}
+	assert.False(t, ProposalFilter{Protocol: "udp"}.Matches(proposal))
+}
+
+func TestSelector_RanksReachableProvidersByLatency(t *testing.T) {
+	client := newSelectorClient(
+		selectorProposal("slow", "openvpn", "LT", "udp"),
+		selectorProposal("fast", "openvpn", "LT", "udp"),
+		selectorProposal("broken", "openvpn", "LT", "udp"),
+		selectorProposal("foreign", "openvpn", "DE", "udp"),
+	)
+	prober := &proberFake{
+		latencies: map[string]time.Duration{"slow": 100 * time.Millisecond},
+		failures:  map[string]error{"broken": errors.New("dialog failed")},
+	}
+	selector := NewSelector(client, &qualityOracleFake{}, prober.probe)
+
+	proposals, err := selector.Rank(selectorConsumerID, ProposalFilter{Country: "LT"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"fast", "slow"}, providerIDs(proposals))
+	assert.NotContains(t, prober.probed, "foreign")
+}
+
+func TestSelector_PrefersProvidersWithBetterQuality(t *testing.T) {
+	client := newSelectorClient(
+		selectorProposal("unreliable", "openvpn", "LT", "udp"),
+		selectorProposal("reliable", "openvpn", "LT", "udp"),
+	)
+	oracle := &qualityOracleFake{metrics: []json.RawMessage{
+		json.RawMessage(`{"proposalId": {"providerId": "unreliable", "serviceType": "openvpn"}, "connectCount": {"success": 1, "fail": 9}}`),
+		json.RawMessage(`{"proposalId": {"providerId": "reliable", "serviceType": "openvpn"}, "connectCount": {"success": 10}}`),
+		json.RawMessage(`malformed`),
+	}}
+	selector := NewSelector(client, oracle, (&proberFake{}).probe)
+
+	proposals, err := selector.Rank(selectorConsumerID, ProposalFilter{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"reliable", "unreliable"}, providerIDs(proposals))
+}
+
+func TestSelector_SkipsProvidersNotAnsweringInTime(t *testing.T) {
+	client := newSelectorClient(
+		selectorProposal("hanging", "openvpn", "LT", "udp"),
+		selectorProposal("fast", "openvpn", "LT", "udp"),
+	)
+	prober := &proberFake{latencies: map[string]time.Duration{"hanging": time.Second}}
+	selector := NewSelector(client, &qualityOracleFake{}, prober.probe)
+	selector.probeTimeout = 50 * time.Millisecond
+
+	proposals, err := selector.Rank(selectorConsumerID, ProposalFilter{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"fast"}, providerIDs(proposals))
+}
+
+func TestSelector_ReturnsErrorWhenNoProviderIsReachable(t *testing.T) {
+	client := newSelectorClient(selectorProposal("broken", "openvpn", "LT", "udp"))
+	prober := &proberFake{failures: map[string]error{"broken": errors.New("dialog failed")}}
+	selector := NewSelector(client, &qualityOracleFake{}, prober.probe)
+
+	_, err := selector.Rank(selectorConsumerID, ProposalFilter{})
+	assert.Equal(t, ErrNoProviders, err)
+
+	_, err = selector.Rank(selectorConsumerID, ProposalFilter{Country: "DE"})
+	assert.Equal(t, ErrNoProviders, err)
+}
+
+func TestSelector_ProbesLimitedNumberOfProviders(t *testing.T) {
+	proposals := make([]dto.ServiceProposal, 0)
+	for i := 0; i < maxProbes+5; i++ {
+		proposals = append(proposals, selectorProposal(string(rune('a'+i)), "openvpn", "LT", "udp"))
+	}
+	prober := &proberFake{}
+	selector := NewSelector(newSelectorClient(proposals...), &qualityOracleFake{}, prober.probe)
+
+	ranked, err := selector.Rank(selectorConsumerID, ProposalFilter{})
+
+	assert.NoError(t, err)
+	assert.Len(t, ranked, maxProbes)
+	assert.Len(t, prober.probed, maxProbes)
+}
+
+type rankerFake struct {
+	proposals []dto.ServiceProposal
+	err       error
+}
+
+func (ranker *rankerFake) Rank(consumerID identity.Identity, filter ProposalFilter) ([]dto.ServiceProposal, error) {
+	return ranker.proposals, ranker.err
+}
+
+type failingManagerFake struct {
+	fakeManager
+	failures  map[string]error
+	attempted []string
+}
+
+func (manager *failingManagerFake) Connect(consumerID, providerID identity.Identity, params ConnectParams) error {
+	manager.attempted = append(manager.attempted, providerID.Address)
+	if err := manager.failures[providerID.Address]; err != nil {
+		return err
+	}
+	return manager.fakeManager.Connect(consumerID, providerID, params)
+}
+
+func TestAutoConnector_FallsBackToNextProvider(t *testing.T) {
+	manager := &failingManagerFake{failures: map[string]error{"first": errors.New("session refused")}}
+	chosen := selectorProposal("second", "openvpn", "LT", "udp")
+	chosen.ID = 2
+	ranker := &rankerFake{proposals: []dto.ServiceProposal{
+		selectorProposal("first", "openvpn", "LT", "udp"),
+		chosen,
+		selectorProposal("third", "openvpn", "LT", "udp"),
+	}}
+	connector := NewAutoConnector(manager, ranker)
+
+	providerID, err := connector.Connect(selectorConsumerID, ProposalFilter{}, ConnectParams{DisableKillSwitch: true})
+
+	assert.NoError(t, err)
+	assert.Equal(t, identity.FromAddress("second"), providerID)
+	assert.Equal(t, []string{"first", "second"}, manager.attempted)
+	assert.Equal(t, ConnectParams{DisableKillSwitch: true, ProposalID: 2}, manager.requestedParams)
+}
+
+func TestAutoConnector_StopsWhenConnectionIsCancelled(t *testing.T) {
+	manager := &failingManagerFake{failures: map[string]error{"first": ErrConnectionCancelled}}
+	ranker := &rankerFake{proposals: []dto.ServiceProposal{
+		selectorProposal("first", "openvpn", "LT", "udp"),
+		selectorProposal("second", "openvpn", "LT", "udp"),
+	}}
+	connector := NewAutoConnector(manager, ranker)
+
+	_, err := connector.Connect(selectorConsumerID, ProposalFilter{}, ConnectParams{})
+
+	assert.Equal(t, ErrConnectionCancelled, err)
+	assert.Equal(t, []string{"first"}, manager.attempted)
+}
+
+func TestAutoConnector_ReturnsLastErrorWhenAllProvidersFail(t *testing.T) {
+	manager := &failingManagerFake{failures: map[string]error{
+		"first":  errors.New("session refused"),
+		"second": errors.New("timed out"),
+	}}
+	ranker := &rankerFake{proposals: []dto.ServiceProposal{
+		selectorProposal("first", "openvpn", "LT", "udp"),
+		selectorProposal("second", "openvpn", "LT", "udp"),
+	}}
+	connector := NewAutoConnector(manager, ranker)
+
+	_, err := connector.Connect(selectorConsumerID, ProposalFilter{}, ConnectParams{})
+
+	assert.EqualError(t, err, "timed out")
+}
@@ -37,28 +37,39 @@ const (
 	Unknown = State("Unknown")
 )
 
+// StateEventTopic is the topic of events published when connection status changes
+const StateEventTopic = "connection-state"
+
+// StateEvent is the payload of connection status change event
+type StateEvent struct {
+	State     State      `json:"state"`
+	SessionID session.ID `json:"sessionId"`
+}
+
 // ConnectionStatus holds connection state and session id of the connnection
 type ConnectionStatus struct {
 	State     State
 	SessionID session.ID
+	// DNS are resolvers of the system while connected
+	DNS []string
 }
 
 func statusConnecting() ConnectionStatus {
-	return ConnectionStatus{Connecting, ""}
+	return ConnectionStatus{State: Connecting}
 }
 
-func statusConnected(sessionID session.ID) ConnectionStatus {
-	return ConnectionStatus{Connected, sessionID}
+func statusConnected(sessionID session.ID, dns []string) ConnectionStatus {
+	return ConnectionStatus{State: Connected, SessionID: sessionID, DNS: dns}
 }
 
 func statusNotConnected() ConnectionStatus {
-	return ConnectionStatus{NotConnected, ""}
+	return ConnectionStatus{State: NotConnected}
 }
 
 func statusReconnecting() ConnectionStatus {
-	return ConnectionStatus{Reconnecting, ""}
+	return ConnectionStatus{State: Reconnecting}
 }
 
 func statusDisconnecting() ConnectionStatus {
-	return ConnectionStatus{Disconnecting, ""}
+	return ConnectionStatus{State: Disconnecting}
 }
@@ -17,7 +17,12 @@
 
 package connection
 
-import "sync"
+import (
+	"errors"
+	"sync"
+
+	"github.com/mysteriumnetwork/node/firewall"
+)
 
 type fakeState string
 
@@ -34,16 +39,28 @@ const (
 	processExited       fakeState = "processExited"
 )
 
+var fakeServerAddress = firewall.ServerAddress{IP: "127.0.0.1", Port: 1194, Protocol: "udp"}
+
+var fakeDNS = []string{"10.8.0.1"}
+
 type connectionFactoryFake struct {
 	vpnClientCreationError error
-	fakeVpnClient          *vpnClientFake
+	// rejectedSessionCreations is a number of following creations, which fail as provider rejects the session
+	rejectedSessionCreations int
+	fakeVpnClient            *vpnClientFake
+	lastOptions              ConnectOptions
 }
 
 func (cff *connectionFactoryFake) CreateConnection(connectionParams ConnectOptions, stateChannel StateChannel) (Connection, error) {
+	cff.lastOptions = connectionParams
 	//each test can set this value to simulate openvpn creation error, this flag is reset BEFORE each test
 	if cff.vpnClientCreationError != nil {
 		return nil, cff.vpnClientCreationError
 	}
+	if cff.rejectedSessionCreations > 0 {
+		cff.rejectedSessionCreations--
+		return nil, errors.New("session not found")
+	}
 	stateCallback := func(state fakeState) {
 		if state == connectedState {
 			stateChannel <- Connected
@@ -100,6 +117,14 @@ func (foc *vpnClientFake) Stop() {
 	foc.fakeProcess.Done()
 }
 
+func (foc *vpnClientFake) ServerAddress() firewall.ServerAddress {
+	return fakeServerAddress
+}
+
+func (foc *vpnClientFake) DNS() []string {
+	return fakeDNS
+}
+
 func (foc *vpnClientFake) reportState(state fakeState) {
 	foc.RLock()
 	defer foc.RUnlock()
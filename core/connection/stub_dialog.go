@@ -21,13 +21,18 @@ import (
 	"sync"
 
 	"github.com/mysteriumnetwork/node/communication"
+	"github.com/mysteriumnetwork/node/firewall"
 	"github.com/mysteriumnetwork/node/identity"
 	"github.com/mysteriumnetwork/node/session"
 )
 
+var fakeBrokerAddress = firewall.ServerAddress{IP: "127.0.0.2", Port: 4222, Protocol: "tcp"}
+
 type fakeDialog struct {
-	peerID identity.Identity
-	closed bool
+	peerID            identity.Identity
+	closed            bool
+	requestCount      int
+	destroyedSessions []session.ID
 
 	sync.RWMutex
 }
@@ -47,6 +52,10 @@ func (fd *fakeDialog) Close() error {
 	return nil
 }
 
+func (fd *fakeDialog) PeerAddresses() []firewall.ServerAddress {
+	return []firewall.ServerAddress{fakeBrokerAddress}
+}
+
 func (fd *fakeDialog) Receive(consumer communication.MessageConsumer) error {
 	return nil
 }
@@ -59,6 +68,15 @@ func (fd *fakeDialog) Send(producer communication.MessageProducer) error {
 }
 
 func (fd *fakeDialog) Request(producer communication.RequestProducer) (responsePtr interface{}, err error) {
+	fd.Lock()
+	defer fd.Unlock()
+
+	if request, ok := producer.Produce().(*session.DestroyRequest); ok {
+		fd.destroyedSessions = append(fd.destroyedSessions, request.SessionID)
+		return &session.DestroyResponse{Success: true}, nil
+	}
+
+	fd.requestCount++
 	return &session.CreateResponse{
 			Success: true,
 			Session: session.SessionDto{
@@ -68,3 +86,17 @@ func (fd *fakeDialog) Request(producer communication.RequestProducer) (responseP
 		},
 		nil
 }
+
+func (fd *fakeDialog) sessionsDestroyed() []session.ID {
+	fd.RLock()
+	defer fd.RUnlock()
+
+	return append([]session.ID{}, fd.destroyedSessions...)
+}
+
+func (fd *fakeDialog) requestsMade() int {
+	fd.RLock()
+	defer fd.RUnlock()
+
+	return fd.requestCount
+}
@@ -0,0 +1,62 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package connection
+
+import (
+	"sync"
+
+	"github.com/mysteriumnetwork/node/firewall"
+)
+
+type fakeKillSwitch struct {
+	enabled bool
+	servers []firewall.ServerAddress
+
+	sync.RWMutex
+}
+
+func (ks *fakeKillSwitch) Enable(servers ...firewall.ServerAddress) error {
+	ks.Lock()
+	defer ks.Unlock()
+
+	ks.enabled = true
+	ks.servers = servers
+	return nil
+}
+
+func (ks *fakeKillSwitch) Disable() error {
+	ks.Lock()
+	defer ks.Unlock()
+
+	ks.enabled = false
+	return nil
+}
+
+func (ks *fakeKillSwitch) isEnabled() bool {
+	ks.RLock()
+	defer ks.RUnlock()
+
+	return ks.enabled
+}
+
+func (ks *fakeKillSwitch) allowedServers() []firewall.ServerAddress {
+	ks.RLock()
+	defer ks.RUnlock()
+
+	return ks.servers
+}
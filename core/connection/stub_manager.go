@@ -0,0 +1,59 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package connection
+
+import (
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/session"
+)
+
+type fakeManager struct {
+	onConnectStatus  ConnectionStatus
+	onConnectError   error
+	requestedParams  ConnectParams
+	status           ConnectionStatus
+	disconnectCalled bool
+	killSwitch       bool
+}
+
+func (fm *fakeManager) Connect(consumerID, providerID identity.Identity, params ConnectParams) error {
+	fm.requestedParams = params
+	if fm.onConnectError != nil {
+		return fm.onConnectError
+	}
+	fm.status = fm.onConnectStatus
+	return nil
+}
+
+func (fm *fakeManager) Status() ConnectionStatus {
+	return fm.status
+}
+
+func (fm *fakeManager) KillSwitchEnabled() bool {
+	return fm.killSwitch
+}
+
+func (fm *fakeManager) Disconnect() error {
+	fm.disconnectCalled = true
+	fm.status = statusNotConnected()
+	return nil
+}
+
+func newConnectedFakeManager(sessionID session.ID) *fakeManager {
+	return &fakeManager{status: statusConnected(sessionID, nil), onConnectStatus: statusConnected(sessionID, nil)}
+}
@@ -17,14 +17,17 @@
 
 package connection
 
-import "github.com/mysteriumnetwork/node/service_discovery/dto"
+import (
+	"github.com/mysteriumnetwork/node/service_discovery/dto"
+	"github.com/mysteriumnetwork/node/session"
+)
 
 type fakePromiseIssuer struct {
 	startCalled bool
 	stopCalled  bool
 }
 
-func (issuer *fakePromiseIssuer) Start(proposal dto.ServiceProposal) error {
+func (issuer *fakePromiseIssuer) Start(proposal dto.ServiceProposal, sessionID session.ID) error {
 	issuer.startCalled = true
 	return nil
 }
@@ -0,0 +1,184 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Event is a notification published by node components to the bus
+type Event struct {
+	Topic   string      `json:"topic"`
+	Time    time.Time   `json:"time"`
+	Payload interface{} `json:"payload"`
+}
+
+// Publisher publishes events of given topic to all interested subscribers
+type Publisher interface {
+	Publish(topic string, payload interface{})
+}
+
+// Subscriber allows to receive all events published to the bus
+type Subscriber interface {
+	// Subscribe returns channel of events and function which unsubscribes and closes the channel
+	Subscribe() (<-chan Event, func())
+}
+
+// Bus dispatches published events to all subscribers. Publishing never blocks - events are queued for subscribers,
+// which do not keep up with consuming them. Queued events of coalesced topics (i.e. statistics) are replaced by newer ones,
+// so only events of other topics pile up in the queue of slow subscriber.
+type Bus struct {
+	bufferSize  int
+	coalesced   map[string]bool
+	subscribers map[int]*subscriber
+	nextID      int
+	timeGetter  func() time.Time
+
+	lock sync.RWMutex
+}
+
+// NewBus creates event bus, which buffers up to given number of events per subscriber.
+// Only the latest queued event of each coalesced topic is delivered to slow subscriber.
+func NewBus(bufferSize int, coalescedTopics ...string) *Bus {
+	coalesced := make(map[string]bool)
+	for _, topic := range coalescedTopics {
+		coalesced[topic] = true
+	}
+	return &Bus{
+		bufferSize:  bufferSize,
+		coalesced:   coalesced,
+		subscribers: make(map[int]*subscriber),
+		timeGetter:  time.Now,
+	}
+}
+
+// Publish sends event with given topic and payload to all subscribers
+func (bus *Bus) Publish(topic string, payload interface{}) {
+	event := Event{Topic: topic, Time: bus.timeGetter(), Payload: payload}
+
+	bus.lock.RLock()
+	defer bus.lock.RUnlock()
+
+	for _, subscriber := range bus.subscribers {
+		subscriber.enqueue(event, bus.coalesced[topic])
+	}
+}
+
+// Subscribe registers new subscriber
+func (bus *Bus) Subscribe() (<-chan Event, func()) {
+	bus.lock.Lock()
+	defer bus.lock.Unlock()
+
+	id := bus.nextID
+	bus.nextID++
+	subscriber := newSubscriber(bus.bufferSize)
+	bus.subscribers[id] = subscriber
+	go subscriber.deliver()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			bus.lock.Lock()
+			delete(bus.subscribers, id)
+			bus.lock.Unlock()
+
+			subscriber.close()
+		})
+	}
+	return subscriber.events, unsubscribe
+}
+
+// subscriber queues events, until they are delivered to its channel
+type subscriber struct {
+	events  chan Event
+	pending []Event
+	lock    sync.Mutex
+
+	wake    chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+func newSubscriber(bufferSize int) *subscriber {
+	return &subscriber{
+		events:  make(chan Event, bufferSize),
+		wake:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+}
+
+// enqueue adds event to the queue, coalesced event replaces queued event of the same topic
+func (sub *subscriber) enqueue(event Event, coalesced bool) {
+	sub.lock.Lock()
+	defer sub.lock.Unlock()
+
+	replaced := false
+	if coalesced {
+		for i := range sub.pending {
+			if sub.pending[i].Topic == event.Topic {
+				sub.pending[i] = event
+				replaced = true
+				break
+			}
+		}
+	}
+	if !replaced {
+		sub.pending = append(sub.pending, event)
+	}
+
+	select {
+	case sub.wake <- struct{}{}:
+	default:
+	}
+}
+
+// deliver moves queued events to the channel of subscriber in order they were published
+func (sub *subscriber) deliver() {
+	defer close(sub.stopped)
+
+	for {
+		sub.lock.Lock()
+		if len(sub.pending) == 0 {
+			sub.lock.Unlock()
+			select {
+			case <-sub.wake:
+				continue
+			case <-sub.done:
+				return
+			}
+		}
+		event := sub.pending[0]
+		sub.pending = sub.pending[1:]
+		sub.lock.Unlock()
+
+		select {
+		case sub.events <- event:
+		case <-sub.done:
+			return
+		}
+	}
+}
+
+// close stops delivery and closes the channel of subscriber, queued events are discarded
+func (sub *subscriber) close() {
+	close(sub.done)
+	<-sub.stopped
+	close(sub.events)
+}
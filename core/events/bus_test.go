@@ -0,0 +1,93 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package events
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var fakeTime = time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestBus(bufferSize int) *Bus {
+	bus := NewBus(bufferSize)
+	bus.timeGetter = func() time.Time { return fakeTime }
+	return bus
+}
+
+func TestBus_PublishDeliversEventToAllSubscribers(t *testing.T) {
+	bus := newTestBus(1)
+	first, _ := bus.Subscribe()
+	second, _ := bus.Subscribe()
+
+	bus.Publish("topic", "payload")
+
+	expected := Event{Topic: "topic", Time: fakeTime, Payload: "payload"}
+	assert.Equal(t, expected, <-first)
+	assert.Equal(t, expected, <-second)
+}
+
+func TestBus_PublishQueuesEventsForSlowSubscriber(t *testing.T) {
+	bus := newTestBus(1)
+	events, _ := bus.Subscribe()
+
+	bus.Publish("topic", 1)
+	bus.Publish("topic", 2)
+	bus.Publish("topic", 3)
+
+	assert.Equal(t, 1, (<-events).Payload)
+	assert.Equal(t, 2, (<-events).Payload)
+	assert.Equal(t, 3, (<-events).Payload)
+}
+
+func TestBus_PublishCoalescesQueuedEventsOfCoalescedTopic(t *testing.T) {
+	bus := NewBus(0, "statistics")
+	bus.timeGetter = func() time.Time { return fakeTime }
+	events, _ := bus.Subscribe()
+
+	bus.Publish("state", "connecting")
+	for i := 1; i <= 100; i++ {
+		bus.Publish("statistics", i)
+	}
+	bus.Publish("state", "connected")
+
+	var received []Event
+	for event := range events {
+		received = append(received, event)
+		if event.Payload == "connected" {
+			break
+		}
+	}
+	assert.Equal(t, "connecting", received[0].Payload)
+	assert.Equal(t, 100, received[len(received)-2].Payload)
+	assert.True(t, len(received) <= 4, "statistics events are not coalesced: %v", received)
+}
+
+func TestBus_UnsubscribeClosesChannel(t *testing.T) {
+	bus := newTestBus(1)
+	events, unsubscribe := bus.Subscribe()
+
+	unsubscribe()
+	unsubscribe()
+	bus.Publish("topic", "payload")
+
+	_, more := <-events
+	assert.False(t, more)
+}
@@ -0,0 +1,48 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package events
+
+import "sync"
+
+// PublisherFake records published events instead of dispatching them
+type PublisherFake struct {
+	events []Event
+
+	lock sync.Mutex
+}
+
+// NewPublisherFake creates fake publisher
+func NewPublisherFake() *PublisherFake {
+	return &PublisherFake{}
+}
+
+// Publish records given event
+func (fake *PublisherFake) Publish(topic string, payload interface{}) {
+	fake.lock.Lock()
+	defer fake.lock.Unlock()
+
+	fake.events = append(fake.events, Event{Topic: topic, Payload: payload})
+}
+
+// Published returns copy of all events recorded so far
+func (fake *PublisherFake) Published() []Event {
+	fake.lock.Lock()
+	defer fake.lock.Unlock()
+
+	return append([]Event{}, fake.events...)
+}
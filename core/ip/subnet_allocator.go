@@ -0,0 +1,180 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ip
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net"
+	"strings"
+	"sync"
+)
+
+// ErrNoFreeSubnet is returned when every subnet of the pool is allocated or used by local networks
+var ErrNoFreeSubnet = errors.New("no free subnet left in the pool")
+
+// subnetMaskSize is the size of allocated subnets, i.e. /24 gives 253 addresses for clients
+const subnetMaskSize = 24
+
+// minRouteMaskSize skips routes wider than /8, i.e. 0.0.0.0/1 and 128.0.0.0/1 installed by VPN clients instead of default route.
+// Such routes cover whole address space and would leave no free subnet in any pool.
+const minRouteMaskSize = 8
+
+// LocalNetworksProvider lists networks of local interfaces and routes, which allocated subnets must not overlap
+type LocalNetworksProvider func() ([]*net.IPNet, error)
+
+// SubnetAllocator hands out free /24 subnets, so every service gets a separate one
+type SubnetAllocator struct {
+	localNetworks LocalNetworksProvider
+	allocated     map[string]bool
+	lock          sync.Mutex
+}
+
+// NewSubnetAllocator creates allocator, which avoids networks of local interfaces and routes
+func NewSubnetAllocator() *SubnetAllocator {
+	return NewSubnetAllocatorWithNetworks(LocalNetworks)
+}
+
+// NewSubnetAllocatorWithNetworks creates allocator, which avoids networks given by provider
+func NewSubnetAllocatorWithNetworks(localNetworks LocalNetworksProvider) *SubnetAllocator {
+	return &SubnetAllocator{
+		localNetworks: localNetworks,
+		allocated:     make(map[string]bool),
+	}
+}
+
+// Allocate picks free /24 subnet within the pool, i.e. "10.8.0.0/16".
+// Pool of /24 or smaller is allocated as is, if it is free.
+func (allocator *SubnetAllocator) Allocate(pool string) (*net.IPNet, error) {
+	_, poolNetwork, err := net.ParseCIDR(pool)
+	if err != nil {
+		return nil, err
+	}
+	if poolNetwork.IP.To4() == nil {
+		return nil, errors.New("only IPv4 subnet pools are supported: " + pool)
+	}
+
+	used, err := allocator.localNetworks()
+	if err != nil {
+		return nil, err
+	}
+
+	allocator.lock.Lock()
+	defer allocator.lock.Unlock()
+
+	poolSize, _ := poolNetwork.Mask.Size()
+	if poolSize >= subnetMaskSize {
+		return allocator.allocate(poolNetwork, used)
+	}
+
+	first := binary.BigEndian.Uint32(poolNetwork.IP.To4())
+	count := uint32(1) << uint(subnetMaskSize-poolSize)
+	for i := uint32(0); i < count; i++ {
+		candidate := &net.IPNet{
+			IP:   make(net.IP, net.IPv4len),
+			Mask: net.CIDRMask(subnetMaskSize, 8*net.IPv4len),
+		}
+		binary.BigEndian.PutUint32(candidate.IP, first+i<<(32-subnetMaskSize))
+
+		if subnet, err := allocator.allocate(candidate, used); err == nil {
+			return subnet, nil
+		}
+	}
+	return nil, ErrNoFreeSubnet
+}
+
+// Release returns subnet to the pool
+func (allocator *SubnetAllocator) Release(subnet *net.IPNet) {
+	allocator.lock.Lock()
+	defer allocator.lock.Unlock()
+
+	delete(allocator.allocated, subnet.String())
+}
+
+func (allocator *SubnetAllocator) allocate(candidate *net.IPNet, used []*net.IPNet) (*net.IPNet, error) {
+	if allocator.allocated[candidate.String()] {
+		return nil, ErrNoFreeSubnet
+	}
+	for _, network := range used {
+		if overlaps(candidate, network) {
+			return nil, ErrNoFreeSubnet
+		}
+	}
+
+	allocator.allocated[candidate.String()] = true
+	return candidate, nil
+}
+
+func overlaps(first, second *net.IPNet) bool {
+	return first.Contains(second.IP) || second.Contains(first.IP)
+}
+
+// LocalNetworks lists networks of local interfaces and, on linux, networks of routing table except routes wider than /8
+func LocalNetworks() ([]*net.IPNet, error) {
+	addresses, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, err
+	}
+
+	var networks []*net.IPNet
+	for _, address := range addresses {
+		if network, ok := address.(*net.IPNet); ok && network.IP.To4() != nil {
+			networks = append(networks, &net.IPNet{IP: network.IP.Mask(network.Mask), Mask: network.Mask})
+		}
+	}
+
+	// routing table is not available on other platforms, interface networks are enough there
+	if routes, err := ioutil.ReadFile("/proc/net/route"); err == nil {
+		networks = append(networks, parseRoutes(string(routes))...)
+	}
+	return networks, nil
+}
+
+// parseRoutes parses destination networks of /proc/net/route, where addresses are little endian hex numbers
+func parseRoutes(routes string) []*net.IPNet {
+	var networks []*net.IPNet
+	for _, line := range strings.Split(routes, "\n")[1:] {
+		fields := strings.Fields(line)
+		if len(fields) < 8 {
+			continue
+		}
+
+		destination, errDestination := hex.DecodeString(fields[1])
+		mask, errMask := hex.DecodeString(fields[7])
+		if errDestination != nil || errMask != nil || len(destination) != net.IPv4len || len(mask) != net.IPv4len {
+			continue
+		}
+		reverse(destination)
+		reverse(mask)
+
+		network := &net.IPNet{IP: net.IP(destination), Mask: net.IPMask(mask)}
+		if size, _ := network.Mask.Size(); size < minRouteMaskSize {
+			continue
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+func reverse(bytes []byte) {
+	for i, j := 0, len(bytes)-1; i < j; i, j = i+1, j-1 {
+		bytes[i], bytes[j] = bytes[j], bytes[i]
+	}
+}
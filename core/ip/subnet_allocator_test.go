@@ -0,0 +1,110 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ip
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func parseNetworks(subnets ...string) LocalNetworksProvider {
+	return func() ([]*net.IPNet, error) {
+		networks := make([]*net.IPNet, len(subnets))
+		for i, subnet := range subnets {
+			_, networks[i], _ = net.ParseCIDR(subnet)
+		}
+		return networks, nil
+	}
+}
+
+func TestSubnetAllocator_AllocateSkipsUsedSubnets(t *testing.T) {
+	allocator := NewSubnetAllocatorWithNetworks(parseNetworks("10.8.0.0/24", "10.8.1.128/25", "192.168.1.0/24"))
+
+	first, err := allocator.Allocate("10.8.0.0/16")
+	assert.NoError(t, err)
+	assert.Equal(t, "10.8.2.0/24", first.String())
+
+	second, err := allocator.Allocate("10.8.0.0/16")
+	assert.NoError(t, err)
+	assert.Equal(t, "10.8.3.0/24", second.String())
+
+	allocator.Release(first)
+	third, err := allocator.Allocate("10.8.0.0/16")
+	assert.NoError(t, err)
+	assert.Equal(t, "10.8.2.0/24", third.String(), "released subnet is reused")
+}
+
+func TestSubnetAllocator_AllocateExactSubnet(t *testing.T) {
+	allocator := NewSubnetAllocatorWithNetworks(parseNetworks("192.168.1.0/24"))
+
+	subnet, err := allocator.Allocate("10.9.0.0/24")
+	assert.NoError(t, err)
+	assert.Equal(t, "10.9.0.0/24", subnet.String())
+
+	_, err = allocator.Allocate("10.9.0.0/24")
+	assert.Equal(t, ErrNoFreeSubnet, err)
+
+	_, err = allocator.Allocate("192.168.0.0/16")
+	assert.NoError(t, err)
+	_, err = allocator.Allocate("192.168.1.0/24")
+	assert.Equal(t, ErrNoFreeSubnet, err, "subnet of local network is not allocated")
+}
+
+func TestSubnetAllocator_AllocateFromExhaustedPool(t *testing.T) {
+	allocator := NewSubnetAllocatorWithNetworks(parseNetworks("10.8.0.0/23"))
+
+	_, err := allocator.Allocate("10.8.0.0/23")
+	assert.Equal(t, ErrNoFreeSubnet, err)
+}
+
+func TestSubnetAllocator_AllocateInvalidPool(t *testing.T) {
+	allocator := NewSubnetAllocatorWithNetworks(parseNetworks())
+
+	_, err := allocator.Allocate("10.8.0.0")
+	assert.Error(t, err)
+	_, err = allocator.Allocate("fd00::/64")
+	assert.Error(t, err)
+}
+
+func TestSubnetAllocator_AllocateFailsWithoutLocalNetworks(t *testing.T) {
+	allocator := NewSubnetAllocatorWithNetworks(func() ([]*net.IPNet, error) {
+		return nil, errors.New("no interfaces")
+	})
+
+	_, err := allocator.Allocate("10.8.0.0/16")
+	assert.EqualError(t, err, "no interfaces")
+}
+
+func TestParseRoutes(t *testing.T) {
+	routes := "Iface\tDestination\tGateway \tFlags\tRefCnt\tUse\tMetric\tMask\t\tMTU\tWindow\tIRTT\n" +
+		"eth0\t00000000\t0101A8C0\t0003\t0\t0\t100\t00000000\t0\t0\t0\n" +
+		"eth0\t0001A8C0\t00000000\t0001\t0\t0\t100\t00FFFFFF\t0\t0\t0\n" +
+		"tun0\t0000080A\t00000000\t0001\t0\t0\t0\t00FFFFFF\t0\t0\t0\n" +
+		"tun0\t00000000\t0100080A\t0003\t0\t0\t0\t00000080\t0\t0\t0\n" +
+		"tun0\t00000080\t0100080A\t0003\t0\t0\t0\t00000080\t0\t0\t0\n" +
+		"tun1\t0000000A\t00000000\t0001\t0\t0\t0\t000000FF\t0\t0\t0\n"
+
+	networks := parseRoutes(routes)
+	assert.Len(t, networks, 3)
+	assert.Equal(t, "192.168.1.0/24", networks[0].String())
+	assert.Equal(t, "10.8.0.0/24", networks[1].String())
+	assert.Equal(t, "10.0.0.0/8", networks[2].String())
+}
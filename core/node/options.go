@@ -33,5 +33,7 @@ type Options struct {
 
 	Openvpn  Openvpn
 	Location OptionsLocation
+	// NATBackend selects packet filtering framework, which services use to forward traffic
+	NATBackend string
 	OptionsNetwork
 }
@@ -17,6 +17,8 @@
 
 package node
 
+import "time"
+
 // OptionsNetwork describes possible parameters of network configuration
 type OptionsNetwork struct {
 	Testnet  bool
@@ -25,11 +27,14 @@ type OptionsNetwork struct {
 	ExperimentIdentityCheck bool
 	ExperimentPromiseCheck  bool
 
-	DiscoveryAPIAddress string
-	BrokerAddress       string
+	DiscoveryAPIAddress      string
+	BrokerAddress            string
+	ProposalsCacheTTL        time.Duration
+	ProposalsRefreshInterval time.Duration
 
-	EtherClientRPC       string
-	EtherPaymentsAddress string
+	EtherClientRPC          string
+	EtherPaymentsAddress    string
+	PromiseClearingInterval time.Duration
 
 	QualityOracle string
 }
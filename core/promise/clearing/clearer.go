@@ -0,0 +1,261 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package clearing
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/cihub/seelog"
+	"github.com/mysteriumnetwork/node/core/promise"
+	"github.com/mysteriumnetwork/node/core/storage"
+)
+
+const logPrefix = "[promise-clearing] "
+
+// ErrAlreadyRunning is returned, when scheduled clearing is started twice
+var ErrAlreadyRunning = errors.New("promise clearing is already running")
+
+type promiseLedger interface {
+	List() ([]promise.LedgerEntry, error)
+}
+
+// Clearer redeems promises from the ledger by submitting them to the payments contract
+type Clearer struct {
+	ledger     promiseLedger
+	transactor Transactor
+	storage    storage.Storage
+	timeGetter func() time.Time
+
+	clearingLock sync.Mutex
+	stop         chan struct{}
+	stopLock     sync.Mutex
+}
+
+// NewClearer creates promise clearer, which keeps submitted transactions in the given storage
+func NewClearer(ledger promiseLedger, transactor Transactor, storage storage.Storage) *Clearer {
+	return &Clearer{
+		ledger:     ledger,
+		transactor: transactor,
+		storage:    storage,
+		timeGetter: time.Now,
+	}
+}
+
+// Clear submits the latest not yet cleared promise of every issuer and benefiter pair.
+// Transactions submitted during this run are returned.
+func (clearer *Clearer) Clear() ([]Transaction, error) {
+	clearer.clearingLock.Lock()
+	defer clearer.clearingLock.Unlock()
+
+	entries, err := clearer.ledger.List()
+	if err != nil {
+		return nil, err
+	}
+	transactions, err := clearer.transactions()
+	if err != nil {
+		return nil, err
+	}
+
+	submitted := make([]Transaction, 0)
+	for _, entry := range latestEntries(entries) {
+		signedPromise := entry.SignedPromise
+		// promises, which issuer hasn't signed for the contract, can't be cleared
+		if signedPromise.ClearingSignature == "" {
+			continue
+		}
+		if isCleared(transactions, entry) {
+			continue
+		}
+
+		transaction, err := clearer.submit(entry)
+		if err != nil {
+			log.Error(logPrefix, fmt.Sprintf("Failed to clear promise #%d of %s: ", signedPromise.Promise.SerialNumber, signedPromise.Promise.IssuerID), err)
+			continue
+		}
+		submitted = append(submitted, transaction)
+	}
+
+	return submitted, nil
+}
+
+// RefreshStatuses updates statuses of pending transactions from blockchain
+func (clearer *Clearer) RefreshStatuses() error {
+	clearer.clearingLock.Lock()
+	defer clearer.clearingLock.Unlock()
+
+	transactions, err := clearer.transactions()
+	if err != nil {
+		return err
+	}
+
+	for _, transaction := range transactions {
+		if transaction.Status != TxPending {
+			continue
+		}
+
+		status, err := clearer.transactor.TransactionStatus(transaction.Hash)
+		if err != nil {
+			log.Warn(logPrefix, "Failed to get status of transaction ", transaction.Hash, ": ", err)
+			continue
+		}
+		if status == transaction.Status {
+			continue
+		}
+
+		transaction.Status = status
+		transaction.Updated = clearer.timeGetter()
+		if err := clearer.storage.Update(&transaction); err != nil {
+			return err
+		}
+		log.Info(logPrefix, "Transaction ", transaction.Hash, " is ", status)
+	}
+
+	return nil
+}
+
+// Transactions returns all clearing transactions ordered by submission time
+func (clearer *Clearer) Transactions() ([]Transaction, error) {
+	clearer.clearingLock.Lock()
+	defer clearer.clearingLock.Unlock()
+
+	return clearer.transactions()
+}
+
+func (clearer *Clearer) transactions() ([]Transaction, error) {
+	var transactions []Transaction
+	if err := clearer.storage.GetAll(&transactions); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(transactions, func(i, j int) bool {
+		return transactions[i].Submitted.Before(transactions[j].Submitted)
+	})
+	return transactions, nil
+}
+
+// Start clears promises and refreshes transaction statuses periodically
+func (clearer *Clearer) Start(interval time.Duration) error {
+	clearer.stopLock.Lock()
+	defer clearer.stopLock.Unlock()
+
+	if clearer.stop != nil {
+		return ErrAlreadyRunning
+	}
+	clearer.stop = make(chan struct{})
+
+	go clearer.scheduleLoop(interval, clearer.stop)
+	return nil
+}
+
+// Stop stops periodic clearing
+func (clearer *Clearer) Stop() {
+	clearer.stopLock.Lock()
+	defer clearer.stopLock.Unlock()
+
+	if clearer.stop != nil {
+		close(clearer.stop)
+		clearer.stop = nil
+	}
+}
+
+func (clearer *Clearer) scheduleLoop(interval time.Duration, stop <-chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		case <-time.After(interval):
+			if err := clearer.RefreshStatuses(); err != nil {
+				log.Error(logPrefix, "Failed to refresh transaction statuses: ", err)
+			}
+			if _, err := clearer.Clear(); err != nil {
+				log.Error(logPrefix, "Scheduled clearing failed: ", err)
+			}
+		}
+	}
+}
+
+func (clearer *Clearer) submit(entry promise.LedgerEntry) (Transaction, error) {
+	txHash, err := clearer.transactor.ClearPromise(entry.SignedPromise)
+	if err != nil {
+		return Transaction{}, err
+	}
+
+	unsignedPromise := entry.SignedPromise.Promise
+	now := clearer.timeGetter()
+	transaction := Transaction{
+		Hash:         txHash,
+		IssuerID:     unsignedPromise.IssuerID,
+		BenefiterID:  unsignedPromise.BenefiterID,
+		SessionID:    entry.SessionID,
+		SerialNumber: unsignedPromise.SerialNumber,
+		Amount:       unsignedPromise.Amount,
+		Status:       TxPending,
+		Submitted:    now,
+		Updated:      now,
+	}
+	log.Info(logPrefix, fmt.Sprintf("Promise #%d of %s submitted for clearing: %s", transaction.SerialNumber, transaction.IssuerID, txHash))
+	return transaction, clearer.storage.Save(&transaction)
+}
+
+// latestEntries picks the entry with the highest promise serial of every issuer and benefiter pair.
+// Promise amounts are cumulative, so clearing the latest promise redeems all earlier ones of the pair.
+func latestEntries(entries []promise.LedgerEntry) []promise.LedgerEntry {
+	latest := make(map[string]promise.LedgerEntry)
+	for _, entry := range entries {
+		key := pairKey(entry.IssuerID, entry.BenefiterID)
+		if previous, found := latest[key]; found && previous.SignedPromise.Promise.SerialNumber >= entry.SignedPromise.Promise.SerialNumber {
+			continue
+		}
+		latest[key] = entry
+	}
+
+	result := make([]promise.LedgerEntry, 0, len(latest))
+	for _, entry := range latest {
+		result = append(result, entry)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return pairKey(result[i].IssuerID, result[i].BenefiterID) < pairKey(result[j].IssuerID, result[j].BenefiterID)
+	})
+	return result
+}
+
+// isCleared checks if the same or later promise of the issuer and benefiter pair was already submitted and not failed
+func isCleared(transactions []Transaction, entry promise.LedgerEntry) bool {
+	key := pairKey(entry.IssuerID, entry.BenefiterID)
+	for _, transaction := range transactions {
+		if transaction.Status == TxFailed {
+			continue
+		}
+		if pairKey(transaction.IssuerID, transaction.BenefiterID) != key {
+			continue
+		}
+		if transaction.SerialNumber >= entry.SignedPromise.Promise.SerialNumber {
+			return true
+		}
+	}
+	return false
+}
+
+func pairKey(issuerID, benefiterID string) string {
+	return strings.ToLower(issuerID) + ":" + strings.ToLower(benefiterID)
+}
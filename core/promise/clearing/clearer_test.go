@@ -0,0 +1,247 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package clearing
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mysteriumnetwork/node/core/promise"
+	"github.com/mysteriumnetwork/node/core/storage"
+	"github.com/mysteriumnetwork/node/money"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	issuerA   = "0x000000000000000000000000000000000000000a"
+	issuerB   = "0x000000000000000000000000000000000000000b"
+	benefiter = "0x0000000000000000000000000000000000000001"
+)
+
+func TestClearer_Clear_SubmitsLatestPromiseOfEveryPair(t *testing.T) {
+	transactor := &fakeTransactor{}
+	ledger := &fakeLedger{entries: []promise.LedgerEntry{
+		ledgerEntry(issuerB, "session-3", 1, 100),
+		ledgerEntry(issuerA, "session-2", 6, 550),
+		ledgerEntry(issuerA, "session-1", 5, 500),
+	}}
+	clearer := NewClearer(ledger, transactor, newTransactionStorageFake())
+
+	submitted, err := clearer.Clear()
+	assert.NoError(t, err)
+	assert.Len(t, submitted, 2)
+	assert.Equal(t, []string{"0xa:6:550", "0xb:1:100"}, transactor.cleared)
+
+	assert.Equal(t, issuerA, submitted[0].IssuerID)
+	assert.Equal(t, "session-2", submitted[0].SessionID)
+	assert.Equal(t, 6, submitted[0].SerialNumber)
+	assert.Equal(t, TxPending, submitted[0].Status)
+
+	transactions, err := clearer.Transactions()
+	assert.NoError(t, err)
+	assert.Len(t, transactions, 2)
+}
+
+func TestClearer_Clear_SkipsAlreadySubmittedPromises(t *testing.T) {
+	transactor := &fakeTransactor{}
+	ledger := &fakeLedger{entries: []promise.LedgerEntry{ledgerEntry(issuerA, "session-1", 1, 100)}}
+	clearer := NewClearer(ledger, transactor, newTransactionStorageFake())
+
+	_, err := clearer.Clear()
+	assert.NoError(t, err)
+
+	submitted, err := clearer.Clear()
+	assert.NoError(t, err)
+	assert.Len(t, submitted, 0)
+
+	ledger.entries = []promise.LedgerEntry{ledgerEntry(issuerA, "session-2", 2, 200)}
+	submitted, err = clearer.Clear()
+	assert.NoError(t, err)
+	assert.Len(t, submitted, 1)
+	assert.Equal(t, "session-2", submitted[0].SessionID)
+	assert.Equal(t, []string{"0xa:1:100", "0xa:2:200"}, transactor.cleared)
+}
+
+func TestClearer_Clear_SkipsPromisesBelowClearedSerial(t *testing.T) {
+	transactor := &fakeTransactor{status: TxSucceeded}
+	ledger := &fakeLedger{entries: []promise.LedgerEntry{ledgerEntry(issuerA, "session-2", 5, 500)}}
+	clearer := NewClearer(ledger, transactor, newTransactionStorageFake())
+
+	_, err := clearer.Clear()
+	assert.NoError(t, err)
+	assert.NoError(t, clearer.RefreshStatuses())
+
+	ledger.entries = []promise.LedgerEntry{ledgerEntry(issuerA, "session-1", 3, 300)}
+	submitted, err := clearer.Clear()
+	assert.NoError(t, err)
+	assert.Len(t, submitted, 0)
+	assert.Equal(t, []string{"0xa:5:500"}, transactor.cleared)
+}
+
+func TestClearer_Clear_SkipsPromisesWithoutClearingSignature(t *testing.T) {
+	transactor := &fakeTransactor{}
+	entry := ledgerEntry(issuerA, "session-1", 1, 100)
+	entry.SignedPromise.ClearingSignature = ""
+	ledger := &fakeLedger{entries: []promise.LedgerEntry{entry}}
+	clearer := NewClearer(ledger, transactor, newTransactionStorageFake())
+
+	submitted, err := clearer.Clear()
+	assert.NoError(t, err)
+	assert.Len(t, submitted, 0)
+	assert.Len(t, transactor.cleared, 0)
+}
+
+func TestClearer_Clear_ResubmitsFailedPromise(t *testing.T) {
+	transactor := &fakeTransactor{status: TxFailed}
+	ledger := &fakeLedger{entries: []promise.LedgerEntry{ledgerEntry(issuerA, "session-1", 1, 100)}}
+	clearer := NewClearer(ledger, transactor, newTransactionStorageFake())
+
+	_, err := clearer.Clear()
+	assert.NoError(t, err)
+	assert.NoError(t, clearer.RefreshStatuses())
+
+	submitted, err := clearer.Clear()
+	assert.NoError(t, err)
+	assert.Len(t, submitted, 1)
+}
+
+func TestClearer_Clear_ContinuesWhenSubmissionFails(t *testing.T) {
+	transactor := &fakeTransactor{failIssuer: issuerA}
+	ledger := &fakeLedger{entries: []promise.LedgerEntry{
+		ledgerEntry(issuerA, "session-1", 1, 100),
+		ledgerEntry(issuerB, "session-2", 1, 100),
+	}}
+	clearer := NewClearer(ledger, transactor, newTransactionStorageFake())
+
+	submitted, err := clearer.Clear()
+	assert.NoError(t, err)
+	assert.Len(t, submitted, 1)
+	assert.Equal(t, issuerB, submitted[0].IssuerID)
+}
+
+func TestClearer_RefreshStatuses_UpdatesPendingTransactions(t *testing.T) {
+	transactor := &fakeTransactor{status: TxPending}
+	ledger := &fakeLedger{entries: []promise.LedgerEntry{ledgerEntry(issuerA, "session-1", 1, 100)}}
+	clearer := NewClearer(ledger, transactor, newTransactionStorageFake())
+
+	_, err := clearer.Clear()
+	assert.NoError(t, err)
+
+	assert.NoError(t, clearer.RefreshStatuses())
+	transactions, _ := clearer.Transactions()
+	assert.Equal(t, TxPending, transactions[0].Status)
+
+	transactor.status = TxSucceeded
+	assert.NoError(t, clearer.RefreshStatuses())
+	transactions, _ = clearer.Transactions()
+	assert.Equal(t, TxSucceeded, transactions[0].Status)
+}
+
+func TestClearer_Start_ClearsOnSchedule(t *testing.T) {
+	transactor := &fakeTransactor{}
+	ledger := &fakeLedger{entries: []promise.LedgerEntry{ledgerEntry(issuerA, "session-1", 1, 100)}}
+	clearer := NewClearer(ledger, transactor, newTransactionStorageFake())
+
+	assert.NoError(t, clearer.Start(time.Millisecond))
+	defer clearer.Stop()
+	assert.Equal(t, ErrAlreadyRunning, clearer.Start(time.Millisecond))
+
+	for i := 0; i < 100; i++ {
+		if transactions, _ := clearer.Transactions(); len(transactions) > 0 {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	assert.Fail(t, "promises were not cleared on schedule")
+}
+
+func ledgerEntry(issuerID, sessionID string, serialNumber int, amount uint64) promise.LedgerEntry {
+	return promise.LedgerEntry{
+		IssuerID:    issuerID,
+		BenefiterID: benefiter,
+		SessionID:   sessionID,
+		SignedPromise: promise.SignedPromise{
+			Promise: promise.Promise{
+				SerialNumber: serialNumber,
+				IssuerID:     issuerID,
+				BenefiterID:  benefiter,
+				Amount:       money.Money{Amount: amount, Currency: money.CURRENCY_MYST},
+			},
+			IssuerSignature:   "signature",
+			ClearingSignature: "clearing-signature",
+		},
+	}
+}
+
+type fakeLedger struct {
+	entries []promise.LedgerEntry
+}
+
+func (ledger *fakeLedger) List() ([]promise.LedgerEntry, error) {
+	return ledger.entries, nil
+}
+
+type fakeTransactor struct {
+	cleared    []string
+	status     TxStatus
+	failIssuer string
+}
+
+func (transactor *fakeTransactor) ClearPromise(signedPromise promise.SignedPromise) (string, error) {
+	unsignedPromise := signedPromise.Promise
+	issuerID := unsignedPromise.IssuerID
+	if issuerID == transactor.failIssuer {
+		return "", errors.New("transaction rejected")
+	}
+	cleared := fmt.Sprintf("0x%s:%d:%d", issuerID[len(issuerID)-1:], unsignedPromise.SerialNumber, unsignedPromise.Amount.Amount)
+	transactor.cleared = append(transactor.cleared, cleared)
+	return fmt.Sprintf("0xtx%d", len(transactor.cleared)), nil
+}
+
+func (transactor *fakeTransactor) TransactionStatus(_ string) (TxStatus, error) {
+	return transactor.status, nil
+}
+
+type transactionStorageFake struct {
+	storage.FakeStorage
+	transactions map[string]Transaction
+}
+
+func newTransactionStorageFake() *transactionStorageFake {
+	return &transactionStorageFake{transactions: make(map[string]Transaction)}
+}
+
+func (fs *transactionStorageFake) Save(object interface{}) error {
+	transaction := object.(*Transaction)
+	fs.transactions[transaction.Hash] = *transaction
+	return nil
+}
+
+func (fs *transactionStorageFake) Update(object interface{}) error {
+	return fs.Save(object)
+}
+
+func (fs *transactionStorageFake) GetAll(array interface{}) error {
+	transactions := array.(*[]Transaction)
+	for _, transaction := range fs.transactions {
+		*transactions = append(*transactions, transaction)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package clearing
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/mysteriumnetwork/node/core/promise"
+	"github.com/mysteriumnetwork/node/identity"
+	payments_identity "github.com/mysteriumnetwork/payments/identity"
+	"github.com/mysteriumnetwork/payments/promises/generated"
+)
+
+// receiverPrefix is prepended to the promise hash, when payments contract recovers receiver from the signature
+const receiverPrefix = "Receiver prefix:"
+
+// Backend is blockchain backend, which can submit transactions and report their receipts.
+// Both ethclient.Client and simulated backend of go-ethereum satisfy it.
+type Backend interface {
+	bind.ContractBackend
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+}
+
+type contractTransactor struct {
+	backend  Backend
+	contract *generated.IdentityPromisesTransactor
+	keystore identity.Keystore
+}
+
+// NewContractTransactor creates transactor, which clears promises in the payments contract.
+// Promises are countersigned and transactions are signed by benefiter account from the keystore,
+// so benefiter identity has to be unlocked.
+func NewContractTransactor(backend Backend, paymentsAddress common.Address, keystore identity.Keystore) (*contractTransactor, error) {
+	contract, err := generated.NewIdentityPromisesTransactor(paymentsAddress, backend)
+	if err != nil {
+		return nil, err
+	}
+
+	return &contractTransactor{
+		backend:  backend,
+		contract: contract,
+		keystore: keystore,
+	}, nil
+}
+
+// ClearPromise submits promise to the payments contract and returns hash of the transaction
+func (transactor *contractTransactor) ClearPromise(signedPromise promise.SignedPromise) (string, error) {
+	unsignedPromise := signedPromise.Promise
+	clearingSignature := identity.SignatureBase64(string(signedPromise.ClearingSignature))
+	issuerSignature, err := payments_identity.DecomposeSignature(clearingSignature.Bytes())
+	if err != nil {
+		return "", err
+	}
+
+	benefiter := common.HexToAddress(unsignedPromise.BenefiterID)
+	receiverSignature, err := transactor.signByReceiver(benefiter, unsignedPromise)
+	if err != nil {
+		return "", err
+	}
+
+	tx, err := transactor.contract.ClearPromise(
+		transactor.transactOpts(benefiter),
+		promise.ClearingExtraDataHash,
+		benefiter,
+		big.NewInt(int64(unsignedPromise.SerialNumber)),
+		new(big.Int).SetUint64(unsignedPromise.Amount.Amount),
+		issuerSignature.V, issuerSignature.R, issuerSignature.S,
+		receiverSignature.V, receiverSignature.R, receiverSignature.S,
+	)
+	if err != nil {
+		return "", err
+	}
+	return tx.Hash().Hex(), nil
+}
+
+// TransactionStatus checks receipt of the transaction
+func (transactor *contractTransactor) TransactionStatus(txHash string) (TxStatus, error) {
+	receipt, err := transactor.backend.TransactionReceipt(context.Background(), common.HexToHash(txHash))
+	if err == ethereum.NotFound || (err == nil && receipt == nil) {
+		return TxPending, nil
+	}
+	if err != nil {
+		return TxPending, err
+	}
+
+	if receipt.Status == types.ReceiptStatusSuccessful {
+		return TxSucceeded, nil
+	}
+	return TxFailed, nil
+}
+
+// signByReceiver countersigns promise hash together with issuer address, as payments contract expects from receiver
+func (transactor *contractTransactor) signByReceiver(receiver common.Address, unsignedPromise promise.Promise) (*payments_identity.DecomposedSignature, error) {
+	hash := crypto.Keccak256(
+		[]byte(receiverPrefix),
+		crypto.Keccak256(unsignedPromise.ClearingMessage()),
+		common.HexToAddress(unsignedPromise.IssuerID).Bytes(),
+	)
+	signature, err := transactor.keystore.SignHash(accounts.Account{Address: receiver}, hash)
+	if err != nil {
+		return nil, err
+	}
+	return payments_identity.DecomposeSignature(signature)
+}
+
+func (transactor *contractTransactor) transactOpts(from common.Address) *bind.TransactOpts {
+	return &bind.TransactOpts{
+		From: from,
+		Signer: func(signer types.Signer, address common.Address, tx *types.Transaction) (*types.Transaction, error) {
+			signature, err := transactor.keystore.SignHash(accounts.Account{Address: address}, signer.Hash(tx).Bytes())
+			if err != nil {
+				return nil, err
+			}
+			return tx.WithSignature(signer, signature)
+		},
+	}
+}
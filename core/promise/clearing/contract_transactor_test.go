@@ -0,0 +1,126 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package clearing
+
+import (
+	"crypto/ecdsa"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/mysteriumnetwork/node/core/promise"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/money"
+	mysttoken "github.com/mysteriumnetwork/payments/mysttoken/generated"
+	promises "github.com/mysteriumnetwork/payments/promises/generated"
+	"github.com/mysteriumnetwork/payments/registry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContractTransactor_ClearPromise(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clearing-keystore")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	ks := keystore.NewKeyStore(dir, keystore.LightScryptN, keystore.LightScryptP)
+
+	deployerKey := newKey(t)
+	deployer := bind.NewKeyedTransactor(deployerKey)
+	issuer := importKey(t, ks, newKey(t))
+	benefiter := importKey(t, ks, newKey(t))
+
+	etherBalance := new(big.Int).Mul(big.NewInt(100), big.NewInt(1e18))
+	backend := backends.NewSimulatedBackend(core.GenesisAlloc{
+		deployer.From: {Balance: etherBalance},
+		benefiter:     {Balance: etherBalance},
+	}, 8000000)
+
+	tokenAddress, _, token, err := mysttoken.DeployMystToken(deployer, backend)
+	assert.NoError(t, err)
+	backend.Commit()
+	paymentsAddress, _, payments, err := promises.DeployIdentityPromises(deployer, backend, tokenAddress, big.NewInt(0))
+	assert.NoError(t, err)
+	backend.Commit()
+
+	registerIdentity(t, payments, deployer, ks, issuer)
+	registerIdentity(t, payments, deployer, ks, benefiter)
+	_, err = token.Mint(deployer, deployer.From, big.NewInt(1000))
+	assert.NoError(t, err)
+	_, err = token.Approve(deployer, paymentsAddress, big.NewInt(1000))
+	assert.NoError(t, err)
+	backend.Commit()
+	_, err = payments.TopUp(deployer, issuer, big.NewInt(1000))
+	assert.NoError(t, err)
+	backend.Commit()
+
+	unsignedPromise := promise.NewPromise(
+		identity.FromAddress(issuer.Hex()),
+		identity.FromAddress(benefiter.Hex()),
+		money.Money{Amount: 100, Currency: money.CURRENCY_MYST},
+	)
+	signedPromise, err := unsignedPromise.SignByIssuer(identity.NewSigner(ks, identity.FromAddress(issuer.Hex())))
+	assert.NoError(t, err)
+
+	transactor, err := NewContractTransactor(backend, paymentsAddress, ks)
+	assert.NoError(t, err)
+	txHash, err := transactor.ClearPromise(*signedPromise)
+	assert.NoError(t, err)
+
+	status, err := transactor.TransactionStatus(txHash)
+	assert.NoError(t, err)
+	assert.Equal(t, TxPending, status)
+
+	backend.Commit()
+	status, err = transactor.TransactionStatus(txHash)
+	assert.NoError(t, err)
+	assert.Equal(t, TxSucceeded, status)
+
+	clearedSerial, err := payments.ClearedPromises(&bind.CallOpts{}, issuer, benefiter)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), clearedSerial.Int64())
+}
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+	return key
+}
+
+func importKey(t *testing.T, ks *keystore.KeyStore, key *ecdsa.PrivateKey) common.Address {
+	account, err := ks.ImportECDSA(key, "")
+	assert.NoError(t, err)
+	assert.NoError(t, ks.Unlock(account, ""))
+	return account.Address
+}
+
+func registerIdentity(t *testing.T, payments *promises.IdentityPromises, opts *bind.TransactOpts, ks *keystore.KeyStore, address common.Address) {
+	data, err := registry.CreateRegistrationData(registry.FromKeystore(ks, address))
+	assert.NoError(t, err)
+
+	var part1, part2 [32]byte
+	copy(part1[:], data.PublicKey.Part1)
+	copy(part2[:], data.PublicKey.Part2)
+	_, err = payments.RegisterIdentity(opts, part1, part2, data.Signature.V, data.Signature.R, data.Signature.S)
+	assert.NoError(t, err)
+}
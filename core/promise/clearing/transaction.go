@@ -0,0 +1,56 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package clearing
+
+import (
+	"time"
+
+	"github.com/mysteriumnetwork/node/core/promise"
+	"github.com/mysteriumnetwork/node/money"
+)
+
+// TxStatus describes state of clearing transaction in blockchain
+type TxStatus string
+
+const (
+	// TxPending means that transaction was submitted, but not mined yet
+	TxPending = TxStatus("Pending")
+	// TxSucceeded means that promise was cleared by the payments contract
+	TxSucceeded = TxStatus("Succeeded")
+	// TxFailed means that transaction was rejected or reverted
+	TxFailed = TxStatus("Failed")
+)
+
+// Transaction records clearing of a single promise, submitted to the payments contract
+type Transaction struct {
+	Hash         string `storm:"id"`
+	IssuerID     string `storm:"index"`
+	BenefiterID  string `storm:"index"`
+	SessionID    string
+	SerialNumber int
+	Amount       money.Money
+	Status       TxStatus
+	Submitted    time.Time
+	Updated      time.Time
+}
+
+// Transactor submits promises to the payments contract and tracks submitted transactions
+type Transactor interface {
+	ClearPromise(signedPromise promise.SignedPromise) (txHash string, err error)
+	TransactionStatus(txHash string) (TxStatus, error)
+}
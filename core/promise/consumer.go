@@ -18,9 +18,11 @@
 package promise
 
 import (
+	"sync"
+
 	"github.com/mysteriumnetwork/node/communication"
-	"github.com/mysteriumnetwork/node/core/storage"
 	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/money"
 	"github.com/mysteriumnetwork/node/service_discovery/dto"
 )
 
@@ -29,19 +31,27 @@ var (
 	responseInternalError  = Response{Success: false, Message: "Internal Error"}
 )
 
-// Consumer process promise-requests
+// Consumer process promise-requests.
+// Every next promise has to have higher serial number and not lower amount than the previously accepted one.
+// Amounts are cumulative for the issuer and benefiter, so only increase of the amount is attributed to the session.
 type Consumer struct {
-	proposal dto.ServiceProposal
-	balance  identity.Balance
-	storage  storage.Storage
+	proposal  dto.ServiceProposal
+	balance   identity.Balance
+	sessionID string
+	recorder  Recorder
+
+	lastPromise     *Promise
+	promised        uint64
+	lastPromiseLock sync.RWMutex
 }
 
 // NewConsumer creates new instance of the promise consumer
-func NewConsumer(proposal dto.ServiceProposal, balance identity.Balance, storage storage.Storage) *Consumer {
+func NewConsumer(proposal dto.ServiceProposal, balance identity.Balance, sessionID string, recorder Recorder) *Consumer {
 	return &Consumer{
-		proposal: proposal,
-		balance:  balance,
-		storage:  storage,
+		proposal:  proposal,
+		balance:   balance,
+		sessionID: sessionID,
+		recorder:  recorder,
 	}
 }
 
@@ -66,9 +76,55 @@ func (c *Consumer) Consume(requestPtr interface{}) (response interface{}, err er
 		return responseInvalidPromise, err
 	}
 
-	if err := c.storage.Store(request.SignedPromise.Promise.IssuerID, &request.SignedPromise.Promise); err != nil {
+	c.lastPromiseLock.Lock()
+	defer c.lastPromiseLock.Unlock()
+
+	receivedPromise := request.SignedPromise.Promise
+	if err := validateSequence(c.lastPromise, receivedPromise); err != nil {
+		return responseInvalidPromise, err
+	}
+
+	previous, err := c.recorder.Record(c.sessionID, *request.SignedPromise)
+	if err == errSerialNotIncreasing || err == errAmountDecreased {
+		return responseInvalidPromise, err
+	}
+	if err != nil {
 		return responseInternalError, err
 	}
+	c.lastPromise = &receivedPromise
+	c.promised += receivedPromise.Amount.Amount - previous.Amount.Amount
 
 	return &Response{Success: true}, nil
 }
+
+// LastPromise returns the latest accepted promise
+func (c *Consumer) LastPromise() (Promise, bool) {
+	c.lastPromiseLock.RLock()
+	defer c.lastPromiseLock.RUnlock()
+
+	if c.lastPromise == nil {
+		return Promise{}, false
+	}
+	return *c.lastPromise, true
+}
+
+// Promised returns amount promised during the session, amounts promised by the issuer to the benefiter before are not included
+func (c *Consumer) Promised() money.Money {
+	c.lastPromiseLock.RLock()
+	defer c.lastPromiseLock.RUnlock()
+
+	return money.Money{Amount: c.promised, Currency: c.proposal.PaymentMethod.GetPrice().Currency}
+}
+
+func validateSequence(previous *Promise, next Promise) error {
+	if previous == nil {
+		return nil
+	}
+	if next.SerialNumber <= previous.SerialNumber {
+		return errSerialNotIncreasing
+	}
+	if next.Amount.Currency != previous.Amount.Currency || next.Amount.Amount < previous.Amount.Amount {
+		return errAmountDecreased
+	}
+	return nil
+}
@@ -18,9 +18,10 @@
 package promise
 
 import (
-	"encoding/json"
+	"crypto/ecdsa"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/mysteriumnetwork/node/core/storage"
 	"github.com/mysteriumnetwork/node/identity"
 	"github.com/mysteriumnetwork/node/money"
@@ -28,20 +29,37 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
-var jsonRequest = []byte(`{
-	"SignedPromise": {
-		"Promise": {
-			"SerialNumber": 1,
-			"IssuerID": "0x8eaf2780c6098dd1baee2b7c8c62f2d92ba1fe29",
-			"BenefiterID": "0x1526273ac60cdebfa2aece92da3261ecb564763a",
-			"Amount": {
-				"amount": 12500000,
-				"currency": "MYST"
-			}
-		},
-		"IssuerSignature": "sXeJmBrjjMXkGraD8ItqNEI+0IommozG4dF24FvgbWQdgHJi10EvJOLt0F2AS5Y0MBe8hlDo3B0vlH2hW5uHSQE="
-	}
-}`)
+const (
+	consumerTestBenefiter = "0x1526273ac60cdebfa2aece92da3261ecb564763a"
+	// consumerTestKey is the private key of the test issuer
+	consumerTestKey = "b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291"
+)
+
+// keySigner signs messages the same way as keystore signer does
+type keySigner struct {
+	key *ecdsa.PrivateKey
+}
+
+func (signer *keySigner) Sign(message []byte) (identity.Signature, error) {
+	signature, err := crypto.Sign(crypto.Keccak256(message), signer.key)
+	return identity.SignatureBytes(signature), err
+}
+
+func newKeySigner(t *testing.T) (*keySigner, identity.Identity) {
+	key, err := crypto.HexToECDSA(consumerTestKey)
+	assert.NoError(t, err)
+	return &keySigner{key: key}, identity.FromAddress(crypto.PubkeyToAddress(key.PublicKey).Hex())
+}
+
+func signedRequest(t *testing.T, serialNumber int, amount uint64) *Request {
+	signer, issuer := newKeySigner(t)
+	promise := NewPromise(issuer, identity.FromAddress(consumerTestBenefiter), money.Money{Amount: amount, Currency: money.CURRENCY_MYST})
+	promise.SerialNumber = serialNumber
+
+	signedPromise, err := promise.SignByIssuer(signer)
+	assert.NoError(t, err)
+	return &Request{SignedPromise: signedPromise}
+}
 
 func TestNewRequest(t *testing.T) {
 	consumer := Consumer{}
@@ -59,76 +77,194 @@ func TestConsumeUnsupportedRequest(t *testing.T) {
 func TestConsumeBadSignature(t *testing.T) {
 	consumer := Consumer{}
 	signedPromise := &SignedPromise{Promise: Promise{}, IssuerSignature: "ProducerSignature"}
-	request := &Request{signedPromise}
+	request := &Request{SignedPromise: signedPromise}
+	response, err := consumer.Consume(request)
+	assert.Equal(t, errBadSignature, err)
+	assert.Equal(t, responseInvalidPromise, response)
+}
+
+func TestConsumeBadClearingSignature(t *testing.T) {
+	request := signedRequest(t, 1, 12500000)
+	request.SignedPromise.ClearingSignature = request.SignedPromise.IssuerSignature
+
+	consumer := Consumer{}
+	response, err := consumer.Consume(request)
+	assert.Equal(t, errBadSignature, err)
+	assert.Equal(t, responseInvalidPromise, response)
+}
+
+func TestConsumeMissingClearingSignature(t *testing.T) {
+	request := signedRequest(t, 1, 12500000)
+	request.SignedPromise.ClearingSignature = ""
+
+	consumer := Consumer{}
 	response, err := consumer.Consume(request)
 	assert.Equal(t, errBadSignature, err)
 	assert.Equal(t, responseInvalidPromise, response)
 }
 
+func TestConsumeCurrencyMismatch(t *testing.T) {
+	proposal := dto.ServiceProposal{
+		ProviderID:    consumerTestBenefiter,
+		PaymentMethod: fakePayment{amount: 1, currency: money.Currency("TEST")},
+	}
+	consumer := Consumer{proposal: proposal, balance: fakeBlockchain(999999999)}
+	response, err := consumer.Consume(signedRequest(t, 1, 12500000))
+	assert.Equal(t, errCurrencyMismatch, err)
+	assert.Equal(t, responseInvalidPromise, response)
+}
+
 func TestConsumeUnknownBenefiter(t *testing.T) {
-	var request Request
-	err := json.Unmarshal(jsonRequest, &request)
-	assert.Nil(t, err)
+	request := signedRequest(t, 1, 12500000)
 
 	consumer := Consumer{}
-	response, err := consumer.Consume(&request)
+	response, err := consumer.Consume(request)
 	assert.Equal(t, errUnknownBenefiter, err)
 	assert.Equal(t, responseInvalidPromise, response)
 
 }
 
 func TestConsumeLowAmount(t *testing.T) {
-	var request Request
-	err := json.Unmarshal(jsonRequest, &request)
-	assert.Nil(t, err)
+	request := signedRequest(t, 1, 12500000)
 
 	proposal := dto.ServiceProposal{
-		ProviderID:    "0x1526273ac60cdebfa2aece92da3261ecb564763a",
-		PaymentMethod: fakePayment{999999999},
+		ProviderID:    consumerTestBenefiter,
+		PaymentMethod: fakePayment{amount: 999999999},
 	}
 	consumer := Consumer{proposal: proposal, balance: fakeBlockchain(12500000)}
-	response, err := consumer.Consume(&request)
+	response, err := consumer.Consume(request)
 	assert.Equal(t, errLowAmount, err)
 	assert.Equal(t, responseInvalidPromise, response)
 }
 
 func TestConsumeLowBalance(t *testing.T) {
-	var request Request
-	err := json.Unmarshal(jsonRequest, &request)
-	assert.Nil(t, err)
+	request := signedRequest(t, 1, 12500000)
 
 	proposal := dto.ServiceProposal{
-		ProviderID:    "0x1526273ac60cdebfa2aece92da3261ecb564763a",
-		PaymentMethod: fakePayment{1},
+		ProviderID:    consumerTestBenefiter,
+		PaymentMethod: fakePayment{amount: 1},
 	}
 	consumer := Consumer{proposal: proposal, balance: fakeBlockchain(1)}
-	response, err := consumer.Consume(&request)
+	response, err := consumer.Consume(request)
 	assert.Equal(t, errLowBalance, err)
 	assert.Equal(t, responseInvalidPromise, response)
 
 }
 
 func TestConsume(t *testing.T) {
-	var request Request
-	err := json.Unmarshal(jsonRequest, &request)
-	assert.Nil(t, err)
+	request := signedRequest(t, 1, 12500000)
 
 	proposal := dto.ServiceProposal{
-		ProviderID:    "0x1526273ac60cdebfa2aece92da3261ecb564763a",
-		PaymentMethod: fakePayment{1},
+		ProviderID:    consumerTestBenefiter,
+		PaymentMethod: fakePayment{amount: 1},
 	}
-	consumer := Consumer{proposal: proposal, balance: fakeBlockchain(999999999), storage: &storage.FakeStorage{}}
-	response, err := consumer.Consume(&request)
+	consumer := Consumer{proposal: proposal, balance: fakeBlockchain(999999999), recorder: NewLedger(&storage.FakeStorage{})}
+	response, err := consumer.Consume(request)
 	assert.NoError(t, err)
 	assert.Equal(t, &Response{Success: true}, response)
 }
 
+func TestConsumeRemembersLastPromise(t *testing.T) {
+	request := signedRequest(t, 1, 12500000)
+
+	proposal := dto.ServiceProposal{
+		ProviderID:    consumerTestBenefiter,
+		PaymentMethod: fakePayment{amount: 1},
+	}
+	consumer := Consumer{proposal: proposal, balance: fakeBlockchain(999999999), recorder: NewLedger(&storage.FakeStorage{})}
+	_, found := consumer.LastPromise()
+	assert.False(t, found)
+
+	_, err := consumer.Consume(request)
+	assert.NoError(t, err)
+
+	lastPromise, found := consumer.LastPromise()
+	assert.True(t, found)
+	assert.Equal(t, request.SignedPromise.Promise, lastPromise)
+}
+
+func TestConsumeAttributesOnlyIncreaseOfAmountToSession(t *testing.T) {
+	_, issuer := newKeySigner(t)
+	ledger := newTestLedger()
+	assert.NoError(t, recordPromise(ledger, "previous-session", ledgerPromiseOf(issuer.Address, consumerTestBenefiter, 3, 10000000)))
+
+	proposal := dto.ServiceProposal{
+		ProviderID:    consumerTestBenefiter,
+		PaymentMethod: fakePayment{amount: 1},
+	}
+	consumer := Consumer{proposal: proposal, balance: fakeBlockchain(999999999), recorder: ledger}
+	assert.Equal(t, money.Money{Amount: 0, Currency: money.CURRENCY_MYST}, consumer.Promised())
+
+	_, err := consumer.Consume(signedRequest(t, 4, 12500000))
+	assert.NoError(t, err)
+	assert.Equal(t, money.Money{Amount: 2500000, Currency: money.CURRENCY_MYST}, consumer.Promised())
+
+	_, err = consumer.Consume(signedRequest(t, 5, 13000000))
+	assert.NoError(t, err)
+	assert.Equal(t, money.Money{Amount: 3000000, Currency: money.CURRENCY_MYST}, consumer.Promised())
+}
+
+func TestConsumeRejectsPromiseNotFollowingPreviousSession(t *testing.T) {
+	_, issuer := newKeySigner(t)
+	ledger := newTestLedger()
+	assert.NoError(t, recordPromise(ledger, "previous-session", ledgerPromiseOf(issuer.Address, consumerTestBenefiter, 3, 10000000)))
+
+	proposal := dto.ServiceProposal{
+		ProviderID:    consumerTestBenefiter,
+		PaymentMethod: fakePayment{amount: 1},
+	}
+	consumer := Consumer{proposal: proposal, balance: fakeBlockchain(999999999), recorder: ledger}
+	response, err := consumer.Consume(signedRequest(t, 1, 12500000))
+	assert.Equal(t, errSerialNotIncreasing, err)
+	assert.Equal(t, responseInvalidPromise, response)
+}
+
+func TestConsumeSerialNotIncreasing(t *testing.T) {
+	request := signedRequest(t, 1, 12500000)
+
+	proposal := dto.ServiceProposal{
+		ProviderID:    consumerTestBenefiter,
+		PaymentMethod: fakePayment{amount: 1},
+	}
+	consumer := Consumer{
+		proposal:    proposal,
+		balance:     fakeBlockchain(999999999),
+		recorder:    NewLedger(&storage.FakeStorage{}),
+		lastPromise: &Promise{SerialNumber: 1, Amount: money.Money{Amount: 1, Currency: money.CURRENCY_MYST}},
+	}
+	response, err := consumer.Consume(request)
+	assert.Equal(t, errSerialNotIncreasing, err)
+	assert.Equal(t, responseInvalidPromise, response)
+}
+
+func TestConsumeAmountDecreased(t *testing.T) {
+	request := signedRequest(t, 1, 12500000)
+
+	proposal := dto.ServiceProposal{
+		ProviderID:    consumerTestBenefiter,
+		PaymentMethod: fakePayment{amount: 1},
+	}
+	consumer := Consumer{
+		proposal:    proposal,
+		balance:     fakeBlockchain(999999999),
+		recorder:    NewLedger(&storage.FakeStorage{}),
+		lastPromise: &Promise{SerialNumber: 0, Amount: money.Money{Amount: 12500001, Currency: money.CURRENCY_MYST}},
+	}
+	response, err := consumer.Consume(request)
+	assert.Equal(t, errAmountDecreased, err)
+	assert.Equal(t, responseInvalidPromise, response)
+}
+
 type fakePayment struct {
-	amount uint64
+	amount   uint64
+	currency money.Currency
 }
 
 func (fp fakePayment) GetPrice() money.Money {
-	return money.Money{Amount: fp.amount}
+	if fp.currency == "" {
+		return money.Money{Amount: fp.amount, Currency: money.CURRENCY_MYST}
+	}
+	return money.Money{Amount: fp.amount, Currency: fp.currency}
 }
 
 func fakeBlockchain(balance uint64) identity.Balance {
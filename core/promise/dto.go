@@ -23,6 +23,8 @@ import "github.com/mysteriumnetwork/node/money"
 type SignedPromise struct {
 	Promise         Promise
 	IssuerSignature Signature
+	// ClearingSignature is issuer signature of the promise in the format payments contract verifies
+	ClearingSignature Signature
 }
 
 // Promise represents payment promise between two parties
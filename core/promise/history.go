@@ -0,0 +1,73 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package promise
+
+import (
+	"sync"
+
+	"github.com/mysteriumnetwork/node/core/storage"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/money"
+)
+
+// IssuedPromise holds the latest promise issued by the issuer to the benefiter
+type IssuedPromise struct {
+	ID      string `storm:"id"`
+	Promise Promise
+}
+
+// IssueHistory remembers the latest promise issued to every benefiter,
+// so serial numbers and amounts keep growing across sessions of the same issuer and benefiter
+type IssueHistory struct {
+	storage storage.Storage
+	lock    sync.Mutex
+}
+
+// NewIssueHistory creates history of issued promises on top of the given storage
+func NewIssueHistory(storage storage.Storage) *IssueHistory {
+	return &IssueHistory{storage: storage}
+}
+
+// IssueNext issues promise, which follows the latest one issued to the benefiter and increases its amount by given value.
+// Promise is remembered only when send succeeds, issuing is serialized, so concurrent sessions do not reuse serial numbers.
+func (history *IssueHistory) IssueNext(
+	issuerID, benefiterID identity.Identity,
+	increase money.Money,
+	send func(Promise) error,
+) (Promise, error) {
+	history.lock.Lock()
+	defer history.lock.Unlock()
+
+	id := pairID(issuerID.Address, benefiterID.Address)
+	var last IssuedPromise
+	err := history.storage.GetOneByField("ID", id, &last)
+	if err != nil && err != storage.ErrNotFound {
+		return Promise{}, err
+	}
+
+	next := NewPromise(issuerID, benefiterID, increase)
+	next.SerialNumber = last.Promise.SerialNumber + 1
+	if last.Promise.Amount.Currency == increase.Currency {
+		next.Amount.Amount += last.Promise.Amount.Amount
+	}
+
+	if err := send(*next); err != nil {
+		return Promise{}, err
+	}
+	return *next, history.storage.Save(&IssuedPromise{ID: id, Promise: *next})
+}
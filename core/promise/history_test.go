@@ -0,0 +1,95 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package promise
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mysteriumnetwork/node/core/storage"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/money"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	historyIssuer    = identity.FromAddress(ledgerIssuer)
+	historyBenefiter = identity.FromAddress(ledgerBenefiter)
+)
+
+func TestIssueHistory_IssueNextContinuesFromLastIssuedPromise(t *testing.T) {
+	history := NewIssueHistory(&historyStorageFake{entries: make(map[string]IssuedPromise)})
+
+	var sent []Promise
+	send := func(promise Promise) error {
+		sent = append(sent, promise)
+		return nil
+	}
+
+	first, err := history.IssueNext(historyIssuer, historyBenefiter, money.Money{Amount: 10, Currency: money.CURRENCY_MYST}, send)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, first.SerialNumber)
+	assert.Equal(t, money.Money{Amount: 10, Currency: money.CURRENCY_MYST}, first.Amount)
+
+	// next session with the same provider
+	second, err := history.IssueNext(historyIssuer, historyBenefiter, money.Money{Amount: 5, Currency: money.CURRENCY_MYST}, send)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, second.SerialNumber)
+	assert.Equal(t, money.Money{Amount: 15, Currency: money.CURRENCY_MYST}, second.Amount)
+	assert.Equal(t, []Promise{first, second}, sent)
+
+	other, err := history.IssueNext(historyIssuer, identity.FromAddress("0x1"), money.Money{Amount: 5, Currency: money.CURRENCY_MYST}, send)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, other.SerialNumber)
+}
+
+func TestIssueHistory_IssueNextForgetsPromiseNotSent(t *testing.T) {
+	history := NewIssueHistory(&historyStorageFake{entries: make(map[string]IssuedPromise)})
+
+	_, err := history.IssueNext(historyIssuer, historyBenefiter, money.Money{Amount: 10, Currency: money.CURRENCY_MYST}, func(Promise) error {
+		return errors.New("dialog closed")
+	})
+	assert.EqualError(t, err, "dialog closed")
+
+	next, err := history.IssueNext(historyIssuer, historyBenefiter, money.Money{Amount: 10, Currency: money.CURRENCY_MYST}, func(Promise) error {
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, next.SerialNumber)
+	assert.Equal(t, uint64(10), next.Amount.Amount)
+}
+
+type historyStorageFake struct {
+	storage.FakeStorage
+	entries map[string]IssuedPromise
+}
+
+func (fs *historyStorageFake) Save(object interface{}) error {
+	entry := object.(*IssuedPromise)
+	fs.entries[entry.ID] = *entry
+	return nil
+}
+
+func (fs *historyStorageFake) GetOneByField(fieldName string, key interface{}, to interface{}) error {
+	entry, found := fs.entries[key.(string)]
+	if !found {
+		return storage.ErrNotFound
+	}
+	*to.(*IssuedPromise) = entry
+	return nil
+}
@@ -0,0 +1,133 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package promise
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mysteriumnetwork/node/core/storage"
+)
+
+// Recorder records promises accepted from consumers and returns the previously recorded promise of the same issuer and benefiter
+type Recorder interface {
+	Record(sessionID string, signedPromise SignedPromise) (previous Promise, err error)
+}
+
+// LedgerEntry holds the latest valid promise issued by the issuer to the benefiter
+type LedgerEntry struct {
+	ID          string `storm:"id"`
+	IssuerID    string `storm:"index"`
+	BenefiterID string `storm:"index"`
+	// SessionID is the session, during which the latest promise was received
+	SessionID     string
+	SignedPromise SignedPromise
+	FirstReceived time.Time
+	LastReceived  time.Time
+}
+
+// Ledger keeps track of promises, which provider has received and can clear later
+type Ledger struct {
+	storage    storage.Storage
+	timeGetter func() time.Time
+	lock       sync.Mutex
+}
+
+// NewLedger creates promise ledger on top of the given storage
+func NewLedger(storage storage.Storage) *Ledger {
+	return &Ledger{
+		storage:    storage,
+		timeGetter: time.Now,
+	}
+}
+
+// Record stores accepted promise, replacing the previous one of the same issuer and benefiter, which is returned.
+// Serial numbers and amounts only grow per issuer and benefiter, so promise,
+// which does not follow the recorded one, is rejected.
+func (ledger *Ledger) Record(sessionID string, signedPromise SignedPromise) (Promise, error) {
+	ledger.lock.Lock()
+	defer ledger.lock.Unlock()
+
+	promise := signedPromise.Promise
+	id := pairID(promise.IssuerID, promise.BenefiterID)
+	entry, found, err := ledger.find(id)
+	if err != nil {
+		return Promise{}, err
+	}
+	previous := entry.SignedPromise.Promise
+	if found {
+		if err := validateSequence(&previous, promise); err != nil {
+			return Promise{}, err
+		}
+	}
+
+	now := ledger.timeGetter()
+	if !found {
+		entry = LedgerEntry{
+			ID:            id,
+			IssuerID:      promise.IssuerID,
+			BenefiterID:   promise.BenefiterID,
+			FirstReceived: now,
+		}
+	}
+	entry.SessionID = sessionID
+	entry.SignedPromise = signedPromise
+	entry.LastReceived = now
+
+	return previous, ledger.storage.Save(&entry)
+}
+
+// List returns latest promises of all issuers
+func (ledger *Ledger) List() ([]LedgerEntry, error) {
+	var entries []LedgerEntry
+	if err := ledger.storage.GetAll(&entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// ListByIssuer returns latest promises of the given issuer to every benefiter
+func (ledger *Ledger) ListByIssuer(issuerID string) ([]LedgerEntry, error) {
+	entries, err := ledger.List()
+	if err != nil {
+		return nil, err
+	}
+
+	issuerEntries := make([]LedgerEntry, 0)
+	for _, entry := range entries {
+		if strings.EqualFold(entry.IssuerID, issuerID) {
+			issuerEntries = append(issuerEntries, entry)
+		}
+	}
+	return issuerEntries, nil
+}
+
+func (ledger *Ledger) find(id string) (LedgerEntry, bool, error) {
+	var entry LedgerEntry
+	err := ledger.storage.GetOneByField("ID", id, &entry)
+	if err == storage.ErrNotFound {
+		return LedgerEntry{}, false, nil
+	}
+	return entry, err == nil, err
+}
+
+// pairID identifies promises of the issuer to the benefiter
+func pairID(issuerID, benefiterID string) string {
+	return strings.ToLower(issuerID) + ":" + strings.ToLower(benefiterID)
+}
@@ -0,0 +1,163 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package promise
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mysteriumnetwork/node/core/storage"
+	"github.com/mysteriumnetwork/node/money"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	ledgerIssuer    = "0x8eaf2780c6098dd1baee2b7c8c62f2d92ba1fe29"
+	ledgerBenefiter = "0x1526273ac60cdebfa2aece92da3261ecb564763a"
+)
+
+func TestLedgerRecordKeepsLatestPromise(t *testing.T) {
+	ledger := newTestLedger()
+
+	firstTime := time.Date(2018, 11, 1, 10, 0, 0, 0, time.UTC)
+	ledger.timeGetter = func() time.Time { return firstTime }
+	assert.NoError(t, recordPromise(ledger, "session-1", ledgerPromise(ledgerIssuer, 1, 100)))
+
+	secondTime := firstTime.Add(time.Minute)
+	ledger.timeGetter = func() time.Time { return secondTime }
+	assert.NoError(t, recordPromise(ledger, "session-1", ledgerPromise(ledgerIssuer, 2, 200)))
+
+	entries, err := ledger.List()
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "session-1", entries[0].SessionID)
+	assert.Equal(t, 2, entries[0].SignedPromise.Promise.SerialNumber)
+	assert.Equal(t, uint64(200), entries[0].SignedPromise.Promise.Amount.Amount)
+	assert.True(t, firstTime.Equal(entries[0].FirstReceived))
+	assert.True(t, secondTime.Equal(entries[0].LastReceived))
+}
+
+func TestLedgerRecordKeepsLatestPromiseOfIssuerAndBenefiter(t *testing.T) {
+	ledger := newTestLedger()
+
+	assert.NoError(t, recordPromise(ledger, "session-1", ledgerPromise(ledgerIssuer, 1, 100)))
+	assert.NoError(t, recordPromise(ledger, "session-1", ledgerPromise(ledgerIssuer, 2, 200)))
+	assert.NoError(t, recordPromise(ledger, "session-2", ledgerPromise(ledgerIssuer, 3, 250)))
+
+	entries, err := ledger.List()
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "session-2", entries[0].SessionID)
+	assert.Equal(t, 3, entries[0].SignedPromise.Promise.SerialNumber)
+	assert.Equal(t, uint64(250), entries[0].SignedPromise.Promise.Amount.Amount)
+}
+
+func TestLedgerRecordRejectsPromiseNotFollowingRecordedOne(t *testing.T) {
+	ledger := newTestLedger()
+
+	assert.NoError(t, recordPromise(ledger, "session-1", ledgerPromise(ledgerIssuer, 2, 200)))
+	assert.Exactly(t, errSerialNotIncreasing, recordPromise(ledger, "session-2", ledgerPromise(ledgerIssuer, 1, 300)))
+	assert.Exactly(t, errAmountDecreased, recordPromise(ledger, "session-2", ledgerPromise(ledgerIssuer, 3, 100)))
+
+	entries, err := ledger.List()
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, 2, entries[0].SignedPromise.Promise.SerialNumber)
+}
+
+func TestLedgerListByIssuer(t *testing.T) {
+	ledger := newTestLedger()
+
+	otherIssuer := "0x0000000000000000000000000000000000000001"
+	assert.NoError(t, recordPromise(ledger, "session-1", ledgerPromise(ledgerIssuer, 1, 100)))
+	assert.NoError(t, recordPromise(ledger, "session-2", ledgerPromise(otherIssuer, 1, 100)))
+
+	entries, err := ledger.ListByIssuer(otherIssuer)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, otherIssuer, entries[0].IssuerID)
+
+	entries, err = ledger.ListByIssuer("0x0000000000000000000000000000000000000002")
+	assert.NoError(t, err)
+	assert.Len(t, entries, 0)
+}
+
+func TestLedgerRecordReturnsPreviousPromise(t *testing.T) {
+	ledger := newTestLedger()
+
+	previous, err := ledger.Record("session-1", ledgerPromise(ledgerIssuer, 1, 100))
+	assert.NoError(t, err)
+	assert.Equal(t, Promise{}, previous)
+
+	previous, err = ledger.Record("session-2", ledgerPromise(ledgerIssuer, 2, 150))
+	assert.NoError(t, err)
+	assert.Equal(t, ledgerPromise(ledgerIssuer, 1, 100).Promise, previous)
+}
+
+func newTestLedger() *Ledger {
+	return NewLedger(&ledgerStorageFake{entries: make(map[string]LedgerEntry)})
+}
+
+func recordPromise(ledger *Ledger, sessionID string, signedPromise SignedPromise) error {
+	_, err := ledger.Record(sessionID, signedPromise)
+	return err
+}
+
+func ledgerPromise(issuerID string, serialNumber int, amount uint64) SignedPromise {
+	return ledgerPromiseOf(issuerID, ledgerBenefiter, serialNumber, amount)
+}
+
+func ledgerPromiseOf(issuerID, benefiterID string, serialNumber int, amount uint64) SignedPromise {
+	return SignedPromise{
+		Promise: Promise{
+			SerialNumber: serialNumber,
+			IssuerID:     issuerID,
+			BenefiterID:  benefiterID,
+			Amount:       money.Money{Amount: amount, Currency: money.CURRENCY_MYST},
+		},
+		IssuerSignature: "signature",
+	}
+}
+
+type ledgerStorageFake struct {
+	storage.FakeStorage
+	entries map[string]LedgerEntry
+}
+
+func (fs *ledgerStorageFake) Save(object interface{}) error {
+	entry := object.(*LedgerEntry)
+	fs.entries[entry.ID] = *entry
+	return nil
+}
+
+func (fs *ledgerStorageFake) GetOneByField(fieldName string, key interface{}, to interface{}) error {
+	entry, found := fs.entries[key.(string)]
+	if !found {
+		return storage.ErrNotFound
+	}
+	*to.(*LedgerEntry) = entry
+	return nil
+}
+
+func (fs *ledgerStorageFake) GetAll(array interface{}) error {
+	entries := array.(*[]LedgerEntry)
+	for _, entry := range fs.entries {
+		*entries = append(*entries, entry)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package promise
+
+import (
+	"time"
+
+	"github.com/mysteriumnetwork/node/money"
+	"github.com/mysteriumnetwork/node/service_discovery/dto"
+)
+
+// Usage describes how much of the service was consumed during session
+type Usage struct {
+	Duration time.Duration
+	Bytes    uint64
+}
+
+// TimeMeteredPayment is implemented by payment methods, which charge price for every started period of service time
+type TimeMeteredPayment interface {
+	dto.PaymentMethod
+	GetDuration() time.Duration
+}
+
+// ByteMeteredPayment is implemented by payment methods, which charge price for every started chunk of transferred bytes
+type ByteMeteredPayment interface {
+	dto.PaymentMethod
+	GetBytes() uint64
+}
+
+// CalculateCharge returns cumulative amount, which consumer owes for given usage of the service.
+// Service is paid in advance, so price of current (not yet finished) period is included.
+// Payment methods without metering are charged their price once.
+func CalculateCharge(method dto.PaymentMethod, usage Usage) money.Money {
+	price := method.GetPrice()
+
+	units := uint64(1)
+	switch metered := method.(type) {
+	case TimeMeteredPayment:
+		if period := metered.GetDuration(); period > 0 {
+			units += uint64(usage.Duration / period)
+		}
+	case ByteMeteredPayment:
+		if chunk := metered.GetBytes(); chunk > 0 {
+			units += usage.Bytes / chunk
+		}
+	}
+
+	return money.Money{Amount: price.Amount * units, Currency: price.Currency}
+}
@@ -0,0 +1,87 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package promise
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mysteriumnetwork/node/money"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakePaymentPerTime struct {
+	price    money.Money
+	duration time.Duration
+}
+
+func (method fakePaymentPerTime) GetPrice() money.Money {
+	return method.price
+}
+
+func (method fakePaymentPerTime) GetDuration() time.Duration {
+	return method.duration
+}
+
+type fakePaymentPerBytes struct {
+	price money.Money
+	bytes uint64
+}
+
+func (method fakePaymentPerBytes) GetPrice() money.Money {
+	return method.price
+}
+
+func (method fakePaymentPerBytes) GetBytes() uint64 {
+	return method.bytes
+}
+
+func TestCalculateCharge_PerTime(t *testing.T) {
+	method := fakePaymentPerTime{money.Money{Amount: 10, Currency: CurrencyToken}, time.Hour}
+
+	var tests = []struct {
+		duration       time.Duration
+		expectedAmount uint64
+	}{
+		{0, 10},
+		{59 * time.Minute, 10},
+		{time.Hour, 20},
+		{150 * time.Minute, 30},
+	}
+	for _, test := range tests {
+		assert.Equal(
+			t,
+			money.Money{Amount: test.expectedAmount, Currency: CurrencyToken},
+			CalculateCharge(method, Usage{Duration: test.duration}),
+		)
+	}
+}
+
+func TestCalculateCharge_PerBytes(t *testing.T) {
+	method := fakePaymentPerBytes{money.Money{Amount: 5, Currency: CurrencyToken}, 1000}
+
+	assert.Equal(t, uint64(5), CalculateCharge(method, Usage{Bytes: 999, Duration: time.Hour}).Amount)
+	assert.Equal(t, uint64(10), CalculateCharge(method, Usage{Bytes: 1000}).Amount)
+	assert.Equal(t, uint64(25), CalculateCharge(method, Usage{Bytes: 4500}).Amount)
+}
+
+func TestCalculateCharge_WithoutMeteringIsChargedOnce(t *testing.T) {
+	method := fakePayment{amount: 7}
+
+	assert.Equal(t, uint64(7), CalculateCharge(method, Usage{Duration: time.Hour, Bytes: 1000}).Amount)
+}
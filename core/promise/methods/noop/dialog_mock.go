@@ -34,6 +34,10 @@ type fakeDialog struct {
 
 	sendMutex   sync.RWMutex
 	sendMessage interface{}
+
+	requestMutex    sync.RWMutex
+	requestsSent    []interface{}
+	requestConsumer communication.RequestConsumer
 }
 
 func (fd *fakeDialog) PeerID() identity.Identity {
@@ -49,13 +53,26 @@ func (fd *fakeDialog) Receive(consumer communication.MessageConsumer) error {
 		return fd.returnError
 	}
 
-	consumer.Consume(fd.returnReceiveMessage)
+	if fd.returnReceiveMessage != nil {
+		consumer.Consume(fd.returnReceiveMessage)
+	}
 	return nil
 }
 func (fd *fakeDialog) Respond(consumer communication.RequestConsumer) error {
+	fd.requestMutex.Lock()
+	defer fd.requestMutex.Unlock()
+
+	fd.requestConsumer = consumer
 	return nil
 }
 
+func (fd *fakeDialog) getRequestConsumer() communication.RequestConsumer {
+	fd.requestMutex.RLock()
+	defer fd.requestMutex.RUnlock()
+
+	return fd.requestConsumer
+}
+
 func (fd *fakeDialog) Send(producer communication.MessageProducer) error {
 	fd.sendMutex.Lock()
 	defer fd.sendMutex.Unlock()
@@ -86,5 +103,16 @@ func (fd *fakeDialog) waitSendMessage() (interface{}, error) {
 }
 
 func (fd *fakeDialog) Request(producer communication.RequestProducer) (responsePtr interface{}, err error) {
+	fd.requestMutex.Lock()
+	defer fd.requestMutex.Unlock()
+
+	fd.requestsSent = append(fd.requestsSent, producer.Produce())
 	return &promise.Response{Success: true}, nil
 }
+
+func (fd *fakeDialog) getRequestsSent() []interface{} {
+	fd.requestMutex.RLock()
+	defer fd.requestMutex.RUnlock()
+
+	return append([]interface{}{}, fd.requestsSent...)
+}
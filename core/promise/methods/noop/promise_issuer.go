@@ -19,60 +19,153 @@ package noop
 
 import (
 	"fmt"
+	"sync"
+	"time"
 
 	log "github.com/cihub/seelog"
+	"github.com/mysteriumnetwork/node/client/stats"
 	"github.com/mysteriumnetwork/node/communication"
 	"github.com/mysteriumnetwork/node/core/promise"
 	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/money"
 	"github.com/mysteriumnetwork/node/service_discovery/dto"
+	"github.com/mysteriumnetwork/node/session"
 )
 
 const issuerLogPrefix = "[promise-issuer] "
 
-// PromiseIssuer issues promises in such way, that no actual money is added to promise
+// IssueHistory issues promises following the ones issued to the same benefiter before
+type IssueHistory interface {
+	IssueNext(issuerID, benefiterID identity.Identity, increase money.Money, send func(promise.Promise) error) (promise.Promise, error)
+}
+
+// PromiseIssuer issues promises in such way, that no actual money is added to promise.
+// Serial numbers and amounts of promises keep growing across sessions with the same provider.
 type PromiseIssuer struct {
-	issuerID identity.Identity
-	dialog   communication.Dialog
-	signer   identity.Signer
+	issuerID    identity.Identity
+	dialog      communication.Dialog
+	signer      identity.Signer
+	statsKeeper stats.SessionStatsKeeper
+	history     IssueHistory
+
+	issueInterval time.Duration
+	timeGetter    func() time.Time
 
 	// these are populated by Start at runtime
-	proposal dto.ServiceProposal
+	proposal     dto.ServiceProposal
+	sessionID    session.ID
+	sessionStart time.Time
+	issued       bool
+	promised     money.Money
+	promiseLock  sync.Mutex
+	stop         chan struct{}
+	stopOnce     sync.Once
 }
 
 // NewPromiseIssuer creates instance of the promise issuer
-func NewPromiseIssuer(issuerID identity.Identity, dialog communication.Dialog, signer identity.Signer) *PromiseIssuer {
-	return &PromiseIssuer{issuerID: issuerID, dialog: dialog, signer: signer}
+func NewPromiseIssuer(
+	issuerID identity.Identity,
+	dialog communication.Dialog,
+	signer identity.Signer,
+	statsKeeper stats.SessionStatsKeeper,
+	history IssueHistory,
+) *PromiseIssuer {
+	return &PromiseIssuer{
+		issuerID:    issuerID,
+		dialog:      dialog,
+		signer:      signer,
+		statsKeeper: statsKeeper,
+		history:     history,
+
+		issueInterval: 5 * time.Second,
+		timeGetter:    time.Now,
+	}
 }
 
-// Start issuing promises for given service proposal
-func (issuer *PromiseIssuer) Start(proposal dto.ServiceProposal) error {
+// Start issues the first promise for the session and keeps issuing new ones, while service usage grows
+func (issuer *PromiseIssuer) Start(proposal dto.ServiceProposal, sessionID session.ID) error {
 	issuer.proposal = proposal
+	issuer.sessionID = sessionID
+	issuer.sessionStart = issuer.timeGetter()
+	issuer.stop = make(chan struct{})
+
+	if err := issuer.issuePromiseIfNeeded(); err != nil {
+		return err
+	}
 
-	if err := issuer.sendNewPromise(); err != nil {
+	if err := issuer.subscribePromiseBalance(); err != nil {
 		return err
 	}
 
-	return issuer.subscribePromiseBalance()
+	go issuer.issueLoop()
+	return nil
 }
 
-// Stop stops issuing promises
+// Stop stops issuing of new promises
 func (issuer *PromiseIssuer) Stop() error {
 	// TODO Should unregister consumers(subscriptions) here
+	issuer.stopOnce.Do(func() {
+		if issuer.stop != nil {
+			close(issuer.stop)
+		}
+	})
 	return nil
 }
 
-func (issuer *PromiseIssuer) sendNewPromise() error {
-	unsignedPromise := promise.NewPromise(
+func (issuer *PromiseIssuer) issueLoop() {
+	for {
+		select {
+		case <-issuer.stop:
+			return
+		case <-time.After(issuer.issueInterval):
+			if err := issuer.issuePromiseIfNeeded(); err != nil {
+				log.Error(issuerLogPrefix, "Failed to issue promise: ", err)
+			}
+		}
+	}
+}
+
+// issuePromiseIfNeeded sends promise with next serial number, when charge for the current usage exceeds the amount promised during the session.
+// Promised amount is increased by the difference on top of the last promise issued to the provider.
+func (issuer *PromiseIssuer) issuePromiseIfNeeded() error {
+	issuer.promiseLock.Lock()
+	defer issuer.promiseLock.Unlock()
+
+	charge := promise.CalculateCharge(issuer.proposal.PaymentMethod, issuer.usage())
+	if issuer.issued && charge.Amount <= issuer.promised.Amount {
+		return nil
+	}
+
+	increase := money.Money{Amount: charge.Amount - issuer.promised.Amount, Currency: charge.Currency}
+	issuedPromise, err := issuer.history.IssueNext(
 		issuer.issuerID,
 		identity.FromAddress(issuer.proposal.ProviderID),
-		issuer.proposal.PaymentMethod.GetPrice())
-
-	signedPromise, err := unsignedPromise.SignByIssuer(issuer.signer)
+		increase,
+		func(unsignedPromise promise.Promise) error {
+			signedPromise, err := unsignedPromise.SignByIssuer(issuer.signer)
+			if err != nil {
+				return err
+			}
+			return signedPromise.Send(issuer.dialog, string(issuer.sessionID))
+		},
+	)
 	if err != nil {
 		return err
 	}
 
-	return signedPromise.Send(issuer.dialog)
+	issuer.issued = true
+	issuer.promised = charge
+	log.Info(issuerLogPrefix, fmt.Sprintf("Promise #%d issued: %s", issuedPromise.SerialNumber, issuedPromise.Amount.String()))
+	return nil
+}
+
+func (issuer *PromiseIssuer) usage() promise.Usage {
+	usage := promise.Usage{Duration: issuer.timeGetter().Sub(issuer.sessionStart)}
+	if issuer.statsKeeper != nil {
+		sessionStats := issuer.statsKeeper.Retrieve()
+		usage.Bytes = sessionStats.BytesSent + sessionStats.BytesReceived
+	}
+	return usage
 }
 
 func (issuer *PromiseIssuer) subscribePromiseBalance() error {
@@ -20,7 +20,9 @@ package noop
 import (
 	"errors"
 	"testing"
+	"time"
 
+	"github.com/mysteriumnetwork/node/client/stats"
 	"github.com/mysteriumnetwork/node/core/connection"
 	"github.com/mysteriumnetwork/node/core/promise"
 	"github.com/mysteriumnetwork/node/identity"
@@ -36,6 +38,14 @@ var (
 		ProviderID:    providerID.Address,
 		PaymentMethod: fakePaymentMethod{},
 	}
+	timeMeteredProposal = dto.ServiceProposal{
+		ProviderID:    providerID.Address,
+		PaymentMethod: fakePaymentPerTime{},
+	}
+	byteMeteredProposal = dto.ServiceProposal{
+		ProviderID:    providerID.Address,
+		PaymentMethod: fakePaymentPerBytes{},
+	}
 )
 
 var _ connection.PromiseIssuer = &PromiseIssuer{}
@@ -45,16 +55,11 @@ func TestPromiseIssuer_Start_SubscriptionFails(t *testing.T) {
 		returnError: errors.New("reject subscriptions"),
 	}
 
-	logs := make([]string, 0)
-	logger := logconfig.ReplaceLogger(logconfig.NewLoggerCapture(&logs))
-	defer logconfig.ReplaceLogger(logger)
-
-	issuer := &PromiseIssuer{dialog: dialog, signer: &identity.SignerFake{}}
-	err := issuer.Start(proposal)
+	issuer := NewPromiseIssuer(identity.Identity{}, dialog, &identity.SignerFake{}, nil, &issueHistoryFake{})
+	err := issuer.Start(proposal, "session-id")
 	defer issuer.Stop()
 
 	assert.EqualError(t, err, "reject subscriptions")
-	assert.Len(t, logs, 0)
 }
 
 func TestPromiseIssuer_Start_SubscriptionOfBalances(t *testing.T) {
@@ -66,12 +71,134 @@ func TestPromiseIssuer_Start_SubscriptionOfBalances(t *testing.T) {
 	logger := logconfig.ReplaceLogger(logconfig.NewLoggerCapture(&logs))
 	defer logconfig.ReplaceLogger(logger)
 
-	issuer := &PromiseIssuer{dialog: dialog, signer: &identity.SignerFake{}}
-	err := issuer.Start(proposal)
+	issuer := NewPromiseIssuer(identity.Identity{}, dialog, &identity.SignerFake{}, nil, &issueHistoryFake{})
+	err := issuer.Start(proposal, "session-id")
+	defer issuer.Stop()
+	assert.NoError(t, err)
+
+	assert.Contains(t, logs, "[promise-issuer] Promise balance notified: 1000000000TEST")
+}
+
+func TestPromiseIssuer_Start_IssuesFirstPromise(t *testing.T) {
+	dialog := &fakeDialog{}
+
+	issuer := NewPromiseIssuer(identity.Identity{}, dialog, &identity.SignerFake{}, nil, &issueHistoryFake{})
+	err := issuer.Start(proposal, "session-id")
+	defer issuer.Stop()
+	assert.NoError(t, err)
+
+	requests := dialog.getRequestsSent()
+	assert.Len(t, requests, 1)
+	assert.Equal(t, "session-id", requests[0].(*promise.Request).SessionID)
+	sentPromise := requests[0].(*promise.Request).SignedPromise.Promise
+	assert.Equal(t, 1, sentPromise.SerialNumber)
+	assert.Equal(t, fakePaymentMethod{}.GetPrice(), sentPromise.Amount)
+	assert.Equal(t, providerID.Address, sentPromise.BenefiterID)
+}
+
+func TestPromiseIssuer_IssuesPromisesAsTimeUsageGrows(t *testing.T) {
+	dialog := &fakeDialog{}
+	now := time.Now()
+
+	issuer := NewPromiseIssuer(identity.Identity{}, dialog, &identity.SignerFake{}, nil, &issueHistoryFake{})
+	issuer.timeGetter = func() time.Time { return now }
+	err := issuer.Start(timeMeteredProposal, "session-id")
+	defer issuer.Stop()
 	assert.NoError(t, err)
 
-	assert.Len(t, logs, 1)
-	assert.Equal(t, "[promise-issuer] Promise balance notified: 1000000000TEST", logs[0])
+	// usage did not grow enough yet
+	assert.NoError(t, issuer.issuePromiseIfNeeded())
+	assert.Len(t, dialog.getRequestsSent(), 1)
+
+	now = now.Add(2 * time.Minute)
+	assert.NoError(t, issuer.issuePromiseIfNeeded())
+
+	requests := dialog.getRequestsSent()
+	assert.Len(t, requests, 2)
+	sentPromise := requests[1].(*promise.Request).SignedPromise.Promise
+	assert.Equal(t, 2, sentPromise.SerialNumber)
+	assert.Equal(t, money.Money{Amount: 30, Currency: money.CURRENCY_MYST}, sentPromise.Amount)
+}
+
+func TestPromiseIssuer_IssuesPromisesAsTrafficUsageGrows(t *testing.T) {
+	dialog := &fakeDialog{}
+	statsKeeper := stats.NewSessionStatsKeeper(time.Now)
+
+	issuer := NewPromiseIssuer(identity.Identity{}, dialog, &identity.SignerFake{}, statsKeeper, &issueHistoryFake{})
+	err := issuer.Start(byteMeteredProposal, "session-id")
+	defer issuer.Stop()
+	assert.NoError(t, err)
+
+	statsKeeper.Save(stats.SessionStats{BytesSent: 600, BytesReceived: 500})
+	assert.NoError(t, issuer.issuePromiseIfNeeded())
+
+	requests := dialog.getRequestsSent()
+	assert.Len(t, requests, 2)
+	sentPromise := requests[1].(*promise.Request).SignedPromise.Promise
+	assert.Equal(t, 2, sentPromise.SerialNumber)
+	assert.Equal(t, money.Money{Amount: 20, Currency: money.CURRENCY_MYST}, sentPromise.Amount)
+}
+
+func TestPromiseIssuer_ContinuesPromisesOfPreviousSession(t *testing.T) {
+	history := &issueHistoryFake{}
+	now := time.Now()
+
+	previous := NewPromiseIssuer(identity.Identity{}, &fakeDialog{}, &identity.SignerFake{}, nil, history)
+	previous.timeGetter = func() time.Time { return now }
+	assert.NoError(t, previous.Start(timeMeteredProposal, "session-id"))
+	now = now.Add(2 * time.Minute)
+	assert.NoError(t, previous.issuePromiseIfNeeded())
+	previous.Stop()
+
+	dialog := &fakeDialog{}
+	issuer := NewPromiseIssuer(identity.Identity{}, dialog, &identity.SignerFake{}, nil, history)
+	issuer.timeGetter = func() time.Time { return now }
+	err := issuer.Start(timeMeteredProposal, "session-id")
+	defer issuer.Stop()
+	assert.NoError(t, err)
+
+	requests := dialog.getRequestsSent()
+	assert.Len(t, requests, 1)
+	sentPromise := requests[0].(*promise.Request).SignedPromise.Promise
+	assert.Equal(t, 3, sentPromise.SerialNumber)
+	assert.Equal(t, money.Money{Amount: 40, Currency: money.CURRENCY_MYST}, sentPromise.Amount)
+}
+
+func TestPromiseIssuer_FailedPromiseIsIssuedAgain(t *testing.T) {
+	dialog := &fakeDialog{}
+	history := &issueHistoryFake{}
+
+	issuer := NewPromiseIssuer(identity.Identity{}, dialog, &identity.SignerFake{}, nil, history)
+	history.sendErr = errors.New("dialog closed")
+	assert.EqualError(t, issuer.Start(timeMeteredProposal, "session-id"), "dialog closed")
+
+	history.sendErr = nil
+	assert.NoError(t, issuer.issuePromiseIfNeeded())
+	assert.Equal(t, 1, history.last.SerialNumber)
+	assert.Equal(t, uint64(10), history.last.Amount.Amount)
+}
+
+type issueHistoryFake struct {
+	last    promise.Promise
+	sendErr error
+}
+
+func (history *issueHistoryFake) IssueNext(
+	issuerID, benefiterID identity.Identity,
+	increase money.Money,
+	send func(promise.Promise) error,
+) (promise.Promise, error) {
+	next := *promise.NewPromise(issuerID, benefiterID, increase)
+	next.SerialNumber = history.last.SerialNumber + 1
+	next.Amount.Amount += history.last.Amount.Amount
+	if history.sendErr != nil {
+		return promise.Promise{}, history.sendErr
+	}
+	if err := send(next); err != nil {
+		return promise.Promise{}, err
+	}
+	history.last = next
+	return next, nil
 }
 
 func testToken(amount float64) money.Money {
@@ -83,3 +210,23 @@ type fakePaymentMethod struct{}
 func (fpm fakePaymentMethod) GetPrice() money.Money {
 	return money.NewMoney(1111111111, money.Currency("FAKE"))
 }
+
+type fakePaymentPerTime struct{}
+
+func (fpm fakePaymentPerTime) GetPrice() money.Money {
+	return money.Money{Amount: 10, Currency: money.CURRENCY_MYST}
+}
+
+func (fpm fakePaymentPerTime) GetDuration() time.Duration {
+	return time.Minute
+}
+
+type fakePaymentPerBytes struct{}
+
+func (fpm fakePaymentPerBytes) GetPrice() money.Money {
+	return money.Money{Amount: 10, Currency: money.CURRENCY_MYST}
+}
+
+func (fpm fakePaymentPerBytes) GetBytes() uint64 {
+	return 1024
+}
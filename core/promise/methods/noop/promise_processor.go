@@ -25,10 +25,10 @@ import (
 	log "github.com/cihub/seelog"
 	"github.com/mysteriumnetwork/node/communication"
 	"github.com/mysteriumnetwork/node/core/promise"
-	"github.com/mysteriumnetwork/node/core/storage"
 	"github.com/mysteriumnetwork/node/identity"
 	"github.com/mysteriumnetwork/node/money"
 	"github.com/mysteriumnetwork/node/service_discovery/dto"
+	"github.com/mysteriumnetwork/node/session"
 )
 
 const (
@@ -38,16 +38,38 @@ const (
 	balanceStopped   = balanceState("Stopped")
 )
 
-// NewPromiseProcessor creates instance of PromiseProcessor
-func NewPromiseProcessor(dialog communication.Dialog, balance identity.Balance, storage storage.Storage) *PromiseProcessor {
+// SessionFinder looks up provider's sessions, which keep traffic counted by the service
+type SessionFinder interface {
+	Find(id session.ID) (session.Session, bool)
+}
+
+// ConsumerRouter passes promises received through the dialog to consumers of its sessions
+type ConsumerRouter interface {
+	Add(sessionID string, consumer *promise.Consumer) error
+	Remove(sessionID string)
+}
+
+// NewPromiseProcessor creates instance of PromiseProcessor, which processes promises of a single session.
+// Sessions of the same dialog share the router.
+func NewPromiseProcessor(
+	dialog communication.Dialog,
+	router ConsumerRouter,
+	balance identity.Balance,
+	recorder promise.Recorder,
+	sessions SessionFinder,
+) *PromiseProcessor {
 	return &PromiseProcessor{
-		dialog:  dialog,
-		balance: balance,
-		storage: storage,
+		dialog:   dialog,
+		router:   router,
+		balance:  balance,
+		recorder: recorder,
+		sessions: sessions,
 
 		balanceInterval: 5 * time.Second,
 		balanceState:    balanceStopped,
 		balanceShutdown: make(chan bool, 1),
+		unpaidTimeout:   time.Minute,
+		timeGetter:      time.Now,
 	}
 }
 
@@ -55,46 +77,62 @@ type balanceState string
 
 // PromiseProcessor process promises in such way, what no actual money is deducted from promise
 type PromiseProcessor struct {
-	dialog  communication.Dialog
-	balance identity.Balance
-	storage storage.Storage
+	dialog   communication.Dialog
+	router   ConsumerRouter
+	balance  identity.Balance
+	recorder promise.Recorder
+	sessions SessionFinder
 
 	balanceInterval   time.Duration
 	balanceState      balanceState
 	balanceStateMutex sync.RWMutex
 	balanceShutdown   chan bool
+	unpaidTimeout     time.Duration
+	timeGetter        func() time.Time
 
 	// these are populated later at runtime
-	lastPromise promise.Promise
+	proposal         dto.ServiceProposal
+	consumer         *promise.Consumer
+	terminate        func()
+	sessionID        session.ID
+	sessionStart     time.Time
+	balanceRequestID int
+	unpaidSince      time.Time
 }
 
-// Start processing promises for given service proposal
-func (processor *PromiseProcessor) Start(proposal dto.ServiceProposal) error {
-	// TODO: replace static value with some real data
-	processor.lastPromise = promise.Promise{
-		Amount: money.NewMoney(10, money.CURRENCY_MYST),
-	}
-
-	consumer := promise.NewConsumer(proposal, processor.balance, processor.storage)
-	if err := processor.dialog.Respond(consumer); err != nil {
+// Start starts processing of promises and notifying consumer about the balance.
+// Session is terminated, when consumer does not cover its usage for longer than unpaid timeout.
+func (processor *PromiseProcessor) Start(proposal dto.ServiceProposal, sessionID session.ID, terminate func()) error {
+	processor.proposal = proposal
+	processor.terminate = terminate
+	processor.sessionID = sessionID
+	processor.sessionStart = processor.timeGetter()
+	processor.balanceRequestID = 0
+	processor.unpaidSince = time.Time{}
+
+	processor.consumer = promise.NewConsumer(proposal, processor.balance, string(sessionID), processor.recorder)
+	if err := processor.router.Add(string(sessionID), processor.consumer); err != nil {
 		return err
 	}
 
 	processor.balanceShutdown = make(chan bool, 1)
+	processor.setBalanceState(balanceNotifying)
 	go processor.balanceLoop()
 
 	return nil
 }
 
-// Stop stops processing promises
+// Stop stops accepting promises of the session and notifying consumer about the balance
 func (processor *PromiseProcessor) Stop() error {
-	processor.balanceShutdown <- true
+	processor.router.Remove(string(processor.sessionID))
+	select {
+	case processor.balanceShutdown <- true:
+	default:
+	}
 	return nil
 }
 
 func (processor *PromiseProcessor) balanceLoop() {
-	processor.setBalanceState(balanceNotifying)
-
 balanceLoop:
 	for {
 		select {
@@ -102,16 +140,54 @@ balanceLoop:
 			break balanceLoop
 
 		case <-time.After(processor.balanceInterval):
-			// TODO: replace static value with some real data
-			processor.balanceSend(
-				promise.BalanceMessage{1, true, processor.lastPromise.Amount},
-			)
+			if paid := processor.balanceCheck(); !paid {
+				log.Warn(processorLogPrefix, "Consumer stopped paying, terminating session")
+				processor.setBalanceState(balanceStopped)
+				processor.terminate()
+				return
+			}
 		}
 	}
 
 	processor.setBalanceState(balanceStopped)
 }
 
+// balanceCheck notifies consumer about its balance and reports whether consumer is still paying for the service
+func (processor *PromiseProcessor) balanceCheck() bool {
+	now := processor.timeGetter()
+	consumed := promise.CalculateCharge(processor.proposal.PaymentMethod, processor.usage(now))
+
+	promised := processor.consumer.Promised()
+	accepted := promised.Currency == consumed.Currency && promised.Amount >= consumed.Amount
+	balance := money.Money{Currency: consumed.Currency}
+	if accepted {
+		balance.Amount = promised.Amount - consumed.Amount
+	}
+
+	processor.balanceRequestID++
+	if err := processor.balanceSend(promise.BalanceMessage{processor.balanceRequestID, accepted, balance}); err != nil {
+		log.Warn(processorLogPrefix, "Failed to send balance: ", err)
+	}
+
+	if accepted {
+		processor.unpaidSince = time.Time{}
+		return true
+	}
+	if processor.unpaidSince.IsZero() {
+		processor.unpaidSince = now
+	}
+	return now.Sub(processor.unpaidSince) < processor.unpaidTimeout
+}
+
+// usage returns time and traffic consumed during the session, as counted by the service
+func (processor *PromiseProcessor) usage(now time.Time) promise.Usage {
+	usage := promise.Usage{Duration: now.Sub(processor.sessionStart)}
+	if sessionInstance, found := processor.sessions.Find(processor.sessionID); found {
+		usage.Bytes = sessionInstance.DataTransfer.Up + sessionInstance.DataTransfer.Down
+	}
+	return usage
+}
+
 func (processor *PromiseProcessor) setBalanceState(state balanceState) {
 	processor.balanceStateMutex.Lock()
 	defer processor.balanceStateMutex.Unlock()
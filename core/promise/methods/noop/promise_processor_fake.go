@@ -17,14 +17,17 @@
 
 package noop
 
-import discovery_dto "github.com/mysteriumnetwork/node/service_discovery/dto"
+import (
+	discovery_dto "github.com/mysteriumnetwork/node/service_discovery/dto"
+	"github.com/mysteriumnetwork/node/session"
+)
 
 // FakePromiseEngine do nothing. It required for the temporary --experiment-promise-check flag.
 // TODO it should be removed once --experiment-promise-check will be deleted.
 type FakePromiseEngine struct{}
 
 // Start fakes promise engine start
-func (*FakePromiseEngine) Start(_ discovery_dto.ServiceProposal) error {
+func (*FakePromiseEngine) Start(_ discovery_dto.ServiceProposal, _ session.ID) error {
 	return nil
 }
 
@@ -32,3 +35,17 @@ func (*FakePromiseEngine) Start(_ discovery_dto.ServiceProposal) error {
 func (*FakePromiseEngine) Stop() error {
 	return nil
 }
+
+// FakePromiseProcessor do nothing at provider side. It required for the temporary --experiment-promise-check flag.
+// TODO it should be removed once --experiment-promise-check will be deleted.
+type FakePromiseProcessor struct{}
+
+// Start fakes promise processor start
+func (*FakePromiseProcessor) Start(_ discovery_dto.ServiceProposal, _ session.ID, _ func()) error {
+	return nil
+}
+
+// Stop fakes promise processor stop
+func (*FakePromiseProcessor) Stop() error {
+	return nil
+}
@@ -18,37 +18,110 @@
 package noop
 
 import (
+	"crypto/ecdsa"
 	"testing"
 	"time"
 
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/mysteriumnetwork/node/core/promise"
 	"github.com/mysteriumnetwork/node/core/storage"
+	"github.com/mysteriumnetwork/node/identity"
 	"github.com/mysteriumnetwork/node/money"
+	"github.com/mysteriumnetwork/node/service_discovery/dto"
 	"github.com/mysteriumnetwork/node/session"
 	"github.com/stretchr/testify/assert"
 )
 
 var _ session.PromiseProcessor = &PromiseProcessor{}
 
+// issuerKey is the private key of the test consumer issuing promises
+const issuerKey = "b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291"
+
+// keySigner signs messages the same way as keystore signer does
+type keySigner struct {
+	key *ecdsa.PrivateKey
+}
+
+func (signer *keySigner) Sign(message []byte) (identity.Signature, error) {
+	signature, err := crypto.Sign(crypto.Keccak256(message), signer.key)
+	return identity.SignatureBytes(signature), err
+}
+
+var paidProposal = dto.ServiceProposal{
+	ProviderID:    "0x1526273ac60cdebfa2aece92da3261ecb564763a",
+	PaymentMethod: fakePaymentPerMinute{money.Money{Amount: 5000000, Currency: money.CURRENCY_MYST}},
+}
+
 func TestPromiseProcessor_Start_SendsBalanceMessages(t *testing.T) {
 	dialog := &fakeDialog{}
 
-	processor := &PromiseProcessor{
-		dialog:          dialog,
-		balanceInterval: time.Millisecond,
-		storage:         &storage.FakeStorage{},
-	}
-	err := processor.Start(proposal)
+	now := time.Now()
+	processor := newTestProcessor(dialog)
+	processor.balanceInterval = time.Hour
+	processor.timeGetter = func() time.Time { return now }
+	err := processor.Start(paidProposal, "session-id", func() {})
 	defer processor.Stop()
+	assert.NoError(t, err)
+	waitForBalanceState(t, processor, balanceNotifying)
+
+	consumePromise(t, dialog)
+	assert.True(t, processor.balanceCheck())
+
+	lastMessage, err := dialog.waitSendMessage()
+	assert.NoError(t, err)
+	assert.Exactly(
+		t,
+		promise.BalanceMessage{1, true, money.Money{Amount: 7500000, Currency: money.CURRENCY_MYST}},
+		lastMessage,
+	)
+
+	now = now.Add(2 * time.Minute)
+	assert.True(t, processor.balanceCheck())
+
+	lastMessage, err = dialog.waitSendMessage()
+	assert.NoError(t, err)
+	assert.Exactly(
+		t,
+		promise.BalanceMessage{2, false, money.Money{Amount: 0, Currency: money.CURRENCY_MYST}},
+		lastMessage,
+	)
+}
 
+func TestPromiseProcessor_ChargesSessionTraffic(t *testing.T) {
+	dialog := &fakeDialog{}
+	sessions := &fakeSessions{}
+
+	processor := newTestProcessor(dialog)
+	processor.sessions = sessions
+	processor.balanceInterval = time.Hour
+	proposal := dto.ServiceProposal{
+		ProviderID:    paidProposal.ProviderID,
+		PaymentMethod: fakePaymentPerMegabyte{money.Money{Amount: 5000000, Currency: money.CURRENCY_MYST}},
+	}
+	err := processor.Start(proposal, "session-id", func() {})
+	defer processor.Stop()
 	assert.NoError(t, err)
 	waitForBalanceState(t, processor, balanceNotifying)
 
+	consumePromise(t, dialog)
+	assert.True(t, processor.balanceCheck())
+
 	lastMessage, err := dialog.waitSendMessage()
 	assert.NoError(t, err)
 	assert.Exactly(
 		t,
-		promise.BalanceMessage{1, true, money.NewMoney(10, money.CURRENCY_MYST)},
+		promise.BalanceMessage{1, true, money.Money{Amount: 7500000, Currency: money.CURRENCY_MYST}},
+		lastMessage,
+	)
+
+	sessions.dataTransfer = session.DataTransfer{Up: 1000000, Down: 1000000}
+	assert.True(t, processor.balanceCheck())
+
+	lastMessage, err = dialog.waitSendMessage()
+	assert.NoError(t, err)
+	assert.Exactly(
+		t,
+		promise.BalanceMessage{2, false, money.Money{Amount: 0, Currency: money.CURRENCY_MYST}},
 		lastMessage,
 	)
 }
@@ -56,12 +129,8 @@ func TestPromiseProcessor_Start_SendsBalanceMessages(t *testing.T) {
 func TestPromiseProcessor_Stop_StopsBalanceMessages(t *testing.T) {
 	dialog := &fakeDialog{}
 
-	processor := &PromiseProcessor{
-		dialog:          dialog,
-		balanceInterval: time.Millisecond,
-		storage:         &storage.FakeStorage{},
-	}
-	err := processor.Start(proposal)
+	processor := newTestProcessor(dialog)
+	err := processor.Start(paidProposal, "session-id", func() {})
 	assert.NoError(t, err)
 	waitForBalanceState(t, processor, balanceNotifying)
 
@@ -70,6 +139,61 @@ func TestPromiseProcessor_Stop_StopsBalanceMessages(t *testing.T) {
 	waitForBalanceState(t, processor, balanceStopped)
 }
 
+func TestPromiseProcessor_TerminatesUnpaidSession(t *testing.T) {
+	dialog := &fakeDialog{}
+	terminated := make(chan struct{})
+
+	processor := newTestProcessor(dialog)
+	processor.unpaidTimeout = 0
+	err := processor.Start(paidProposal, "session-id", func() { close(terminated) })
+	defer processor.Stop()
+	assert.NoError(t, err)
+
+	select {
+	case <-terminated:
+	case <-time.After(time.Second):
+		assert.Fail(t, "unpaid session was not terminated")
+	}
+	waitForBalanceState(t, processor, balanceStopped)
+
+	lastMessage, err := dialog.waitSendMessage()
+	assert.NoError(t, err)
+	assert.Exactly(
+		t,
+		promise.BalanceMessage{1, false, money.Money{Amount: 0, Currency: money.CURRENCY_MYST}},
+		lastMessage,
+	)
+}
+
+func newTestProcessor(dialog *fakeDialog) *PromiseProcessor {
+	processor := NewPromiseProcessor(
+		dialog,
+		promise.NewConsumerRouter(dialog),
+		fakeBalance(999999999),
+		promise.NewLedger(&storage.FakeStorage{}),
+		&fakeSessions{},
+	)
+	processor.balanceInterval = time.Millisecond
+	return processor
+}
+
+func consumePromise(t *testing.T, dialog *fakeDialog) {
+	key, err := crypto.HexToECDSA(issuerKey)
+	assert.NoError(t, err)
+	unsignedPromise := promise.NewPromise(
+		identity.FromAddress(crypto.PubkeyToAddress(key.PublicKey).Hex()),
+		identity.FromAddress(paidProposal.ProviderID),
+		money.Money{Amount: 12500000, Currency: money.CURRENCY_MYST},
+	)
+	signedPromise, err := unsignedPromise.SignByIssuer(&keySigner{key: key})
+	assert.NoError(t, err)
+
+	consumer := dialog.getRequestConsumer()
+	assert.NotNil(t, consumer)
+	_, err = consumer.Consume(&promise.Request{SignedPromise: signedPromise, SessionID: "session-id"})
+	assert.NoError(t, err)
+}
+
 func waitForBalanceState(t *testing.T, processor *PromiseProcessor, expectedState balanceState) {
 	for i := 0; i < 10; i++ {
 		if processor.getBalanceState() == expectedState {
@@ -79,3 +203,41 @@ func waitForBalanceState(t *testing.T, processor *PromiseProcessor, expectedStat
 	}
 	assert.Fail(t, "State expected to be ", string(expectedState))
 }
+
+func fakeBalance(amount uint64) identity.Balance {
+	return func(_ identity.Identity) (uint64, error) {
+		return amount, nil
+	}
+}
+
+type fakePaymentPerMinute struct {
+	price money.Money
+}
+
+func (fpm fakePaymentPerMinute) GetPrice() money.Money {
+	return fpm.price
+}
+
+func (fpm fakePaymentPerMinute) GetDuration() time.Duration {
+	return time.Minute
+}
+
+type fakePaymentPerMegabyte struct {
+	price money.Money
+}
+
+func (fpm fakePaymentPerMegabyte) GetPrice() money.Money {
+	return fpm.price
+}
+
+func (fpm fakePaymentPerMegabyte) GetBytes() uint64 {
+	return 1000000
+}
+
+type fakeSessions struct {
+	dataTransfer session.DataTransfer
+}
+
+func (sessions *fakeSessions) Find(id session.ID) (session.Session, bool) {
+	return session.Session{ID: id, DataTransfer: sessions.dataTransfer}, true
+}
@@ -24,11 +24,14 @@ import (
 // Producer creates requests/responses of the "promise-create" events
 type Producer struct {
 	SignedPromise *SignedPromise
+	SessionID     string
 }
 
-// Request structure represents message from service provider to receive new promise from consumer
+// Request structure represents message from service provider to receive new promise from consumer.
+// SessionID tells which session of the dialog the promise pays for.
 type Request struct {
 	SignedPromise *SignedPromise
+	SessionID     string
 }
 
 // Response structure represents service provider response to given session request from consumer
@@ -51,5 +54,6 @@ func (p *Producer) NewResponse() (responsePtr interface{}) {
 func (p *Producer) Produce() (requestPtr interface{}) {
 	return &Request{
 		SignedPromise: p.SignedPromise,
+		SessionID:     p.SessionID,
 	}
 }
@@ -38,8 +38,8 @@ func TestNewResponse(t *testing.T) {
 
 func TestProduce(t *testing.T) {
 	signedPromise := &SignedPromise{Promise: Promise{}, IssuerSignature: "ProducerSignature"}
-	producer := Producer{SignedPromise: signedPromise}
+	producer := Producer{SignedPromise: signedPromise, SessionID: "session-id"}
 
-	assert.Equal(t, &Request{signedPromise}, producer.Produce())
+	assert.Equal(t, &Request{SignedPromise: signedPromise, SessionID: "session-id"}, producer.Produce())
 
 }
@@ -20,9 +20,14 @@
 package promise
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
+	"math/big"
 
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/mysteriumnetwork/node/communication"
 	"github.com/mysteriumnetwork/node/identity"
 	"github.com/mysteriumnetwork/node/money"
@@ -31,12 +36,23 @@ import (
 
 const endpoint = "promise-create"
 
+// clearingIssuerPrefix is prepended to the promise, when payments contract recovers issuer from the signature
+const clearingIssuerPrefix = "Issuer prefix:"
+
+// ClearingExtraDataHash is the hash of promise extra data passed to the payments contract.
+// Promises don't carry any extra data yet, so it is a hash of empty data.
+var ClearingExtraDataHash = crypto.Keccak256Hash()
+
 var (
-	errLowAmount          = errors.New("promise amount less than the service proposal price")
-	errLowBalance         = errors.New("issuer balance less than the promise amount")
-	errBadSignature       = errors.New("invalid Signature for the provided identity")
-	errUnknownBenefiter   = errors.New("unknown promise benefiter received")
-	errUnsupportedRequest = errors.New("unsupported request")
+	errLowAmount           = errors.New("promise amount less than the service proposal price")
+	errCurrencyMismatch    = errors.New("promise currency differs from the service proposal price")
+	errLowBalance          = errors.New("issuer balance less than the promise amount")
+	errBadSignature        = errors.New("invalid Signature for the provided identity")
+	errUnknownBenefiter    = errors.New("unknown promise benefiter received")
+	errUnsupportedRequest  = errors.New("unsupported request")
+	errSerialNotIncreasing = errors.New("promise serial number is not higher than the previous one")
+	errAmountDecreased     = errors.New("promise amount is lower than the previous one")
+	errUnknownSession      = errors.New("promise is issued for unknown session")
 )
 
 // NewPromise creates new Promise object filled by the requested arguments
@@ -56,16 +72,33 @@ func (p *Promise) SignByIssuer(issuerSigner identity.Signer) (*SignedPromise, er
 		return nil, err
 	}
 	signature, err := issuerSigner.Sign(out)
+	if err != nil {
+		return nil, err
+	}
+	clearingSignature, err := issuerSigner.Sign(p.ClearingMessage())
 
 	return &SignedPromise{
-		Promise:         *p,
-		IssuerSignature: Signature(signature.Base64()),
+		Promise:           *p,
+		IssuerSignature:   Signature(signature.Base64()),
+		ClearingSignature: Signature(clearingSignature.Base64()),
 	}, err
 }
 
-// Send sends signed promise via the communication channel
-func (sp *SignedPromise) Send(sender communication.Sender) error {
-	responsePtr, err := sender.Request(&Producer{SignedPromise: sp})
+// ClearingMessage packs the promise the same way payments contract does before hashing it.
+// Signature of this message lets the benefiter clear the promise in the contract.
+func (p *Promise) ClearingMessage() []byte {
+	return bytes.Join([][]byte{
+		[]byte(clearingIssuerPrefix),
+		ClearingExtraDataHash.Bytes(),
+		common.HexToAddress(p.BenefiterID).Bytes(),
+		abi.U256(big.NewInt(int64(p.SerialNumber))),
+		abi.U256(new(big.Int).SetUint64(p.Amount.Amount)),
+	}, nil)
+}
+
+// Send sends signed promise for the session via the communication channel
+func (sp *SignedPromise) Send(sender communication.Sender, sessionID string) error {
+	responsePtr, err := sender.Request(&Producer{SignedPromise: sp, SessionID: sessionID})
 
 	response := responsePtr.(*Response)
 	if err != nil || !response.Success {
@@ -75,8 +108,8 @@ func (sp *SignedPromise) Send(sender communication.Sender) error {
 	return nil
 }
 
-// Validate check signed promise to be valid. It checks signature, benefiter address.
-// Also it compares the promised amount to be enough for the proposal.
+// Validate check signed promise to be valid. It checks signatures, benefiter address.
+// Also it compares the promised amount to be enough for the proposal and to be in the currency of proposal price.
 // And finally it checks that issuer have enough balance to issue the promice.
 func (sp *SignedPromise) Validate(proposal dto.ServiceProposal, balance identity.Balance) error {
 	receivedPromise, err := json.Marshal(sp.Promise)
@@ -90,6 +123,11 @@ func (sp *SignedPromise) Validate(proposal dto.ServiceProposal, balance identity
 	if !verifier.Verify(receivedPromise, signature) {
 		return errBadSignature
 	}
+	// promise, which benefiter can not clear in the payments contract, is worthless
+	clearingSignature := identity.SignatureBase64(string(sp.ClearingSignature))
+	if !verifier.Verify(sp.Promise.ClearingMessage(), clearingSignature) {
+		return errBadSignature
+	}
 
 	benefiter := identity.FromAddress(sp.Promise.BenefiterID)
 	if benefiter.Address != proposal.ProviderID {
@@ -98,6 +136,9 @@ func (sp *SignedPromise) Validate(proposal dto.ServiceProposal, balance identity
 
 	price := proposal.PaymentMethod.GetPrice()
 	promisedValue := sp.Promise.Amount
+	if promisedValue.Currency != price.Currency {
+		return errCurrencyMismatch
+	}
 	if promisedValue.Amount < price.Amount {
 		return errLowAmount
 	}
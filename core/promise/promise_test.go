@@ -19,8 +19,10 @@ package promise
 
 import (
 	"encoding/base64"
+	"math/big"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/mysteriumnetwork/node/identity"
 	"github.com/mysteriumnetwork/node/money"
 	"github.com/stretchr/testify/assert"
@@ -54,6 +56,26 @@ func TestSignByIssuer(t *testing.T) {
 
 	expectedSignature := base64.StdEncoding.EncodeToString([]byte("FakeSignature"))
 	assert.Equal(t, expectedSignature, string(signedPromise.IssuerSignature))
+	assert.Equal(t, expectedSignature, string(signedPromise.ClearingSignature))
+}
+
+func TestClearingMessage(t *testing.T) {
+	promise := Promise{
+		SerialNumber: 2,
+		IssuerID:     "0x8eaf2780c6098dd1baee2b7c8c62f2d92ba1fe29",
+		BenefiterID:  "0x1526273ac60cdebfa2aece92da3261ecb564763a",
+		Amount:       money.Money{Amount: 300, Currency: "TEST"},
+	}
+
+	message := promise.ClearingMessage()
+	assert.Len(t, message, len(clearingIssuerPrefix)+32+20+32+32)
+	assert.Equal(t, clearingIssuerPrefix, string(message[:len(clearingIssuerPrefix)]))
+
+	message = message[len(clearingIssuerPrefix):]
+	assert.Equal(t, ClearingExtraDataHash.Bytes(), message[:32])
+	assert.Equal(t, common.HexToAddress(promise.BenefiterID).Bytes(), message[32:52])
+	assert.Equal(t, int64(2), new(big.Int).SetBytes(message[52:84]).Int64())
+	assert.Equal(t, int64(300), new(big.Int).SetBytes(message[84:116]).Int64())
 }
 
 type fakeSigner struct{}
@@ -0,0 +1,103 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package promise
+
+import (
+	"sync"
+
+	"github.com/mysteriumnetwork/node/communication"
+)
+
+// ConsumerRouter passes promise requests of the dialog to consumers of the sessions, which promises pay for.
+// Dialog has single responder per endpoint, so it is shared by all sessions of the dialog.
+type ConsumerRouter struct {
+	dialog communication.Dialog
+
+	consumers  map[string]*Consumer
+	responding bool
+	lock       sync.Mutex
+}
+
+// NewConsumerRouter creates router of promise requests received through the dialog
+func NewConsumerRouter(dialog communication.Dialog) *ConsumerRouter {
+	return &ConsumerRouter{
+		dialog:    dialog,
+		consumers: make(map[string]*Consumer),
+	}
+}
+
+// Add starts passing promises of the session to the consumer
+func (router *ConsumerRouter) Add(sessionID string, consumer *Consumer) error {
+	router.lock.Lock()
+	defer router.lock.Unlock()
+
+	if !router.responding {
+		if err := router.dialog.Respond(router); err != nil {
+			return err
+		}
+		router.responding = true
+	}
+	router.consumers[sessionID] = consumer
+	return nil
+}
+
+// Remove stops passing promises of the session
+func (router *ConsumerRouter) Remove(sessionID string) {
+	router.lock.Lock()
+	defer router.lock.Unlock()
+
+	delete(router.consumers, sessionID)
+}
+
+// GetRequestEndpoint returns endpoint where to receive requests
+func (router *ConsumerRouter) GetRequestEndpoint() communication.RequestEndpoint {
+	return endpoint
+}
+
+// NewRequest creates struct where request from endpoint will be serialized
+func (router *ConsumerRouter) NewRequest() (requestPtr interface{}) {
+	return &Request{}
+}
+
+// Consume passes request to consumer of the session.
+// Consumers of older versions do not tell the session, their promises go to the only session of the dialog.
+func (router *ConsumerRouter) Consume(requestPtr interface{}) (response interface{}, err error) {
+	request, ok := requestPtr.(*Request)
+	if !ok {
+		return responseInvalidPromise, errUnsupportedRequest
+	}
+
+	consumer, found := router.find(request.SessionID)
+	if !found {
+		return responseInvalidPromise, errUnknownSession
+	}
+	return consumer.Consume(request)
+}
+
+func (router *ConsumerRouter) find(sessionID string) (*Consumer, bool) {
+	router.lock.Lock()
+	defer router.lock.Unlock()
+
+	if sessionID == "" && len(router.consumers) == 1 {
+		for _, consumer := range router.consumers {
+			return consumer, true
+		}
+	}
+	consumer, found := router.consumers[sessionID]
+	return consumer, found
+}
@@ -0,0 +1,93 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package promise
+
+import (
+	"testing"
+
+	"github.com/mysteriumnetwork/node/communication"
+	"github.com/mysteriumnetwork/node/core/storage"
+	"github.com/mysteriumnetwork/node/service_discovery/dto"
+	"github.com/stretchr/testify/assert"
+)
+
+type respondingDialogFake struct {
+	communication.Dialog
+	consumers []communication.RequestConsumer
+}
+
+func (dialog *respondingDialogFake) Respond(consumer communication.RequestConsumer) error {
+	dialog.consumers = append(dialog.consumers, consumer)
+	return nil
+}
+
+func newRouterTestConsumer() *Consumer {
+	proposal := dto.ServiceProposal{
+		ProviderID:    consumerTestBenefiter,
+		PaymentMethod: fakePayment{amount: 1},
+	}
+	return NewConsumer(proposal, fakeBlockchain(999999999), "", NewLedger(&storage.FakeStorage{}))
+}
+
+func TestConsumerRouter_PassesPromisesToConsumerOfSession(t *testing.T) {
+	dialog := &respondingDialogFake{}
+	router := NewConsumerRouter(dialog)
+	first, second := newRouterTestConsumer(), newRouterTestConsumer()
+	assert.NoError(t, router.Add("session-1", first))
+	assert.NoError(t, router.Add("session-2", second))
+	assert.Len(t, dialog.consumers, 1)
+
+	request := signedRequest(t, 1, 12500000)
+	request.SessionID = "session-2"
+	response, err := dialog.consumers[0].Consume(request)
+	assert.NoError(t, err)
+	assert.Equal(t, &Response{Success: true}, response)
+
+	_, found := first.LastPromise()
+	assert.False(t, found)
+	_, found = second.LastPromise()
+	assert.True(t, found)
+}
+
+func TestConsumerRouter_PassesPromisesWithoutSessionToOnlySession(t *testing.T) {
+	router := NewConsumerRouter(&respondingDialogFake{})
+	consumer := newRouterTestConsumer()
+	assert.NoError(t, router.Add("session-1", consumer))
+
+	_, err := router.Consume(signedRequest(t, 1, 12500000))
+	assert.NoError(t, err)
+	_, found := consumer.LastPromise()
+	assert.True(t, found)
+
+	assert.NoError(t, router.Add("session-2", newRouterTestConsumer()))
+	response, err := router.Consume(signedRequest(t, 2, 13000000))
+	assert.Equal(t, errUnknownSession, err)
+	assert.Equal(t, responseInvalidPromise, response)
+}
+
+func TestConsumerRouter_RejectsPromisesOfRemovedSession(t *testing.T) {
+	router := NewConsumerRouter(&respondingDialogFake{})
+	assert.NoError(t, router.Add("session-1", newRouterTestConsumer()))
+	router.Remove("session-1")
+
+	request := signedRequest(t, 1, 12500000)
+	request.SessionID = "session-1"
+	response, err := router.Consume(request)
+	assert.Equal(t, errUnknownSession, err)
+	assert.Equal(t, responseInvalidPromise, response)
+}
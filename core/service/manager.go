@@ -19,6 +19,10 @@ package service
 
 import (
 	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
 
 	log "github.com/cihub/seelog"
 	"github.com/mysteriumnetwork/node/communication"
@@ -36,6 +40,18 @@ var (
 	ErrorLocation = errors.New("failed to detect service location")
 	// ErrUnsupportedServiceType indicates that manager tried to create an unsupported service type
 	ErrUnsupportedServiceType = errors.New("unsupported service type")
+	// ErrorServiceNotFound indicates that manager does not run service with given id
+	ErrorServiceNotFound = errors.New("service not found")
+)
+
+// State describes whether the service is serving consumers
+type State string
+
+const (
+	// Running means service was started and serves consumers
+	Running = State("Running")
+	// NotRunning means service process exited by itself
+	NotRunning = State("NotRunning")
 )
 
 // ServiceFactory initiates instance which is able to serve connections
@@ -48,11 +64,22 @@ type Service interface {
 	Stop() error
 }
 
-// DialogWaiterFactory initiates communication channel which waits for incoming dialogs
-type DialogWaiterFactory func(providerID identity.Identity) communication.DialogWaiter
+// Discovery announces proposal of the service to the network
+type Discovery interface {
+	Start(providerID identity.Identity, proposal dto_discovery.ServiceProposal)
+	Status() discovery.Status
+	Wait()
+	Stop()
+}
+
+// DialogWaiterFactory initiates communication channel which waits for incoming dialogs of the service
+type DialogWaiterFactory func(providerID identity.Identity, serviceID int) communication.DialogWaiter
 
 // DialogHandlerFactory initiates instance which is able to handle incoming dialogs
-type DialogHandlerFactory func(dto_discovery.ServiceProposal, session.ConfigProvider) communication.DialogHandler
+type DialogHandlerFactory func(dto_discovery.ServiceProposal, session.ConfigProvider, session.AdmissionPolicy) communication.DialogHandler
+
+// DiscoveryFactory initiates discovery of a single service
+type DiscoveryFactory func() Discovery
 
 // NewManager creates new instance of pluggable services manager
 func NewManager(
@@ -60,14 +87,16 @@ func NewManager(
 	serviceFactory ServiceFactory,
 	dialogWaiterFactory DialogWaiterFactory,
 	dialogHandlerFactory DialogHandlerFactory,
-	discoveryService *discovery.Discovery,
+	discoveryFactory DiscoveryFactory,
 ) *Manager {
 	return &Manager{
 		identityHandler:      identityLoader,
 		serviceFactory:       serviceFactory,
 		dialogWaiterFactory:  dialogWaiterFactory,
 		dialogHandlerFactory: dialogHandlerFactory,
-		discovery:            discoveryService,
+		discoveryFactory:     discoveryFactory,
+		instances:            make(map[int]*Instance),
+		reservedIDs:          make(map[int]bool),
 	}
 }
 
@@ -75,77 +104,265 @@ func NewManager(
 type Manager struct {
 	identityHandler identity_selector.Handler
 
-	dialogWaiterFactory  func(identity identity.Identity) communication.DialogWaiter
-	dialogWaiter         communication.DialogWaiter
+	dialogWaiterFactory  DialogWaiterFactory
 	dialogHandlerFactory DialogHandlerFactory
+	discoveryFactory     DiscoveryFactory
+	serviceFactory       ServiceFactory
+
+	lastOrder   int
+	instances   map[int]*Instance
+	reservedIDs map[int]bool
+	mutex       sync.Mutex
+}
+
+// Instance represents service running on the node
+type Instance struct {
+	id           int
+	order        int
+	options      Options
+	proposal     dto_discovery.ServiceProposal
+	service      Service
+	dialogWaiter communication.DialogWaiter
+	discovery    Discovery
 
-	serviceFactory ServiceFactory
-	service        Service
+	done    chan struct{}
+	waitErr error
 
-	discovery *discovery.Discovery
+	unannounce    sync.Once
+	errUnannounce error
+}
+
+// ID returns identifier of the service, which is also the ID of its proposal
+func (instance *Instance) ID() int {
+	return instance.id
+}
+
+// Options returns options the service was started with
+func (instance *Instance) Options() Options {
+	return instance.options
+}
+
+// Proposal returns proposal announced by the service
+func (instance *Instance) Proposal() dto_discovery.ServiceProposal {
+	return instance.proposal
+}
+
+// State returns whether the service is still running
+func (instance *Instance) State() State {
+	select {
+	case <-instance.done:
+		return NotRunning
+	default:
+		return Running
+	}
+}
+
+// DiscoveryStatus returns stage of the service proposal registration
+func (instance *Instance) DiscoveryStatus() discovery.Status {
+	return instance.discovery.Status()
+}
+
+// Wait blocks until service is stopped
+func (instance *Instance) Wait() error {
+	log.Info(logPrefix, "Waiting for discovery of service ", instance.id, " to finish")
+	instance.discovery.Wait()
+
+	log.Info(logPrefix, "Waiting for service ", instance.id, " to finish")
+	<-instance.done
+	return instance.waitErr
+}
+
+// watch waits for the service process to exit and withdraws its proposal, so consumers are not sent to a dead service
+func (instance *Instance) watch() {
+	instance.waitErr = instance.service.Wait()
+	if instance.waitErr != nil {
+		log.Error(logPrefix, "Service ", instance.id, " exited with error: ", instance.waitErr)
+	}
+	if err := instance.stopAnnouncing(); err != nil {
+		log.Warn(logPrefix, "Failed to stop dialogs of exited service ", instance.id, ": ", err)
+	}
+	close(instance.done)
+}
+
+// stopAnnouncing stops discovery and dialog waiter of the service, only the first call takes effect
+func (instance *Instance) stopAnnouncing() error {
+	instance.unannounce.Do(func() {
+		if instance.discovery != nil {
+			instance.discovery.Stop()
+		}
+		if instance.dialogWaiter != nil {
+			instance.errUnannounce = instance.dialogWaiter.Stop()
+		}
+	})
+	return instance.errUnannounce
+}
+
+func (instance *Instance) stop() error {
+	var errService error
+
+	errDialogWaiter := instance.stopAnnouncing()
+	if instance.service != nil {
+		errService = instance.service.Stop()
+	}
+
+	if errDialogWaiter != nil {
+		return errDialogWaiter
+	}
+	return errService
 }
 
 // Start starts service - does not block
-func (manager *Manager) Start(options Options) (err error) {
+func (manager *Manager) Start(options Options) (id int, err error) {
 	loadIdentity := identity_selector.NewLoader(manager.identityHandler, options.Identity, options.Passphrase)
 	providerID, err := loadIdentity()
 	if err != nil {
-		return err
+		return 0, err
+	}
+
+	instance := &Instance{
+		options: options,
+		done:    make(chan struct{}),
 	}
+	instance.id, instance.order = manager.reserveID(providerID, options.Type)
+	defer func() {
+		if err != nil {
+			manager.releaseID(instance.id)
+			if errStop := instance.stop(); errStop != nil {
+				log.Warn(logPrefix, "Failed to stop partially started service: ", errStop)
+			}
+		}
+	}()
 
-	manager.service, err = manager.serviceFactory(options)
+	instance.service, err = manager.serviceFactory(options)
 	if err != nil {
-		return err
+		return 0, err
 	}
-	proposal, sessionConfigProvider, err := manager.service.Start(providerID)
+	proposal, sessionConfigProvider, err := instance.service.Start(providerID)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	manager.dialogWaiter = manager.dialogWaiterFactory(providerID)
-	providerContact, err := manager.dialogWaiter.Start()
+	instance.dialogWaiter = manager.dialogWaiterFactory(providerID, instance.id)
+	providerContact, err := instance.dialogWaiter.Start()
 	if err != nil {
-		return err
+		return 0, err
 	}
+	proposal.ID = instance.id
 	proposal.SetProviderContact(providerID, providerContact)
+	instance.proposal = proposal
 
-	dialogHandler := manager.dialogHandlerFactory(proposal, sessionConfigProvider)
-	if err = manager.dialogWaiter.ServeDialogs(dialogHandler); err != nil {
-		return err
+	dialogHandler := manager.dialogHandlerFactory(proposal, sessionConfigProvider, options.Admission)
+	if err = instance.dialogWaiter.ServeDialogs(dialogHandler); err != nil {
+		return 0, err
 	}
 
-	manager.discovery.Start(providerID, proposal)
-	return nil
+	instance.discovery = manager.discoveryFactory()
+	instance.discovery.Start(providerID, proposal)
+	go instance.watch()
+
+	manager.mutex.Lock()
+	manager.instances[instance.id] = instance
+	delete(manager.reservedIDs, instance.id)
+	manager.mutex.Unlock()
+
+	log.Info(logPrefix, "Service ", instance.id, " of type ", options.Type, " started")
+	return instance.id, nil
 }
 
-// Wait blocks until service is stopped
-func (manager *Manager) Wait() error {
-	log.Info(logPrefix, "Waiting for discovery service to finish")
-	manager.discovery.Wait()
+// Stop stops the service with given id
+func (manager *Manager) Stop(id int) error {
+	manager.mutex.Lock()
+	instance, found := manager.instances[id]
+	delete(manager.instances, id)
+	manager.mutex.Unlock()
 
-	log.Info(logPrefix, "Waiting for service to finish")
-	return manager.service.Wait()
+	if !found {
+		return ErrorServiceNotFound
+	}
+	return instance.stop()
 }
 
-// Kill stops service
-func (manager *Manager) Kill() error {
-	var errDialogWaiter, errService error
+// Get returns service with given id
+func (manager *Manager) Get(id int) (*Instance, bool) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	instance, found := manager.instances[id]
+	return instance, found
+}
+
+// List returns services running on the node in the order they were started
+func (manager *Manager) List() []*Instance {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	instances := make([]*Instance, 0, len(manager.instances))
+	for _, instance := range manager.instances {
+		instances = append(instances, instance)
+	}
+	sort.Slice(instances, func(i, j int) bool {
+		return instances[i].order < instances[j].order
+	})
+	return instances
+}
+
+// Wait blocks until all running services are stopped or any of them fails
+func (manager *Manager) Wait() error {
+	instances := manager.List()
 
-	if manager.discovery != nil {
-		manager.discovery.Stop()
+	errs := make(chan error, len(instances))
+	for _, instance := range instances {
+		go func(instance *Instance) { errs <- instance.Wait() }(instance)
 	}
-	if manager.dialogWaiter != nil {
-		errDialogWaiter = manager.dialogWaiter.Stop()
+	for range instances {
+		if err := <-errs; err != nil {
+			return err
+		}
 	}
-	if manager.service != nil {
-		errService = manager.service.Stop()
+	return nil
+}
+
+// Kill stops all services
+func (manager *Manager) Kill() error {
+	var firstErr error
+	for _, instance := range manager.List() {
+		if err := manager.Stop(instance.id); err != nil && firstErr == nil {
+			firstErr = err
+		}
 	}
+	return firstErr
+}
 
-	if errDialogWaiter != nil {
-		return errDialogWaiter
+// reserveID picks proposal ID for the service, which is derived from provider identity and service type.
+// Same services get same IDs after node restart, so proposals cached by consumers never point to a service of another type.
+func (manager *Manager) reserveID(providerID identity.Identity, serviceType string) (id, order int) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	for index := 0; ; index++ {
+		id = proposalID(providerID, serviceType, index)
+		if _, found := manager.instances[id]; !found && !manager.reservedIDs[id] {
+			break
+		}
 	}
-	if errService != nil {
-		return errService
+	manager.reservedIDs[id] = true
+	manager.lastOrder++
+	return id, manager.lastOrder
+}
+
+func (manager *Manager) releaseID(id int) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	delete(manager.reservedIDs, id)
+}
+
+// proposalID hashes service key into a positive non zero ID, which stays within int32 on every platform
+func proposalID(providerID identity.Identity, serviceType string, index int) int {
+	hash := fnv.New32a()
+	fmt.Fprintf(hash, "%s/%s/%d", providerID.Address, serviceType, index)
+	if id := int(hash.Sum32() & 0x7fffffff); id != 0 {
+		return id
 	}
-	return nil
+	return 1
 }
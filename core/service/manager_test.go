@@ -0,0 +1,236 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mysteriumnetwork/node/communication"
+	"github.com/mysteriumnetwork/node/discovery"
+	"github.com/mysteriumnetwork/node/identity"
+	dto_discovery "github.com/mysteriumnetwork/node/service_discovery/dto"
+	"github.com/mysteriumnetwork/node/session"
+	"github.com/stretchr/testify/assert"
+)
+
+type identityHandlerFake struct{}
+
+func (handler *identityHandlerFake) UseExisting(address, passphrase string) (identity.Identity, error) {
+	return identity.FromAddress(address), nil
+}
+
+func (handler *identityHandlerFake) UseLast(passphrase string) (identity.Identity, error) {
+	return identity.FromAddress("0x1"), nil
+}
+
+func (handler *identityHandlerFake) UseNew(passphrase string) (identity.Identity, error) {
+	return identity.FromAddress("0x1"), nil
+}
+
+type dialogWaiterFake struct {
+	serviceID int
+	stopped   bool
+}
+
+func (waiter *dialogWaiterFake) Start() (dto_discovery.Contact, error) {
+	return dto_discovery.Contact{Type: "fake", Definition: waiter.serviceID}, nil
+}
+
+func (waiter *dialogWaiterFake) Stop() error {
+	waiter.stopped = true
+	return nil
+}
+
+func (waiter *dialogWaiterFake) ServeDialogs(communication.DialogHandler) error {
+	return nil
+}
+
+type discoveryFake struct {
+	proposal dto_discovery.ServiceProposal
+	stopped  bool
+}
+
+func (fake *discoveryFake) Start(providerID identity.Identity, proposal dto_discovery.ServiceProposal) {
+	fake.proposal = proposal
+}
+
+func (fake *discoveryFake) Status() discovery.Status {
+	return discovery.PingProposal
+}
+
+func (fake *discoveryFake) Wait() {}
+
+func (fake *discoveryFake) Stop() {
+	fake.stopped = true
+}
+
+type managerTestContext struct {
+	manager       *Manager
+	dialogWaiters []*dialogWaiterFake
+	discoveries   []*discoveryFake
+	admissions    []session.AdmissionPolicy
+}
+
+func newManagerTestContext(serviceFactory ServiceFactory) *managerTestContext {
+	tc := &managerTestContext{}
+	tc.manager = NewManager(
+		&identityHandlerFake{},
+		serviceFactory,
+		func(providerID identity.Identity, serviceID int) communication.DialogWaiter {
+			waiter := &dialogWaiterFake{serviceID: serviceID}
+			tc.dialogWaiters = append(tc.dialogWaiters, waiter)
+			return waiter
+		},
+		func(proposal dto_discovery.ServiceProposal, configProvider session.ConfigProvider, admission session.AdmissionPolicy) communication.DialogHandler {
+			tc.admissions = append(tc.admissions, admission)
+			return nil
+		},
+		func() Discovery {
+			fake := &discoveryFake{}
+			tc.discoveries = append(tc.discoveries, fake)
+			return fake
+		},
+	)
+	return tc
+}
+
+func fakeServiceFactory(Options) (Service, error) {
+	return &serviceFake{}, nil
+}
+
+func TestManager_StartAssignsUniqueProposalIDs(t *testing.T) {
+	tc := newManagerTestContext(fakeServiceFactory)
+
+	firstID, err := tc.manager.Start(Options{Type: "fake"})
+	assert.NoError(t, err)
+	secondID, err := tc.manager.Start(Options{Type: "fake", Admission: session.AdmissionPolicy{MaxSessions: 1}})
+	assert.NoError(t, err)
+
+	assert.NotEqual(t, firstID, secondID)
+	assert.Len(t, tc.discoveries, 2)
+	assert.Equal(t, firstID, tc.discoveries[0].proposal.ID)
+	assert.Equal(t, secondID, tc.discoveries[1].proposal.ID)
+	assert.Equal(t, "0x1", tc.discoveries[1].proposal.ProviderID)
+	assert.Equal(
+		t,
+		dto_discovery.ContactList{{Type: "fake", Definition: secondID}},
+		tc.discoveries[1].proposal.ProviderContacts,
+	)
+	assert.Equal(t, []session.AdmissionPolicy{{}, {MaxSessions: 1}}, tc.admissions)
+
+	instances := tc.manager.List()
+	assert.Len(t, instances, 2)
+	assert.Equal(t, firstID, instances[0].ID())
+	assert.Equal(t, secondID, instances[1].ID())
+	assert.Equal(t, Running, instances[1].State())
+	assert.Equal(t, discovery.PingProposal, instances[1].DiscoveryStatus())
+}
+
+func TestManager_StartAssignsSameProposalIDsAfterRestart(t *testing.T) {
+	firstRun := newManagerTestContext(fakeServiceFactory)
+	openvpnID, _ := firstRun.manager.Start(Options{Type: "openvpn"})
+	wireguardID, _ := firstRun.manager.Start(Options{Type: "wireguard"})
+	assert.NotEqual(t, openvpnID, wireguardID)
+
+	secondRun := newManagerTestContext(fakeServiceFactory)
+	id, err := secondRun.manager.Start(Options{Type: "wireguard"})
+	assert.NoError(t, err)
+	assert.Equal(t, wireguardID, id)
+
+	id, err = secondRun.manager.Start(Options{Type: "openvpn"})
+	assert.NoError(t, err)
+	assert.Equal(t, openvpnID, id)
+}
+
+func TestManager_StartAssignsNewProposalIDAfterFailedStart(t *testing.T) {
+	tc := newManagerTestContext(func(options Options) (Service, error) {
+		if options.Admission.MaxSessions > 0 {
+			return &serviceFake{onStartReturnError: errors.New("port in use")}, nil
+		}
+		return &serviceFake{}, nil
+	})
+	_, err := tc.manager.Start(Options{Type: "fake", Admission: session.AdmissionPolicy{MaxSessions: 1}})
+	assert.Error(t, err)
+
+	id, err := tc.manager.Start(Options{Type: "fake"})
+	assert.NoError(t, err)
+	assert.Equal(t, proposalID(identity.FromAddress("0x1"), "fake", 0), id)
+}
+
+func TestManager_InstanceStateWhenServiceExits(t *testing.T) {
+	fakeService := &serviceFake{}
+	tc := newManagerTestContext(func(Options) (Service, error) {
+		return fakeService, nil
+	})
+	id, err := tc.manager.Start(Options{Type: "fake"})
+	assert.NoError(t, err)
+
+	fakeService.Stop()
+	instance, found := tc.manager.Get(id)
+	assert.True(t, found)
+	assert.NoError(t, instance.Wait())
+	assert.Equal(t, NotRunning, instance.State())
+	assert.True(t, tc.discoveries[0].stopped)
+	assert.True(t, tc.dialogWaiters[0].stopped)
+}
+
+func TestManager_StopStopsSingleService(t *testing.T) {
+	tc := newManagerTestContext(fakeServiceFactory)
+	firstID, _ := tc.manager.Start(Options{Type: "fake"})
+	secondID, _ := tc.manager.Start(Options{Type: "fake"})
+
+	assert.NoError(t, tc.manager.Stop(firstID))
+
+	assert.True(t, tc.discoveries[0].stopped)
+	assert.True(t, tc.dialogWaiters[0].stopped)
+	assert.False(t, tc.discoveries[1].stopped)
+	assert.False(t, tc.dialogWaiters[1].stopped)
+	_, found := tc.manager.Get(firstID)
+	assert.False(t, found)
+	_, found = tc.manager.Get(secondID)
+	assert.True(t, found)
+
+	assert.Exactly(t, ErrorServiceNotFound, tc.manager.Stop(firstID))
+}
+
+func TestManager_KillStopsAllServices(t *testing.T) {
+	tc := newManagerTestContext(fakeServiceFactory)
+	tc.manager.Start(Options{Type: "fake"})
+	tc.manager.Start(Options{Type: "fake"})
+
+	assert.NoError(t, tc.manager.Kill())
+
+	assert.True(t, tc.discoveries[0].stopped)
+	assert.True(t, tc.discoveries[1].stopped)
+	assert.Empty(t, tc.manager.List())
+	assert.NoError(t, tc.manager.Wait())
+}
+
+func TestManager_StartFailsWhenServiceFails(t *testing.T) {
+	startErr := errors.New("port in use")
+	tc := newManagerTestContext(func(Options) (Service, error) {
+		return &serviceFake{onStartReturnError: startErr}, nil
+	})
+
+	_, err := tc.manager.Start(Options{Type: "fake"})
+
+	assert.Exactly(t, startErr, err)
+	assert.Empty(t, tc.manager.List())
+	assert.Empty(t, tc.dialogWaiters)
+}
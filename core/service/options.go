@@ -17,12 +17,20 @@
 
 package service
 
+import (
+	"github.com/mysteriumnetwork/node/nat"
+	"github.com/mysteriumnetwork/node/session"
+)
+
 // Options describes options which are required to start a service
 type Options struct {
 	Identity   string
 	Passphrase string
 	Type       string
 	Options    TransportOptions
+	Admission  session.AdmissionPolicy
+	// TrafficPolicy restricts destinations, which consumers may reach through the service
+	TrafficPolicy nat.Policy
 }
 
 // TransportOptions represents any type of options for plugable service
@@ -17,15 +17,22 @@
 
 package service
 
+import "encoding/json"
+
+// OptionsParser unserializes transport options of the service from JSON
+type OptionsParser func(*json.RawMessage) (TransportOptions, error)
+
 // Registry holds all pluggable services
 type Registry struct {
-	factories map[string]ServiceFactory
+	factories     map[string]ServiceFactory
+	optionParsers map[string]OptionsParser
 }
 
 // NewRegistry creates a registry of pluggable services
 func NewRegistry() *Registry {
 	return &Registry{
-		factories: make(map[string]ServiceFactory),
+		factories:     make(map[string]ServiceFactory),
+		optionParsers: make(map[string]OptionsParser),
 	}
 }
 
@@ -43,3 +50,18 @@ func (registry *Registry) Create(options Options) (Service, error) {
 
 	return createService(options)
 }
+
+// RegisterOptionsParser registers JSON parser of the pluggable service options
+func (registry *Registry) RegisterOptionsParser(serviceType string, parser OptionsParser) {
+	registry.optionParsers[serviceType] = parser
+}
+
+// ParseOptions unserializes transport options of the pluggable service from JSON
+func (registry *Registry) ParseOptions(serviceType string, data *json.RawMessage) (TransportOptions, error) {
+	parse, exists := registry.optionParsers[serviceType]
+	if !exists {
+		return nil, ErrUnsupportedServiceType
+	}
+
+	return parse(data)
+}
@@ -18,6 +18,7 @@
 package service
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -67,3 +68,19 @@ func TestRegistry_Create_Existing(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, serviceMock, service)
 }
+
+func TestRegistry_ParseOptions(t *testing.T) {
+	registry := NewRegistry()
+	registry.RegisterOptionsParser("fake-service", func(data *json.RawMessage) (TransportOptions, error) {
+		return string(*data), nil
+	})
+
+	data := json.RawMessage(`{"port": 1}`)
+	options, err := registry.ParseOptions("fake-service", &data)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"port": 1}`, options)
+
+	options, err = registry.ParseOptions("other-service", &data)
+	assert.Equal(t, ErrUnsupportedServiceType, err)
+	assert.Nil(t, options)
+}
@@ -18,6 +18,8 @@
 package service
 
 import (
+	"sync"
+
 	"github.com/mysteriumnetwork/node/identity"
 	dto_discovery "github.com/mysteriumnetwork/node/service_discovery/dto"
 	"github.com/mysteriumnetwork/node/session"
@@ -27,16 +29,25 @@ var _ Service = &serviceFake{}
 
 type serviceFake struct {
 	onStartReturnError error
+	stopped            chan struct{}
+	stopOnce           sync.Once
 }
 
 func (service *serviceFake) Start(identity.Identity) (dto_discovery.ServiceProposal, session.ConfigProvider, error) {
+	service.stopped = make(chan struct{})
 	return dto_discovery.ServiceProposal{}, nil, service.onStartReturnError
 }
 
 func (service *serviceFake) Wait() error {
+	<-service.stopped
 	return nil
 }
 
 func (service *serviceFake) Stop() error {
+	service.stopOnce.Do(func() {
+		if service.stopped != nil {
+			close(service.stopped)
+		}
+	})
 	return nil
 }
@@ -44,7 +44,7 @@ func (b *bolt) Store(issuer string, data interface{}) error {
 	return b.db.From(issuer).Save(data)
 }
 
-// GetAll allows to get all promises by the issuer
+// GetAllFrom allows to get all promises by the issuer
 func (b *bolt) GetAllFrom(issuer string, data interface{}) error {
 	return b.db.From(issuer).All(data)
 }
@@ -69,6 +69,15 @@ func (b *bolt) GetAll(array interface{}) error {
 	return b.db.All(array)
 }
 
+// GetOneByField finds object, which field has given value, returns storage.ErrNotFound if there is no such object
+func (b *bolt) GetOneByField(fieldName string, key interface{}, to interface{}) error {
+	err := b.db.One(fieldName, key, to)
+	if err == storm.ErrNotFound {
+		return storage.ErrNotFound
+	}
+	return err
+}
+
 // Close closes database
 func (b *bolt) Close() error {
 	return b.db.Close()
@@ -17,6 +17,11 @@
 
 package storage
 
+import "errors"
+
+// ErrNotFound is returned when object looked up by its field is not stored
+var ErrNotFound = errors.New("object not found")
+
 // Storage stores persistent objects for future usage
 type Storage interface {
 	Store(issuer string, data interface{}) error
@@ -24,5 +29,7 @@ type Storage interface {
 	Save(object interface{}) error
 	Update(object interface{}) error
 	GetAll(array interface{}) error
+	GetOneByField(fieldName string, key interface{}, to interface{}) error
+	GetAllFrom(issuer string, array interface{}) error
 	Close() error
 }
@@ -35,5 +35,11 @@ func (fs *FakeStorage) Update(interface{}) error { return nil }
 // GetAll for testing
 func (fs *FakeStorage) GetAll(interface{}) error { return nil }
 
+// GetOneByField for testing
+func (fs *FakeStorage) GetOneByField(string, interface{}, interface{}) error { return ErrNotFound }
+
+// GetAllFrom for testing
+func (fs *FakeStorage) GetAllFrom(string, interface{}) error { return nil }
+
 // Close for testing
 func (fs *FakeStorage) Close() error { return nil }
@@ -44,6 +44,34 @@ const (
 
 const logPrefix = "[discovery] "
 
+// StatusEventTopic is the topic of events published when proposal registration status changes
+const StatusEventTopic = "discovery-status"
+
+// StatusEvent is the payload of proposal registration status change event
+type StatusEvent struct {
+	Status string `json:"status"`
+}
+
+var statusNames = map[Status]string{
+	IdentityUnregistered:     "IdentityUnregistered",
+	WaitingForRegistration:   "WaitingForRegistration",
+	IdentityRegisterFailed:   "IdentityRegisterFailed",
+	RegisterProposal:         "RegisterProposal",
+	PingProposal:             "PingProposal",
+	UnregisterProposal:       "UnregisterProposal",
+	UnregisterProposalFailed: "UnregisterProposalFailed",
+	ProposalUnregistered:     "ProposalUnregistered",
+	StatusUndefined:          "StatusUndefined",
+}
+
+// String returns human readable name of registration stage
+func (status Status) String() string {
+	if name, ok := statusNames[status]; ok {
+		return name
+	}
+	return statusNames[StatusUndefined]
+}
+
 // Start launches discovery service
 func (d *Discovery) Start(ownIdentity identity.Identity, proposal dto_discovery.ServiceProposal) {
 	d.RLock()
@@ -66,6 +94,14 @@ func (d *Discovery) Start(ownIdentity identity.Identity, proposal dto_discovery.
 	go d.mainDiscoveryLoop(stopLoop)
 }
 
+// Status returns current stage of proposal registration
+func (d *Discovery) Status() Status {
+	d.RLock()
+	defer d.RUnlock()
+
+	return d.status
+}
+
 // Wait wait for proposal announcements to stop / unregister
 func (d *Discovery) Wait() {
 	d.proposalAnnouncementStopped.Wait()
@@ -191,6 +227,9 @@ func (d *Discovery) changeStatus(status Status) {
 	d.Lock()
 	defer d.Unlock()
 
+	if d.status != status {
+		d.eventPublisher.Publish(StatusEventTopic, StatusEvent{Status: status.String()})
+	}
 	d.status = status
 
 	go func() {
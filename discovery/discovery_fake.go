@@ -20,6 +20,7 @@ package discovery
 import (
 	"sync"
 
+	"github.com/mysteriumnetwork/node/core/events"
 	"github.com/mysteriumnetwork/node/identity"
 	identity_registry "github.com/mysteriumnetwork/node/identity/registry"
 	"github.com/mysteriumnetwork/node/server"
@@ -35,5 +36,6 @@ func NewFakeDiscovery() *Discovery {
 		},
 		identityRegistration: &identity_registry.FakeRegistrationDataProvider{},
 		mysteriumClient:      server.NewClientFake(),
+		eventPublisher:       events.NewPublisherFake(),
 	}
 }
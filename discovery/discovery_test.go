@@ -21,6 +21,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/mysteriumnetwork/node/core/events"
 	"github.com/mysteriumnetwork/node/identity"
 	identity_registry "github.com/mysteriumnetwork/node/identity/registry"
 	"github.com/mysteriumnetwork/node/service_discovery/dto"
@@ -84,6 +85,23 @@ func TestStartStopUnregisterProposal(t *testing.T) {
 	assert.Equal(t, ProposalUnregistered, actualStatus)
 }
 
+func TestStatusChangesArePublished(t *testing.T) {
+	d := NewFakeDiscovery()
+	d.identityRegistry = &identity_registry.FakeRegistry{RegistrationEventExists: false, Registered: true}
+
+	d.Start(providerID, proposal)
+	observeStatus(d, PingProposal)
+
+	assert.Equal(
+		t,
+		[]events.Event{
+			{Topic: StatusEventTopic, Payload: StatusEvent{Status: "RegisterProposal"}},
+			{Topic: StatusEventTopic, Payload: StatusEvent{Status: "PingProposal"}},
+		},
+		d.eventPublisher.(*events.PublisherFake).Published(),
+	)
+}
+
 func observeStatus(d *Discovery, status Status) Status {
 	for {
 		d.RLock()
@@ -20,6 +20,7 @@ package discovery
 import (
 	"sync"
 
+	"github.com/mysteriumnetwork/node/core/events"
 	"github.com/mysteriumnetwork/node/identity"
 	identity_registry "github.com/mysteriumnetwork/node/identity/registry"
 	"github.com/mysteriumnetwork/node/server"
@@ -40,6 +41,7 @@ type Discovery struct {
 	proposalAnnouncementStopped *sync.WaitGroup
 	unsubscribe                 func()
 	stop                        func()
+	eventPublisher              events.Publisher
 
 	sync.RWMutex
 }
@@ -50,6 +52,7 @@ func NewService(
 	identityRegistration identity_registry.RegistrationDataProvider,
 	mysteriumClient server.Client,
 	signerCreate identity.SignerFactory,
+	eventPublisher events.Publisher,
 ) *Discovery {
 	return &Discovery{
 		identityRegistry:     identityRegistry,
@@ -61,6 +64,7 @@ func NewService(
 		proposalAnnouncementStopped: &sync.WaitGroup{},
 		unsubscribe:                 func() {},
 		stop:                        func() {},
+		eventPublisher:              eventPublisher,
 		RWMutex:                     sync.RWMutex{},
 	}
 }
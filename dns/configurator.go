@@ -0,0 +1,146 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package dns
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+
+	log "github.com/cihub/seelog"
+)
+
+const logPrefix = "[dns] "
+
+// stateFileName keeps devices, which DNS was applied on, in runtime directory
+const stateFileName = "dns-devices"
+
+// ErrUnsupported is returned when there is no known way to configure DNS of the system
+var ErrUnsupported = errors.New("DNS configuration of the system is not supported")
+
+// backend changes resolvers of the system with particular tool
+type backend interface {
+	name() string
+	apply(device string, servers []string) error
+	restore(device string) error
+}
+
+// configurator remembers devices, which resolvers were applied on, so they are restored after unclean shutdown
+type configurator struct {
+	backend   backend
+	stateFile string
+	lock      sync.Mutex
+}
+
+func (c *configurator) Apply(device string, servers []string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.backend == nil {
+		return ErrUnsupported
+	}
+	if err := c.writeDevices(append(c.readDevices(), device)); err != nil {
+		log.Warn(logPrefix, "Failed to remember DNS configuration: ", err)
+	}
+	if err := c.backend.apply(device, servers); err != nil {
+		return err
+	}
+
+	log.Info(logPrefix, "System DNS is served by ", strings.Join(servers, ", "), " through ", device, " (", c.backend.name(), ")")
+	return nil
+}
+
+func (c *configurator) Restore(device string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.backend == nil {
+		return nil
+	}
+	err := c.backend.restore(device)
+
+	devices := make([]string, 0)
+	for _, remembered := range c.readDevices() {
+		if remembered != device {
+			devices = append(devices, remembered)
+		}
+	}
+	if err := c.writeDevices(devices); err != nil {
+		log.Warn(logPrefix, "Failed to forget DNS configuration: ", err)
+	}
+
+	if err == nil {
+		log.Info(logPrefix, "System DNS restored")
+	}
+	return err
+}
+
+func (c *configurator) Clean() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	devices := c.readDevices()
+	if c.backend == nil || len(devices) == 0 {
+		return nil
+	}
+
+	for _, device := range devices {
+		// configuration disappears together with device for some backends
+		if err := c.backend.restore(device); err != nil {
+			log.Debug(logPrefix, "Failed to restore DNS of ", device, ": ", err)
+		}
+	}
+	log.Info(logPrefix, "System DNS left by previous connection restored")
+	return c.writeDevices(nil)
+}
+
+func (c *configurator) readDevices() []string {
+	content, err := ioutil.ReadFile(c.stateFile)
+	if err != nil {
+		return nil
+	}
+	return strings.Fields(string(content))
+}
+
+func (c *configurator) writeDevices(devices []string) error {
+	if len(devices) == 0 {
+		if err := os.Remove(c.stateFile); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	unique := make([]string, 0, len(devices))
+	for _, device := range devices {
+		if !contains(unique, device) {
+			unique = append(unique, device)
+		}
+	}
+	return ioutil.WriteFile(c.stateFile, []byte(strings.Join(unique, "\n")+"\n"), 0600)
+}
+
+func contains(values []string, value string) bool {
+	for _, listed := range values {
+		if listed == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package dns
+
+import (
+	"errors"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// NewConfigurator returns linux DNS configurator, based on systemd-resolved or resolvconf, whichever is detected
+func NewConfigurator(runtimeDir string) Configurator {
+	return &configurator{
+		backend:   detectBackend(sudoExecutor),
+		stateFile: filepath.Join(runtimeDir, stateFileName),
+	}
+}
+
+func detectBackend(execute executor) backend {
+	if _, err := exec.LookPath("resolvectl"); err == nil && usesSystemdResolved("/etc/resolv.conf") {
+		return &resolvedBackend{execute: execute}
+	}
+	if _, err := exec.LookPath("resolvconf"); err == nil {
+		return &resolvconfBackend{execute: execute}
+	}
+	return nil
+}
+
+// usesSystemdResolved tells if resolv.conf is managed by systemd-resolved, otherwise its link settings have no effect
+func usesSystemdResolved(resolvConf string) bool {
+	if target, err := filepath.EvalSymlinks(resolvConf); err == nil && strings.Contains(target, "systemd/resolve") {
+		return true
+	}
+	content, err := ioutil.ReadFile(resolvConf)
+	return err == nil && strings.Contains(string(content), "nameserver 127.0.0.53")
+}
+
+type executor func(input string, arguments ...string) error
+
+func sudoExecutor(input string, arguments ...string) error {
+	cmd := exec.Command("sudo", arguments...)
+	cmd.Stdin = strings.NewReader(input)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return errors.New(strings.Join(arguments, " ") + " failed: " + err.Error() + " " + strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// resolvedBackend makes tunnel device the default route of systemd-resolved queries
+type resolvedBackend struct {
+	execute executor
+}
+
+func (b *resolvedBackend) name() string {
+	return "systemd-resolved"
+}
+
+func (b *resolvedBackend) apply(device string, servers []string) error {
+	if err := b.execute("", append([]string{"resolvectl", "dns", device}, servers...)...); err != nil {
+		return err
+	}
+	// routing domain "~." sends queries of all domains through the tunnel
+	return b.execute("", "resolvectl", "domain", device, "~.")
+}
+
+func (b *resolvedBackend) restore(device string) error {
+	return b.execute("", "resolvectl", "revert", device)
+}
+
+// resolvconfBackend adds resolvers as record of tunnel device, which is ordered before records of other devices
+type resolvconfBackend struct {
+	execute executor
+}
+
+func (b *resolvconfBackend) name() string {
+	return "resolvconf"
+}
+
+func (b *resolvconfBackend) apply(device string, servers []string) error {
+	var records strings.Builder
+	for _, server := range servers {
+		records.WriteString("nameserver " + server + "\n")
+	}
+	return b.execute(records.String(), "resolvconf", "-a", resolvconfRecord(device))
+}
+
+func (b *resolvconfBackend) restore(device string) error {
+	return b.execute("", "resolvconf", "-d", resolvconfRecord(device), "-f")
+}
+
+func resolvconfRecord(device string) string {
+	return device + ".mysterium"
+}
@@ -0,0 +1,88 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package dns
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type command struct {
+	input     string
+	arguments string
+}
+
+func recordingExecutor(commands *[]command) executor {
+	return func(input string, arguments ...string) error {
+		*commands = append(*commands, command{input, strings.Join(arguments, " ")})
+		return nil
+	}
+}
+
+func TestResolvedBackend(t *testing.T) {
+	var commands []command
+	backend := &resolvedBackend{execute: recordingExecutor(&commands)}
+
+	assert.NoError(t, backend.apply("tun0", []string{"1.1.1.1", "8.8.8.8"}))
+	assert.NoError(t, backend.restore("tun0"))
+	assert.Equal(
+		t,
+		[]command{
+			{"", "resolvectl dns tun0 1.1.1.1 8.8.8.8"},
+			{"", "resolvectl domain tun0 ~."},
+			{"", "resolvectl revert tun0"},
+		},
+		commands,
+	)
+}
+
+func TestResolvconfBackend(t *testing.T) {
+	var commands []command
+	backend := &resolvconfBackend{execute: recordingExecutor(&commands)}
+
+	assert.NoError(t, backend.apply("tun0", []string{"1.1.1.1", "8.8.8.8"}))
+	assert.NoError(t, backend.restore("tun0"))
+	assert.Equal(
+		t,
+		[]command{
+			{"nameserver 1.1.1.1\nnameserver 8.8.8.8\n", "resolvconf -a tun0.mysterium"},
+			{"", "resolvconf -d tun0.mysterium -f"},
+		},
+		commands,
+	)
+}
+
+func TestUsesSystemdResolved(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dns")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	stub := filepath.Join(dir, "stub-resolv.conf")
+	assert.NoError(t, ioutil.WriteFile(stub, []byte("nameserver 127.0.0.53\noptions edns0\n"), 0600))
+	static := filepath.Join(dir, "resolv.conf")
+	assert.NoError(t, ioutil.WriteFile(static, []byte("nameserver 192.168.1.1\n"), 0600))
+
+	assert.True(t, usesSystemdResolved(stub))
+	assert.False(t, usesSystemdResolved(static))
+	assert.False(t, usesSystemdResolved(filepath.Join(dir, "missing")))
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package dns
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type backendFake struct {
+	applied    map[string][]string
+	restored   []string
+	restoreErr error
+}
+
+func (b *backendFake) name() string {
+	return "fake"
+}
+
+func (b *backendFake) apply(device string, servers []string) error {
+	b.applied[device] = servers
+	return nil
+}
+
+func (b *backendFake) restore(device string) error {
+	b.restored = append(b.restored, device)
+	return b.restoreErr
+}
+
+func newTestConfigurator(t *testing.T) (*configurator, *backendFake, func()) {
+	dir, err := ioutil.TempDir("", "dns")
+	assert.NoError(t, err)
+
+	backend := &backendFake{applied: make(map[string][]string)}
+	return &configurator{backend: backend, stateFile: filepath.Join(dir, stateFileName)}, backend, func() { os.RemoveAll(dir) }
+}
+
+func TestConfigurator_ApplyAndRestore(t *testing.T) {
+	configurator, backend, cleanup := newTestConfigurator(t)
+	defer cleanup()
+
+	assert.NoError(t, configurator.Apply("tun0", []string{"1.1.1.1"}))
+	assert.Equal(t, []string{"1.1.1.1"}, backend.applied["tun0"])
+	assert.Equal(t, []string{"tun0"}, configurator.readDevices())
+
+	assert.NoError(t, configurator.Restore("tun0"))
+	assert.Equal(t, []string{"tun0"}, backend.restored)
+	assert.Empty(t, configurator.readDevices())
+	_, err := os.Stat(configurator.stateFile)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestConfigurator_CleanRestoresDevicesLeftByCrash(t *testing.T) {
+	configurator, backend, cleanup := newTestConfigurator(t)
+	defer cleanup()
+
+	assert.NoError(t, configurator.Apply("tun0", []string{"1.1.1.1"}))
+	assert.NoError(t, configurator.Apply("tun1", []string{"1.1.1.1"}))
+	assert.NoError(t, configurator.Apply("tun0", []string{"8.8.8.8"}))
+	assert.Equal(t, []string{"tun0", "tun1"}, configurator.readDevices())
+
+	backend.restoreErr = errors.New("link not found")
+	assert.NoError(t, configurator.Clean())
+	assert.Equal(t, []string{"tun0", "tun1"}, backend.restored)
+	assert.Empty(t, configurator.readDevices())
+
+	assert.NoError(t, configurator.Clean())
+	assert.Len(t, backend.restored, 2)
+}
+
+func TestConfigurator_WithoutBackend(t *testing.T) {
+	configurator := &configurator{}
+
+	assert.Equal(t, ErrUnsupported, configurator.Apply("tun0", []string{"1.1.1.1"}))
+	assert.NoError(t, configurator.Restore("tun0"))
+	assert.NoError(t, configurator.Clean())
+}
@@ -0,0 +1,28 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package dns
+
+// Configurator takes over DNS of the system for the duration of VPN connection
+type Configurator interface {
+	// Apply makes given servers the resolvers of the system, queries are sent through given tunnel device
+	Apply(device string, servers []string) error
+	// Restore returns resolvers of the system, which were in use before Apply on given device
+	Restore(device string) error
+	// Clean restores resolvers left by connections, which were not stopped gracefully
+	Clean() error
+}
@@ -114,7 +114,12 @@ func consumerConnectFlow(t *testing.T, tequilapi *tequilapi_client.Client, consu
 	})
 	assert.NoError(t, err)
 
-	_, err = tequilapi.Connect(consumerID, proposal.ProviderID, endpoints.ConnectOptions{true})
+	_, err = tequilapi.Connect(
+		consumerID,
+		proposal.ProviderID,
+		tequilapi_client.ProposalSelector{ProposalID: proposal.ID},
+		endpoints.ConnectOptions{DisableKillSwitch: true},
+	)
 	assert.NoError(t, err)
 
 	err = waitForCondition(func() (bool, error) {
@@ -17,7 +17,13 @@
 
 package firewall
 
-// NewKillSwitch returns mocked kill switch service
+// NewKillSwitch returns linux os specific kill switch service based on ip tables
 func NewKillSwitch() KillSwitch {
-	return &iptablesKillSwitch{}
+	killSwitch := &iptablesKillSwitch{
+		execute: sudoIptables,
+	}
+	if ipv6Supported() {
+		killSwitch.execute6 = sudoIp6tables
+	}
+	return killSwitch
 }
@@ -19,6 +19,15 @@ package firewall
 
 // KillSwitch enables fw rules restricting all communication except via VPN
 type KillSwitch interface {
-	Enable()
-	Disable()
+	// Enable blocks all outgoing traffic except traffic to given servers and through tunnel interfaces
+	Enable(servers ...ServerAddress) error
+	// Disable removes kill switch rules, including ones left after unclean shutdown
+	Disable() error
+}
+
+// ServerAddress describes server (e.g. VPN server or provider's broker) which stays reachable while kill switch is enabled
+type ServerAddress struct {
+	IP       string
+	Port     int
+	Protocol string
 }
@@ -21,9 +21,11 @@ type fakeKillSwitch struct {
 }
 
 // Enable enables kill switch mock
-func (ks *fakeKillSwitch) Enable() {
+func (ks *fakeKillSwitch) Enable(servers ...ServerAddress) error {
+	return nil
 }
 
-// Enable disables kill switch mock
-func (ks *fakeKillSwitch) Disable() {
+// Disable disables kill switch mock
+func (ks *fakeKillSwitch) Disable() error {
+	return nil
 }
@@ -17,13 +17,165 @@
 
 package firewall
 
+import (
+	"errors"
+	"net"
+	"os"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+
+	log "github.com/cihub/seelog"
+)
+
+const (
+	killSwitchLogPrefix = "[kill-switch] "
+	// killSwitchChain is a dedicated chain owned by node, it can be inspected with `iptables -L MYST_KILL_SWITCH`
+	killSwitchChain = "MYST_KILL_SWITCH"
+	// killSwitchNewChain holds rules being built, it replaces killSwitchChain once complete
+	killSwitchNewChain = "MYST_KILL_SWITCH_NEW"
+	// tunnelInterfaces matches all tun devices which VPN clients create
+	tunnelInterfaces = "tun+"
+	// wireguardInterfaces matches all WireGuard devices which node creates
+	wireguardInterfaces = "mystwg+"
+)
+
+// iptablesExecutor runs single iptables command with given arguments
+type iptablesExecutor func(arguments ...string) error
+
 type iptablesKillSwitch struct {
+	execute iptablesExecutor
+	// execute6 runs ip6tables commands, it is nil if host has no IPv6 support
+	execute6 iptablesExecutor
+}
+
+// Enable creates kill switch chain which drops all outgoing traffic except to given servers and through tunnel.
+// Rules are replaced atomically, so traffic is not leaked while previously enabled kill switch is updated.
+// IPv6 traffic is restricted the same way, tunnels do not route it, so only IPv6 servers are let through.
+func (ks *iptablesKillSwitch) Enable(servers ...ServerAddress) error {
+	var servers4, servers6 []ServerAddress
+	destinations := make([]string, 0, len(servers))
+	for _, server := range servers {
+		if ip := net.ParseIP(server.IP); ip != nil && ip.To4() == nil {
+			servers6 = append(servers6, server)
+		} else {
+			servers4 = append(servers4, server)
+		}
+		destinations = append(destinations, net.JoinHostPort(server.IP, strconv.Itoa(server.Port)))
+	}
+
+	if err := replaceChain(ks.execute, servers4); err != nil {
+		log.Error(killSwitchLogPrefix, "Failed to enable kill switch: ", err)
+		return err
+	}
+	if ks.execute6 != nil {
+		if err := replaceChain(ks.execute6, servers6); err != nil {
+			log.Error(killSwitchLogPrefix, "Failed to enable IPv6 kill switch: ", err)
+			return err
+		}
+	}
+
+	log.Info(killSwitchLogPrefix, "Outgoing traffic restricted to servers ", strings.Join(destinations, ", "), " and tunnel interfaces")
+	return nil
+}
+
+// Disable removes kill switch chains, does nothing if chains do not exist
+func (ks *iptablesKillSwitch) Disable() error {
+	executors := []iptablesExecutor{ks.execute}
+	if ks.execute6 != nil {
+		executors = append(executors, ks.execute6)
+	}
+	for _, execute := range executors {
+		if err := removeChain(execute, killSwitchNewChain); err != nil {
+			return err
+		}
+		if err := removeChain(execute, killSwitchChain); err != nil {
+			return err
+		}
+	}
+
+	log.Info(killSwitchLogPrefix, "Kill switch disabled")
+	return nil
+}
+
+// replaceChain builds kill switch rules in a new chain and swaps it with the current one.
+// Current rules stay in effect if building fails.
+func replaceChain(execute iptablesExecutor, servers []ServerAddress) error {
+	// new chain is left over if previous enabling failed half way
+	if err := removeChain(execute, killSwitchNewChain); err != nil {
+		return err
+	}
+
+	rules := [][]string{
+		{"--new-chain", killSwitchNewChain},
+		{"--append", killSwitchNewChain, "--out-interface", "lo", "--jump", "RETURN"},
+		{"--append", killSwitchNewChain, "--out-interface", tunnelInterfaces, "--jump", "RETURN"},
+		{"--append", killSwitchNewChain, "--out-interface", wireguardInterfaces, "--jump", "RETURN"},
+	}
+	for _, server := range servers {
+		rules = append(rules, []string{
+			"--append", killSwitchNewChain,
+			"--destination", server.IP,
+			"--protocol", server.Protocol,
+			"--destination-port", strconv.Itoa(server.Port),
+			"--jump", "RETURN",
+		})
+	}
+	rules = append(rules,
+		[]string{"--append", killSwitchNewChain, "--jump", "DROP"},
+		[]string{"--insert", "OUTPUT", "--jump", killSwitchNewChain},
+	)
+	for _, rule := range rules {
+		if err := execute(rule...); err != nil {
+			if errRemove := removeChain(execute, killSwitchNewChain); errRemove != nil {
+				log.Error(killSwitchLogPrefix, "Failed to rollback kill switch rules: ", errRemove)
+			}
+			return err
+		}
+	}
+
+	// traffic is already restricted by the new chain, so the old one can be removed
+	if err := removeChain(execute, killSwitchChain); err != nil {
+		return err
+	}
+	return execute("--rename-chain", killSwitchNewChain, killSwitchChain)
+}
+
+// removeChain removes the chain together with jump to it, does nothing if chain does not exist
+func removeChain(execute iptablesExecutor, chain string) error {
+	if err := execute("--numeric", "--list", chain); err != nil {
+		return nil
+	}
+
+	// jump may be missing if previous enabling failed half way
+	if err := execute("--delete", "OUTPUT", "--jump", chain); err != nil {
+		log.Warn(killSwitchLogPrefix, "Failed to remove kill switch jump: ", err)
+	}
+	if err := execute("--flush", chain); err != nil {
+		return err
+	}
+	return execute("--delete-chain", chain)
+}
+
+func sudoIptables(arguments ...string) error {
+	return sudoExecute("/sbin/iptables", arguments...)
+}
+
+func sudoIp6tables(arguments ...string) error {
+	return sudoExecute("/sbin/ip6tables", arguments...)
 }
 
-// Enable enables kill switch mock
-func (ks *iptablesKillSwitch) Enable() {
+func sudoExecute(command string, arguments ...string) error {
+	cmd := exec.Command("sudo", append([]string{command}, arguments...)...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return errors.New(path.Base(command) + " " + strings.Join(arguments, " ") + " failed: " + err.Error() + " " + strings.TrimSpace(string(output)))
+	}
+	return nil
 }
 
-// Enable disables kill switch mock
-func (ks *iptablesKillSwitch) Disable() {
+// ipv6Supported checks if kernel has IPv6 enabled, kill switch has to restrict IPv6 traffic then
+func ipv6Supported() bool {
+	_, err := os.Stat("/proc/net/if_inet6")
+	return err == nil
 }
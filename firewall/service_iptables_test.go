@@ -0,0 +1,198 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package firewall
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeIptables struct {
+	commands []string
+	chains   []string
+	failOn   string
+}
+
+func (fi *fakeIptables) execute(arguments ...string) error {
+	command := strings.Join(arguments, " ")
+	fi.commands = append(fi.commands, command)
+
+	if strings.HasPrefix(command, "--numeric --list ") && !fi.hasChain(arguments[2]) {
+		return errors.New("no chain/target/match by that name")
+	}
+	if fi.failOn != "" && strings.HasPrefix(command, fi.failOn) {
+		return errors.New("iptables failed")
+	}
+	return nil
+}
+
+func (fi *fakeIptables) hasChain(chain string) bool {
+	for _, existing := range fi.chains {
+		if existing == chain {
+			return true
+		}
+	}
+	return false
+}
+
+var server = ServerAddress{IP: "1.2.3.4", Port: 1194, Protocol: "udp"}
+
+func TestIptablesKillSwitch_Enable(t *testing.T) {
+	iptables := &fakeIptables{}
+	killSwitch := &iptablesKillSwitch{execute: iptables.execute}
+
+	assert.NoError(t, killSwitch.Enable(server))
+	assert.Equal(
+		t,
+		[]string{
+			"--numeric --list MYST_KILL_SWITCH_NEW",
+			"--new-chain MYST_KILL_SWITCH_NEW",
+			"--append MYST_KILL_SWITCH_NEW --out-interface lo --jump RETURN",
+			"--append MYST_KILL_SWITCH_NEW --out-interface tun+ --jump RETURN",
+			"--append MYST_KILL_SWITCH_NEW --out-interface mystwg+ --jump RETURN",
+			"--append MYST_KILL_SWITCH_NEW --destination 1.2.3.4 --protocol udp --destination-port 1194 --jump RETURN",
+			"--append MYST_KILL_SWITCH_NEW --jump DROP",
+			"--insert OUTPUT --jump MYST_KILL_SWITCH_NEW",
+			"--numeric --list MYST_KILL_SWITCH",
+			"--rename-chain MYST_KILL_SWITCH_NEW MYST_KILL_SWITCH",
+		},
+		iptables.commands,
+	)
+}
+
+func TestIptablesKillSwitch_EnableAllowsEveryServer(t *testing.T) {
+	iptables := &fakeIptables{}
+	killSwitch := &iptablesKillSwitch{execute: iptables.execute}
+	broker := ServerAddress{IP: "5.6.7.8", Port: 4222, Protocol: "tcp"}
+
+	assert.NoError(t, killSwitch.Enable(server, broker))
+	assert.Equal(
+		t,
+		[]string{
+			"--append MYST_KILL_SWITCH_NEW --destination 1.2.3.4 --protocol udp --destination-port 1194 --jump RETURN",
+			"--append MYST_KILL_SWITCH_NEW --destination 5.6.7.8 --protocol tcp --destination-port 4222 --jump RETURN",
+			"--append MYST_KILL_SWITCH_NEW --jump DROP",
+			"--insert OUTPUT --jump MYST_KILL_SWITCH_NEW",
+		},
+		iptables.commands[5:9],
+	)
+}
+
+func TestIptablesKillSwitch_EnableReplacesEnabledChainAfterNewOneIsInEffect(t *testing.T) {
+	iptables := &fakeIptables{chains: []string{killSwitchChain}}
+	killSwitch := &iptablesKillSwitch{execute: iptables.execute}
+
+	assert.NoError(t, killSwitch.Enable(server))
+	assert.Equal(
+		t,
+		[]string{
+			"--append MYST_KILL_SWITCH_NEW --jump DROP",
+			"--insert OUTPUT --jump MYST_KILL_SWITCH_NEW",
+			"--numeric --list MYST_KILL_SWITCH",
+			"--delete OUTPUT --jump MYST_KILL_SWITCH",
+			"--flush MYST_KILL_SWITCH",
+			"--delete-chain MYST_KILL_SWITCH",
+			"--rename-chain MYST_KILL_SWITCH_NEW MYST_KILL_SWITCH",
+		},
+		iptables.commands[6:],
+	)
+}
+
+func TestIptablesKillSwitch_EnableRemovesStaleNewChain(t *testing.T) {
+	iptables := &fakeIptables{chains: []string{killSwitchNewChain}}
+	killSwitch := &iptablesKillSwitch{execute: iptables.execute}
+
+	assert.NoError(t, killSwitch.Enable(server))
+	assert.Equal(
+		t,
+		[]string{
+			"--numeric --list MYST_KILL_SWITCH_NEW",
+			"--delete OUTPUT --jump MYST_KILL_SWITCH_NEW",
+			"--flush MYST_KILL_SWITCH_NEW",
+			"--delete-chain MYST_KILL_SWITCH_NEW",
+			"--new-chain MYST_KILL_SWITCH_NEW",
+		},
+		iptables.commands[:5],
+	)
+}
+
+func TestIptablesKillSwitch_EnableFailureKeepsEnabledChain(t *testing.T) {
+	iptables := &fakeIptables{chains: []string{killSwitchChain}, failOn: "--append MYST_KILL_SWITCH_NEW --jump DROP"}
+	killSwitch := &iptablesKillSwitch{execute: iptables.execute}
+
+	assert.EqualError(t, killSwitch.Enable(server), "iptables failed")
+	for _, command := range iptables.commands {
+		assert.NotEqual(t, "--delete OUTPUT --jump MYST_KILL_SWITCH", command)
+		assert.NotEqual(t, "--delete-chain MYST_KILL_SWITCH", command)
+	}
+}
+
+func TestIptablesKillSwitch_EnableRestrictsIPv6(t *testing.T) {
+	iptables := &fakeIptables{}
+	ip6tables := &fakeIptables{}
+	killSwitch := &iptablesKillSwitch{execute: iptables.execute, execute6: ip6tables.execute}
+	server6 := ServerAddress{IP: "2001:db8::1", Port: 4222, Protocol: "tcp"}
+
+	assert.NoError(t, killSwitch.Enable(server, server6))
+	assert.Contains(t, iptables.commands, "--append MYST_KILL_SWITCH_NEW --destination 1.2.3.4 --protocol udp --destination-port 1194 --jump RETURN")
+	assert.NotContains(t, iptables.commands, "--append MYST_KILL_SWITCH_NEW --destination 2001:db8::1 --protocol tcp --destination-port 4222 --jump RETURN")
+	assert.Equal(
+		t,
+		[]string{
+			"--numeric --list MYST_KILL_SWITCH_NEW",
+			"--new-chain MYST_KILL_SWITCH_NEW",
+			"--append MYST_KILL_SWITCH_NEW --out-interface lo --jump RETURN",
+			"--append MYST_KILL_SWITCH_NEW --out-interface tun+ --jump RETURN",
+			"--append MYST_KILL_SWITCH_NEW --out-interface mystwg+ --jump RETURN",
+			"--append MYST_KILL_SWITCH_NEW --destination 2001:db8::1 --protocol tcp --destination-port 4222 --jump RETURN",
+			"--append MYST_KILL_SWITCH_NEW --jump DROP",
+			"--insert OUTPUT --jump MYST_KILL_SWITCH_NEW",
+			"--numeric --list MYST_KILL_SWITCH",
+			"--rename-chain MYST_KILL_SWITCH_NEW MYST_KILL_SWITCH",
+		},
+		ip6tables.commands,
+	)
+}
+
+func TestIptablesKillSwitch_DisableWithoutChain(t *testing.T) {
+	iptables := &fakeIptables{}
+	killSwitch := &iptablesKillSwitch{execute: iptables.execute}
+
+	assert.NoError(t, killSwitch.Disable())
+	assert.Equal(t, []string{"--numeric --list MYST_KILL_SWITCH_NEW", "--numeric --list MYST_KILL_SWITCH"}, iptables.commands)
+}
+
+func TestIptablesKillSwitch_Disable(t *testing.T) {
+	iptables := &fakeIptables{chains: []string{killSwitchChain}}
+	ip6tables := &fakeIptables{chains: []string{killSwitchChain}}
+	killSwitch := &iptablesKillSwitch{execute: iptables.execute, execute6: ip6tables.execute}
+
+	assert.NoError(t, killSwitch.Disable())
+	expected := []string{
+		"--numeric --list MYST_KILL_SWITCH_NEW",
+		"--numeric --list MYST_KILL_SWITCH",
+		"--delete OUTPUT --jump MYST_KILL_SWITCH",
+		"--flush MYST_KILL_SWITCH",
+		"--delete-chain MYST_KILL_SWITCH",
+	}
+	assert.Equal(t, expected, iptables.commands)
+	assert.Equal(t, expected, ip6tables.commands)
+}
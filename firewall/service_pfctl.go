@@ -21,9 +21,11 @@ type pfCtlKillSwitch struct {
 }
 
 // Enable enables kill switch mock
-func (ks *pfCtlKillSwitch) Enable() {
+func (ks *pfCtlKillSwitch) Enable(servers ...ServerAddress) error {
+	return nil
 }
 
-// Enable disables kill switch mock
-func (ks *pfCtlKillSwitch) Disable() {
+// Disable disables kill switch mock
+func (ks *pfCtlKillSwitch) Disable() error {
+	return nil
 }
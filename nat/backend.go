@@ -0,0 +1,39 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package nat
+
+// backend manages NAT rules in packet filtering framework, within chain or table dedicated to node
+type backend interface {
+	// setup creates dedicated chain or table
+	setup() error
+	// apply replaces all rules in dedicated chain or table
+	apply(rules []RuleForwarding) error
+	// teardown removes dedicated chain or table together with its rules, does nothing if it does not exist
+	teardown() error
+}
+
+func newBackend(name string, execute commandExecutor) (backend, error) {
+	switch name {
+	case BackendIPTables:
+		return &backendIPTables{execute: execute}, nil
+	case BackendNFTables:
+		return &backendNFTables{execute: execute}, nil
+	default:
+		return nil, ErrUnsupportedBackend
+	}
+}
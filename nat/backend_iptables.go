@@ -0,0 +1,136 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package nat
+
+import (
+	"strconv"
+
+	log "github.com/cihub/seelog"
+)
+
+const iptablesBinary = "/sbin/iptables"
+
+// iptablesChain is a dedicated chain owned by node, which is jumped to from builtin chain
+type iptablesChain struct {
+	table   string
+	name    string
+	builtin string
+}
+
+var (
+	// iptablesNATChain can be inspected with `iptables -t nat -L MYST_NAT`
+	iptablesNATChain = iptablesChain{table: "nat", name: "MYST_NAT", builtin: "POSTROUTING"}
+	// iptablesFilterChain can be inspected with `iptables -L MYST_FORWARD`
+	iptablesFilterChain = iptablesChain{table: "filter", name: "MYST_FORWARD", builtin: "FORWARD"}
+)
+
+type backendIPTables struct {
+	execute commandExecutor
+}
+
+func (b *backendIPTables) setup() error {
+	for _, chain := range []iptablesChain{iptablesNATChain, iptablesFilterChain} {
+		if err := b.iptables(chain.table, "--new-chain", chain.name); err != nil {
+			return err
+		}
+		if err := b.iptables(chain.table, "--insert", chain.builtin, "--jump", chain.name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *backendIPTables) apply(rules []RuleForwarding) error {
+	if err := b.iptables(iptablesNATChain.table, "--flush", iptablesNATChain.name); err != nil {
+		return err
+	}
+	if err := b.iptables(iptablesFilterChain.table, "--flush", iptablesFilterChain.name); err != nil {
+		return err
+	}
+
+	for _, rule := range rules {
+		err := b.iptables(
+			iptablesNATChain.table,
+			"--append", iptablesNATChain.name,
+			"--source", rule.SourceAddress,
+			"!", "--destination", rule.SourceAddress,
+			"--jump", "SNAT", "--to-source", rule.TargetIP,
+		)
+		if err != nil {
+			return err
+		}
+
+		for _, filter := range iptablesFilterRules(rule.SourceAddress, rule.Policy) {
+			if err := b.iptables(iptablesFilterChain.table, filter...); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (b *backendIPTables) teardown() error {
+	for _, chain := range []iptablesChain{iptablesNATChain, iptablesFilterChain} {
+		if err := b.iptables(chain.table, "--numeric", "--list", chain.name); err != nil {
+			continue
+		}
+
+		// jump may be missing if previous setup failed half way
+		if err := b.iptables(chain.table, "--delete", chain.builtin, "--jump", chain.name); err != nil {
+			log.Warn(natLogPrefix, "Failed to remove jump to ", chain.name, " chain: ", err)
+		}
+		if err := b.iptables(chain.table, "--flush", chain.name); err != nil {
+			return err
+		}
+		if err := b.iptables(chain.table, "--delete-chain", chain.name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *backendIPTables) iptables(table string, args ...string) error {
+	_, err := b.execute(iptablesBinary, append([]string{"--table", table}, args...)...)
+	return err
+}
+
+// iptablesFilterRules drops packets from source subnet, which are not allowed by policy
+func iptablesFilterRules(source string, policy Policy) [][]string {
+	var rules [][]string
+	drop := func(match ...string) {
+		rule := append([]string{"--append", iptablesFilterChain.name, "--source", source}, match...)
+		rules = append(rules, append(rule, "--jump", "DROP"))
+	}
+
+	for _, network := range policy.blockedNetworks() {
+		drop("--destination", network)
+	}
+	for _, port := range policy.DeniedPorts {
+		drop("--protocol", "tcp", "--destination-port", strconv.Itoa(port))
+		drop("--protocol", "udp", "--destination-port", strconv.Itoa(port))
+	}
+	if len(policy.AllowedNetworks) > 0 {
+		for _, network := range policy.AllowedNetworks {
+			rules = append(rules, []string{
+				"--append", iptablesFilterChain.name, "--source", source, "--destination", network, "--jump", "RETURN",
+			})
+		}
+		drop()
+	}
+	return rules
+}
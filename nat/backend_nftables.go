@@ -0,0 +1,110 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package nat
+
+import "strconv"
+
+const (
+	nftablesBinary = "nft"
+	// nftablesTable is a dedicated table owned by node, it can be inspected with `nft list table ip myst_nat`
+	nftablesTable       = "myst_nat"
+	nftablesNATChain    = "postrouting"
+	nftablesFilterChain = "forward"
+)
+
+type backendNFTables struct {
+	execute commandExecutor
+}
+
+func (b *backendNFTables) setup() error {
+	if err := b.nft("add", "table", "ip", nftablesTable); err != nil {
+		return err
+	}
+	err := b.nft(
+		"add", "chain", "ip", nftablesTable, nftablesNATChain,
+		"{", "type", "nat", "hook", "postrouting", "priority", "100", ";", "}",
+	)
+	if err != nil {
+		return err
+	}
+	return b.nft(
+		"add", "chain", "ip", nftablesTable, nftablesFilterChain,
+		"{", "type", "filter", "hook", "forward", "priority", "0", ";", "}",
+	)
+}
+
+func (b *backendNFTables) apply(rules []RuleForwarding) error {
+	if err := b.nft("flush", "chain", "ip", nftablesTable, nftablesNATChain); err != nil {
+		return err
+	}
+	if err := b.nft("flush", "chain", "ip", nftablesTable, nftablesFilterChain); err != nil {
+		return err
+	}
+
+	for _, rule := range rules {
+		err := b.nft(
+			"add", "rule", "ip", nftablesTable, nftablesNATChain,
+			"ip", "saddr", rule.SourceAddress,
+			"ip", "daddr", "!=", rule.SourceAddress,
+			"snat", "to", rule.TargetIP,
+		)
+		if err != nil {
+			return err
+		}
+
+		for _, filter := range nftablesFilterRules(rule.SourceAddress, rule.Policy) {
+			if err := b.nft(append([]string{"add", "rule", "ip", nftablesTable, nftablesFilterChain}, filter...)...); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (b *backendNFTables) teardown() error {
+	if err := b.nft("list", "table", "ip", nftablesTable); err != nil {
+		return nil
+	}
+	return b.nft("delete", "table", "ip", nftablesTable)
+}
+
+func (b *backendNFTables) nft(args ...string) error {
+	_, err := b.execute(nftablesBinary, args...)
+	return err
+}
+
+// nftablesFilterRules drops packets from source subnet, which are not allowed by policy
+func nftablesFilterRules(source string, policy Policy) [][]string {
+	var rules [][]string
+	for _, network := range policy.blockedNetworks() {
+		rules = append(rules, []string{"ip", "saddr", source, "ip", "daddr", network, "drop"})
+	}
+	for _, port := range policy.DeniedPorts {
+		rules = append(rules,
+			[]string{"ip", "saddr", source, "tcp", "dport", strconv.Itoa(port), "drop"},
+			[]string{"ip", "saddr", source, "udp", "dport", strconv.Itoa(port), "drop"},
+		)
+	}
+	if len(policy.AllowedNetworks) > 0 {
+		for _, network := range policy.AllowedNetworks {
+			rules = append(rules, []string{"ip", "saddr", source, "ip", "daddr", network, "return"})
+		}
+		rules = append(rules, []string{"ip", "saddr", source, "drop"})
+	}
+	return rules
+}
@@ -0,0 +1,39 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package nat
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+)
+
+// commandExecutor runs system command and returns its combined output
+type commandExecutor func(name string, args ...string) (string, error)
+
+func runCommand(name string, args ...string) (string, error) {
+	output, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		return string(output), errors.New(name + " " + strings.Join(args, " ") + " failed: " + err.Error() + " " + strings.TrimSpace(string(output)))
+	}
+	return string(output), nil
+}
+
+func sudoCommand(name string, args ...string) (string, error) {
+	return runCommand("sudo", append([]string{name}, args...)...)
+}
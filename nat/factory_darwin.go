@@ -17,15 +17,14 @@
 
 package nat
 
-import "os/exec"
-
-// NewService returns fake nat service since there are no iptables on darwin
-func NewService() NATService {
+// NewService returns darwin os specific nat service based on pfctl
+func NewService(options Options) (NATService, error) {
 	return &servicePFCtl{
 		ipForward: serviceIPForward{
-			CommandEnable:  exec.Command("/usr/sbin/sysctl", "-w", "net.inet.ip.forwarding=1"),
-			CommandDisable: exec.Command("/usr/sbin/sysctl", "-w", "net.inet.ip.forwarding=0"),
-			CommandRead:    exec.Command("/usr/sbin/sysctl", "-n", "net.inet.ip.forwarding"),
+			execute:        runCommand,
+			CommandEnable:  []string{"/usr/sbin/sysctl", "-w", "net.inet.ip.forwarding=1"},
+			CommandDisable: []string{"/usr/sbin/sysctl", "-w", "net.inet.ip.forwarding=0"},
+			CommandRead:    []string{"/usr/sbin/sysctl", "-n", "net.inet.ip.forwarding"},
 		},
-	}
+	}, nil
 }
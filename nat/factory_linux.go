@@ -17,15 +17,44 @@
 
 package nat
 
-import "os/exec"
+import (
+	"os/exec"
+	"path/filepath"
+)
 
-// NewService returns linux os specific nat service based on ip tables
-func NewService() NATService {
-	return &serviceIPTables{
+// NewService returns linux os specific nat service based on iptables or nftables
+func NewService(options Options) (NATService, error) {
+	backendName := options.Backend
+	if backendName == "" || backendName == BackendAuto {
+		backendName = detectBackend()
+	}
+
+	newLinuxBackend := func(name string) (backend, error) {
+		return newBackend(name, sudoCommand)
+	}
+	selected, err := newLinuxBackend(backendName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &serviceStateful{
+		backendName: backendName,
+		backend:     selected,
+		newBackend:  newLinuxBackend,
 		ipForward: serviceIPForward{
-			CommandEnable:  exec.Command("sudo", "/sbin/sysctl", "-w", "net.ipv4.ip_forward=1"),
-			CommandDisable: exec.Command("sudo", "/sbin/sysctl", "-w", "net.ipv4.ip_forward=0"),
-			CommandRead:    exec.Command("/sbin/sysctl", "-n", "net.ipv4.ip_forward"),
+			execute:        sudoCommand,
+			CommandEnable:  []string{"/sbin/sysctl", "-w", "net.ipv4.ip_forward=1"},
+			CommandDisable: []string{"/sbin/sysctl", "-w", "net.ipv4.ip_forward=0"},
+			CommandRead:    []string{"/sbin/sysctl", "-n", "net.ipv4.ip_forward"},
 		},
+		stateFile: filepath.Join(options.RuntimeDir, stateFileName),
+	}, nil
+}
+
+// detectBackend prefers nftables, as iptables may be only a compatibility layer over it
+func detectBackend() string {
+	if _, err := exec.LookPath(nftablesBinary); err == nil {
+		return BackendNFTables
 	}
+	return BackendIPTables
 }
@@ -17,6 +17,7 @@
 
 package nat
 
-func NewService() NATService {
-	return &serviceFake{}
+// NewService returns fake nat service, since there is no NAT support on windows yet
+func NewService(options Options) (NATService, error) {
+	return &serviceFake{}, nil
 }
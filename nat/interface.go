@@ -17,15 +17,44 @@
 
 package nat
 
-// NATService describes fake nat service for darwin
+import "errors"
+
+// NATService forwards packets of service subnets to the outbound IP of the node
 type NATService interface {
-	Add(rule RuleForwarding)
-	Start() error
-	Stop()
+	// Add starts forwarding packets by given rule, IP forwarding is enabled with the first rule
+	Add(rule RuleForwarding) error
+	// Del stops forwarding packets by given rule, IP forwarding is restored with the last rule removed
+	Del(rule RuleForwarding) error
+	// ClearStale removes rules left by previous run of the node, if it crashed
+	ClearStale() error
+	// Disable removes all rules
+	Disable()
 }
 
-// RuleForwarding describes fake nat rule
+// RuleForwarding describes NAT rule, forwarding packets from source subnet to target IP.
+// Source subnet identifies the rule, so every service should have a separate one.
 type RuleForwarding struct {
 	SourceAddress string
 	TargetIP      string
+	// Policy restricts destinations of forwarded packets
+	Policy Policy
+}
+
+// Supported NAT backends
+const (
+	// BackendAuto selects nftables if it is available, iptables otherwise
+	BackendAuto     = "auto"
+	BackendIPTables = "iptables"
+	BackendNFTables = "nftables"
+)
+
+// ErrUnsupportedBackend is returned when NAT service is requested with unknown backend
+var ErrUnsupportedBackend = errors.New("unsupported NAT backend")
+
+// Options describes NAT service configuration
+type Options struct {
+	// Backend selects packet filtering framework, BackendAuto is used if empty
+	Backend string
+	// RuntimeDir is where applied rules are persisted
+	RuntimeDir string
 }
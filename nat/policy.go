@@ -0,0 +1,63 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package nat
+
+import (
+	"fmt"
+	"net"
+)
+
+// LocalNetworks are private (RFC1918) and link-local ranges, traffic to which is dropped unless policy allows it
+var LocalNetworks = []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "169.254.0.0/16"}
+
+// Policy restricts destinations, which consumers may reach through the service.
+// Zero value policy blocks local networks only.
+type Policy struct {
+	// AllowLocalNetworks permits traffic to LocalNetworks of the provider
+	AllowLocalNetworks bool `json:"allow_local_networks"`
+	// DeniedNetworks lists destination subnets, traffic to which is dropped
+	DeniedNetworks []string `json:"denied_networks,omitempty"`
+	// DeniedPorts lists destination ports, TCP and UDP traffic to which is dropped
+	DeniedPorts []int `json:"denied_ports,omitempty"`
+	// AllowedNetworks restricts traffic to given destination subnets only, if not empty
+	AllowedNetworks []string `json:"allowed_networks,omitempty"`
+}
+
+// Validate checks that policy contains valid subnets and ports
+func (policy Policy) Validate() error {
+	for _, subnet := range append(append([]string{}, policy.DeniedNetworks...), policy.AllowedNetworks...) {
+		if _, _, err := net.ParseCIDR(subnet); err != nil {
+			return fmt.Errorf("invalid network in traffic policy: %s", subnet)
+		}
+	}
+	for _, port := range policy.DeniedPorts {
+		if port < 1 || port > 65535 {
+			return fmt.Errorf("invalid port in traffic policy: %d", port)
+		}
+	}
+	return nil
+}
+
+// blockedNetworks lists destination subnets, traffic to which is dropped
+func (policy Policy) blockedNetworks() []string {
+	var blocked []string
+	if !policy.AllowLocalNetworks {
+		blocked = append(blocked, LocalNetworks...)
+	}
+	return append(blocked, policy.DeniedNetworks...)
+}
@@ -0,0 +1,90 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package nat
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicy_Validate(t *testing.T) {
+	tests := []struct {
+		policy      Policy
+		expectedErr error
+	}{
+		{Policy{}, nil},
+		{Policy{DeniedNetworks: []string{"1.2.3.0/24"}, DeniedPorts: []int{25, 465}, AllowedNetworks: []string{"0.0.0.0/0"}}, nil},
+		{Policy{DeniedNetworks: []string{"1.2.3.4"}}, errors.New("invalid network in traffic policy: 1.2.3.4")},
+		{Policy{AllowedNetworks: []string{"example.com"}}, errors.New("invalid network in traffic policy: example.com")},
+		{Policy{DeniedPorts: []int{0}}, errors.New("invalid port in traffic policy: 0")},
+		{Policy{DeniedPorts: []int{65536}}, errors.New("invalid port in traffic policy: 65536")},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expectedErr, test.policy.Validate())
+	}
+}
+
+func TestIptablesFilterRules(t *testing.T) {
+	assert.Equal(
+		t,
+		[][]string{
+			{"--append", "MYST_FORWARD", "--source", "10.8.0.0/24", "--destination", "10.0.0.0/8", "--jump", "DROP"},
+			{"--append", "MYST_FORWARD", "--source", "10.8.0.0/24", "--destination", "172.16.0.0/12", "--jump", "DROP"},
+			{"--append", "MYST_FORWARD", "--source", "10.8.0.0/24", "--destination", "192.168.0.0/16", "--jump", "DROP"},
+			{"--append", "MYST_FORWARD", "--source", "10.8.0.0/24", "--destination", "169.254.0.0/16", "--jump", "DROP"},
+		},
+		iptablesFilterRules("10.8.0.0/24", Policy{}),
+	)
+
+	assert.Equal(
+		t,
+		[][]string{
+			{"--append", "MYST_FORWARD", "--source", "10.8.0.0/24", "--destination", "1.2.3.0/24", "--jump", "DROP"},
+			{"--append", "MYST_FORWARD", "--source", "10.8.0.0/24", "--protocol", "tcp", "--destination-port", "25", "--jump", "DROP"},
+			{"--append", "MYST_FORWARD", "--source", "10.8.0.0/24", "--protocol", "udp", "--destination-port", "25", "--jump", "DROP"},
+			{"--append", "MYST_FORWARD", "--source", "10.8.0.0/24", "--destination", "8.8.8.0/24", "--jump", "RETURN"},
+			{"--append", "MYST_FORWARD", "--source", "10.8.0.0/24", "--jump", "DROP"},
+		},
+		iptablesFilterRules("10.8.0.0/24", Policy{
+			AllowLocalNetworks: true,
+			DeniedNetworks:     []string{"1.2.3.0/24"},
+			DeniedPorts:        []int{25},
+			AllowedNetworks:    []string{"8.8.8.0/24"},
+		}),
+	)
+}
+
+func TestNftablesFilterRules(t *testing.T) {
+	assert.Equal(
+		t,
+		[][]string{
+			{"ip", "saddr", "10.8.0.0/24", "ip", "daddr", "10.0.0.0/8", "drop"},
+			{"ip", "saddr", "10.8.0.0/24", "ip", "daddr", "172.16.0.0/12", "drop"},
+			{"ip", "saddr", "10.8.0.0/24", "ip", "daddr", "192.168.0.0/16", "drop"},
+			{"ip", "saddr", "10.8.0.0/24", "ip", "daddr", "169.254.0.0/16", "drop"},
+			{"ip", "saddr", "10.8.0.0/24", "tcp", "dport", "465", "drop"},
+			{"ip", "saddr", "10.8.0.0/24", "udp", "dport", "465", "drop"},
+			{"ip", "saddr", "10.8.0.0/24", "ip", "daddr", "8.8.8.0/24", "return"},
+			{"ip", "saddr", "10.8.0.0/24", "drop"},
+		},
+		nftablesFilterRules("10.8.0.0/24", Policy{DeniedPorts: []int{465}, AllowedNetworks: []string{"8.8.8.0/24"}}),
+	)
+}
@@ -20,12 +20,17 @@ package nat
 type serviceFake struct {
 }
 
-func (service *serviceFake) Add(rule RuleForwarding) {
+func (service *serviceFake) Add(rule RuleForwarding) error {
+	return nil
+}
+
+func (service *serviceFake) Del(rule RuleForwarding) error {
+	return nil
 }
 
-func (service *serviceFake) Start() error {
+func (service *serviceFake) ClearStale() error {
 	return nil
 }
 
-func (service *serviceFake) Stop() {
+func (service *serviceFake) Disable() {
 }
@@ -18,52 +18,53 @@
 package nat
 
 import (
-	"os/exec"
 	"strings"
 
 	log "github.com/cihub/seelog"
 )
 
+const natLogPrefix = "[nat] "
+
+// serviceIPForward toggles IP forwarding through sysctl, given commands are run by executor
 type serviceIPForward struct {
-	CommandEnable  *exec.Cmd
-	CommandDisable *exec.Cmd
-	CommandRead    *exec.Cmd
-	forward        bool
+	execute        commandExecutor
+	CommandEnable  []string
+	CommandDisable []string
+	CommandRead    []string
 }
 
-func (service *serviceIPForward) Enable() error {
+// Enable turns IP forwarding on, returns true if it was off before
+func (service *serviceIPForward) Enable() (bool, error) {
 	if service.Enabled() {
-		service.forward = true
 		log.Info(natLogPrefix, "IP forwarding already enabled")
-		return nil
+		return false, nil
 	}
 
-	if output, err := service.CommandEnable.CombinedOutput(); err != nil {
-		log.Warn("Failed to enable IP forwarding: ", service.CommandEnable.Args, " Returned exit error: ", err.Error(), " Cmd output: ", string(output))
-		return err
+	if _, err := service.execute(service.CommandEnable[0], service.CommandEnable[1:]...); err != nil {
+		log.Warn(natLogPrefix, "Failed to enable IP forwarding: ", err)
+		return false, err
 	}
 
 	log.Info(natLogPrefix, "IP forwarding enabled")
-	return nil
+	return true, nil
 }
 
+// Disable turns IP forwarding off
 func (service *serviceIPForward) Disable() {
-	if service.forward {
+	if _, err := service.execute(service.CommandDisable[0], service.CommandDisable[1:]...); err != nil {
+		log.Warn(natLogPrefix, "Failed to disable IP forwarding: ", err)
 		return
 	}
 
-	if output, err := service.CommandDisable.CombinedOutput(); err != nil {
-		log.Warn("Failed to disable IP forwarding: ", service.CommandDisable.Args, " Returned exit error: ", err.Error(), " Cmd output: ", string(output))
-	}
-
 	log.Info(natLogPrefix, "IP forwarding disabled")
 }
 
+// Enabled checks if IP forwarding is on
 func (service *serviceIPForward) Enabled() bool {
-	output, err := service.CommandEnable.Output()
+	output, err := service.execute(service.CommandRead[0], service.CommandRead[1:]...)
 	if err != nil {
-		log.Warn("Failed to check IP forwarding status: ", service.CommandRead.Args, " Returned exit error: ", err.Error(), " Cmd output: ", string(output))
+		log.Warn(natLogPrefix, "Failed to check IP forwarding status: ", err)
 	}
 
-	return strings.TrimSpace(string(output)) == "1"
+	return strings.TrimSpace(output) == "1"
 }
@@ -23,33 +23,77 @@ import (
 	"net"
 	"os/exec"
 	"strings"
+	"sync"
 
 	log "github.com/cihub/seelog"
 	"github.com/mysteriumnetwork/node/utils"
 )
 
 type servicePFCtl struct {
-	rules     []RuleForwarding
-	ipForward serviceIPForward
+	rules             []RuleForwarding
+	ipForward         serviceIPForward
+	forwardingEnabled bool
+	lock              sync.Mutex
 }
 
-func (service *servicePFCtl) Add(rule RuleForwarding) {
-	service.rules = append(service.rules, rule)
+func (service *servicePFCtl) Add(rule RuleForwarding) error {
+	service.lock.Lock()
+	defer service.lock.Unlock()
+
+	if len(service.rules) == 0 {
+		forwardingEnabled, err := service.ipForward.Enable()
+		if err != nil {
+			log.Warn(natLogPrefix, "Failed to enable IP forwarding: ", err)
+		}
+		service.forwardingEnabled = forwardingEnabled
+	}
+
+	rules := append(service.rules, rule)
+	if err := service.enableRules(rules); err != nil {
+		return err
+	}
+	service.rules = rules
+	return nil
 }
 
-func (service *servicePFCtl) Start() error {
-	err := service.ipForward.Enable()
-	if err != nil {
-		log.Warn(natLogPrefix, "Failed to enable IP forwarding: ", err)
+func (service *servicePFCtl) Del(rule RuleForwarding) error {
+	service.lock.Lock()
+	defer service.lock.Unlock()
+
+	var rules []RuleForwarding
+	for _, existing := range service.rules {
+		if existing.SourceAddress != rule.SourceAddress {
+			rules = append(rules, existing)
+		}
 	}
+	service.rules = rules
 
-	service.clearStaleRules()
-	return service.enableRules()
+	if len(rules) == 0 {
+		service.disable()
+		return nil
+	}
+	return service.enableRules(rules)
 }
 
-func (service *servicePFCtl) Stop() {
+func (service *servicePFCtl) ClearStale() error {
 	service.disableRules()
-	service.ipForward.Disable()
+	return nil
+}
+
+func (service *servicePFCtl) Disable() {
+	service.lock.Lock()
+	defer service.lock.Unlock()
+
+	service.rules = nil
+	service.disable()
+}
+
+func (service *servicePFCtl) disable() {
+	service.disableRules()
+	if service.forwardingEnabled {
+		service.ipForward.Disable()
+		service.forwardingEnabled = false
+	}
 }
 
 func ifaceByAddress(ipAddress string) (string, error) {
@@ -72,42 +116,66 @@ func ifaceByAddress(ipAddress string) (string, error) {
 	return "", errors.New("not able to determine outbound ethernet interface")
 }
 
-func (service *servicePFCtl) enableRules() error {
-	for _, rule := range service.rules {
+// enableRules loads all given rules at once, as pfctl replaces whole ruleset
+func (service *servicePFCtl) enableRules(rules []RuleForwarding) error {
+	natRules := make([]string, len(rules))
+	var filterRules []string
+	for i, rule := range rules {
 		iface, err := ifaceByAddress(rule.TargetIP)
 		if err != nil {
 			return err
 		}
-		natRule := fmt.Sprintf("nat on %v inet from %v to any -> %v", iface, rule.SourceAddress, rule.TargetIP)
-		arguments := fmt.Sprintf(`echo "%v" | /sbin/pfctl -vEf -`, natRule)
-		cmd := exec.Command(
-			"sh",
-			"-c",
-			arguments,
-		)
+		natRules[i] = fmt.Sprintf("nat on %v inet from %v to any -> %v", iface, rule.SourceAddress, rule.TargetIP)
+		filterRules = append(filterRules, pfctlFilterRules(rule.SourceAddress, rule.Policy)...)
+	}
+	// translation rules must precede filter rules in pf ruleset
+	ruleset := strings.Join(append(natRules, filterRules...), "\n")
+	arguments := fmt.Sprintf(`printf "%v\n" | /sbin/pfctl -vEf -`, ruleset)
+	cmd := exec.Command(
+		"sh",
+		"-c",
+		arguments,
+	)
 
-		if output, err := cmd.CombinedOutput(); err != nil {
-			if !strings.Contains(string(output), natRule) {
-				log.Warn("Failed to create pfctl rule: ", cmd.Args, " Returned exit error: ", err.Error(), " Cmd output: ", string(output))
-				return err
-			}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		if !strings.Contains(string(output), natRules[len(natRules)-1]) {
+			log.Warn("Failed to create pfctl rule: ", cmd.Args, " Returned exit error: ", err.Error(), " Cmd output: ", string(output))
+			return err
 		}
+	}
 
+	for _, rule := range rules {
 		log.Info(natLogPrefix, "NAT rule from '", rule.SourceAddress, "' to IP: ", rule.TargetIP, " added")
 	}
 	return nil
 }
 
-func (service *servicePFCtl) disableRules() {
-	cmd := utils.SplitCommand("/sbin/pfctl", "-F nat")
+// pfctlFilterRules drops packets from source subnet, which are not allowed by policy
+func pfctlFilterRules(source string, policy Policy) []string {
+	var rules []string
+	for _, network := range policy.blockedNetworks() {
+		rules = append(rules, fmt.Sprintf("block drop quick inet from %v to %v", source, network))
+	}
+	for _, port := range policy.DeniedPorts {
+		rules = append(rules, fmt.Sprintf("block drop quick inet proto { tcp udp } from %v to any port %v", source, port))
+	}
+	if len(policy.AllowedNetworks) > 0 {
+		for _, network := range policy.AllowedNetworks {
+			rules = append(rules, fmt.Sprintf("pass quick inet from %v to %v", source, network))
+		}
+		rules = append(rules, fmt.Sprintf("block drop quick inet from %v to any", source))
+	}
+	return rules
+}
 
-	if output, err := cmd.CombinedOutput(); err != nil {
-		log.Warn("Failed cleanup pfctl rules: ", cmd.Args, " Returned exit error: ", err.Error(), " Cmd output: ", string(output))
+// disableRules flushes both translation and filter rules, as enableRules replaces the whole ruleset
+func (service *servicePFCtl) disableRules() {
+	for _, modifier := range []string{"nat", "rules"} {
+		cmd := utils.SplitCommand("/sbin/pfctl", "-F "+modifier)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			log.Warn("Failed cleanup pfctl rules: ", cmd.Args, " Returned exit error: ", err.Error(), " Cmd output: ", string(output))
+		}
 	}
 
 	log.Info(natLogPrefix, "NAT rules cleared")
 }
-
-func (service *servicePFCtl) clearStaleRules() {
-	service.disableRules()
-}
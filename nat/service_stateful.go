@@ -0,0 +1,183 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package nat
+
+import (
+	"sync"
+
+	log "github.com/cihub/seelog"
+)
+
+// serviceStateful applies rules with given backend and persists them, so they can be removed after crash
+type serviceStateful struct {
+	backendName string
+	backend     backend
+	newBackend  func(name string) (backend, error)
+	ipForward   serviceIPForward
+	stateFile   string
+
+	lock              sync.Mutex
+	rules             []RuleForwarding
+	active            bool
+	forwardingEnabled bool
+}
+
+// Add starts forwarding packets by given rule
+func (service *serviceStateful) Add(rule RuleForwarding) error {
+	service.lock.Lock()
+	defer service.lock.Unlock()
+
+	for _, existing := range service.rules {
+		if existing.SourceAddress == rule.SourceAddress {
+			return nil
+		}
+	}
+
+	if !service.active {
+		if err := service.activate(); err != nil {
+			return err
+		}
+	}
+
+	rules := append(service.rules, rule)
+	if err := service.backend.apply(rules); err != nil {
+		return err
+	}
+	service.rules = rules
+	service.persist()
+
+	log.Info(natLogPrefix, "Forwarding packets from '", rule.SourceAddress, "' to IP: ", rule.TargetIP)
+	return nil
+}
+
+// Del stops forwarding packets by given rule
+func (service *serviceStateful) Del(rule RuleForwarding) error {
+	service.lock.Lock()
+	defer service.lock.Unlock()
+
+	var rules []RuleForwarding
+	for _, existing := range service.rules {
+		if existing.SourceAddress != rule.SourceAddress {
+			rules = append(rules, existing)
+		}
+	}
+	if len(rules) == len(service.rules) {
+		return nil
+	}
+
+	if len(rules) == 0 {
+		service.rules = nil
+		return service.deactivate()
+	}
+
+	if err := service.backend.apply(rules); err != nil {
+		return err
+	}
+	service.rules = rules
+	service.persist()
+
+	log.Info(natLogPrefix, "Stopped forwarding packets from '", rule.SourceAddress, "' to IP: ", rule.TargetIP)
+	return nil
+}
+
+// ClearStale reverts changes recorded in state file by previous run of the node
+func (service *serviceStateful) ClearStale() error {
+	service.lock.Lock()
+	defer service.lock.Unlock()
+
+	stale, err := loadState(service.stateFile)
+	if err != nil || stale == nil {
+		return err
+	}
+
+	log.Info(natLogPrefix, "Removing ", len(stale.Rules), " stale rules left by ", stale.Backend)
+	staleBackend, err := service.newBackend(stale.Backend)
+	if err != nil {
+		return err
+	}
+	if err := staleBackend.teardown(); err != nil {
+		return err
+	}
+	if stale.ForwardingEnabled {
+		service.ipForward.Disable()
+	}
+	return removeState(service.stateFile)
+}
+
+// Disable removes all rules
+func (service *serviceStateful) Disable() {
+	service.lock.Lock()
+	defer service.lock.Unlock()
+
+	if !service.active {
+		return
+	}
+	service.rules = nil
+	if err := service.deactivate(); err != nil {
+		log.Warn(natLogPrefix, "Failed to remove NAT rules: ", err)
+	}
+}
+
+func (service *serviceStateful) activate() (err error) {
+	service.forwardingEnabled, err = service.ipForward.Enable()
+	if err != nil {
+		log.Warn(natLogPrefix, "Failed to enable IP forwarding: ", err)
+	}
+	// state is persisted before any rule is created, so half way setup can be reverted after crash
+	service.persist()
+
+	// chain may be left if previous run failed half way
+	if err = service.backend.teardown(); err == nil {
+		err = service.backend.setup()
+	}
+	if err != nil {
+		service.deactivate()
+		return err
+	}
+
+	service.active = true
+	return nil
+}
+
+func (service *serviceStateful) deactivate() error {
+	if err := service.backend.teardown(); err != nil {
+		service.persist()
+		return err
+	}
+	if service.forwardingEnabled {
+		service.ipForward.Disable()
+	}
+
+	service.active = false
+	service.forwardingEnabled = false
+	if err := removeState(service.stateFile); err != nil {
+		log.Warn(natLogPrefix, "Failed to remove NAT state: ", err)
+	}
+	return nil
+}
+
+func (service *serviceStateful) persist() {
+	current := state{
+		Backend:           service.backendName,
+		ForwardingEnabled: service.forwardingEnabled,
+		Rules:             service.rules,
+	}
+	if err := saveState(service.stateFile, current); err != nil {
+		log.Warn(natLogPrefix, "Failed to persist NAT state: ", err)
+	}
+}
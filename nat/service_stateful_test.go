@@ -0,0 +1,258 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package nat
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type executorFake struct {
+	commands []string
+	outputs  map[string]string
+	// failOn lists prefixes of commands which fail
+	failOn []string
+}
+
+func (executor *executorFake) execute(name string, args ...string) (string, error) {
+	command := strings.Join(append([]string{name}, args...), " ")
+	executor.commands = append(executor.commands, command)
+	for _, prefix := range executor.failOn {
+		if strings.HasPrefix(command, prefix) {
+			return "", errors.New("command failed")
+		}
+	}
+	return executor.outputs[command], nil
+}
+
+// rules allow local networks, so no filtering rules are created for them
+var (
+	allowAll      = Policy{AllowLocalNetworks: true}
+	ruleOpenvpn   = RuleForwarding{SourceAddress: "10.8.0.0/24", TargetIP: "192.168.1.10", Policy: allowAll}
+	ruleWireguard = RuleForwarding{SourceAddress: "10.182.0.0/24", TargetIP: "192.168.1.10", Policy: allowAll}
+)
+
+func newTestService(t *testing.T, backendName string, executor *executorFake) (*serviceStateful, func()) {
+	dir, err := ioutil.TempDir("", "nat")
+	assert.NoError(t, err)
+
+	newTestBackend := func(name string) (backend, error) {
+		return newBackend(name, executor.execute)
+	}
+	selected, err := newTestBackend(backendName)
+	assert.NoError(t, err)
+
+	service := &serviceStateful{
+		backendName: backendName,
+		backend:     selected,
+		newBackend:  newTestBackend,
+		ipForward: serviceIPForward{
+			execute:        executor.execute,
+			CommandEnable:  []string{"sysctl", "-w", "net.ipv4.ip_forward=1"},
+			CommandDisable: []string{"sysctl", "-w", "net.ipv4.ip_forward=0"},
+			CommandRead:    []string{"sysctl", "-n", "net.ipv4.ip_forward"},
+		},
+		stateFile: filepath.Join(dir, stateFileName),
+	}
+	return service, func() { os.RemoveAll(dir) }
+}
+
+func TestServiceStateful_IPTables(t *testing.T) {
+	executor := &executorFake{outputs: map[string]string{"sysctl -n net.ipv4.ip_forward": "0\n"}}
+	service, cleanup := newTestService(t, BackendIPTables, executor)
+	defer cleanup()
+
+	assert.NoError(t, service.Add(ruleOpenvpn))
+	assert.NoError(t, service.Add(ruleWireguard))
+	assert.Equal(
+		t,
+		[]string{
+			"sysctl -n net.ipv4.ip_forward",
+			"sysctl -w net.ipv4.ip_forward=1",
+			"/sbin/iptables --table nat --numeric --list MYST_NAT",
+			"/sbin/iptables --table nat --delete POSTROUTING --jump MYST_NAT",
+			"/sbin/iptables --table nat --flush MYST_NAT",
+			"/sbin/iptables --table nat --delete-chain MYST_NAT",
+			"/sbin/iptables --table filter --numeric --list MYST_FORWARD",
+			"/sbin/iptables --table filter --delete FORWARD --jump MYST_FORWARD",
+			"/sbin/iptables --table filter --flush MYST_FORWARD",
+			"/sbin/iptables --table filter --delete-chain MYST_FORWARD",
+			"/sbin/iptables --table nat --new-chain MYST_NAT",
+			"/sbin/iptables --table nat --insert POSTROUTING --jump MYST_NAT",
+			"/sbin/iptables --table filter --new-chain MYST_FORWARD",
+			"/sbin/iptables --table filter --insert FORWARD --jump MYST_FORWARD",
+			"/sbin/iptables --table nat --flush MYST_NAT",
+			"/sbin/iptables --table filter --flush MYST_FORWARD",
+			"/sbin/iptables --table nat --append MYST_NAT --source 10.8.0.0/24 ! --destination 10.8.0.0/24 --jump SNAT --to-source 192.168.1.10",
+			"/sbin/iptables --table nat --flush MYST_NAT",
+			"/sbin/iptables --table filter --flush MYST_FORWARD",
+			"/sbin/iptables --table nat --append MYST_NAT --source 10.8.0.0/24 ! --destination 10.8.0.0/24 --jump SNAT --to-source 192.168.1.10",
+			"/sbin/iptables --table nat --append MYST_NAT --source 10.182.0.0/24 ! --destination 10.182.0.0/24 --jump SNAT --to-source 192.168.1.10",
+		},
+		executor.commands,
+	)
+
+	executor.commands = nil
+	assert.NoError(t, service.Del(ruleOpenvpn))
+	assert.NoError(t, service.Del(ruleWireguard))
+	assert.Equal(
+		t,
+		[]string{
+			"/sbin/iptables --table nat --flush MYST_NAT",
+			"/sbin/iptables --table filter --flush MYST_FORWARD",
+			"/sbin/iptables --table nat --append MYST_NAT --source 10.182.0.0/24 ! --destination 10.182.0.0/24 --jump SNAT --to-source 192.168.1.10",
+			"/sbin/iptables --table nat --numeric --list MYST_NAT",
+			"/sbin/iptables --table nat --delete POSTROUTING --jump MYST_NAT",
+			"/sbin/iptables --table nat --flush MYST_NAT",
+			"/sbin/iptables --table nat --delete-chain MYST_NAT",
+			"/sbin/iptables --table filter --numeric --list MYST_FORWARD",
+			"/sbin/iptables --table filter --delete FORWARD --jump MYST_FORWARD",
+			"/sbin/iptables --table filter --flush MYST_FORWARD",
+			"/sbin/iptables --table filter --delete-chain MYST_FORWARD",
+			"sysctl -w net.ipv4.ip_forward=0",
+		},
+		executor.commands,
+	)
+}
+
+func TestServiceStateful_NFTables(t *testing.T) {
+	executor := &executorFake{
+		outputs: map[string]string{"sysctl -n net.ipv4.ip_forward": "1\n"},
+		failOn:  []string{"nft list table"},
+	}
+	service, cleanup := newTestService(t, BackendNFTables, executor)
+	defer cleanup()
+
+	assert.NoError(t, service.Add(ruleOpenvpn))
+	service.Disable()
+	assert.Equal(
+		t,
+		[]string{
+			"sysctl -n net.ipv4.ip_forward",
+			"nft list table ip myst_nat",
+			"nft add table ip myst_nat",
+			"nft add chain ip myst_nat postrouting { type nat hook postrouting priority 100 ; }",
+			"nft add chain ip myst_nat forward { type filter hook forward priority 0 ; }",
+			"nft flush chain ip myst_nat postrouting",
+			"nft flush chain ip myst_nat forward",
+			"nft add rule ip myst_nat postrouting ip saddr 10.8.0.0/24 ip daddr != 10.8.0.0/24 snat to 192.168.1.10",
+			"nft list table ip myst_nat",
+		},
+		executor.commands,
+		"forwarding, which was enabled before, is kept",
+	)
+}
+
+func TestServiceStateful_AddSameRuleTwice(t *testing.T) {
+	executor := &executorFake{}
+	service, cleanup := newTestService(t, BackendNFTables, executor)
+	defer cleanup()
+
+	assert.NoError(t, service.Add(ruleOpenvpn))
+	commandsCount := len(executor.commands)
+	assert.NoError(t, service.Add(ruleOpenvpn))
+	assert.Len(t, executor.commands, commandsCount)
+}
+
+func TestServiceStateful_AddFailure(t *testing.T) {
+	executor := &executorFake{
+		outputs: map[string]string{"sysctl -n net.ipv4.ip_forward": "0\n"},
+		failOn:  []string{"nft list table", "nft add chain"},
+	}
+	service, cleanup := newTestService(t, BackendNFTables, executor)
+	defer cleanup()
+
+	assert.EqualError(t, service.Add(ruleOpenvpn), "command failed")
+	assert.Equal(t, "sysctl -w net.ipv4.ip_forward=0", executor.commands[len(executor.commands)-1])
+
+	_, err := os.Stat(service.stateFile)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestServiceStateful_PersistsRules(t *testing.T) {
+	executor := &executorFake{outputs: map[string]string{"sysctl -n net.ipv4.ip_forward": "0\n"}}
+	service, cleanup := newTestService(t, BackendNFTables, executor)
+	defer cleanup()
+
+	assert.NoError(t, service.Add(ruleOpenvpn))
+	assert.NoError(t, service.Add(ruleWireguard))
+	assert.NoError(t, service.Del(ruleOpenvpn))
+
+	persisted, err := loadState(service.stateFile)
+	assert.NoError(t, err)
+	assert.Equal(
+		t,
+		&state{Backend: BackendNFTables, ForwardingEnabled: true, Rules: []RuleForwarding{ruleWireguard}},
+		persisted,
+	)
+
+	assert.NoError(t, service.Del(ruleWireguard))
+	persisted, err = loadState(service.stateFile)
+	assert.NoError(t, err)
+	assert.Nil(t, persisted)
+}
+
+func TestServiceStateful_ClearStale(t *testing.T) {
+	executor := &executorFake{}
+	service, cleanup := newTestService(t, BackendNFTables, executor)
+	defer cleanup()
+
+	stale := state{Backend: BackendIPTables, ForwardingEnabled: true, Rules: []RuleForwarding{ruleOpenvpn}}
+	assert.NoError(t, saveState(service.stateFile, stale))
+
+	assert.NoError(t, service.ClearStale())
+	assert.Equal(
+		t,
+		[]string{
+			"/sbin/iptables --table nat --numeric --list MYST_NAT",
+			"/sbin/iptables --table nat --delete POSTROUTING --jump MYST_NAT",
+			"/sbin/iptables --table nat --flush MYST_NAT",
+			"/sbin/iptables --table nat --delete-chain MYST_NAT",
+			"/sbin/iptables --table filter --numeric --list MYST_FORWARD",
+			"/sbin/iptables --table filter --delete FORWARD --jump MYST_FORWARD",
+			"/sbin/iptables --table filter --flush MYST_FORWARD",
+			"/sbin/iptables --table filter --delete-chain MYST_FORWARD",
+			"sysctl -w net.ipv4.ip_forward=0",
+		},
+		executor.commands,
+	)
+
+	persisted, err := loadState(service.stateFile)
+	assert.NoError(t, err)
+	assert.Nil(t, persisted)
+}
+
+func TestServiceStateful_ClearStaleWithoutState(t *testing.T) {
+	executor := &executorFake{}
+	service, cleanup := newTestService(t, BackendNFTables, executor)
+	defer cleanup()
+
+	assert.NoError(t, service.ClearStale())
+	assert.Empty(t, executor.commands)
+}
+
+func TestNewBackend_Unsupported(t *testing.T) {
+	_, err := newBackend("ipfw", (&executorFake{}).execute)
+	assert.Equal(t, ErrUnsupportedBackend, err)
+}
@@ -0,0 +1,67 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package nat
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+)
+
+// stateFileName is the file in runtime directory, where rules applied by node are kept
+const stateFileName = "nat-state.json"
+
+// state describes changes made by node, so they can be reverted after crash
+type state struct {
+	Backend string `json:"backend"`
+	// ForwardingEnabled is true if node turned IP forwarding on
+	ForwardingEnabled bool             `json:"forwarding_enabled"`
+	Rules             []RuleForwarding `json:"rules"`
+}
+
+// loadState reads persisted state, nil is returned if there is none
+func loadState(path string) (*state, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var loaded state
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return nil, err
+	}
+	return &loaded, nil
+}
+
+func saveState(path string, current state) error {
+	data, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+func removeState(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -28,10 +28,16 @@ import (
 type Client interface {
 	RegisterIdentity(id identity.Identity, signer identity.Signer) (err error)
 
-	FindProposals(providerID string) (proposals []dto_discovery.ServiceProposal, err error)
+	FindProposals(filter ProposalsFilter) (proposals []dto_discovery.ServiceProposal, err error)
 	RegisterProposal(proposal dto_discovery.ServiceProposal, signer identity.Signer) (err error)
 	UnregisterProposal(proposal dto_discovery.ServiceProposal, signer identity.Signer) (err error)
 	PingProposal(proposal dto_discovery.ServiceProposal, signer identity.Signer) (err error)
 
 	SendSessionStats(sessionId session.ID, sessionStats dto.SessionStats, signer identity.Signer) (err error)
 }
+
+// ProposalsFilter defines proposals searched in discovery, empty fields match any value
+type ProposalsFilter struct {
+	ProviderID  string
+	ServiceType string
+}
@@ -58,3 +58,42 @@ func Parse(msg json.RawMessage, proposal interface{}) ([]byte, error) {
 	}
 	return out, err
 }
+
+// ConnectCount holds statistics of consumer connection attempts to the proposal
+type ConnectCount struct {
+	Success int `json:"success"`
+	Fail    int `json:"fail"`
+	Timeout int `json:"timeout"`
+}
+
+// SuccessRate returns share of successful connection attempts, ok is false when there were no attempts
+func (count ConnectCount) SuccessRate() (rate float64, ok bool) {
+	total := count.Success + count.Fail + count.Timeout
+	if total <= 0 {
+		return 0, false
+	}
+	return float64(count.Success) / float64(total), true
+}
+
+// ProposalKey identifies service of provider in metrics
+type ProposalKey struct {
+	ProviderID  string `json:"providerId"`
+	ServiceType string `json:"serviceType"`
+}
+
+// ParseConnectCounts parses connection statistics of proposals from quality oracle metrics, malformed metrics are skipped
+func ParseConnectCounts(messages []json.RawMessage) map[ProposalKey]ConnectCount {
+	counts := make(map[ProposalKey]ConnectCount, len(messages))
+	for _, msg := range messages {
+		var metrics struct {
+			ProposalID   ProposalKey  `json:"proposalId"`
+			ConnectCount ConnectCount `json:"connectCount"`
+		}
+		if err := json.Unmarshal(msg, &metrics); err != nil {
+			log.Warn(mysteriumMetricsLogPrefix, "Failed to parse metrics: ", err)
+			continue
+		}
+		counts[metrics.ProposalID] = metrics.ConnectCount
+	}
+	return counts
+}
@@ -131,10 +131,13 @@ func (mApi *mysteriumAPI) PingProposal(proposal dto_discovery.ServiceProposal, s
 }
 
 // FindProposals fetches currently active service proposals from discovery
-func (mApi *mysteriumAPI) FindProposals(providerID string) ([]dto_discovery.ServiceProposal, error) {
+func (mApi *mysteriumAPI) FindProposals(filter ProposalsFilter) ([]dto_discovery.ServiceProposal, error) {
 	values := url.Values{}
-	if providerID != "" {
-		values.Set("node_key", providerID)
+	if filter.ProviderID != "" {
+		values.Set("node_key", filter.ProviderID)
+	}
+	if filter.ServiceType != "" {
+		values.Set("service_type", filter.ServiceType)
 	}
 
 	req, err := requests.NewGetRequest(mApi.discoveryAPIAddress, "proposals", values)
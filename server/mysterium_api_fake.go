@@ -53,7 +53,7 @@ func (client *ClientFake) UnregisterProposal(proposal dto_discovery.ServicePropo
 
 	for _, pr := range client.proposalsMock {
 		if proposal.ProviderID != pr.ProviderID {
-			remainingProposals = append(remainingProposals, proposal)
+			remainingProposals = append(remainingProposals, pr)
 		}
 	}
 	client.proposalsMock = remainingProposals
@@ -79,13 +79,16 @@ func (client *ClientFake) PingProposal(proposal dto_discovery.ServiceProposal, s
 }
 
 // FindProposals fetches announced proposals by given filters
-func (client *ClientFake) FindProposals(providerID string) (proposals []dto_discovery.ServiceProposal, err error) {
-	log.Info(mysteriumAPILogPrefix, "Fake proposals requested for provider: ", providerID)
+func (client *ClientFake) FindProposals(filter ProposalsFilter) (proposals []dto_discovery.ServiceProposal, err error) {
+	log.Info(mysteriumAPILogPrefix, "Fake proposals requested for provider: ", filter.ProviderID)
 
 	for _, proposal := range client.proposalsMock {
 		var filterMatched = true
-		if providerID != "" {
-			filterMatched = filterMatched && (providerID == proposal.ProviderID)
+		if filter.ProviderID != "" {
+			filterMatched = filterMatched && (filter.ProviderID == proposal.ProviderID)
+		}
+		if filter.ServiceType != "" {
+			filterMatched = filterMatched && (filter.ServiceType == proposal.ServiceType)
 		}
 		if filterMatched {
 			proposals = append(proposals, proposal)
@@ -20,6 +20,7 @@ package server
 import (
 	"net"
 	"net/http"
+	"net/url"
 	"testing"
 	"time"
 
@@ -63,3 +64,19 @@ func createHTTPServer(handlerFunc http.HandlerFunc) (address string, err error)
 	go http.Serve(listener, handlerFunc)
 	return listener.Addr().String(), nil
 }
+
+func TestFindProposalsPassesFilterToDiscovery(t *testing.T) {
+	queries := make(chan url.Values, 1)
+	address, err := createHTTPServer(func(writer http.ResponseWriter, request *http.Request) {
+		queries <- request.URL.Query()
+		writer.Write([]byte(`{"proposals": []}`))
+	})
+	assert.NoError(t, err)
+
+	api := NewClient("http://" + address)
+	proposals, err := api.FindProposals(ProposalsFilter{ProviderID: "0x1", ServiceType: "openvpn"})
+
+	assert.NoError(t, err)
+	assert.Len(t, proposals, 0)
+	assert.Equal(t, url.Values{"node_key": {"0x1"}, "service_type": {"openvpn"}}, <-queries)
+}
@@ -0,0 +1,251 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package server
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/cihub/seelog"
+	"github.com/mysteriumnetwork/node/core/storage"
+	dto_discovery "github.com/mysteriumnetwork/node/service_discovery/dto"
+)
+
+const proposalCacheLogPrefix = "[proposal-cache] "
+
+// proposalCacheBucket is storage bucket of cached proposals
+const proposalCacheBucket = "proposals"
+
+// ErrCacheAlreadyRunning indicates that background refresh of cache is already started
+var ErrCacheAlreadyRunning = errors.New("proposal cache refresh is already running")
+
+// CachedProposal is service proposal kept in local cache
+type CachedProposal struct {
+	Key      string `storm:"id"`
+	Proposal dto_discovery.ServiceProposal
+	Fetched  time.Time
+}
+
+// Age returns how long ago proposal was fetched from discovery
+func (cached CachedProposal) Age(now time.Time) time.Duration {
+	return now.Sub(cached.Fetched)
+}
+
+// ProposalCache keeps proposals fetched from discovery in local storage,
+// so that consumer can find them while discovery is slow or unreachable
+type ProposalCache struct {
+	Client
+	storage    storage.Storage
+	ttl        time.Duration
+	timeGetter func() time.Time
+
+	lock          sync.Mutex
+	lastRefreshed time.Time
+
+	stop     chan struct{}
+	stopLock sync.Mutex
+}
+
+// NewProposalCache creates cache of proposals fetched through the given client, proposals expire after the given TTL
+func NewProposalCache(client Client, storage storage.Storage, ttl time.Duration) *ProposalCache {
+	return &ProposalCache{
+		Client:     client,
+		storage:    storage,
+		ttl:        ttl,
+		timeGetter: time.Now,
+	}
+}
+
+// FindProposals fetches proposals from discovery and caches them,
+// cached proposals are returned if discovery fails
+func (cache *ProposalCache) FindProposals(filter ProposalsFilter) ([]dto_discovery.ServiceProposal, error) {
+	proposals, err := cache.Client.FindProposals(filter)
+	if err != nil {
+		cached, cacheErr := cache.cached(filter)
+		if cacheErr != nil || len(cached) == 0 {
+			return nil, err
+		}
+		log.Warn(proposalCacheLogPrefix, "Failed to fetch proposals, using cached ones: ", err)
+		return proposalsOf(cached), nil
+	}
+
+	if err := cache.store(filter, proposals); err != nil {
+		log.Error(proposalCacheLogPrefix, "Failed to cache proposals: ", err)
+	}
+	return proposals, nil
+}
+
+// CachedProposals returns proposals from cache, all proposals are fetched from discovery first if cache is not fresh.
+// Cached proposals, which have not expired yet, are returned if discovery fails
+func (cache *ProposalCache) CachedProposals(filter ProposalsFilter) ([]CachedProposal, error) {
+	if cache.fresh() {
+		return cache.cached(filter)
+	}
+
+	proposals, err := cache.Client.FindProposals(ProposalsFilter{})
+	if err != nil {
+		cached, cacheErr := cache.cached(filter)
+		if cacheErr != nil || len(cached) == 0 {
+			return nil, err
+		}
+		log.Warn(proposalCacheLogPrefix, "Failed to refresh proposals, using cached ones: ", err)
+		return cached, nil
+	}
+
+	if err := cache.store(ProposalsFilter{}, proposals); err != nil {
+		log.Error(proposalCacheLogPrefix, "Failed to cache proposals: ", err)
+	}
+	now := cache.timeGetter()
+	fetched := make([]CachedProposal, 0, len(proposals))
+	for _, proposal := range proposals {
+		if filter.matches(proposal) {
+			fetched = append(fetched, CachedProposal{Key: ProposalCacheKey(proposal), Proposal: proposal, Fetched: now})
+		}
+	}
+	return fetched, nil
+}
+
+// Refresh replaces cached proposals with all proposals fetched from discovery
+func (cache *ProposalCache) Refresh() error {
+	proposals, err := cache.Client.FindProposals(ProposalsFilter{})
+	if err != nil {
+		return err
+	}
+	return cache.store(ProposalsFilter{}, proposals)
+}
+
+// Start refreshes cache periodically
+func (cache *ProposalCache) Start(interval time.Duration) error {
+	cache.stopLock.Lock()
+	defer cache.stopLock.Unlock()
+
+	if cache.stop != nil {
+		return ErrCacheAlreadyRunning
+	}
+	cache.stop = make(chan struct{})
+
+	go cache.refreshLoop(interval, cache.stop)
+	return nil
+}
+
+// Stop stops periodic refresh of cache
+func (cache *ProposalCache) Stop() {
+	cache.stopLock.Lock()
+	defer cache.stopLock.Unlock()
+
+	if cache.stop != nil {
+		close(cache.stop)
+		cache.stop = nil
+	}
+}
+
+func (cache *ProposalCache) refreshLoop(interval time.Duration, stop <-chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		case <-time.After(interval):
+			if err := cache.Refresh(); err != nil {
+				log.Warn(proposalCacheLogPrefix, "Failed to refresh proposals: ", err)
+			}
+		}
+	}
+}
+
+func (cache *ProposalCache) fresh() bool {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	return !cache.lastRefreshed.IsZero() && cache.timeGetter().Sub(cache.lastRefreshed) < cache.ttl
+}
+
+// store caches fetched proposals, proposals matching the filter, which were not fetched, and expired proposals are removed
+func (cache *ProposalCache) store(filter ProposalsFilter, proposals []dto_discovery.ServiceProposal) error {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	now := cache.timeGetter()
+	fetched := make(map[string]bool, len(proposals))
+	for _, proposal := range proposals {
+		cached := CachedProposal{Key: ProposalCacheKey(proposal), Proposal: proposal, Fetched: now}
+		if err := cache.storage.Store(proposalCacheBucket, &cached); err != nil {
+			return err
+		}
+		fetched[cached.Key] = true
+	}
+
+	var all []CachedProposal
+	if err := cache.storage.GetAllFrom(proposalCacheBucket, &all); err != nil {
+		return err
+	}
+	for i := range all {
+		stale := !fetched[all[i].Key] && filter.matches(all[i].Proposal)
+		if stale || all[i].Age(now) >= cache.ttl {
+			if err := cache.storage.Delete(proposalCacheBucket, &all[i]); err != nil {
+				return err
+			}
+		}
+	}
+
+	if filter == (ProposalsFilter{}) {
+		cache.lastRefreshed = now
+	}
+	return nil
+}
+
+// cached returns proposals matching the filter, which have not expired yet
+func (cache *ProposalCache) cached(filter ProposalsFilter) ([]CachedProposal, error) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	var all []CachedProposal
+	if err := cache.storage.GetAllFrom(proposalCacheBucket, &all); err != nil {
+		return nil, err
+	}
+
+	now := cache.timeGetter()
+	cached := make([]CachedProposal, 0, len(all))
+	for _, entry := range all {
+		if filter.matches(entry.Proposal) && entry.Age(now) < cache.ttl {
+			cached = append(cached, entry)
+		}
+	}
+	return cached, nil
+}
+
+func (filter ProposalsFilter) matches(proposal dto_discovery.ServiceProposal) bool {
+	if filter.ProviderID != "" && filter.ProviderID != proposal.ProviderID {
+		return false
+	}
+	return filter.ServiceType == "" || filter.ServiceType == proposal.ServiceType
+}
+
+// ProposalCacheKey identifies proposal in cache, provider may publish several proposals of the same service type
+func ProposalCacheKey(proposal dto_discovery.ServiceProposal) string {
+	return proposal.ProviderID + ":" + proposal.ServiceType + ":" + strconv.Itoa(proposal.ID)
+}
+
+func proposalsOf(cached []CachedProposal) []dto_discovery.ServiceProposal {
+	proposals := make([]dto_discovery.ServiceProposal, len(cached))
+	for i, entry := range cached {
+		proposals[i] = entry.Proposal
+	}
+	return proposals
+}
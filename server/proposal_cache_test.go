@@ -0,0 +1,204 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package server
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mysteriumnetwork/node/core/storage"
+	dto_discovery "github.com/mysteriumnetwork/node/service_discovery/dto"
+	"github.com/stretchr/testify/assert"
+)
+
+var errDiscoveryDown = errors.New("discovery is down")
+
+type cacheStorageFake struct {
+	storage.FakeStorage
+	entries map[string]CachedProposal
+}
+
+func newCacheStorageFake() *cacheStorageFake {
+	return &cacheStorageFake{entries: make(map[string]CachedProposal)}
+}
+
+func (fs *cacheStorageFake) Store(issuer string, data interface{}) error {
+	entry := data.(*CachedProposal)
+	fs.entries[entry.Key] = *entry
+	return nil
+}
+
+func (fs *cacheStorageFake) Delete(issuer string, data interface{}) error {
+	delete(fs.entries, data.(*CachedProposal).Key)
+	return nil
+}
+
+func (fs *cacheStorageFake) GetAllFrom(issuer string, array interface{}) error {
+	entries := array.(*[]CachedProposal)
+	for _, entry := range fs.entries {
+		*entries = append(*entries, entry)
+	}
+	return nil
+}
+
+type discoveryFake struct {
+	*ClientFake
+	err      error
+	requests int
+}
+
+func (client *discoveryFake) FindProposals(filter ProposalsFilter) ([]dto_discovery.ServiceProposal, error) {
+	client.requests++
+	if client.err != nil {
+		return nil, client.err
+	}
+	return client.ClientFake.FindProposals(filter)
+}
+
+type cacheContext struct {
+	discovery *discoveryFake
+	storage   *cacheStorageFake
+	cache     *ProposalCache
+	now       time.Time
+}
+
+func newCacheContext(providerIDs ...string) *cacheContext {
+	context := &cacheContext{
+		discovery: &discoveryFake{ClientFake: NewClientFake()},
+		storage:   newCacheStorageFake(),
+		now:       time.Date(2018, 11, 1, 10, 0, 0, 0, time.UTC),
+	}
+	for _, providerID := range providerIDs {
+		context.discovery.RegisterProposal(dto_discovery.ServiceProposal{ProviderID: providerID, ServiceType: "openvpn"}, nil)
+	}
+	context.cache = NewProposalCache(context.discovery, context.storage, time.Hour)
+	context.cache.timeGetter = func() time.Time { return context.now }
+	return context
+}
+
+func providersOf(proposals []dto_discovery.ServiceProposal) []string {
+	providers := make([]string, len(proposals))
+	for i, proposal := range proposals {
+		providers[i] = proposal.ProviderID
+	}
+	return providers
+}
+
+func TestProposalCacheFallsBackToCachedProposalWhenDiscoveryFails(t *testing.T) {
+	context := newCacheContext("provider1", "provider2")
+
+	proposals, err := context.cache.FindProposals(ProposalsFilter{ProviderID: "provider1"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"provider1"}, providersOf(proposals))
+
+	context.discovery.err = errDiscoveryDown
+	context.now = context.now.Add(time.Minute)
+
+	proposals, err = context.cache.FindProposals(ProposalsFilter{ProviderID: "provider1"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"provider1"}, providersOf(proposals))
+
+	_, err = context.cache.FindProposals(ProposalsFilter{ProviderID: "provider2"})
+	assert.Equal(t, errDiscoveryDown, err)
+}
+
+func TestProposalCacheDoesNotServeExpiredProposals(t *testing.T) {
+	context := newCacheContext("provider1")
+
+	_, err := context.cache.FindProposals(ProposalsFilter{})
+	assert.NoError(t, err)
+
+	context.discovery.err = errDiscoveryDown
+	context.now = context.now.Add(time.Hour)
+
+	_, err = context.cache.FindProposals(ProposalsFilter{ProviderID: "provider1"})
+	assert.Equal(t, errDiscoveryDown, err)
+}
+
+func TestProposalCacheServesCachedProposalsWhileFresh(t *testing.T) {
+	context := newCacheContext("provider1", "provider2")
+
+	cached, err := context.cache.CachedProposals(ProposalsFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, cached, 2)
+	assert.Equal(t, 1, context.discovery.requests)
+
+	context.now = context.now.Add(30 * time.Minute)
+	cached, err = context.cache.CachedProposals(ProposalsFilter{ProviderID: "provider2"})
+	assert.NoError(t, err)
+	assert.Len(t, cached, 1)
+	assert.Equal(t, "provider2", cached[0].Proposal.ProviderID)
+	assert.Equal(t, 30*time.Minute, cached[0].Age(context.now))
+	assert.Equal(t, 1, context.discovery.requests)
+}
+
+func TestProposalCacheServesCachedProposalsWhenRefreshFails(t *testing.T) {
+	context := newCacheContext("provider1")
+
+	_, err := context.cache.FindProposals(ProposalsFilter{ProviderID: "provider1"})
+	assert.NoError(t, err)
+
+	context.discovery.err = errDiscoveryDown
+	cached, err := context.cache.CachedProposals(ProposalsFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, cached, 1)
+
+	_, err = context.cache.CachedProposals(ProposalsFilter{ServiceType: "wireguard"})
+	assert.Equal(t, errDiscoveryDown, err)
+}
+
+func TestProposalCacheRefreshRemovesUnregisteredProposals(t *testing.T) {
+	context := newCacheContext("provider1", "provider2")
+
+	assert.NoError(t, context.cache.Refresh())
+	assert.Len(t, context.storage.entries, 2)
+
+	context.discovery.UnregisterProposal(dto_discovery.ServiceProposal{ProviderID: "provider1", ServiceType: "openvpn"}, nil)
+	assert.NoError(t, context.cache.Refresh())
+
+	assert.Len(t, context.storage.entries, 1)
+	assert.Contains(t, context.storage.entries, "provider2:openvpn:0")
+}
+
+func TestProposalCacheKeepsProposalsOfSameServiceTypeFromOneProvider(t *testing.T) {
+	context := newCacheContext()
+	context.discovery.RegisterProposal(dto_discovery.ServiceProposal{ID: 1, ProviderID: "provider1", ServiceType: "openvpn"}, nil)
+	context.discovery.RegisterProposal(dto_discovery.ServiceProposal{ID: 2, ProviderID: "provider1", ServiceType: "openvpn"}, nil)
+
+	assert.NoError(t, context.cache.Refresh())
+	assert.Len(t, context.storage.entries, 2)
+	assert.Contains(t, context.storage.entries, "provider1:openvpn:1")
+	assert.Contains(t, context.storage.entries, "provider1:openvpn:2")
+
+	cached, err := context.cache.CachedProposals(ProposalsFilter{ProviderID: "provider1"})
+	assert.NoError(t, err)
+	assert.Len(t, cached, 2)
+}
+
+func TestProposalCacheRefreshesPeriodically(t *testing.T) {
+	context := newCacheContext("provider1")
+	context.cache.timeGetter = time.Now
+
+	assert.NoError(t, context.cache.Start(10*time.Millisecond))
+	assert.Equal(t, ErrCacheAlreadyRunning, context.cache.Start(10*time.Millisecond))
+	time.Sleep(50 * time.Millisecond)
+	context.cache.Stop()
+
+	assert.True(t, context.cache.fresh())
+}
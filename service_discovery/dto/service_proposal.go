@@ -57,8 +57,6 @@ type ServiceProposal struct {
 // SetProviderContact updates service proposal description with general data
 func (proposal *ServiceProposal) SetProviderContact(providerID identity.Identity, providerContact Contact) {
 	proposal.Format = proposalFormat
-	// TODO This will be generated later
-	proposal.ID = 1
 	proposal.ProviderID = providerID.Address
 	proposal.ProviderContacts = ContactList{providerContact}
 }
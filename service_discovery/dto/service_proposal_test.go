@@ -42,7 +42,7 @@ func Test_ServiceProposal_SetProviderContact(t *testing.T) {
 	assert.Exactly(
 		t,
 		ServiceProposal{
-			ID:               1,
+			ID:               123,
 			Format:           proposalFormat,
 			ProviderID:       providerID.Address,
 			ProviderContacts: ContactList{providerContact},
@@ -20,11 +20,39 @@ package openvpn
 import (
 	"github.com/mysteriumnetwork/go-openvpn/openvpn"
 	"github.com/mysteriumnetwork/go-openvpn/openvpn/management"
+	"github.com/mysteriumnetwork/node/firewall"
 )
 
+// Client represents openvpn client process connected to remote VPN server
+type Client struct {
+	openvpn.Process
+	vpnConfig *VPNConfig
+	activeDNS func() []string
+}
+
 // NewClient creates openvpn client with given config params
-func NewClient(openvpnBinary string, config *ClientConfig, middlewares ...management.Middleware) openvpn.Process {
-	return openvpn.CreateNewProcess(openvpnBinary, config.GenericConfig, middlewares...)
+func NewClient(openvpnBinary string, config *ClientConfig, middlewares ...management.Middleware) *Client {
+	return &Client{
+		Process:   openvpn.CreateNewProcess(openvpnBinary, config.GenericConfig, middlewares...),
+		vpnConfig: config.VPNConfig,
+	}
+}
+
+// ServerAddress returns address of VPN server which client connects to
+func (client *Client) ServerAddress() firewall.ServerAddress {
+	return firewall.ServerAddress{
+		IP:       client.vpnConfig.RemoteIP,
+		Port:     client.vpnConfig.RemotePort,
+		Protocol: client.vpnConfig.RemoteProtocol,
+	}
+}
+
+// DNS returns resolvers of the system, which are used while client is connected
+func (client *Client) DNS() []string {
+	if client.activeDNS == nil {
+		return nil
+	}
+	return client.activeDNS()
 }
 
 //VPNConfig structure represents VPN configuration options for given session
@@ -34,4 +62,13 @@ type VPNConfig struct {
 	RemoteProtocol  string `json:"protocol"`
 	TLSPresharedKey string `json:"TLSPresharedKey"`
 	CACertificate   string `json:"CACertificate"`
+
+	// Ciphers are data channel ciphers supported by provider, in order of preference
+	Ciphers []string `json:"ciphers,omitempty"`
+	// TLSCipher is control channel cipher of provider
+	TLSCipher string `json:"tlsCipher,omitempty"`
+	// RenegotiationSeconds is interval of data channel key renegotiation
+	RenegotiationSeconds int `json:"renegSec,omitempty"`
+	// DNS are resolvers pushed by provider
+	DNS []string `json:"dns,omitempty"`
 }
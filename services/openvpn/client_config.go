@@ -19,6 +19,9 @@ package openvpn
 
 import (
 	"encoding/json"
+	"net"
+	"strconv"
+	"strings"
 
 	"github.com/mysteriumnetwork/go-openvpn/openvpn/config"
 )
@@ -26,6 +29,9 @@ import (
 // ClientConfig represents specific "openvpn as client" configuration
 type ClientConfig struct {
 	*config.GenericConfig
+	VPNConfig *VPNConfig
+	// DNS are resolvers used through the tunnel
+	DNS []string
 }
 
 // SetClientMode adds config arguments for openvpn behave as client
@@ -50,30 +56,96 @@ func (c *ClientConfig) SetProtocol(protocol string) {
 	}
 }
 
+// Defaults are used when provider does not announce its options
+var (
+	// DefaultCiphers are data channel ciphers in order of preference
+	DefaultCiphers = []string{"AES-256-GCM"}
+	// DefaultDNS are resolvers (OpenDNS) used when neither provider nor consumer specify any
+	DefaultDNS = []string{"208.67.222.222", "208.67.220.220"}
+)
+
+const (
+	// DefaultTLSCipher is control channel cipher
+	DefaultTLSCipher = "TLS-ECDHE-ECDSA-WITH-AES-256-GCM-SHA384"
+	// DefaultRenegotiationSeconds is interval of data channel key renegotiation
+	DefaultRenegotiationSeconds = 60
+)
+
+// ClientOptions are preferences of consumer for the connection
+type ClientOptions struct {
+	// DNS are resolvers used instead of resolvers pushed by provider
+	DNS []string
+	// IncludeCIDRs when not empty, only traffic to given networks is routed through VPN
+	IncludeCIDRs []string
+	// ExcludeCIDRs are networks, which traffic bypasses VPN
+	ExcludeCIDRs []string
+}
+
+// SetCiphers sets data channel cipher preferred by provider and ciphers, which can be negotiated with provider
+func (c *ClientConfig) SetCiphers(ciphers []string) {
+	if len(ciphers) == 0 {
+		ciphers = DefaultCiphers
+	}
+	c.SetParam("cipher", ciphers[0])
+	c.SetParam("ncp-ciphers", strings.Join(ciphers, ":"))
+}
+
+// SetRouting routes all traffic through VPN, or only traffic to included networks. Traffic to excluded networks
+// bypasses VPN in both cases.
+func (c *ClientConfig) SetRouting(includeCIDRs, excludeCIDRs []string) {
+	if len(includeCIDRs) == 0 {
+		c.SetParam("redirect-gateway", "def1", "bypass-dhcp")
+	} else {
+		c.SetFlag("route-nopull")
+	}
+
+	for _, cidr := range includeCIDRs {
+		c.setRoute(cidr)
+	}
+	for _, cidr := range excludeCIDRs {
+		c.setRoute(cidr, "net_gateway")
+	}
+}
+
+func (c *ClientConfig) setRoute(cidr string, gateway ...string) {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return
+	}
+	c.SetParam("route", append([]string{network.IP.String(), net.IP(network.Mask).String()}, gateway...)...)
+}
+
+// SetDNS sets resolvers, which are used during connection
+func (c *ClientConfig) SetDNS(servers []string) {
+	c.DNS = servers
+	for _, server := range servers {
+		c.SetParam("dhcp-option", "DNS", server)
+	}
+}
+
 func defaultClientConfig(runtimeDir string, scriptSearchPath string) *ClientConfig {
-	clientConfig := ClientConfig{config.NewConfig(runtimeDir, scriptSearchPath)}
+	clientConfig := ClientConfig{GenericConfig: config.NewConfig(runtimeDir, scriptSearchPath)}
 
 	clientConfig.SetDevice("tun")
-	clientConfig.SetParam("cipher", "AES-256-GCM")
 	clientConfig.SetParam("verb", "3")
-	clientConfig.SetParam("tls-cipher", "TLS-ECDHE-ECDSA-WITH-AES-256-GCM-SHA384")
 	clientConfig.SetKeepAlive(10, 60)
 	clientConfig.SetPingTimerRemote()
 	clientConfig.SetPersistKey()
 
-	clientConfig.SetParam("reneg-sec", "60")
 	clientConfig.SetParam("resolv-retry", "infinite")
-	clientConfig.SetParam("redirect-gateway", "def1", "bypass-dhcp")
-	clientConfig.SetParam("dhcp-option", "DNS", "208.67.222.222")
-	clientConfig.SetParam("dhcp-option", "DNS", "208.67.220.220")
 
 	return &clientConfig
 }
 
 // NewClientConfigFromSession creates client configuration structure for given VPNConfig, configuration dir to store serialized file args, and
-// configuration filename to store other args
+// configuration filename to store other args. Options of provider are overridden by given options of consumer.
 // TODO this will become the part of openvpn service consumer separate package
-func NewClientConfigFromSession(sessionConfig []byte, configDir string, runtimeDir string) (*ClientConfig, error) {
+func NewClientConfigFromSession(
+	sessionConfig []byte,
+	options ClientOptions,
+	configDir string,
+	runtimeDir string,
+) (*ClientConfig, error) {
 	vpnConfig := &VPNConfig{}
 	err := json.Unmarshal(sessionConfig, vpnConfig)
 	if err != nil {
@@ -84,13 +156,44 @@ func NewClientConfigFromSession(sessionConfig []byte, configDir string, runtimeD
 	if err != nil {
 		return nil, err
 	}
+	if err = ValidateClientOptions(options); err != nil {
+		return nil, err
+	}
 
 	clientFileConfig := newClientConfig(runtimeDir, configDir)
+	clientFileConfig.VPNConfig = vpnConfig
 	clientFileConfig.SetReconnectRetry(2)
 	clientFileConfig.SetClientMode(vpnConfig.RemoteIP, vpnConfig.RemotePort)
 	clientFileConfig.SetProtocol(vpnConfig.RemoteProtocol)
 	clientFileConfig.SetTLSCACertificate(vpnConfig.CACertificate)
 	clientFileConfig.SetTLSCrypt(vpnConfig.TLSPresharedKey)
 
+	clientFileConfig.SetCiphers(vpnConfig.Ciphers)
+	clientFileConfig.SetParam("tls-cipher", firstString(vpnConfig.TLSCipher, DefaultTLSCipher))
+	clientFileConfig.SetParam("reneg-sec", strconv.Itoa(firstInt(vpnConfig.RenegotiationSeconds, DefaultRenegotiationSeconds)))
+	clientFileConfig.SetRouting(options.IncludeCIDRs, options.ExcludeCIDRs)
+	switch {
+	case len(options.DNS) > 0:
+		clientFileConfig.SetDNS(options.DNS)
+	case len(vpnConfig.DNS) > 0:
+		clientFileConfig.SetDNS(vpnConfig.DNS)
+	default:
+		clientFileConfig.SetDNS(DefaultDNS)
+	}
+
 	return clientFileConfig, nil
 }
+
+func firstString(value, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
+
+func firstInt(value, defaultValue int) int {
+	if value == 0 {
+		return defaultValue
+	}
+	return value
+}
@@ -1,4 +1,4 @@
-// +build !windows
+// +build !windows,!linux
 
 /*
  * Copyright (C) 2017 The "MysteriumNetwork/node" Authors.
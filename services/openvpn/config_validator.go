@@ -23,10 +23,24 @@ import (
 	"crypto/x509"
 	"encoding/pem"
 	"errors"
+	"fmt"
 	"net"
 	"strings"
 )
 
+// SupportedCiphers are data channel ciphers, which can be negotiated between provider and consumer
+var SupportedCiphers = []string{"AES-256-GCM", "AES-128-GCM", "CHACHA20-POLY1305", "AES-256-CBC", "AES-128-CBC"}
+
+// SupportedTLSCiphers are control channel ciphers, which can be used with ECDSA certificates of provider
+var SupportedTLSCiphers = []string{
+	"TLS-ECDHE-ECDSA-WITH-AES-256-GCM-SHA384",
+	"TLS-ECDHE-ECDSA-WITH-AES-128-GCM-SHA256",
+	"TLS-ECDHE-ECDSA-WITH-CHACHA20-POLY1305-SHA256",
+}
+
+// minRenegotiationSeconds prevents providers from exhausting consumers by too frequent renegotiation
+const minRenegotiationSeconds = 10
+
 // ValidateConfig is function which takes VPNConfig as argument, checks it and returns error if validation fails
 type ValidateConfig func(config *VPNConfig) error
 
@@ -44,6 +58,10 @@ func NewDefaultValidator() *ConfigValidator {
 			validIPFormat,
 			validTLSPresharedKey,
 			validCACertificate,
+			validCiphers,
+			validTLSCipher,
+			validRenegotiation,
+			validDNS,
 		},
 	}
 }
@@ -136,3 +154,73 @@ func validCACertificate(config *VPNConfig) error {
 	}
 	return nil
 }
+
+func validCiphers(config *VPNConfig) error {
+	for _, cipher := range config.Ciphers {
+		if !contains(SupportedCiphers, cipher) {
+			return errors.New("unsupported cipher: " + cipher)
+		}
+	}
+	return nil
+}
+
+func validTLSCipher(config *VPNConfig) error {
+	if config.TLSCipher != "" && !contains(SupportedTLSCiphers, config.TLSCipher) {
+		return errors.New("unsupported TLS cipher: " + config.TLSCipher)
+	}
+	return nil
+}
+
+func validRenegotiation(config *VPNConfig) error {
+	if config.RenegotiationSeconds != 0 && config.RenegotiationSeconds < minRenegotiationSeconds {
+		return fmt.Errorf("renegotiation interval should be at least %d seconds", minRenegotiationSeconds)
+	}
+	return nil
+}
+
+func validDNS(config *VPNConfig) error {
+	return validateIPv4s(config.DNS)
+}
+
+// ValidateClientOptions checks DNS servers and networks chosen by consumer
+func ValidateClientOptions(options ClientOptions) error {
+	if err := validateIPv4s(options.DNS); err != nil {
+		return err
+	}
+	if err := validateIPv4Networks(options.IncludeCIDRs); err != nil {
+		return err
+	}
+	return validateIPv4Networks(options.ExcludeCIDRs)
+}
+
+func validateIPv4Networks(cidrs []string) error {
+	for _, cidr := range cidrs {
+		ip, _, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return errors.New("invalid network " + cidr)
+		}
+		if ip.To4() == nil {
+			return errors.New("IPv4 network is expected: " + cidr)
+		}
+	}
+	return nil
+}
+
+func validateIPv4s(addresses []string) error {
+	for _, address := range addresses {
+		ip := net.ParseIP(address)
+		if ip == nil || ip.To4() == nil {
+			return errors.New("invalid IPv4 address " + address)
+		}
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, listed := range values {
+		if listed == value {
+			return true
+		}
+	}
+	return false
+}
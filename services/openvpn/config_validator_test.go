@@ -50,11 +50,15 @@ YFcPCscvdnZ1U8hTUaREZmDB2w9eaGyCM4YXAg==
 
 func TestValidatorReturnsNilErrorOnValidVPNConfig(t *testing.T) {
 	vpnConfig := &VPNConfig{
-		"1.2.3.4",
-		10999,
-		"tcp",
-		tlsTestKey,
-		caCertificate,
+		RemoteIP:             "1.2.3.4",
+		RemotePort:           10999,
+		RemoteProtocol:       "tcp",
+		TLSPresharedKey:      tlsTestKey,
+		CACertificate:        caCertificate,
+		Ciphers:              []string{"AES-256-GCM", "CHACHA20-POLY1305"},
+		TLSCipher:            DefaultTLSCipher,
+		RenegotiationSeconds: 3600,
+		DNS:                  []string{"10.8.0.1"},
 	}
 	assert.NoError(t, NewDefaultValidator().IsValid(vpnConfig))
 }
@@ -83,3 +87,34 @@ func TestCACertificateIsValid(t *testing.T) {
 	vpnConfig := VPNConfig{CACertificate: caCertificate}
 	assert.NoError(t, validCACertificate(&vpnConfig))
 }
+
+func TestUnsupportedCipherIsNotAllowed(t *testing.T) {
+	assert.NoError(t, validCiphers(&VPNConfig{}))
+	assert.NoError(t, validCiphers(&VPNConfig{Ciphers: []string{"AES-128-GCM"}}))
+	assert.Error(t, validCiphers(&VPNConfig{Ciphers: []string{"AES-128-GCM", "BF-CBC"}}))
+}
+
+func TestUnsupportedTLSCipherIsNotAllowed(t *testing.T) {
+	assert.NoError(t, validTLSCipher(&VPNConfig{}))
+	assert.Error(t, validTLSCipher(&VPNConfig{TLSCipher: "TLS-DHE-RSA-WITH-AES-128-CBC-SHA"}))
+}
+
+func TestTooFrequentRenegotiationIsNotAllowed(t *testing.T) {
+	assert.NoError(t, validRenegotiation(&VPNConfig{}))
+	assert.Error(t, validRenegotiation(&VPNConfig{RenegotiationSeconds: 1}))
+}
+
+func TestDNSShouldBeIPv4(t *testing.T) {
+	assert.NoError(t, validDNS(&VPNConfig{DNS: []string{"1.1.1.1"}}))
+	assert.Error(t, validDNS(&VPNConfig{DNS: []string{"resolver.local"}}))
+	assert.Error(t, validDNS(&VPNConfig{DNS: []string{"2001:4860:4860::8888"}}))
+}
+
+func TestValidateClientOptions(t *testing.T) {
+	assert.NoError(t, ValidateClientOptions(ClientOptions{}))
+	assert.NoError(t, ValidateClientOptions(ClientOptions{DNS: []string{"1.1.1.1"}, IncludeCIDRs: []string{"192.168.0.0/16"}}))
+	assert.Error(t, ValidateClientOptions(ClientOptions{DNS: []string{"one.one"}}))
+	assert.Error(t, ValidateClientOptions(ClientOptions{IncludeCIDRs: []string{"192.168.0.0"}}))
+	assert.Error(t, ValidateClientOptions(ClientOptions{IncludeCIDRs: []string{"2001:db8::/32"}}))
+	assert.Error(t, ValidateClientOptions(ClientOptions{ExcludeCIDRs: []string{"10.0.0.0/33"}}))
+}
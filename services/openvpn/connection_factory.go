@@ -18,6 +18,8 @@
 package openvpn
 
 import (
+	"errors"
+	"net"
 	"time"
 
 	"github.com/mysteriumnetwork/go-openvpn/openvpn/management"
@@ -26,14 +28,20 @@ import (
 	"github.com/mysteriumnetwork/go-openvpn/openvpn/middlewares/state"
 	"github.com/mysteriumnetwork/node/client/stats"
 	"github.com/mysteriumnetwork/node/core/connection"
+	"github.com/mysteriumnetwork/node/core/events"
 	"github.com/mysteriumnetwork/node/core/location"
 	"github.com/mysteriumnetwork/node/identity"
 	"github.com/mysteriumnetwork/node/server"
 	"github.com/mysteriumnetwork/node/services/openvpn/middlewares/client/bytescount"
+	client_dns "github.com/mysteriumnetwork/node/services/openvpn/middlewares/client/dns"
 	openvpn_session "github.com/mysteriumnetwork/node/services/openvpn/session"
+	"github.com/mysteriumnetwork/node/services/openvpn/splittunnel"
 	"github.com/mysteriumnetwork/node/session"
 )
 
+// domainRefreshInterval is how often addresses of split tunnelling domains are resolved again
+const domainRefreshInterval = 5 * time.Minute
+
 // ProcessBasedConnectionFactory represents a factory for creating process-based openvpn connections
 type ProcessBasedConnectionFactory struct {
 	mysteriumAPIClient    server.Client
@@ -42,25 +50,32 @@ type ProcessBasedConnectionFactory struct {
 	runtimeDirectory      string
 	originalLocationCache location.Cache
 	signerFactory         identity.SignerFactory
-	statsKeeper           stats.SessionStatsKeeper
+	eventPublisher        events.Publisher
+	dnsConfigurator       client_dns.Configurator
+	lookup                splittunnel.LookupFunc
+	routeTable            splittunnel.RouteTable
 }
 
 // NewProcessBasedConnectionFactory creates a new ProcessBasedConnectionFactory
 func NewProcessBasedConnectionFactory(
 	mysteriumAPIClient server.Client,
 	openvpnBinary, configDirectory, runtimeDirectory string,
-	statsKeeper stats.SessionStatsKeeper,
 	originalLocationCache location.Cache,
 	signerFactory identity.SignerFactory,
+	eventPublisher events.Publisher,
+	dnsConfigurator client_dns.Configurator,
 ) *ProcessBasedConnectionFactory {
 	return &ProcessBasedConnectionFactory{
 		mysteriumAPIClient:    mysteriumAPIClient,
 		openvpnBinary:         openvpnBinary,
 		configDirectory:       configDirectory,
 		runtimeDirectory:      runtimeDirectory,
-		statsKeeper:           statsKeeper,
 		originalLocationCache: originalLocationCache,
 		signerFactory:         signerFactory,
+		eventPublisher:        eventPublisher,
+		dnsConfigurator:       dnsConfigurator,
+		lookup:                net.LookupIP,
+		routeTable:            splittunnel.NewRouteTable(),
 	}
 }
 
@@ -69,14 +84,14 @@ func (op *ProcessBasedConnectionFactory) newAuthMiddleware(sessionID session.ID,
 	return auth.NewMiddleware(credentialsProvider)
 }
 
-func (op *ProcessBasedConnectionFactory) newBytecountMiddleware() management.Middleware {
-	statsSaver := bytescount.NewSessionStatsSaver(op.statsKeeper)
+func (op *ProcessBasedConnectionFactory) newBytecountMiddleware(statsKeeper stats.SessionStatsKeeper) management.Middleware {
+	statsSaver := bytescount.NewSessionStatsSaver(statsKeeper, op.eventPublisher)
 	return openvpn_bytescount.NewMiddleware(statsSaver, 1*time.Second)
 }
 
 func (op *ProcessBasedConnectionFactory) newStateMiddleware(session session.ID, signer identity.Signer, connectionOptions connection.ConnectOptions, stateChannel connection.StateChannel) management.Middleware {
 	statsSender := stats.NewRemoteStatsSender(
-		op.statsKeeper,
+		connectionOptions.StatsKeeper,
 		op.mysteriumAPIClient,
 		session,
 		connectionOptions.ProviderID,
@@ -90,22 +105,60 @@ func (op *ProcessBasedConnectionFactory) newStateMiddleware(session session.ID,
 
 // CreateConnection implements the connection.ConnectionCreator interface
 func (op *ProcessBasedConnectionFactory) CreateConnection(options connection.ConnectOptions, stateChannel connection.StateChannel) (connection.Connection, error) {
-	vpnClientConfig, err := NewClientConfigFromSession(options.SessionConfig, op.configDirectory, op.runtimeDirectory)
+	splitTunnel := options.Params.SplitTunnel
+	clientOptions := ClientOptions{
+		DNS:          options.Params.DNS,
+		IncludeCIDRs: append(append([]string{}, splitTunnel.IncludeCIDRs...), splittunnel.ResolveCIDRs(splitTunnel.IncludeDomains, op.lookup)...),
+		ExcludeCIDRs: append(append([]string{}, splitTunnel.ExcludeCIDRs...), splittunnel.ResolveCIDRs(splitTunnel.ExcludeDomains, op.lookup)...),
+	}
+	if len(splitTunnel.IncludeCIDRs)+len(splitTunnel.IncludeDomains) > 0 && len(clientOptions.IncludeCIDRs) == 0 {
+		// routing all traffic through VPN instead would surprise consumer
+		return nil, errors.New("none of included domains could be resolved")
+	}
+	vpnClientConfig, err := NewClientConfigFromSession(options.SessionConfig, clientOptions, op.configDirectory, op.runtimeDirectory)
 	if err != nil {
 		return nil, err
 	}
 
 	signer := op.signerFactory(options.ConsumerID)
 
-	stateMiddleware := op.newStateMiddleware(options.SessionID, signer, options, stateChannel)
-	authMiddleware := op.newAuthMiddleware(options.SessionID, signer)
-	byteCountMiddleware := op.newBytecountMiddleware()
+	middlewares := make([]management.Middleware, 0)
+	if len(splitTunnel.IncludeDomains)+len(splitTunnel.ExcludeDomains) > 0 {
+		middlewares = append(middlewares, op.newSplitTunnelMiddleware(splitTunnel, clientOptions, vpnClientConfig.VPNConfig.RemoteIP))
+	}
+	// resolvers are applied before connected state is reported
+	dnsMiddleware := client_dns.NewMiddleware(op.dnsConfigurator, vpnClientConfig.DNS)
+	middlewares = append(
+		middlewares,
+		dnsMiddleware,
+		op.newStateMiddleware(options.SessionID, signer, options, stateChannel),
+		op.newBytecountMiddleware(options.StatsKeeper),
+		op.newAuthMiddleware(options.SessionID, signer),
+	)
+
+	client := NewClient(op.openvpnBinary, vpnClientConfig, middlewares...)
+	client.activeDNS = dnsMiddleware.Active
+	return client, nil
+}
 
-	return NewClient(
-		op.openvpnBinary,
-		vpnClientConfig,
-		stateMiddleware,
-		byteCountMiddleware,
-		authMiddleware,
-	), nil
+// newSplitTunnelMiddleware keeps routes of domains up to date. Included domains are routed like other included networks,
+// excluded ones are routed like VPN server, which is always reached bypassing VPN.
+func (op *ProcessBasedConnectionFactory) newSplitTunnelMiddleware(
+	splitTunnel connection.SplitTunnelParams,
+	clientOptions ClientOptions,
+	serverIP string,
+) management.Middleware {
+	var includedLike string
+	if len(clientOptions.IncludeCIDRs) > 0 {
+		ip, _, _ := net.ParseCIDR(clientOptions.IncludeCIDRs[0])
+		includedLike = ip.String()
+	}
+
+	return splittunnel.NewMiddleware(
+		op.routeTable,
+		op.lookup,
+		domainRefreshInterval,
+		splittunnel.Domains{Names: splitTunnel.IncludeDomains, RoutedLike: includedLike, Routed: clientOptions.IncludeCIDRs},
+		splittunnel.Domains{Names: splitTunnel.ExcludeDomains, RoutedLike: serverIP, Routed: clientOptions.ExcludeCIDRs},
+	)
 }
@@ -18,10 +18,14 @@
 package openvpn
 
 import (
+	"errors"
+	"net"
 	"testing"
 
 	"github.com/mysteriumnetwork/node/core/connection"
+	"github.com/mysteriumnetwork/node/core/events"
 	"github.com/mysteriumnetwork/node/core/location"
+	"github.com/mysteriumnetwork/node/firewall"
 	"github.com/mysteriumnetwork/node/identity"
 	"github.com/mysteriumnetwork/node/server"
 	"github.com/stretchr/testify/assert"
@@ -49,13 +53,18 @@ func (cf *cacheFake) RefreshAndGet() (location.Location, error) {
 
 var _ connection.ConnectionCreator = (&ProcessBasedConnectionFactory{}).CreateConnection
 
+type dnsConfiguratorFake struct{}
+
+func (c *dnsConfiguratorFake) Apply(device string, servers []string) error { return nil }
+func (c *dnsConfiguratorFake) Restore(device string) error                 { return nil }
+
 func fakeSignerFactory(_ identity.Identity) identity.Signer {
 	return &identity.SignerFake{}
 }
 
 func TestConnectionFactory_ErrorsOnInvalidConfig(t *testing.T) {
 	clientFake := server.NewClientFake()
-	factory := NewProcessBasedConnectionFactory(clientFake, "./", "./", "./", &fakeSessionStatsKeeper{}, &cacheFake{}, fakeSignerFactory)
+	factory := NewProcessBasedConnectionFactory(clientFake, "./", "./", "./", &cacheFake{}, fakeSignerFactory, events.NewPublisherFake(), &dnsConfiguratorFake{})
 	channel := make(chan connection.State)
 	connectionOptions := connection.ConnectOptions{}
 	_, err := factory.CreateConnection(connectionOptions, channel)
@@ -65,14 +74,75 @@ func TestConnectionFactory_ErrorsOnInvalidConfig(t *testing.T) {
 
 func TestConnectionFactory_CreatesConnection(t *testing.T) {
 	clientFake := server.NewClientFake()
-	factory := NewProcessBasedConnectionFactory(clientFake, "./", "./", "./", &fakeSessionStatsKeeper{}, &cacheFake{}, fakeSignerFactory)
+	factory := NewProcessBasedConnectionFactory(clientFake, "./", "./", "./", &cacheFake{}, fakeSignerFactory, events.NewPublisherFake(), &dnsConfiguratorFake{})
 	channel := make(chan connection.State)
 	connectionOptions := connection.ConnectOptions{
 		ConsumerID:    identity.Identity{Address: "consumer"},
 		ProviderID:    identity.Identity{Address: "provider"},
 		SessionConfig: fakeSessionConfig,
+		StatsKeeper:   &fakeSessionStatsKeeper{},
 	}
 	conn, err := factory.CreateConnection(connectionOptions, channel)
 	assert.Nil(t, err)
 	assert.NotNil(t, conn)
 }
+
+func TestConnectionFactory_ConnectionExposesServerAddress(t *testing.T) {
+	clientFake := server.NewClientFake()
+	factory := NewProcessBasedConnectionFactory(clientFake, "./", "./", "./", &cacheFake{}, fakeSignerFactory, events.NewPublisherFake(), &dnsConfiguratorFake{})
+	channel := make(chan connection.State)
+	connectionOptions := connection.ConnectOptions{
+		ConsumerID:    identity.Identity{Address: "consumer"},
+		ProviderID:    identity.Identity{Address: "provider"},
+		SessionConfig: fakeSessionConfig,
+		StatsKeeper:   &fakeSessionStatsKeeper{},
+	}
+	conn, err := factory.CreateConnection(connectionOptions, channel)
+	assert.NoError(t, err)
+
+	addressProvider, ok := conn.(connection.ServerAddressProvider)
+	assert.True(t, ok)
+	assert.Equal(
+		t,
+		firewall.ServerAddress{IP: "1.2.3.4", Port: 10999, Protocol: "tcp"},
+		addressProvider.ServerAddress(),
+	)
+}
+
+func TestConnectionFactory_ErrorsWhenIncludedDomainsAreNotResolved(t *testing.T) {
+	clientFake := server.NewClientFake()
+	factory := NewProcessBasedConnectionFactory(clientFake, "./", "./", "./", &cacheFake{}, fakeSignerFactory, events.NewPublisherFake(), &dnsConfiguratorFake{})
+	factory.lookup = func(host string) ([]net.IP, error) {
+		return nil, errors.New("no such host")
+	}
+	channel := make(chan connection.State)
+	connectionOptions := connection.ConnectOptions{
+		ConsumerID:    identity.Identity{Address: "consumer"},
+		ProviderID:    identity.Identity{Address: "provider"},
+		SessionConfig: fakeSessionConfig,
+		StatsKeeper:   &fakeSessionStatsKeeper{},
+		Params: connection.ConnectParams{
+			SplitTunnel: connection.SplitTunnelParams{IncludeDomains: []string{"unknown.host"}},
+		},
+	}
+	_, err := factory.CreateConnection(connectionOptions, channel)
+	assert.EqualError(t, err, "none of included domains could be resolved")
+}
+
+func TestConnectionFactory_ConnectionExposesDNS(t *testing.T) {
+	clientFake := server.NewClientFake()
+	factory := NewProcessBasedConnectionFactory(clientFake, "./", "./", "./", &cacheFake{}, fakeSignerFactory, events.NewPublisherFake(), &dnsConfiguratorFake{})
+	channel := make(chan connection.State)
+	connectionOptions := connection.ConnectOptions{
+		ConsumerID:    identity.Identity{Address: "consumer"},
+		ProviderID:    identity.Identity{Address: "provider"},
+		SessionConfig: fakeSessionConfig,
+		StatsKeeper:   &fakeSessionStatsKeeper{},
+	}
+	conn, err := factory.CreateConnection(connectionOptions, channel)
+	assert.NoError(t, err)
+
+	dnsProvider, ok := conn.(connection.DNSProvider)
+	assert.True(t, ok)
+	assert.Nil(t, dnsProvider.DNS(), "resolvers are not applied until connected")
+}
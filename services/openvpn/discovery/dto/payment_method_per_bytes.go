@@ -37,3 +37,8 @@ type PaymentPerBytes struct {
 func (method PaymentPerBytes) GetPrice() money.Money {
 	return method.Price
 }
+
+// GetBytes returns amount of bytes provided for paid price
+func (method PaymentPerBytes) GetBytes() uint64 {
+	return uint64(method.Bytes.Bytes())
+}
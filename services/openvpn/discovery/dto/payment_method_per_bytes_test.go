@@ -21,11 +21,19 @@ import (
 	"encoding/json"
 	"testing"
 
+	"github.com/mysteriumnetwork/node/core/promise"
 	"github.com/mysteriumnetwork/node/datasize"
 	"github.com/mysteriumnetwork/node/money"
 	"github.com/stretchr/testify/assert"
 )
 
+var _ promise.ByteMeteredPayment = PaymentPerBytes{}
+
+func TestPaymentMethodPerBytesReportsBytes(t *testing.T) {
+	method := PaymentPerBytes{Bytes: datasize.BitSize(8 * 1024)}
+	assert.Equal(t, uint64(1024), method.GetBytes())
+}
+
 var (
 	price = money.NewMoney(0.5, money.CURRENCY_MYST)
 )
@@ -22,10 +22,13 @@ import (
 	"testing"
 	"time"
 
+	"github.com/mysteriumnetwork/node/core/promise"
 	"github.com/mysteriumnetwork/node/money"
 	"github.com/stretchr/testify/assert"
 )
 
+var _ promise.TimeMeteredPayment = PaymentPerTime{}
+
 func TestPaymentMethodPerTimeSerialize(t *testing.T) {
 	price := money.NewMoney(0.5, money.CURRENCY_MYST)
 
@@ -18,6 +18,7 @@
 package dto
 
 import (
+	"github.com/mysteriumnetwork/node/nat"
 	dto_discovery "github.com/mysteriumnetwork/node/service_discovery/dto"
 )
 
@@ -34,9 +35,20 @@ type ServiceDefinition struct {
 
 	// Transport protocol used by service
 	Protocol string `json:"protocol,omitempty"`
+
+	// Data channel ciphers supported by service, in order of preference
+	Ciphers []string `json:"ciphers,omitempty"`
+
+	// Destinations, which consumers may reach through the service
+	TrafficPolicy *nat.Policy `json:"traffic_policy,omitempty"`
 }
 
 // GetLocation returns geographic location of service definition provider
 func (service ServiceDefinition) GetLocation() dto_discovery.Location {
 	return service.Location
 }
+
+// GetProtocol returns transport protocol used by service
+func (service ServiceDefinition) GetProtocol() string {
+	return service.Protocol
+}
@@ -22,6 +22,7 @@ import (
 	"testing"
 
 	"github.com/mysteriumnetwork/node/datasize"
+	"github.com/mysteriumnetwork/node/nat"
 	dto_discovery "github.com/mysteriumnetwork/node/service_discovery/dto"
 	"github.com/stretchr/testify/assert"
 )
@@ -44,6 +45,7 @@ func TestServiceDefinitionSerialize(t *testing.T) {
 				LocationOriginate: locationUS,
 				SessionBandwidth:  Bandwidth(10 * datasize.Bit),
 				Protocol:          protocol,
+				TrafficPolicy:     &nat.Policy{DeniedPorts: []int{25}},
 			},
 			`{
 				"location": {
@@ -53,7 +55,11 @@ func TestServiceDefinitionSerialize(t *testing.T) {
 					"country": "US"
 				},
 				"session_bandwidth": 10,
-				"protocol": "tcp"
+				"protocol": "tcp",
+				"traffic_policy": {
+					"allow_local_networks": false,
+					"denied_ports": [25]
+				}
 			}`,
 		},
 		{
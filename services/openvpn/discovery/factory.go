@@ -22,6 +22,7 @@ import (
 
 	"github.com/mysteriumnetwork/node/datasize"
 	"github.com/mysteriumnetwork/node/money"
+	"github.com/mysteriumnetwork/node/nat"
 	dto_discovery "github.com/mysteriumnetwork/node/service_discovery/dto"
 	"github.com/mysteriumnetwork/node/services/openvpn"
 	"github.com/mysteriumnetwork/node/services/openvpn/discovery/dto"
@@ -31,14 +32,19 @@ import (
 func NewServiceProposalWithLocation(
 	serviceLocation dto_discovery.Location,
 	protocol string,
+	sessionBandwidth datasize.BitSize,
+	ciphers []string,
+	trafficPolicy nat.Policy,
 ) dto_discovery.ServiceProposal {
 	return dto_discovery.ServiceProposal{
 		ServiceType: openvpn.ServiceType,
 		ServiceDefinition: dto.ServiceDefinition{
 			Location:          serviceLocation,
 			LocationOriginate: serviceLocation,
-			SessionBandwidth:  dto.Bandwidth(10 * datasize.MB),
+			SessionBandwidth:  dto.Bandwidth(sessionBandwidth),
 			Protocol:          protocol,
+			Ciphers:           ciphers,
+			TrafficPolicy:     &trafficPolicy,
 		},
 		PaymentMethodType: dto.PaymentMethodPerTime,
 		PaymentMethod: dto.PaymentPerTime{
@@ -21,7 +21,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/mysteriumnetwork/node/datasize"
 	"github.com/mysteriumnetwork/node/money"
+	"github.com/mysteriumnetwork/node/nat"
 	dto_discovery "github.com/mysteriumnetwork/node/service_discovery/dto"
 	"github.com/mysteriumnetwork/node/services/openvpn/discovery/dto"
 	"github.com/stretchr/testify/assert"
@@ -33,7 +35,7 @@ var (
 )
 
 func Test_NewServiceProposalWithLocation(t *testing.T) {
-	proposal := NewServiceProposalWithLocation(locationLTTelia, protocol)
+	proposal := NewServiceProposalWithLocation(locationLTTelia, protocol, 10*datasize.MB, []string{"AES-256-GCM"}, nat.Policy{DeniedPorts: []int{25}})
 
 	assert.Exactly(
 		t,
@@ -44,6 +46,8 @@ func Test_NewServiceProposalWithLocation(t *testing.T) {
 				LocationOriginate: locationLTTelia,
 				SessionBandwidth:  83886080,
 				Protocol:          "tcp",
+				Ciphers:           []string{"AES-256-GCM"},
+				TrafficPolicy:     &nat.Policy{DeniedPorts: []int{25}},
 			},
 
 			PaymentMethodType: "PER_TIME",
@@ -0,0 +1,176 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package dnsproxy
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/cihub/seelog"
+)
+
+const logPrefix = "[dns-proxy] "
+
+const (
+	bindRetryInterval = time.Second
+	queryTimeout      = 5 * time.Second
+	maxPacketSize     = 4096
+)
+
+// Proxy forwards DNS queries of VPN clients over UDP to resolver of the provider
+type Proxy struct {
+	upstream string
+
+	lock sync.Mutex
+	conn net.PacketConn
+	stop chan struct{}
+}
+
+// NewProxy creates proxy, which forwards queries to given upstream resolver address (host:port)
+func NewProxy(upstream string) *Proxy {
+	return &Proxy{upstream: upstream}
+}
+
+// Start serves DNS on given address in background.
+// Binding is retried until address becomes available, i.e. until tun device of the server is up.
+func (proxy *Proxy) Start(address string) {
+	proxy.lock.Lock()
+	defer proxy.lock.Unlock()
+
+	if proxy.stop != nil {
+		return
+	}
+	proxy.stop = make(chan struct{})
+	go proxy.serve(address, proxy.stop)
+}
+
+// Stop stops serving DNS, it does nothing when proxy is not started
+func (proxy *Proxy) Stop() {
+	proxy.lock.Lock()
+	defer proxy.lock.Unlock()
+
+	if proxy.stop == nil {
+		return
+	}
+	close(proxy.stop)
+	proxy.stop = nil
+	if proxy.conn != nil {
+		proxy.conn.Close()
+		proxy.conn = nil
+	}
+}
+
+func (proxy *Proxy) serve(address string, stop chan struct{}) {
+	conn, ok := proxy.listen(address, stop)
+	if !ok {
+		return
+	}
+	log.Info(logPrefix, "Serving DNS on ", address, ", forwarding to ", proxy.upstream)
+
+	buffer := make([]byte, maxPacketSize)
+	for {
+		size, client, err := conn.ReadFrom(buffer)
+		if err != nil {
+			select {
+			case <-stop:
+			default:
+				log.Error(logPrefix, "Failed to read DNS query: ", err)
+			}
+			return
+		}
+
+		query := make([]byte, size)
+		copy(query, buffer[:size])
+		go proxy.forward(conn, client, query)
+	}
+}
+
+func (proxy *Proxy) listen(address string, stop chan struct{}) (net.PacketConn, bool) {
+	for {
+		conn, err := net.ListenPacket("udp", address)
+		if err == nil {
+			proxy.lock.Lock()
+			defer proxy.lock.Unlock()
+
+			select {
+			case <-stop:
+				conn.Close()
+				return nil, false
+			default:
+				proxy.conn = conn
+				return conn, true
+			}
+		}
+
+		select {
+		case <-stop:
+			return nil, false
+		case <-time.After(bindRetryInterval):
+		}
+	}
+}
+
+func (proxy *Proxy) forward(conn net.PacketConn, client net.Addr, query []byte) {
+	upstream, err := net.DialTimeout("udp", proxy.upstream, queryTimeout)
+	if err != nil {
+		log.Warn(logPrefix, "Failed to reach resolver: ", err)
+		return
+	}
+	defer upstream.Close()
+
+	upstream.SetDeadline(time.Now().Add(queryTimeout))
+	if _, err = upstream.Write(query); err != nil {
+		log.Warn(logPrefix, "Failed to forward DNS query: ", err)
+		return
+	}
+
+	response := make([]byte, maxPacketSize)
+	size, err := upstream.Read(response)
+	if err != nil {
+		log.Debug(logPrefix, "No answer from resolver: ", err)
+		return
+	}
+	if _, err = conn.WriteTo(response[:size], client); err != nil {
+		log.Debug(logPrefix, "Failed to answer DNS query: ", err)
+	}
+}
+
+// SystemResolver returns address of the first nameserver configured in given resolv.conf, or fallback server when there is none
+func SystemResolver(resolvConf string, fallback string) string {
+	server := fallback
+
+	file, err := os.Open(resolvConf)
+	if err == nil {
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) >= 2 && fields[0] == "nameserver" && net.ParseIP(fields[1]) != nil {
+				server = fields[1]
+				break
+			}
+		}
+	}
+
+	return net.JoinHostPort(server, "53")
+}
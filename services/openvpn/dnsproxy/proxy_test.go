@@ -0,0 +1,96 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package dnsproxy
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProxy_ForwardsQueries(t *testing.T) {
+	upstream, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer upstream.Close()
+	go func() {
+		buffer := make([]byte, maxPacketSize)
+		for {
+			size, client, err := upstream.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			upstream.WriteTo(append([]byte("answer:"), buffer[:size]...), client)
+		}
+	}()
+
+	address := freeAddress(t)
+	proxy := NewProxy(upstream.LocalAddr().String())
+	proxy.Start(address)
+	defer proxy.Stop()
+
+	client, err := net.Dial("udp", address)
+	assert.NoError(t, err)
+	defer client.Close()
+
+	response := make([]byte, maxPacketSize)
+	var size int
+	for attempt := 0; attempt < 50; attempt++ {
+		client.Write([]byte("query"))
+		client.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+		if size, err = client.Read(response); err == nil {
+			break
+		}
+		// proxy may be not bound yet
+		time.Sleep(20 * time.Millisecond)
+	}
+	assert.NoError(t, err)
+	assert.Equal(t, "answer:query", string(response[:size]))
+}
+
+func TestProxy_StopWithoutStart(t *testing.T) {
+	proxy := NewProxy("127.0.0.1:53")
+	proxy.Stop()
+	proxy.Start(freeAddress(t))
+	proxy.Stop()
+	proxy.Stop()
+}
+
+func TestSystemResolver(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dnsproxy")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	resolvConf := filepath.Join(dir, "resolv.conf")
+	err = ioutil.WriteFile(resolvConf, []byte("# comment\nsearch lan\nnameserver 192.168.1.1\nnameserver 8.8.8.8\n"), 0600)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "192.168.1.1:53", SystemResolver(resolvConf, "1.1.1.1"))
+	assert.Equal(t, "1.1.1.1:53", SystemResolver(filepath.Join(dir, "missing"), "1.1.1.1"))
+}
+
+func freeAddress(t *testing.T) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer conn.Close()
+	return conn.LocalAddr().String()
+}
@@ -20,12 +20,15 @@ package bytescount
 import (
 	"github.com/mysteriumnetwork/go-openvpn/openvpn/middlewares/client/bytescount"
 	"github.com/mysteriumnetwork/node/client/stats"
+	"github.com/mysteriumnetwork/node/core/events"
 )
 
-// NewSessionStatsSaver returns stats handler, which saves stats stats keeper
-func NewSessionStatsSaver(statsKeeper stats.SessionStatsKeeper) bytescount.SessionStatsHandler {
+// NewSessionStatsSaver returns stats handler, which saves stats to stats keeper and publishes them as event
+func NewSessionStatsSaver(statsKeeper stats.SessionStatsKeeper, eventPublisher events.Publisher) bytescount.SessionStatsHandler {
 	return func(bc bytescount.Bytecount) error {
-		statsKeeper.Save(stats.SessionStats{BytesSent: uint64(bc.BytesOut), BytesReceived: uint64(bc.BytesIn)})
+		sessionStats := stats.SessionStats{BytesSent: uint64(bc.BytesOut), BytesReceived: uint64(bc.BytesIn)}
+		statsKeeper.Save(sessionStats)
+		eventPublisher.Publish(stats.SessionStatsEventTopic, sessionStats)
 		return nil
 	}
 }
@@ -23,14 +23,22 @@ import (
 
 	"github.com/mysteriumnetwork/go-openvpn/openvpn/middlewares/client/bytescount"
 	"github.com/mysteriumnetwork/node/client/stats"
+	"github.com/mysteriumnetwork/node/core/events"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestNewSessionStatsSaver(t *testing.T) {
 	statsKeeper := stats.NewSessionStatsKeeper(time.Now)
 
-	saver := NewSessionStatsSaver(statsKeeper)
-	stats := stats.SessionStats{BytesSent: 1, BytesReceived: 2}
+	publisher := events.NewPublisherFake()
+
+	saver := NewSessionStatsSaver(statsKeeper, publisher)
+	sessionStats := stats.SessionStats{BytesSent: 1, BytesReceived: 2}
 	saver(bytescount.Bytecount{BytesOut: 1, BytesIn: 2})
-	assert.Equal(t, stats, statsKeeper.Retrieve())
+	assert.Equal(t, sessionStats, statsKeeper.Retrieve())
+	assert.Equal(
+		t,
+		[]events.Event{{Topic: stats.SessionStatsEventTopic, Payload: sessionStats}},
+		publisher.Published(),
+	)
 }
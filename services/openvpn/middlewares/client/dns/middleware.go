@@ -0,0 +1,148 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package dns
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"sync"
+
+	log "github.com/cihub/seelog"
+	"github.com/mysteriumnetwork/go-openvpn/openvpn/management"
+)
+
+const logPrefix = "[openvpn-dns] "
+
+// Configurator takes over DNS of the system
+type Configurator interface {
+	Apply(device string, servers []string) error
+	Restore(device string) error
+}
+
+// DeviceFinder returns name of network device, which has given address
+type DeviceFinder func(ip net.IP) (string, error)
+
+// Middleware points DNS of the system to resolvers of the tunnel once client is connected, and restores it when client stops
+type Middleware struct {
+	configurator Configurator
+	servers      []string
+	findDevice   DeviceFinder
+
+	lock   sync.Mutex
+	device string
+}
+
+// NewMiddleware creates management middleware, which applies given resolvers on tunnel device
+func NewMiddleware(configurator Configurator, servers []string) *Middleware {
+	return &Middleware{
+		configurator: configurator,
+		servers:      servers,
+		findDevice:   findDeviceByIP,
+	}
+}
+
+// Start does nothing, resolvers are applied once tunnel is up
+func (m *Middleware) Start(connection management.Connection) error {
+	return nil
+}
+
+// Stop restores resolvers of the system
+func (m *Middleware) Stop(connection management.Connection) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.restore()
+	return nil
+}
+
+// ConsumeLine applies resolvers on connected state ">STATE:{time},CONNECTED,SUCCESS,{local ip},{remote ip},...",
+// the line is left for other middlewares
+func (m *Middleware) ConsumeLine(line string) (bool, error) {
+	if !strings.HasPrefix(line, ">STATE:") {
+		return false, nil
+	}
+	fields := strings.Split(strings.TrimPrefix(line, ">STATE:"), ",")
+	if len(fields) < 4 || fields[1] != "CONNECTED" {
+		return false, nil
+	}
+
+	device, err := m.findDevice(net.ParseIP(fields[3]))
+	if err != nil {
+		log.Warn(logPrefix, "DNS of the system is not protected, tunnel device not found: ", err)
+		return false, nil
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.device != "" && m.device != device {
+		// tunnel device changed while reconnecting
+		m.restore()
+	}
+	if err := m.configurator.Apply(device, m.servers); err != nil {
+		log.Warn(logPrefix, "DNS of the system is not protected: ", err)
+		return false, nil
+	}
+	m.device = device
+	return false, nil
+}
+
+// Active returns resolvers of the system, which are applied by the middleware
+func (m *Middleware) Active() []string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.device == "" {
+		return nil
+	}
+	return m.servers
+}
+
+func (m *Middleware) restore() {
+	if m.device == "" {
+		return
+	}
+	if err := m.configurator.Restore(m.device); err != nil {
+		log.Warn(logPrefix, "Failed to restore DNS of the system: ", err)
+	}
+	m.device = ""
+}
+
+func findDeviceByIP(ip net.IP) (string, error) {
+	if ip == nil {
+		return "", errors.New("invalid tunnel address")
+	}
+
+	devices, err := net.Interfaces()
+	if err != nil {
+		return "", err
+	}
+	for _, device := range devices {
+		addresses, err := device.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, address := range addresses {
+			if network, ok := address.(*net.IPNet); ok && network.IP.Equal(ip) {
+				return device.Name, nil
+			}
+		}
+	}
+	return "", errors.New("no device with address " + ip.String())
+}
@@ -0,0 +1,98 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package dns
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/mysteriumnetwork/go-openvpn/openvpn/management"
+	"github.com/stretchr/testify/assert"
+)
+
+type configuratorFake struct {
+	applied  map[string][]string
+	restored []string
+	applyErr error
+}
+
+func (c *configuratorFake) Apply(device string, servers []string) error {
+	if c.applyErr != nil {
+		return c.applyErr
+	}
+	c.applied[device] = servers
+	return nil
+}
+
+func (c *configuratorFake) Restore(device string) error {
+	c.restored = append(c.restored, device)
+	return nil
+}
+
+func newTestMiddleware(configurator Configurator) *Middleware {
+	middleware := NewMiddleware(configurator, []string{"1.1.1.1"})
+	middleware.findDevice = func(ip net.IP) (string, error) {
+		switch ip.String() {
+		case "10.8.0.2":
+			return "tun0", nil
+		case "10.8.0.3":
+			return "tun1", nil
+		}
+		return "", errors.New("no device")
+	}
+	return middleware
+}
+
+func TestMiddleware_AppliesResolversWhenConnected(t *testing.T) {
+	configurator := &configuratorFake{applied: make(map[string][]string)}
+	middleware := newTestMiddleware(configurator)
+	connection := &management.MockConnection{}
+	assert.NoError(t, middleware.Start(connection))
+
+	consumed, err := middleware.ConsumeLine(">STATE:1522855903,ASSIGN_IP,,10.8.0.2,,,,")
+	assert.NoError(t, err)
+	assert.False(t, consumed)
+	assert.Empty(t, configurator.applied)
+	assert.Nil(t, middleware.Active())
+
+	consumed, err = middleware.ConsumeLine(">STATE:1522855903,CONNECTED,SUCCESS,10.8.0.2,1.2.3.4,1194,,")
+	assert.NoError(t, err)
+	assert.False(t, consumed)
+	assert.Equal(t, map[string][]string{"tun0": {"1.1.1.1"}}, configurator.applied)
+	assert.Equal(t, []string{"1.1.1.1"}, middleware.Active())
+
+	// tunnel is recreated on other device after reconnect
+	middleware.ConsumeLine(">STATE:1522855910,CONNECTED,SUCCESS,10.8.0.3,1.2.3.4,1194,,")
+	assert.Equal(t, []string{"tun0"}, configurator.restored)
+
+	assert.NoError(t, middleware.Stop(connection))
+	assert.Equal(t, []string{"tun0", "tun1"}, configurator.restored)
+	assert.Nil(t, middleware.Active())
+}
+
+func TestMiddleware_ReportsNoResolversWhenNotApplied(t *testing.T) {
+	configurator := &configuratorFake{applied: make(map[string][]string), applyErr: errors.New("unsupported")}
+	middleware := newTestMiddleware(configurator)
+
+	middleware.ConsumeLine(">STATE:1522855903,CONNECTED,SUCCESS,10.8.0.2,1.2.3.4,1194,,")
+	assert.Nil(t, middleware.Active())
+
+	assert.NoError(t, middleware.Stop(&management.MockConnection{}))
+	assert.Empty(t, configurator.restored)
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package bytescount
+
+import (
+	"regexp"
+	"strconv"
+	"time"
+
+	log "github.com/cihub/seelog"
+	"github.com/mysteriumnetwork/go-openvpn/openvpn/management"
+)
+
+const logPrefix = "[server-bytescount] "
+
+// clientBytecountLine is sent by OpenVPN server for every client in given interval, i.e.
+// ">BYTECOUNT_CLI:{CID},{BYTES_IN},{BYTES_OUT}"
+var clientBytecountLine = regexp.MustCompile(`^>BYTECOUNT_CLI:(\d+),(\d+),(\d+)$`)
+
+// ClientStatsHandler is invoked with total traffic counters of OpenVPN client
+type ClientStatsHandler func(clientID int, bytesIn, bytesOut uint64) error
+
+type middleware struct {
+	statsHandler ClientStatsHandler
+	interval     time.Duration
+}
+
+// NewMiddleware returns server middleware, which polls traffic counters of every connected client
+func NewMiddleware(statsHandler ClientStatsHandler, interval time.Duration) management.Middleware {
+	return &middleware{
+		statsHandler: statsHandler,
+		interval:     interval,
+	}
+}
+
+func (m *middleware) Start(connection management.Connection) error {
+	_, err := connection.SingleLineCommand("bytecount %d", int(m.interval.Seconds()))
+	return err
+}
+
+func (m *middleware) Stop(connection management.Connection) error {
+	_, err := connection.SingleLineCommand("bytecount %d", 0)
+	return err
+}
+
+func (m *middleware) ConsumeLine(line string) (bool, error) {
+	match := clientBytecountLine.FindStringSubmatch(line)
+	if len(match) == 0 {
+		return false, nil
+	}
+
+	clientID, err := strconv.Atoi(match[1])
+	if err != nil {
+		return true, err
+	}
+	bytesIn, err := strconv.ParseUint(match[2], 10, 64)
+	if err != nil {
+		return true, err
+	}
+	bytesOut, err := strconv.ParseUint(match[3], 10, 64)
+	if err != nil {
+		return true, err
+	}
+
+	// counters of clients, which are not authenticated yet, have no session to be saved to
+	if err := m.statsHandler(clientID, bytesIn, bytesOut); err != nil {
+		log.Debug(logPrefix, "Client traffic not saved: ", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package bytescount
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mysteriumnetwork/go-openvpn/openvpn/management"
+	"github.com/stretchr/testify/assert"
+)
+
+type clientStats struct {
+	clientID          int
+	bytesIn, bytesOut uint64
+}
+
+type statsRecorder struct {
+	stats []clientStats
+}
+
+func (recorder *statsRecorder) handle(clientID int, bytesIn, bytesOut uint64) error {
+	recorder.stats = append(recorder.stats, clientStats{clientID, bytesIn, bytesOut})
+	return nil
+}
+
+func TestMiddleware_StartAndStop(t *testing.T) {
+	recorder := &statsRecorder{}
+	middleware := NewMiddleware(recorder.handle, 5*time.Second)
+	connection := &management.MockConnection{}
+
+	assert.NoError(t, middleware.Start(connection))
+	assert.Equal(t, "bytecount 5", connection.LastLine)
+
+	assert.NoError(t, middleware.Stop(connection))
+	assert.Equal(t, "bytecount 0", connection.LastLine)
+}
+
+func TestMiddleware_ConsumeLine(t *testing.T) {
+	var tests = []struct {
+		line          string
+		expectConsume bool
+		expectStats   []clientStats
+	}{
+		{">BYTECOUNT_CLI:3,1024,4096", true, []clientStats{{3, 1024, 4096}}},
+		{">BYTECOUNT_CLI:18446744073709551615,1,2", true, nil},
+		{">BYTECOUNT:1024,4096", false, nil},
+		{">CLIENT:ESTABLISHED,3", false, nil},
+	}
+
+	for _, test := range tests {
+		recorder := &statsRecorder{}
+		middleware := NewMiddleware(recorder.handle, 5*time.Second)
+
+		consumed, _ := middleware.ConsumeLine(test.line)
+		assert.Equal(t, test.expectConsume, consumed, test.line)
+		assert.Equal(t, test.expectStats, recorder.stats, test.line)
+	}
+}
@@ -0,0 +1,194 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package pki
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+)
+
+const (
+	caValidity     = 10 * 365 * 24 * time.Hour
+	serverValidity = 365 * 24 * time.Hour
+	// presharedKeySize is the size of OpenVPN static key in bytes
+	presharedKeySize = 256
+)
+
+// Material is TLS material of OpenVPN server in PEM format.
+// Certificate authority is long lived, while server certificate and pre-shared key are rotated.
+type Material struct {
+	CACertificate     string `json:"ca_certificate"`
+	CAKey             string `json:"ca_key"`
+	ServerCertificate string `json:"server_certificate"`
+	ServerKey         string `json:"server_key"`
+	PresharedKey      string `json:"preshared_key"`
+	// IssuedAt is the time, when server certificate and pre-shared key were issued
+	IssuedAt time.Time `json:"issued_at"`
+	// ExpiresAt is the time, when server certificate expires
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// NewMaterial creates certificate authority and issues server certificate with pre-shared key
+func NewMaterial(caSubject, serverSubject pkix.Name, now time.Time) (Material, error) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return Material{}, err
+	}
+
+	template := &x509.Certificate{
+		Subject:               caSubject,
+		NotBefore:             now,
+		NotAfter:              now.Add(caValidity),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+	}
+	caCertificate, err := createCertificate(template, template, caKey, caKey)
+	if err != nil {
+		return Material{}, err
+	}
+	caKeyPEM, err := keyToPEM(caKey)
+	if err != nil {
+		return Material{}, err
+	}
+
+	material := Material{
+		CACertificate: caCertificate,
+		CAKey:         caKeyPEM,
+	}
+	return material.Rotate(serverSubject, now)
+}
+
+// Rotate returns material with new server certificate and pre-shared key, issued by the same certificate authority
+func (material Material) Rotate(serverSubject pkix.Name, now time.Time) (Material, error) {
+	caCertificate, caKey, err := material.parseAuthority()
+	if err != nil {
+		return Material{}, err
+	}
+
+	serverKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return Material{}, err
+	}
+	expiresAt := now.Add(serverValidity)
+	if expiresAt.After(caCertificate.NotAfter) {
+		expiresAt = caCertificate.NotAfter
+	}
+	template := &x509.Certificate{
+		Subject:     serverSubject,
+		NotBefore:   now,
+		NotAfter:    expiresAt,
+		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyAgreement,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	serverCertificate, err := createCertificate(template, caCertificate, serverKey, caKey)
+	if err != nil {
+		return Material{}, err
+	}
+	serverKeyPEM, err := keyToPEM(serverKey)
+	if err != nil {
+		return Material{}, err
+	}
+	presharedKey, err := newPresharedKey()
+	if err != nil {
+		return Material{}, err
+	}
+
+	material.ServerCertificate = serverCertificate
+	material.ServerKey = serverKeyPEM
+	material.PresharedKey = presharedKey
+	material.IssuedAt = now
+	material.ExpiresAt = expiresAt
+	return material, nil
+}
+
+// RotationDue returns true, when server certificate and pre-shared key are older than given interval
+// or server certificate expires within the interval. Zero interval disables rotation before expiration.
+func (material Material) RotationDue(interval time.Duration, now time.Time) bool {
+	if now.After(material.ExpiresAt.Add(-24 * time.Hour)) {
+		return true
+	}
+	return interval > 0 && !now.Before(material.IssuedAt.Add(interval))
+}
+
+func (material Material) parseAuthority() (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	certificateBlock, _ := pem.Decode([]byte(material.CACertificate))
+	if certificateBlock == nil {
+		return nil, nil, errors.New("invalid CA certificate")
+	}
+	certificate, err := x509.ParseCertificate(certificateBlock.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyBlock, _ := pem.Decode([]byte(material.CAKey))
+	if keyBlock == nil {
+		return nil, nil, errors.New("invalid CA key")
+	}
+	key, err := x509.ParseECPrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	return certificate, key, nil
+}
+
+func createCertificate(template, parent *x509.Certificate, key, parentKey *ecdsa.PrivateKey) (string, error) {
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return "", err
+	}
+	template.SerialNumber = serialNumber
+
+	certificateBytes, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificateBytes})), nil
+}
+
+func keyToPEM(key *ecdsa.PrivateKey) (string, error) {
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes})), nil
+}
+
+// newPresharedKey generates key in OpenVPN static key format, used by tls-crypt
+func newPresharedKey() (string, error) {
+	key := make([]byte, presharedKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+
+	lines := []string{"-----BEGIN OpenVPN Static key V1-----"}
+	for i := 0; i < len(key); i += 16 {
+		lines = append(lines, hex.EncodeToString(key[i:i+16]))
+	}
+	lines = append(lines, "-----END OpenVPN Static key V1-----", "")
+	return strings.Join(lines, "\n"), nil
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package pki
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	caSubject     = pkix.Name{CommonName: "CA"}
+	serverSubject = pkix.Name{CommonName: "0x1"}
+	issuedAt      = time.Date(2018, 12, 1, 10, 0, 0, 0, time.UTC)
+)
+
+func parseCertificate(t *testing.T, certificatePEM string) *x509.Certificate {
+	block, _ := pem.Decode([]byte(certificatePEM))
+	assert.NotNil(t, block)
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	assert.NoError(t, err)
+	return certificate
+}
+
+func TestNewMaterial(t *testing.T) {
+	material, err := NewMaterial(caSubject, serverSubject, issuedAt)
+	assert.NoError(t, err)
+
+	ca := parseCertificate(t, material.CACertificate)
+	assert.True(t, ca.IsCA)
+	assert.Equal(t, "CA", ca.Subject.CommonName)
+
+	server := parseCertificate(t, material.ServerCertificate)
+	assert.Equal(t, "0x1", server.Subject.CommonName)
+	assert.NoError(t, server.CheckSignatureFrom(ca))
+	assert.Equal(t, issuedAt, material.IssuedAt)
+	assert.Equal(t, issuedAt.Add(serverValidity), material.ExpiresAt)
+
+	assert.Contains(t, material.ServerKey, "EC PRIVATE KEY")
+	assert.Contains(t, material.PresharedKey, "-----BEGIN OpenVPN Static key V1-----\n")
+	assert.Len(t, material.PresharedKey, 38+16*33+36)
+}
+
+func TestMaterial_RotateKeepsAuthority(t *testing.T) {
+	material, err := NewMaterial(caSubject, serverSubject, issuedAt)
+	assert.NoError(t, err)
+
+	rotatedAt := issuedAt.Add(time.Hour)
+	rotated, err := material.Rotate(serverSubject, rotatedAt)
+	assert.NoError(t, err)
+
+	assert.Equal(t, material.CACertificate, rotated.CACertificate)
+	assert.Equal(t, material.CAKey, rotated.CAKey)
+	assert.NotEqual(t, material.ServerCertificate, rotated.ServerCertificate)
+	assert.NotEqual(t, material.ServerKey, rotated.ServerKey)
+	assert.NotEqual(t, material.PresharedKey, rotated.PresharedKey)
+	assert.Equal(t, rotatedAt, rotated.IssuedAt)
+
+	ca := parseCertificate(t, rotated.CACertificate)
+	assert.NoError(t, parseCertificate(t, rotated.ServerCertificate).CheckSignatureFrom(ca))
+}
+
+func TestMaterial_RotationDue(t *testing.T) {
+	material := Material{IssuedAt: issuedAt, ExpiresAt: issuedAt.Add(serverValidity)}
+
+	assert.False(t, material.RotationDue(time.Hour, issuedAt.Add(59*time.Minute)))
+	assert.True(t, material.RotationDue(time.Hour, issuedAt.Add(time.Hour)))
+	assert.False(t, material.RotationDue(0, issuedAt.Add(30*24*time.Hour)))
+	assert.True(t, material.RotationDue(0, material.ExpiresAt.Add(-time.Hour)))
+}
@@ -0,0 +1,111 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package pki
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+
+	"github.com/mysteriumnetwork/node/identity"
+)
+
+// keyMessage is signed by provider identity to derive encryption key of the store
+const keyMessage = "MystOpenvpnTLSMaterial"
+
+// ErrNoMaterial is returned when store does not contain TLS material yet
+var ErrNoMaterial = errors.New("no TLS material stored")
+
+// Store keeps TLS material on disk, encrypted with the key only provider identity can derive
+type Store struct {
+	path   string
+	signer identity.Signer
+}
+
+// NewStore creates store of TLS material in given file
+func NewStore(path string, signer identity.Signer) *Store {
+	return &Store{
+		path:   path,
+		signer: signer,
+	}
+}
+
+// Load reads and decrypts stored TLS material
+func (store *Store) Load() (Material, error) {
+	sealed, err := ioutil.ReadFile(store.path)
+	if os.IsNotExist(err) {
+		return Material{}, ErrNoMaterial
+	}
+	if err != nil {
+		return Material{}, err
+	}
+
+	aead, err := store.cipher()
+	if err != nil {
+		return Material{}, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return Material{}, errors.New("TLS material is corrupted")
+	}
+	data, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return Material{}, err
+	}
+
+	var material Material
+	err = json.Unmarshal(data, &material)
+	return material, err
+}
+
+// Save encrypts and writes TLS material
+func (store *Store) Save(material Material) error {
+	data, err := json.Marshal(material)
+	if err != nil {
+		return err
+	}
+
+	aead, err := store.cipher()
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(store.path, aead.Seal(nonce, nonce, data, nil), 0600)
+}
+
+func (store *Store) cipher() (cipher.AEAD, error) {
+	signature, err := store.signer.Sign([]byte(keyMessage))
+	if err != nil {
+		return nil, err
+	}
+	key := sha256.Sum256(signature.Bytes())
+
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
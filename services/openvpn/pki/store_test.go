@@ -0,0 +1,79 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package pki
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/stretchr/testify/assert"
+)
+
+type signerFake struct {
+	signature string
+}
+
+func (signer *signerFake) Sign(message []byte) (identity.Signature, error) {
+	if signer.signature == "" {
+		return identity.Signature{}, errors.New("identity is locked")
+	}
+	return identity.SignatureBytes([]byte(signer.signature + string(message))), nil
+}
+
+func newTestStore(t *testing.T, signature string) (*Store, func()) {
+	dir, err := ioutil.TempDir("", "pki")
+	assert.NoError(t, err)
+	return NewStore(filepath.Join(dir, "openvpn-tls"), &signerFake{signature}), func() { os.RemoveAll(dir) }
+}
+
+func TestStore_SaveAndLoad(t *testing.T) {
+	store, cleanup := newTestStore(t, "provider")
+	defer cleanup()
+
+	_, err := store.Load()
+	assert.Equal(t, ErrNoMaterial, err)
+
+	material := Material{CACertificate: "ca", CAKey: "secret-ca-key", PresharedKey: "secret-psk", IssuedAt: issuedAt}
+	assert.NoError(t, store.Save(material))
+
+	data, err := ioutil.ReadFile(store.path)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "secret")
+
+	loaded, err := store.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, material, loaded)
+}
+
+func TestStore_LoadWithOtherIdentity(t *testing.T) {
+	store, cleanup := newTestStore(t, "provider")
+	defer cleanup()
+	assert.NoError(t, store.Save(Material{CAKey: "secret-ca-key"}))
+
+	otherStore := NewStore(store.path, &signerFake{"other-provider"})
+	_, err := otherStore.Load()
+	assert.Error(t, err)
+
+	lockedStore := NewStore(store.path, &signerFake{})
+	_, err = lockedStore.Load()
+	assert.EqualError(t, err, "identity is locked")
+}
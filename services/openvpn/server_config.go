@@ -18,8 +18,11 @@
 package openvpn
 
 import (
+	"strconv"
+	"strings"
+
 	"github.com/mysteriumnetwork/go-openvpn/openvpn/config"
-	"github.com/mysteriumnetwork/go-openvpn/openvpn/tls"
+	"github.com/mysteriumnetwork/node/services/openvpn/pki"
 )
 
 // ServerConfig defines openvpn in server mode configuration structure
@@ -49,33 +52,45 @@ func (c *ServerConfig) SetProtocol(protocol string) {
 	}
 }
 
+// SetCiphers sets preferred data channel cipher and ciphers, which can be negotiated with clients
+func (c *ServerConfig) SetCiphers(ciphers []string) {
+	if len(ciphers) == 0 {
+		ciphers = DefaultCiphers
+	}
+	c.SetParam("cipher", ciphers[0])
+	c.SetParam("ncp-ciphers", strings.Join(ciphers, ":"))
+}
+
 // NewServerConfig creates server configuration structure from given basic parameters
 func NewServerConfig(
 	runtimeDir string,
 	configDir string,
 	network, netmask string,
-	secPrimitives *tls.Primitives,
+	device string,
+	tlsMaterial pki.Material,
 	port int,
 	protocol string,
+	ciphers []string,
+	tlsCipher string,
+	renegotiationSeconds int,
 ) *ServerConfig {
 	serverConfig := ServerConfig{config.NewConfig(runtimeDir, configDir)}
 	serverConfig.SetServerMode(port, network, netmask)
+	serverConfig.SetDevice(device)
+	serverConfig.SetParam("dev-type", "tun")
 	serverConfig.SetTLSServer()
 	serverConfig.SetProtocol(protocol)
-	serverConfig.SetTLSCACertificate(secPrimitives.CertificateAuthority.ToPEMFormat())
-	serverConfig.SetTLSPrivatePubKeys(
-		secPrimitives.ServerCertificate.ToPEMFormat(),
-		secPrimitives.ServerCertificate.KeyToPEMFormat(),
-	)
-	serverConfig.SetTLSCrypt(secPrimitives.PresharedKey.ToPEMFormat())
+	serverConfig.SetTLSCACertificate(tlsMaterial.CACertificate)
+	serverConfig.SetTLSPrivatePubKeys(tlsMaterial.ServerCertificate, tlsMaterial.ServerKey)
+	serverConfig.SetTLSCrypt(tlsMaterial.PresharedKey)
 
-	serverConfig.SetParam("cipher", "AES-256-GCM")
+	serverConfig.SetCiphers(ciphers)
 	serverConfig.SetParam("verb", "3")
 	serverConfig.SetParam("tls-version-min", "1.2")
 	serverConfig.SetFlag("management-client-auth")
 	serverConfig.SetParam("verify-client-cert", "none")
-	serverConfig.SetParam("tls-cipher", "TLS-ECDHE-ECDSA-WITH-AES-256-GCM-SHA384")
-	serverConfig.SetParam("reneg-sec", "60")
+	serverConfig.SetParam("tls-cipher", firstString(tlsCipher, DefaultTLSCipher))
+	serverConfig.SetParam("reneg-sec", strconv.Itoa(firstInt(renegotiationSeconds, DefaultRenegotiationSeconds)))
 	serverConfig.SetKeepAlive(10, 60)
 	serverConfig.SetPingTimerRemote()
 	serverConfig.SetPersistKey()
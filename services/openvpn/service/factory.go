@@ -18,111 +18,188 @@
 package service
 
 import (
+	"net"
+	"path/filepath"
+	"time"
+
 	log "github.com/cihub/seelog"
 	"github.com/mysteriumnetwork/go-openvpn/openvpn"
 	"github.com/mysteriumnetwork/go-openvpn/openvpn/middlewares/server/auth"
 	"github.com/mysteriumnetwork/go-openvpn/openvpn/middlewares/state"
-	"github.com/mysteriumnetwork/go-openvpn/openvpn/tls"
 	"github.com/mysteriumnetwork/node/core/ip"
 	"github.com/mysteriumnetwork/node/core/location"
 	"github.com/mysteriumnetwork/node/core/node"
+	"github.com/mysteriumnetwork/node/datasize"
 	"github.com/mysteriumnetwork/node/identity"
 	"github.com/mysteriumnetwork/node/nat"
 	dto_discovery "github.com/mysteriumnetwork/node/service_discovery/dto"
 	openvpn_service "github.com/mysteriumnetwork/node/services/openvpn"
 	openvpn_discovery "github.com/mysteriumnetwork/node/services/openvpn/discovery"
+	"github.com/mysteriumnetwork/node/services/openvpn/dnsproxy"
+	server_bytescount "github.com/mysteriumnetwork/node/services/openvpn/middlewares/server/bytescount"
+	"github.com/mysteriumnetwork/node/services/openvpn/pki"
 	openvpn_session "github.com/mysteriumnetwork/node/services/openvpn/session"
+	"github.com/mysteriumnetwork/node/services/openvpn/shaper"
 	"github.com/mysteriumnetwork/node/session"
 )
 
+// clientStatsInterval is how often traffic counters of sessions are updated
+const clientStatsInterval = 5 * time.Second
+
+// tlsMaterialFile is the name of encrypted TLS material file in config directory
+const tlsMaterialFile = "openvpn-tls.enc"
+
+// resolvConf is used to find resolver, which DNS queries of consumers are forwarded to when LocalDNS is served
+const resolvConf = "/etc/resolv.conf"
+
 // NewManager creates new instance of Openvpn service
 func NewManager(
 	nodeOptions node.Options,
 	serviceOptions Options,
+	trafficPolicy nat.Policy,
 	ipResolver ip.Resolver,
 	locationResolver location.Resolver,
-	sessionMap openvpn_session.SessionMap,
+	sessionStorage SessionStorage,
+	natService nat.NATService,
+	subnetAllocator SubnetAllocator,
+	signerFactory identity.SignerFactory,
 ) *Manager {
-	natService := nat.NewService()
-	sessionValidator := openvpn_session.NewValidator(sessionMap, identity.NewExtractor())
+	upstreamDNS := dnsproxy.SystemResolver(resolvConf, openvpn_service.DefaultDNS[0])
 
 	return &Manager{
 		locationResolver:             locationResolver,
 		ipResolver:                   ipResolver,
 		natService:                   natService,
-		proposalFactory:              newProposalFactory(serviceOptions),
+		subnetPool:                   serviceOptions.OpenvpnSubnet,
+		subnetAllocator:              subnetAllocator,
+		port:                         serviceOptions.OpenvpnPort,
+		rotationPort:                 rotationPort(serviceOptions),
+		trafficPolicy:                trafficPolicy,
+		sessionStorage:               sessionStorage,
+		tlsStoreFactory:              newTLSStoreFactory(nodeOptions, signerFactory),
+		tlsRotation:                  time.Duration(serviceOptions.TLSRotation) * time.Hour,
+		tlsRotationGrace:             time.Duration(serviceOptions.TLSRotationGrace) * time.Hour,
+		tlsCheckInterval:             tlsCheckInterval,
+		timeGetter:                   time.Now,
+		dns:                          serviceOptions.DNS,
+		proposalFactory:              newProposalFactory(serviceOptions, trafficPolicy),
 		sessionConfigProviderFactory: newSessionConfigProviderFactory(serviceOptions),
 		vpnServerConfigFactory:       newServerConfigFactory(nodeOptions, serviceOptions),
-		vpnServerFactory:             newServerFactory(nodeOptions, sessionValidator),
+		vpnServerFactory:             newServerFactory(nodeOptions, serviceOptions, sessionStorage),
+		dnsProxyFactory: func() DNSProxy {
+			return dnsproxy.NewProxy(upstreamDNS)
+		},
 	}
 }
 
-func newProposalFactory(serviceOptions Options) ProposalFactory {
+// rotationPort returns port of server with rotated TLS material, which defaults to the port next to the service
+func rotationPort(serviceOptions Options) int {
+	if serviceOptions.RotationPort > 0 {
+		return serviceOptions.RotationPort
+	}
+	return serviceOptions.OpenvpnPort + rotationPortOffset
+}
+
+func newProposalFactory(serviceOptions Options, trafficPolicy nat.Policy) ProposalFactory {
 	return func(currentLocation dto_discovery.Location) dto_discovery.ServiceProposal {
-		return openvpn_discovery.NewServiceProposalWithLocation(currentLocation, serviceOptions.OpenvpnProtocol)
+		return openvpn_discovery.NewServiceProposalWithLocation(
+			currentLocation,
+			serviceOptions.OpenvpnProtocol,
+			datasize.BitSize(serviceOptions.SessionBandwidth)*datasize.MB,
+			serviceOptions.Ciphers,
+			trafficPolicy,
+		)
 	}
 }
 
 // newServerConfigFactory returns function generating server config and generates required security primitives
 func newServerConfigFactory(nodeOptions node.Options, serviceOptions Options) ServerConfigFactory {
-	return func(secPrimitives *tls.Primitives) *openvpn_service.ServerConfig {
+	return func(tlsMaterial pki.Material, subnet *net.IPNet, device string, port int) *openvpn_service.ServerConfig {
 		// TODO: check nodeOptions for --openvpn-transport option
 		return openvpn_service.NewServerConfig(
 			nodeOptions.Directories.Runtime,
 			nodeOptions.Directories.Config,
-			"10.8.0.0", "255.255.255.0",
-			secPrimitives,
-			serviceOptions.OpenvpnPort,
+			subnet.IP.String(), net.IP(subnet.Mask).String(),
+			device,
+			tlsMaterial,
+			port,
 			serviceOptions.OpenvpnProtocol,
+			serviceOptions.Ciphers,
+			serviceOptions.TLSCipher,
+			serviceOptions.RenegotiationSeconds,
 		)
 	}
 }
 
-func newServerFactory(nodeOptions node.Options, sessionValidator *openvpn_session.Validator) ServerFactory {
-	return func(config *openvpn_service.ServerConfig) openvpn.Process {
-		return openvpn.CreateNewProcess(
+// newServerFactory returns function creating server process, every process gets its own middlewares,
+// as client IDs of management interface are unique only within a process
+func newServerFactory(nodeOptions node.Options, serviceOptions Options, sessionStorage SessionStorage) ServerFactory {
+	return func(config *openvpn_service.ServerConfig) (openvpn.Process, Shaper) {
+		sessionShaper := shaper.NewShaper(datasize.BitSize(serviceOptions.SessionBandwidth) * datasize.MB)
+		clientKiller := openvpn_session.NewClientKiller()
+		sessionValidator := openvpn_session.NewValidator(sessionStorage, identity.NewExtractor(), sessionShaper.Unlimit, clientKiller.Kill)
+
+		process := openvpn.CreateNewProcess(
 			nodeOptions.Openvpn.BinaryPath(),
 			config.GenericConfig,
+			shaper.NewMiddleware(sessionShaper),
+			server_bytescount.NewMiddleware(sessionValidator.UpdateDataTransfer, clientStatsInterval),
 			auth.NewMiddleware(sessionValidator.Validate, sessionValidator.Cleanup),
+			clientKiller,
 			state.NewMiddleware(vpnStateCallback),
 		)
+		return process, sessionShaper
 	}
 }
 
 func newSessionConfigProviderFactory(serviceOptions Options) SessionConfigProviderFactory {
-	return func(secPrimitives *tls.Primitives, outboundIP, publicIP string) session.ConfigProvider {
+	return func(currentServer func() ServerEndpoint, outboundIP, publicIP string) session.ConfigProvider {
 		serverIP := vpnServerIP(serviceOptions, outboundIP, publicIP)
 
-		return newSessionConfigProvider(serviceOptions, secPrimitives, serverIP)
+		return newSessionConfigProvider(serviceOptions, currentServer, serverIP)
 	}
 }
 
 // newSessionConfigProvider returns function generating session config for remote client
-func newSessionConfigProvider(serviceOptions Options, secPrimitives *tls.Primitives, serverIP string) session.ConfigProvider {
+func newSessionConfigProvider(serviceOptions Options, currentServer func() ServerEndpoint, serverIP string) session.ConfigProvider {
 	// TODO: check nodeOptions for --openvpn-transport option
 	return func() (session.ServiceConfiguration, error) {
+		server := currentServer()
 		return &openvpn_service.VPNConfig{
-			serverIP,
-			serviceOptions.OpenvpnPort,
-			serviceOptions.OpenvpnProtocol,
-			secPrimitives.PresharedKey.ToPEMFormat(),
-			secPrimitives.CertificateAuthority.ToPEMFormat(),
+			RemoteIP:             serverIP,
+			RemotePort:           server.Port,
+			RemoteProtocol:       serviceOptions.OpenvpnProtocol,
+			TLSPresharedKey:      server.TLSMaterial.PresharedKey,
+			CACertificate:        server.TLSMaterial.CACertificate,
+			Ciphers:              serviceOptions.Ciphers,
+			TLSCipher:            serviceOptions.TLSCipher,
+			RenegotiationSeconds: serviceOptions.RenegotiationSeconds,
+			DNS:                  server.DNS,
 		}, nil
 	}
 }
 
+// newTLSStoreFactory returns factory of TLS material stores, encrypted with provider identity
+func newTLSStoreFactory(nodeOptions node.Options, signerFactory identity.SignerFactory) TLSStoreFactory {
+	return func(providerID identity.Identity) TLSStore {
+		return pki.NewStore(filepath.Join(nodeOptions.Directories.Config, tlsMaterialFile), signerFactory(providerID))
+	}
+}
+
 func vpnServerIP(serviceOptions Options, outboundIP, publicIP string) string {
 	//TODO public ip could be overridden by arg nodeOptions if needed
 	if publicIP != outboundIP {
 		log.Warnf(
 			`WARNING: It seems that publicly visible ip: [%s] does not match your local machines ip: [%s]. 
-You should probably need to do port forwarding on your router: %s:%v -> %s:%v.`,
+You should probably need to do port forwarding on your router: %s:%v-%v -> %s:%v-%v.`,
 			publicIP,
 			outboundIP,
 			publicIP,
 			serviceOptions.OpenvpnPort,
+			serviceOptions.OpenvpnPort+rotationPortOffset,
 			outboundIP,
 			serviceOptions.OpenvpnPort,
+			serviceOptions.OpenvpnPort+rotationPortOffset,
 		)
 
 	}
@@ -18,13 +18,30 @@
 package service
 
 import (
+	"encoding/json"
+	"strings"
+
+	"github.com/mysteriumnetwork/node/services/openvpn"
 	"github.com/urfave/cli"
 )
 
+// LocalDNS is value of DNS option which makes provider to serve DNS on VPN server address
+const LocalDNS = "local"
+
 // Options describes options which are required to start Openvpn service
 type Options struct {
-	OpenvpnProtocol string
-	OpenvpnPort     int
+	OpenvpnProtocol  string `json:"protocol"`
+	OpenvpnPort      int    `json:"port"`
+	OpenvpnSubnet    string `json:"subnet"`
+	SessionBandwidth int    `json:"session_bandwidth"`
+	TLSRotation      int    `json:"tls_rotation"`
+	TLSRotationGrace int    `json:"tls_rotation_grace"`
+	RotationPort     int    `json:"rotation_port"`
+
+	Ciphers              []string `json:"ciphers"`
+	TLSCipher            string   `json:"tls_cipher"`
+	RenegotiationSeconds int      `json:"reneg_sec"`
+	DNS                  []string `json:"dns"`
 }
 
 var (
@@ -35,20 +52,110 @@ var (
 	}
 	portFlag = cli.IntFlag{
 		Name:  "openvpn.port",
-		Usage: "Openvpn port to use. Default 1194",
+		Usage: "Openvpn port to use, server of rotated TLS material listens on openvpn.rotation-port. Default 1194",
 		Value: 1194,
 	}
+	rotationPortFlag = cli.IntFlag{
+		Name:  "openvpn.rotation-port",
+		Usage: "Port of Openvpn server with rotated TLS material, servers alternate between this port and openvpn.port after every TLS rotation, so both have to be reachable. Default openvpn.port + 1",
+	}
+	subnetFlag = cli.StringFlag{
+		Name:  "openvpn.subnet",
+		Usage: "Openvpn subnet of clients, or pool to pick free /24 subnet from. Server of rotated TLS material needs a subnet from the pool too. Default 10.8.0.0/16",
+		Value: "10.8.0.0/16",
+	}
+	sessionBandwidthFlag = cli.IntFlag{
+		Name:  "openvpn.session-bandwidth",
+		Usage: "Bandwidth limit of every Openvpn session in MB/s, 0 disables limit. Default 10",
+		Value: 10,
+	}
+	tlsRotationFlag = cli.IntFlag{
+		Name:  "openvpn.tls-rotation",
+		Usage: "Hours after which Openvpn server certificate and pre-shared key are rotated, 0 disables rotation. Default 168",
+		Value: 168,
+	}
+	tlsRotationGraceFlag = cli.IntFlag{
+		Name:  "openvpn.tls-rotation-grace",
+		Usage: "Hours for which server of previous TLS material keeps serving its sessions after rotation, sessions left are terminated afterwards. Default 24",
+		Value: 24,
+	}
+	ciphersFlag = cli.StringFlag{
+		Name:  "openvpn.ciphers",
+		Usage: "Comma separated Openvpn data channel ciphers in order of preference. Default AES-256-GCM,AES-128-GCM",
+		Value: "AES-256-GCM,AES-128-GCM",
+	}
+	tlsCipherFlag = cli.StringFlag{
+		Name:  "openvpn.tls-cipher",
+		Usage: "Openvpn control channel cipher. Default " + openvpn.DefaultTLSCipher,
+		Value: openvpn.DefaultTLSCipher,
+	}
+	renegotiationFlag = cli.IntFlag{
+		Name:  "openvpn.reneg-sec",
+		Usage: "Seconds after which Openvpn data channel key is renegotiated. Default 60",
+		Value: openvpn.DefaultRenegotiationSeconds,
+	}
+	dnsFlag = cli.StringFlag{
+		Name:  "openvpn.dns",
+		Usage: "Comma separated DNS servers pushed to consumers, '" + LocalDNS + "' serves DNS on Openvpn server address. Default " + strings.Join(openvpn.DefaultDNS, ","),
+		Value: strings.Join(openvpn.DefaultDNS, ","),
+	}
 )
 
 // RegisterFlags function register Openvpn flags to flag list
 func RegisterFlags(flags *[]cli.Flag) {
-	*flags = append(*flags, protocolFlag, portFlag)
+	*flags = append(*flags, protocolFlag, portFlag, rotationPortFlag, subnetFlag, sessionBandwidthFlag, tlsRotationFlag, tlsRotationGraceFlag,
+		ciphersFlag, tlsCipherFlag, renegotiationFlag, dnsFlag,
+	)
 }
 
 // ParseFlags function fills in Openvpn options from CLI context
 func ParseFlags(ctx *cli.Context) Options {
 	return Options{
-		OpenvpnProtocol: ctx.String(protocolFlag.Name),
-		OpenvpnPort:     ctx.Int(portFlag.Name),
+		OpenvpnProtocol:  ctx.String(protocolFlag.Name),
+		OpenvpnPort:      ctx.Int(portFlag.Name),
+		OpenvpnSubnet:    ctx.String(subnetFlag.Name),
+		SessionBandwidth: ctx.Int(sessionBandwidthFlag.Name),
+		TLSRotation:      ctx.Int(tlsRotationFlag.Name),
+		TLSRotationGrace: ctx.Int(tlsRotationGraceFlag.Name),
+		RotationPort:     ctx.Int(rotationPortFlag.Name),
+
+		Ciphers:              splitList(ctx.String(ciphersFlag.Name)),
+		TLSCipher:            ctx.String(tlsCipherFlag.Name),
+		RenegotiationSeconds: ctx.Int(renegotiationFlag.Name),
+		DNS:                  splitList(ctx.String(dnsFlag.Name)),
+	}
+}
+
+// ParseJSONOptions function fills in Openvpn options from JSON request, absent options take default flag values
+func ParseJSONOptions(request *json.RawMessage) (Options, error) {
+	options := Options{
+		OpenvpnProtocol:  protocolFlag.Value,
+		OpenvpnPort:      portFlag.Value,
+		OpenvpnSubnet:    subnetFlag.Value,
+		SessionBandwidth: sessionBandwidthFlag.Value,
+		TLSRotation:      tlsRotationFlag.Value,
+		TLSRotationGrace: tlsRotationGraceFlag.Value,
+		RotationPort:     rotationPortFlag.Value,
+
+		Ciphers:              splitList(ciphersFlag.Value),
+		TLSCipher:            tlsCipherFlag.Value,
+		RenegotiationSeconds: renegotiationFlag.Value,
+		DNS:                  splitList(dnsFlag.Value),
+	}
+	if request == nil || len(*request) == 0 {
+		return options, nil
+	}
+
+	err := json.Unmarshal(*request, &options)
+	return options, err
+}
+
+func splitList(value string) []string {
+	list := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
 	}
+	return list
 }
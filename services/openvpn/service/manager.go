@@ -19,10 +19,14 @@ package service
 
 import (
 	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
 
 	log "github.com/cihub/seelog"
 	"github.com/mysteriumnetwork/go-openvpn/openvpn"
-	"github.com/mysteriumnetwork/go-openvpn/openvpn/tls"
 	"github.com/mysteriumnetwork/node/core/ip"
 	"github.com/mysteriumnetwork/node/core/location"
 	"github.com/mysteriumnetwork/node/core/service"
@@ -30,35 +34,132 @@ import (
 	"github.com/mysteriumnetwork/node/nat"
 	dto_discovery "github.com/mysteriumnetwork/node/service_discovery/dto"
 	openvpn_service "github.com/mysteriumnetwork/node/services/openvpn"
+	"github.com/mysteriumnetwork/node/services/openvpn/pki"
+	openvpn_session "github.com/mysteriumnetwork/node/services/openvpn/session"
 	"github.com/mysteriumnetwork/node/session"
 )
 
 const logPrefix = "[service-openvpn] "
 
+// tlsCheckInterval is how often TLS material is checked for rotation
+const tlsCheckInterval = time.Hour
+
+// rotationPortOffset is added to the port of the service to get default port of server, which runs with rotated TLS material.
+// Servers of consecutive TLS material alternate between these ports, as previous server keeps running until its sessions expire.
+const rotationPortOffset = 1
+
+// errRotationPortInUse is returned, when server of rotated TLS material is configured to listen on the port of current server
+var errRotationPortInUse = errors.New("rotation port has to differ from the port of the service")
+
 // ServerConfigFactory callback generates session config for remote client
-type ServerConfigFactory func(*tls.Primitives) *openvpn_service.ServerConfig
+type ServerConfigFactory func(tlsMaterial pki.Material, subnet *net.IPNet, device string, port int) *openvpn_service.ServerConfig
+
+// ServerFactory initiates Openvpn server instance during runtime together with shaper limiting its clients
+type ServerFactory func(*openvpn_service.ServerConfig) (openvpn.Process, Shaper)
 
-// ServerFactory initiates Openvpn server instance during runtime
-type ServerFactory func(*openvpn_service.ServerConfig) openvpn.Process
+// DNSProxyFactory initiates DNS proxy for consumers of single server
+type DNSProxyFactory func() DNSProxy
 
 // ProposalFactory prepares service proposal during runtime
 type ProposalFactory func(currentLocation dto_discovery.Location) dto_discovery.ServiceProposal
 
-// SessionConfigProviderFactory initiates ConfigProvider instance during runtime
-type SessionConfigProviderFactory func(secPrimitives *tls.Primitives, outboundIP, publicIP string) session.ConfigProvider
+// ServerEndpoint describes the server, which new sessions are issued for
+type ServerEndpoint struct {
+	TLSMaterial pki.Material
+	Port        int
+	DNS         []string
+}
+
+// SessionConfigProviderFactory initiates ConfigProvider instance during runtime,
+// provided session configs point to the server, which is current at the time of session creation
+type SessionConfigProviderFactory func(currentServer func() ServerEndpoint, outboundIP, publicIP string) session.ConfigProvider
+
+// SubnetAllocator hands out subnets, which do not collide with local networks and other services
+type SubnetAllocator interface {
+	Allocate(pool string) (*net.IPNet, error)
+	Release(subnet *net.IPNet)
+}
+
+// Shaper limits bandwidth of sessions on server tun device
+type Shaper interface {
+	Start(device string)
+	Stop()
+}
+
+// DNSProxy serves DNS to consumers on VPN server address
+type DNSProxy interface {
+	Start(address string)
+	Stop()
+}
+
+// SessionStorage keeps sessions served by provider
+type SessionStorage interface {
+	openvpn_session.SessionMap
+	GetAll() []session.Session
+}
+
+// TLSStore keeps TLS material of the service between restarts
+type TLSStore interface {
+	Load() (pki.Material, error)
+	Save(pki.Material) error
+}
+
+// TLSStoreFactory initiates TLS store of given provider
+type TLSStoreFactory func(providerID identity.Identity) TLSStore
 
 // Manager represents entrypoint for Openvpn service with top level components
 type Manager struct {
 	ipResolver       ip.Resolver
 	natService       nat.NATService
+	outboundIP       string
+	subnetPool       string
+	subnetAllocator  SubnetAllocator
+	port             int
+	rotationPort     int
+	trafficPolicy    nat.Policy
+	sessionStorage   SessionStorage
 	locationResolver location.Resolver
 	proposalFactory  ProposalFactory
+	dns              []string
 
 	sessionConfigProviderFactory SessionConfigProviderFactory
 
+	tlsStoreFactory   TLSStoreFactory
+	tlsStore          TLSStore
+	tlsRotation       time.Duration
+	tlsRotationGrace  time.Duration
+	tlsCheckInterval  time.Duration
+	serverCertSubject pkix.Name
+	stopRotation      chan struct{}
+	timeGetter        func() time.Time
+
 	vpnServerConfigFactory ServerConfigFactory
 	vpnServerFactory       ServerFactory
-	vpnServer              openvpn.Process
+	dnsProxyFactory        DNSProxyFactory
+	// current server is the one new sessions are issued for,
+	// previous server keeps serving sessions issued before TLS rotation until they expire
+	current     *vpnServer
+	previous    *vpnServer
+	serversLock sync.RWMutex
+}
+
+// vpnServer is OpenVPN server running with single generation of TLS material in its own subnet
+type vpnServer struct {
+	tlsMaterial pki.Material
+	port        int
+	subnet      *net.IPNet
+	device      string
+	dns         []string
+	natRule     *nat.RuleForwarding
+	process     openvpn.Process
+	shaper      Shaper
+	dnsProxy    DNSProxy
+	// exited is closed with exitErr set, when server process exits
+	exited  chan struct{}
+	exitErr error
+	// replaced is closed, when server stops being current
+	replaced   chan struct{}
+	replacedAt time.Time
 }
 
 // Start starts service - does not block
@@ -67,6 +168,11 @@ func (manager *Manager) Start(providerID identity.Identity) (
 	sessionConfigProvider session.ConfigProvider,
 	err error,
 ) {
+	if manager.tlsRotation > 0 && manager.rotationPort == manager.port {
+		err = errRotationPortInUse
+		return
+	}
+
 	publicIP, err := manager.ipResolver.GetPublicIP()
 	if err != nil {
 		return
@@ -77,16 +183,7 @@ func (manager *Manager) Start(providerID identity.Identity) (
 	if err != nil {
 		return
 	}
-
-	manager.natService.Add(nat.RuleForwarding{
-		SourceAddress: "10.8.0.0/24",
-		TargetIP:      outboundIP,
-	})
-
-	err = manager.natService.Start()
-	if err != nil {
-		log.Warn(logPrefix, "received nat service error: ", err, " trying to proceed.")
-	}
+	manager.outboundIP = outboundIP
 
 	currentCountry, err := manager.locationResolver.ResolveCountry(publicIP)
 	if err != nil {
@@ -102,47 +199,323 @@ func (manager *Manager) Start(providerID identity.Identity) (
 		Organization:       []string{"Mysterium Network"},
 		OrganizationalUnit: []string{"Mysterium Team"},
 	}
-	serverCertSubject := pkix.Name{
+	manager.serverCertSubject = pkix.Name{
 		Country:            []string{currentCountry},
 		Organization:       []string{"Mysterium node operator company"},
 		OrganizationalUnit: []string{"Node operator team"},
 		CommonName:         providerID.Address,
 	}
 
-	primitives, err := tls.NewTLSPrimitives(caSubject, serverCertSubject)
+	manager.tlsStore = manager.tlsStoreFactory(providerID)
+	tlsMaterial, err := manager.loadTLSMaterial(caSubject)
 	if err != nil {
 		return
 	}
 
-	vpnServerConfig := manager.vpnServerConfigFactory(primitives)
-	manager.vpnServer = manager.vpnServerFactory(vpnServerConfig)
-	if err = manager.vpnServer.Start(); err != nil {
+	current, err := manager.startServer(tlsMaterial, manager.port)
+	if err != nil {
+		return
+	}
+	manager.serversLock.Lock()
+	manager.current = current
+	manager.serversLock.Unlock()
+
+	sessionConfigProvider = manager.sessionConfigProviderFactory(manager.currentServer, outboundIP, publicIP)
+	if err = validateSessionConfig(sessionConfigProvider); err != nil {
+		log.Error(logPrefix, "Consumers would refuse session config: ", err)
 		return
 	}
 
+	manager.stopRotation = make(chan struct{})
+	go manager.rotateTLSMaterial(manager.stopRotation)
+
 	proposal = manager.proposalFactory(currentLocation)
-	sessionConfigProvider = manager.sessionConfigProviderFactory(primitives, outboundIP, publicIP)
 	return
 }
 
+// startServer starts server with given TLS material in newly allocated subnet, resources are released on failure
+func (manager *Manager) startServer(tlsMaterial pki.Material, port int) (server *vpnServer, err error) {
+	server = &vpnServer{
+		tlsMaterial: tlsMaterial,
+		port:        port,
+		exited:      make(chan struct{}),
+		replaced:    make(chan struct{}),
+	}
+	defer func() {
+		if err != nil {
+			manager.stopServer(server)
+			server = nil
+		}
+	}()
+
+	if server.subnet, err = manager.subnetAllocator.Allocate(manager.subnetPool); err != nil {
+		return
+	}
+	log.Info(logPrefix, "Subnet allocated: ", server.subnet)
+
+	natRule := nat.RuleForwarding{
+		SourceAddress: server.subnet.String(),
+		TargetIP:      manager.outboundIP,
+		Policy:        manager.trafficPolicy,
+	}
+	// traffic policy is enforced by NAT rules, so service must not run without them
+	if err = manager.natService.Add(natRule); err != nil {
+		log.Error(logPrefix, "Failed to add NAT rule: ", err)
+		return
+	}
+	server.natRule = &natRule
+
+	var localDNS bool
+	server.dns, localDNS = manager.sessionDNS(server.subnet)
+	server.device = deviceName(server.subnet)
+	server.process, server.shaper = manager.vpnServerFactory(
+		manager.vpnServerConfigFactory(tlsMaterial, server.subnet, server.device, port),
+	)
+	server.shaper.Start(server.device)
+	if err = server.process.Start(); err != nil {
+		server.process = nil
+		return
+	}
+	go func() {
+		server.exitErr = server.process.Wait()
+		close(server.exited)
+	}()
+
+	if localDNS {
+		server.dnsProxy = manager.dnsProxyFactory()
+		server.dnsProxy.Start(net.JoinHostPort(serverIP(server.subnet).String(), "53"))
+	}
+	return server, nil
+}
+
+// stopServer stops server and releases its resources, it is safe to call with partially started server
+func (manager *Manager) stopServer(server *vpnServer) {
+	if server.process != nil {
+		server.process.Stop()
+	}
+	if server.shaper != nil {
+		server.shaper.Stop()
+	}
+	if server.dnsProxy != nil {
+		server.dnsProxy.Stop()
+	}
+	if server.natRule != nil {
+		if err := manager.natService.Del(*server.natRule); err != nil {
+			log.Warn(logPrefix, "Failed to remove NAT rule: ", err)
+		}
+		server.natRule = nil
+	}
+	if server.subnet != nil {
+		manager.subnetAllocator.Release(server.subnet)
+	}
+}
+
 // Wait blocks until service is stopped
 func (manager *Manager) Wait() error {
-	return manager.vpnServer.Wait()
+	for {
+		manager.serversLock.RLock()
+		current := manager.current
+		manager.serversLock.RUnlock()
+
+		select {
+		case <-current.exited:
+			manager.serversLock.RLock()
+			replaced := manager.current != current
+			manager.serversLock.RUnlock()
+			if !replaced {
+				return current.exitErr
+			}
+		case <-current.replaced:
+		}
+		// server was replaced by the one with rotated TLS material, continue waiting for the new one
+	}
 }
 
 // Stop stops service
 func (manager *Manager) Stop() error {
-	if manager.natService != nil {
-		manager.natService.Stop()
+	if manager.stopRotation != nil {
+		close(manager.stopRotation)
+		manager.stopRotation = nil
+	}
+
+	// rotation holds the lock, while servers are replaced
+	manager.serversLock.Lock()
+	defer manager.serversLock.Unlock()
+
+	if manager.previous != nil {
+		manager.stopServer(manager.previous)
+		manager.previous = nil
+	}
+	if manager.current != nil {
+		manager.stopServer(manager.current)
+	}
+	return nil
+}
+
+// loadTLSMaterial reuses TLS material of previous runs, it is created when there is none yet
+// and rotated when rotation is due
+func (manager *Manager) loadTLSMaterial(caSubject pkix.Name) (pki.Material, error) {
+	now := manager.timeGetter()
+
+	tlsMaterial, err := manager.tlsStore.Load()
+	if err != nil {
+		if err != pki.ErrNoMaterial {
+			log.Warn(logPrefix, "Failed to load TLS material, new certificate authority is created: ", err)
+		}
+		if tlsMaterial, err = pki.NewMaterial(caSubject, manager.serverCertSubject, now); err != nil {
+			return tlsMaterial, err
+		}
+	} else if tlsMaterial.RotationDue(manager.tlsRotation, now) {
+		log.Info(logPrefix, "Rotating server certificate and pre-shared key")
+		if tlsMaterial, err = tlsMaterial.Rotate(manager.serverCertSubject, now); err != nil {
+			return tlsMaterial, err
+		}
+	} else {
+		return tlsMaterial, nil
 	}
 
-	if manager.vpnServer != nil {
-		manager.vpnServer.Stop()
+	if err := manager.tlsStore.Save(tlsMaterial); err != nil {
+		log.Warn(logPrefix, "Failed to save TLS material: ", err)
+	}
+	return tlsMaterial, nil
+}
+
+// currentServer describes the server, which new sessions are issued for
+func (manager *Manager) currentServer() ServerEndpoint {
+	manager.serversLock.RLock()
+	defer manager.serversLock.RUnlock()
+
+	return ServerEndpoint{
+		TLSMaterial: manager.current.tlsMaterial,
+		Port:        manager.current.port,
+		DNS:         manager.current.dns,
+	}
+}
+
+func (manager *Manager) rotateTLSMaterial(stop <-chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		case <-time.After(manager.tlsCheckInterval):
+			if err := manager.rotateTLSMaterialIfDue(); err != nil {
+				log.Error(logPrefix, "Failed to rotate TLS material: ", err)
+			}
+		}
+	}
+}
+
+// rotateTLSMaterialIfDue rotates server certificate and pre-shared key and starts a new server with them.
+// New sessions are issued for the new server, while the previous one keeps serving its sessions until they expire
+// or grace period is over. Rotation is postponed while the server of previous rotation still has sessions.
+func (manager *Manager) rotateTLSMaterialIfDue() error {
+	manager.serversLock.Lock()
+	defer manager.serversLock.Unlock()
+
+	manager.stopPreviousServerIfIdle()
+	if !manager.current.tlsMaterial.RotationDue(manager.tlsRotation, manager.timeGetter()) {
+		return nil
+	}
+	if manager.previous != nil {
+		log.Info(logPrefix, "TLS rotation postponed, server of previous TLS material still has sessions")
+		return nil
+	}
+
+	tlsMaterial, err := manager.current.tlsMaterial.Rotate(manager.serverCertSubject, manager.timeGetter())
+	if err != nil {
+		return err
+	}
+	port := manager.port
+	if manager.current.port == manager.port {
+		port = manager.rotationPort
+	}
+	server, err := manager.startServer(tlsMaterial, port)
+	if err != nil {
+		return err
+	}
+	if err := manager.tlsStore.Save(tlsMaterial); err != nil {
+		log.Warn(logPrefix, "Failed to save TLS material: ", err)
 	}
 
+	log.Info(logPrefix, "Server with rotated certificate and pre-shared key started on port ", port)
+	manager.previous, manager.current = manager.current, server
+	manager.previous.replacedAt = manager.timeGetter()
+	close(manager.previous.replaced)
+	manager.stopPreviousServerIfIdle()
 	return nil
 }
 
+// stopPreviousServerIfIdle stops server of previous TLS material, when sessions issued for it are gone.
+// Sessions left after grace period are terminated, so consumers have to request new sessions of the current server.
+func (manager *Manager) stopPreviousServerIfIdle() {
+	if manager.previous == nil {
+		return
+	}
+	if sessions := manager.sessionsIssuedWith(manager.previous.tlsMaterial); len(sessions) > 0 {
+		if manager.timeGetter().Sub(manager.previous.replacedAt) < manager.tlsRotationGrace {
+			log.Info(logPrefix, "Server of previous TLS material kept running, sessions left: ", len(sessions))
+			return
+		}
+
+		log.Warn(logPrefix, "Grace period of previous TLS material is over, terminating sessions left: ", len(sessions))
+		for _, sessionInstance := range sessions {
+			manager.sessionStorage.Remove(sessionInstance.ID)
+		}
+	}
+
+	log.Info(logPrefix, "Stopping server of previous TLS material on port ", manager.previous.port)
+	manager.stopServer(manager.previous)
+	manager.previous = nil
+}
+
+func (manager *Manager) sessionsIssuedWith(tlsMaterial pki.Material) (sessions []session.Session) {
+	for _, sessionInstance := range manager.sessionStorage.GetAll() {
+		config, ok := sessionInstance.Config.(*openvpn_service.VPNConfig)
+		if ok && config.TLSPresharedKey == tlsMaterial.PresharedKey {
+			sessions = append(sessions, sessionInstance)
+		}
+	}
+	return
+}
+
+// sessionDNS returns resolvers pushed to consumers, LocalDNS is replaced by address of VPN server
+func (manager *Manager) sessionDNS(subnet *net.IPNet) (dns []string, local bool) {
+	for _, server := range manager.dns {
+		if server == LocalDNS {
+			server = serverIP(subnet).String()
+			local = true
+		}
+		dns = append(dns, server)
+	}
+	return dns, local
+}
+
+// validateSessionConfig checks that consumers accept session configs of the service
+func validateSessionConfig(sessionConfigProvider session.ConfigProvider) error {
+	config, err := sessionConfigProvider()
+	if err != nil {
+		return err
+	}
+	vpnConfig, ok := config.(*openvpn_service.VPNConfig)
+	if !ok {
+		return errors.New("unexpected session config type")
+	}
+	return openvpn_service.NewDefaultValidator().IsValid(vpnConfig)
+}
+
+// serverIP is the address of VPN server in its subnet, which is the first host address
+func serverIP(subnet *net.IPNet) net.IP {
+	ip := make(net.IP, len(subnet.IP.To4()))
+	copy(ip, subnet.IP.To4())
+	ip[len(ip)-1]++
+	return ip
+}
+
+// deviceName names tun device of the server after its subnet, so that several services get different devices
+func deviceName(subnet *net.IPNet) string {
+	return fmt.Sprintf("mystvpn%x", []byte(subnet.IP.To4()[:3]))
+}
+
 func vpnStateCallback(state openvpn.State) {
 	switch state {
 	case openvpn.ProcessStarted:
@@ -0,0 +1,363 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package service
+
+import (
+	"crypto/x509/pkix"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/mysteriumnetwork/go-openvpn/openvpn"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/nat"
+	openvpn_service "github.com/mysteriumnetwork/node/services/openvpn"
+	"github.com/mysteriumnetwork/node/services/openvpn/pki"
+	"github.com/mysteriumnetwork/node/session"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	caSubject = pkix.Name{CommonName: "CA"}
+	now       = time.Date(2018, 12, 1, 10, 0, 0, 0, time.UTC)
+)
+
+type tlsStoreFake struct {
+	material pki.Material
+	loadErr  error
+	saved    int
+}
+
+func (store *tlsStoreFake) Load() (pki.Material, error) {
+	return store.material, store.loadErr
+}
+
+func (store *tlsStoreFake) Save(material pki.Material) error {
+	store.material = material
+	store.loadErr = nil
+	store.saved++
+	return nil
+}
+
+type processFake struct {
+	port    int
+	started bool
+	done    chan struct{}
+}
+
+func (process *processFake) Start() error {
+	process.started = true
+	return nil
+}
+
+func (process *processFake) Wait() error {
+	<-process.done
+	return nil
+}
+
+func (process *processFake) Stop() {
+	close(process.done)
+}
+
+func (process *processFake) stopped() bool {
+	select {
+	case <-process.done:
+		return true
+	default:
+		return false
+	}
+}
+
+type sessionStorageFake struct {
+	sessions []session.Session
+}
+
+func (storage *sessionStorageFake) Add(session.Session) {}
+func (storage *sessionStorageFake) Find(session.ID) (session.Session, bool) {
+	return session.Session{}, false
+}
+func (storage *sessionStorageFake) MarkConnected(session.ID)                         {}
+func (storage *sessionStorageFake) AddDataTransfer(session.ID, session.DataTransfer) {}
+func (storage *sessionStorageFake) Remove(id session.ID) {
+	for i, sessionInstance := range storage.sessions {
+		if sessionInstance.ID == id {
+			storage.sessions = append(storage.sessions[:i], storage.sessions[i+1:]...)
+			return
+		}
+	}
+}
+
+func (storage *sessionStorageFake) GetAll() []session.Session { return storage.sessions }
+
+type subnetAllocatorFake struct {
+	allocated []string
+}
+
+func (allocator *subnetAllocatorFake) Allocate(pool string) (*net.IPNet, error) {
+	_, subnet, err := net.ParseCIDR(fmt.Sprintf("10.8.%d.0/24", len(allocator.allocated)))
+	allocator.allocated = append(allocator.allocated, subnet.String())
+	return subnet, err
+}
+
+func (allocator *subnetAllocatorFake) Release(subnet *net.IPNet) {
+	for i, allocated := range allocator.allocated {
+		if allocated == subnet.String() {
+			allocator.allocated[i] = ""
+		}
+	}
+}
+
+type natServiceFake struct {
+	rules []string
+}
+
+func (service *natServiceFake) Add(rule nat.RuleForwarding) error {
+	service.rules = append(service.rules, rule.SourceAddress)
+	return nil
+}
+
+func (service *natServiceFake) Del(rule nat.RuleForwarding) error {
+	for i, source := range service.rules {
+		if source == rule.SourceAddress {
+			service.rules = append(service.rules[:i], service.rules[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (service *natServiceFake) ClearStale() error { return nil }
+func (service *natServiceFake) Disable()          {}
+
+type shaperFake struct{}
+
+func (shaper *shaperFake) Start(device string) {}
+func (shaper *shaperFake) Stop()               {}
+
+type dnsProxyFake struct {
+	address string
+}
+
+func (proxy *dnsProxyFake) Start(address string) { proxy.address = address }
+func (proxy *dnsProxyFake) Stop()                { proxy.address = "" }
+
+func newTestManager(store *tlsStoreFake, storage *sessionStorageFake) (*Manager, *[]*processFake) {
+	var processes []*processFake
+	var configuredPort int
+	manager := &Manager{
+		natService:        &natServiceFake{},
+		subnetAllocator:   &subnetAllocatorFake{},
+		port:              1194,
+		rotationPort:      1195,
+		sessionStorage:    storage,
+		tlsStore:          store,
+		tlsRotation:       time.Hour,
+		tlsRotationGrace:  24 * time.Hour,
+		serverCertSubject: pkix.Name{CommonName: "0x1"},
+		timeGetter:        func() time.Time { return now },
+		vpnServerConfigFactory: func(tlsMaterial pki.Material, subnet *net.IPNet, device string, port int) *openvpn_service.ServerConfig {
+			configuredPort = port
+			return &openvpn_service.ServerConfig{}
+		},
+		vpnServerFactory: func(config *openvpn_service.ServerConfig) (openvpn.Process, Shaper) {
+			process := &processFake{port: configuredPort, done: make(chan struct{})}
+			processes = append(processes, process)
+			return process, &shaperFake{}
+		},
+		dnsProxyFactory: func() DNSProxy {
+			return &dnsProxyFake{}
+		},
+	}
+	return manager, &processes
+}
+
+func TestManager_LoadTLSMaterialCreatesNew(t *testing.T) {
+	store := &tlsStoreFake{loadErr: pki.ErrNoMaterial}
+	manager, _ := newTestManager(store, &sessionStorageFake{})
+
+	material, err := manager.loadTLSMaterial(caSubject)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, material.CACertificate)
+	assert.Equal(t, now, material.IssuedAt)
+	assert.Equal(t, material, store.material)
+	assert.Equal(t, 1, store.saved)
+}
+
+func TestManager_LoadTLSMaterialReusesStored(t *testing.T) {
+	stored, err := pki.NewMaterial(caSubject, pkix.Name{CommonName: "0x1"}, now.Add(-time.Minute))
+	assert.NoError(t, err)
+	store := &tlsStoreFake{material: stored}
+	manager, _ := newTestManager(store, &sessionStorageFake{})
+
+	material, err := manager.loadTLSMaterial(caSubject)
+	assert.NoError(t, err)
+	assert.Equal(t, stored, material)
+	assert.Equal(t, 0, store.saved)
+
+	manager.timeGetter = func() time.Time { return now.Add(time.Hour) }
+	material, err = manager.loadTLSMaterial(caSubject)
+	assert.NoError(t, err)
+	assert.Equal(t, stored.CACertificate, material.CACertificate)
+	assert.NotEqual(t, stored.PresharedKey, material.PresharedKey)
+	assert.Equal(t, 1, store.saved)
+}
+
+func TestManager_RotateTLSMaterialIfDue(t *testing.T) {
+	stored, err := pki.NewMaterial(caSubject, pkix.Name{CommonName: "0x1"}, now.Add(-2*time.Hour))
+	assert.NoError(t, err)
+	storage := &sessionStorageFake{
+		sessions: []session.Session{
+			{ID: "old", Config: &openvpn_service.VPNConfig{TLSPresharedKey: stored.PresharedKey}},
+			{ID: "other-service", Config: "wireguard config"},
+		},
+	}
+	store := &tlsStoreFake{material: stored}
+	manager, processes := newTestManager(store, storage)
+	manager.current, err = manager.startServer(stored, manager.port)
+	assert.NoError(t, err)
+	waitResult := make(chan error)
+	go func() {
+		waitResult <- manager.Wait()
+	}()
+
+	assert.NoError(t, manager.rotateTLSMaterialIfDue())
+	rotated := manager.currentServer()
+	assert.Equal(t, stored.CACertificate, rotated.TLSMaterial.CACertificate)
+	assert.NotEqual(t, stored.PresharedKey, rotated.TLSMaterial.PresharedKey)
+	assert.Equal(t, rotated.TLSMaterial, store.material)
+	assert.Equal(t, 1195, rotated.Port)
+	assert.Len(t, *processes, 2)
+	assert.True(t, (*processes)[1].started)
+	assert.Equal(t, 1195, (*processes)[1].port)
+	assert.False(t, (*processes)[0].stopped(), "previous server keeps serving old sessions")
+	assert.Equal(t, []string{"10.8.0.0/24", "10.8.1.0/24"}, manager.natService.(*natServiceFake).rules)
+
+	select {
+	case <-waitResult:
+		assert.Fail(t, "Wait returned after TLS rotation")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	storage.sessions = storage.sessions[1:]
+	assert.NoError(t, manager.rotateTLSMaterialIfDue())
+	assert.True(t, (*processes)[0].stopped(), "previous server is stopped after old sessions expire")
+	assert.Equal(t, rotated, manager.currentServer())
+	assert.Equal(t, []string{"10.8.1.0/24"}, manager.natService.(*natServiceFake).rules)
+
+	manager.Stop()
+	select {
+	case err := <-waitResult:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		assert.Fail(t, "Wait did not return after stop")
+	}
+}
+
+func TestManager_RotateTLSMaterialIsPostponedWhilePreviousServerHasSessions(t *testing.T) {
+	stored, err := pki.NewMaterial(caSubject, pkix.Name{CommonName: "0x1"}, now.Add(-2*time.Hour))
+	assert.NoError(t, err)
+	storage := &sessionStorageFake{
+		sessions: []session.Session{
+			{ID: "old", Config: &openvpn_service.VPNConfig{TLSPresharedKey: stored.PresharedKey}},
+		},
+	}
+	manager, processes := newTestManager(&tlsStoreFake{material: stored}, storage)
+	manager.current, err = manager.startServer(stored, manager.port)
+	assert.NoError(t, err)
+
+	assert.NoError(t, manager.rotateTLSMaterialIfDue())
+	rotated := manager.currentServer()
+	assert.Len(t, *processes, 2)
+
+	manager.timeGetter = func() time.Time { return now.Add(2 * time.Hour) }
+	assert.NoError(t, manager.rotateTLSMaterialIfDue())
+	assert.Equal(t, rotated, manager.currentServer())
+	assert.Len(t, *processes, 2)
+
+	storage.sessions = nil
+	assert.NoError(t, manager.rotateTLSMaterialIfDue())
+	assert.NotEqual(t, rotated.TLSMaterial.PresharedKey, manager.currentServer().TLSMaterial.PresharedKey)
+	assert.Equal(t, 1194, manager.currentServer().Port)
+	assert.Len(t, *processes, 3)
+	assert.True(t, (*processes)[0].stopped())
+	assert.True(t, (*processes)[1].stopped(), "previous server without sessions is stopped right after rotation")
+
+	manager.Stop()
+}
+
+func TestManager_RotateTLSMaterialTerminatesSessionsAfterGracePeriod(t *testing.T) {
+	stored, err := pki.NewMaterial(caSubject, pkix.Name{CommonName: "0x1"}, now.Add(-2*time.Hour))
+	assert.NoError(t, err)
+	storage := &sessionStorageFake{
+		sessions: []session.Session{
+			{ID: "old", Config: &openvpn_service.VPNConfig{TLSPresharedKey: stored.PresharedKey}},
+			{ID: "other-service", Config: "wireguard config"},
+		},
+	}
+	manager, processes := newTestManager(&tlsStoreFake{material: stored}, storage)
+	manager.rotationPort = 443
+	manager.current, err = manager.startServer(stored, manager.port)
+	assert.NoError(t, err)
+
+	assert.NoError(t, manager.rotateTLSMaterialIfDue())
+	assert.Equal(t, 443, manager.currentServer().Port)
+	assert.False(t, (*processes)[0].stopped())
+
+	manager.timeGetter = func() time.Time { return now.Add(23 * time.Hour) }
+	assert.NoError(t, manager.rotateTLSMaterialIfDue())
+	assert.False(t, (*processes)[0].stopped(), "previous server keeps serving old sessions during grace period")
+	assert.Len(t, *processes, 2)
+
+	manager.timeGetter = func() time.Time { return now.Add(24 * time.Hour) }
+	assert.NoError(t, manager.rotateTLSMaterialIfDue())
+	assert.True(t, (*processes)[0].stopped(), "previous server is stopped after grace period")
+	assert.Equal(t, []session.Session{{ID: "other-service", Config: "wireguard config"}}, storage.sessions)
+	assert.Len(t, *processes, 3, "postponed rotation takes place")
+	assert.Equal(t, 1194, manager.currentServer().Port)
+
+	manager.Stop()
+}
+
+func TestManager_StartFailsWhenRotationPortIsServicePort(t *testing.T) {
+	manager, _ := newTestManager(&tlsStoreFake{}, &sessionStorageFake{})
+	manager.rotationPort = manager.port
+
+	_, _, err := manager.Start(identity.FromAddress("0x1"))
+	assert.Equal(t, errRotationPortInUse, err)
+}
+
+func TestRotationPort(t *testing.T) {
+	assert.Equal(t, 1195, rotationPort(Options{OpenvpnPort: 1194}))
+	assert.Equal(t, 443, rotationPort(Options{OpenvpnPort: 1194, RotationPort: 443}))
+}
+
+func TestManager_SessionDNSReplacesLocal(t *testing.T) {
+	manager, _ := newTestManager(&tlsStoreFake{}, &sessionStorageFake{})
+	_, subnet, _ := net.ParseCIDR("10.8.3.0/24")
+
+	manager.dns = []string{"1.1.1.1"}
+	dns, local := manager.sessionDNS(subnet)
+	assert.Equal(t, []string{"1.1.1.1"}, dns)
+	assert.False(t, local)
+
+	manager.dns = []string{LocalDNS, "1.1.1.1"}
+	dns, local = manager.sessionDNS(subnet)
+	assert.Equal(t, []string{"10.8.3.1", "1.1.1.1"}, dns)
+	assert.True(t, local)
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package session
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/mysteriumnetwork/go-openvpn/openvpn/management"
+)
+
+// ErrManagementNotConnected is returned when client is killed before OpenVPN management interface is connected
+var ErrManagementNotConnected = errors.New("management interface is not connected")
+
+// ClientKiller is a management middleware, which disconnects OpenVPN clients on request
+type ClientKiller struct {
+	lock       sync.Mutex
+	connection management.Connection
+}
+
+// NewClientKiller creates client killer, it has to be registered as OpenVPN server middleware
+func NewClientKiller() *ClientKiller {
+	return &ClientKiller{}
+}
+
+// Start remembers management connection, which is used to kill clients
+func (killer *ClientKiller) Start(connection management.Connection) error {
+	killer.lock.Lock()
+	defer killer.lock.Unlock()
+
+	killer.connection = connection
+	return nil
+}
+
+// Stop forgets management connection
+func (killer *ClientKiller) Stop(connection management.Connection) error {
+	killer.lock.Lock()
+	defer killer.lock.Unlock()
+
+	killer.connection = nil
+	return nil
+}
+
+// ConsumeLine does not consume any lines
+func (killer *ClientKiller) ConsumeLine(line string) (bool, error) {
+	return false, nil
+}
+
+// Kill disconnects OpenVPN client with given id
+func (killer *ClientKiller) Kill(clientID int) error {
+	killer.lock.Lock()
+	defer killer.lock.Unlock()
+
+	if killer.connection == nil {
+		return ErrManagementNotConnected
+	}
+	_, err := killer.connection.SingleLineCommand("client-kill %d", clientID)
+	return err
+}
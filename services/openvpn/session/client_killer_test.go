@@ -0,0 +1,91 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package session
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mysteriumnetwork/go-openvpn/openvpn/management"
+	"github.com/mysteriumnetwork/node/core/events"
+	"github.com/mysteriumnetwork/node/session"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientKiller_Kill(t *testing.T) {
+	killer := NewClientKiller()
+	assert.Equal(t, ErrManagementNotConnected, killer.Kill(1))
+
+	connection := &management.MockConnection{}
+	assert.NoError(t, killer.Start(connection))
+	assert.NoError(t, killer.Kill(1))
+	assert.Equal(t, "client-kill 1", connection.LastLine)
+
+	assert.NoError(t, killer.Stop(connection))
+	assert.Equal(t, ErrManagementNotConnected, killer.Kill(2))
+}
+
+func newRevocableValidator(storage SessionMap) (*Validator, chan int) {
+	killed := make(chan int, 1)
+	validator := NewValidator(storage, &mockIdentityExtractor{identityExisting, nil}, func(int) {}, func(clientID int) error {
+		killed <- clientID
+		return nil
+	})
+	return validator, killed
+}
+
+func TestValidator_KillsClientOfRemovedSession(t *testing.T) {
+	storage := session.NewStorageMemory(events.NewPublisherFake())
+	sessionInstance := sessionExisting
+	sessionInstance.Done = make(chan struct{})
+	storage.Add(sessionInstance)
+	validator, killed := newRevocableValidator(storage)
+
+	authenticated, err := validator.Validate(5, sessionExistingString, "not important")
+	assert.NoError(t, err)
+	assert.True(t, authenticated)
+
+	storage.Remove(sessionInstance.ID)
+	select {
+	case clientID := <-killed:
+		assert.Equal(t, 5, clientID)
+	case <-time.After(time.Second):
+		assert.Fail(t, "client was not killed")
+	}
+
+	authenticated, err = validator.Validate(6, sessionExistingString, "not important")
+	assert.Error(t, err)
+	assert.False(t, authenticated)
+}
+
+func TestValidator_DoesNotKillDisconnectedClient(t *testing.T) {
+	storage := session.NewStorageMemory(events.NewPublisherFake())
+	sessionInstance := sessionExisting
+	sessionInstance.Done = make(chan struct{})
+	storage.Add(sessionInstance)
+	validator, killed := newRevocableValidator(storage)
+
+	validator.Validate(5, sessionExistingString, "not important")
+	assert.NoError(t, validator.Cleanup(sessionExistingString))
+
+	select {
+	case clientID := <-killed:
+		assert.Fail(t, "disconnected client was killed", "client: %d", clientID)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
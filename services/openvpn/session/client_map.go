@@ -28,6 +28,8 @@ import (
 type SessionMap interface {
 	Add(session.Session)
 	Find(session.ID) (session.Session, bool)
+	MarkConnected(session.ID)
+	AddDataTransfer(session.ID, session.DataTransfer)
 	Remove(session.ID)
 }
 
@@ -45,7 +47,10 @@ func (cm *clientMap) FindClientSession(clientID int, id session.ID) (session.Ses
 		return session.Session{}, false, errors.New("no underlying session exists, possible break-in attempt")
 	}
 
+	cm.sessionMapLock.Lock()
 	sessionClientID, clientIDExist := cm.sessionClientIDs[id]
+	cm.sessionMapLock.Unlock()
+
 	if clientIDExist && clientID != sessionClientID {
 		return sessionInstance, false, errors.New("provided clientID does not mach active clientID")
 	}
@@ -66,6 +71,36 @@ func (cm *clientMap) UpdateClientSession(clientID int, id session.ID) bool {
 	return cm.sessionClientIDs[id] == clientID
 }
 
+// FindSessionClientID returns OpenVPN client id of given session
+func (cm *clientMap) FindSessionClientID(id session.ID) (int, bool) {
+	cm.sessionMapLock.Lock()
+	defer cm.sessionMapLock.Unlock()
+
+	clientID, clientIDExist := cm.sessionClientIDs[id]
+	return clientID, clientIDExist
+}
+
+// FindClientSessionID returns id of the session, which OpenVPN client is authenticated with
+func (cm *clientMap) FindClientSessionID(clientID int) (session.ID, bool) {
+	cm.sessionMapLock.Lock()
+	defer cm.sessionMapLock.Unlock()
+
+	for id, sessionClientID := range cm.sessionClientIDs {
+		if sessionClientID == clientID {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// RemoveClient forgets OpenVPN client of given session
+func (cm *clientMap) RemoveClient(id session.ID) {
+	cm.sessionMapLock.Lock()
+	defer cm.sessionMapLock.Unlock()
+
+	delete(cm.sessionClientIDs, id)
+}
+
 // RemoveSession removes given session from underlying session managers
 func (cm *clientMap) RemoveSession(id session.ID) error {
 	cm.sessionMapLock.Lock()
@@ -18,23 +18,45 @@
 package session
 
 import (
+	"errors"
+	"strconv"
 	"sync"
 
+	log "github.com/cihub/seelog"
 	"github.com/mysteriumnetwork/node/identity"
 	"github.com/mysteriumnetwork/node/session"
 )
 
+const logPrefix = "[openvpn-session] "
+
 // SignaturePrefix is used to prefix with each session string before calculating signature or extracting identity
 const SignaturePrefix = "MystVpnSessionId:"
 
+// ClientCleanup releases resources held by OpenVPN client, when its session is cleaned up
+type ClientCleanup func(clientID int)
+
+// ClientKill disconnects OpenVPN client
+type ClientKill func(clientID int) error
+
 // Validator structure that keeps attributes needed Validator operations
 type Validator struct {
 	clientMap         *clientMap
 	identityExtractor identity.Extractor
+	clientCleanup     ClientCleanup
+	clientKill        ClientKill
+
+	// clientTransfers keeps the latest traffic counters reported for every OpenVPN client
+	clientTransfers map[int]session.DataTransfer
+	transferLock    sync.Mutex
 }
 
 // NewValidator return Validator instance
-func NewValidator(sessionMap SessionMap, extractor identity.Extractor) *Validator {
+func NewValidator(
+	sessionMap SessionMap,
+	extractor identity.Extractor,
+	clientCleanup ClientCleanup,
+	clientKill ClientKill,
+) *Validator {
 	return &Validator{
 		clientMap: &clientMap{
 			sessions:         sessionMap,
@@ -42,6 +64,9 @@ func NewValidator(sessionMap SessionMap, extractor identity.Extractor) *Validato
 			sessionMapLock:   sync.Mutex{},
 		},
 		identityExtractor: extractor,
+		clientCleanup:     clientCleanup,
+		clientKill:        clientKill,
+		clientTransfers:   make(map[int]session.DataTransfer),
 	}
 }
 
@@ -64,12 +89,78 @@ func (v *Validator) Validate(clientID int, sessionString, signatureString string
 	if err != nil {
 		return false, err
 	}
-	return currentSession.ConsumerID == extractedIdentity, nil
+	if currentSession.ConsumerID != extractedIdentity {
+		return false, nil
+	}
+
+	v.clientMap.sessions.MarkConnected(sessionID)
+	if !found && currentSession.Done != nil {
+		go v.killClientWhenDone(clientID, currentSession)
+	}
+	return true, nil
+}
+
+// killClientWhenDone disconnects client, when its session is destroyed by provider, i.e. revoked or unpaid.
+// Client is not killed, when session is cleaned up because of client disconnect.
+// Destroyed session can not be used to authenticate again.
+func (v *Validator) killClientWhenDone(clientID int, sessionInstance session.Session) {
+	<-sessionInstance.Done
+
+	if _, found := v.clientMap.FindSessionClientID(sessionInstance.ID); !found {
+		return
+	}
+	v.clientMap.RemoveClient(sessionInstance.ID)
+	v.forgetClientTransfer(clientID)
+	v.clientCleanup(clientID)
+
+	log.Info(logPrefix, "Killing client ", clientID, " of destroyed session: ", sessionInstance.ID)
+	if err := v.clientKill(clientID); err != nil {
+		log.Error(logPrefix, "Failed to kill client ", clientID, ": ", err)
+	}
+}
+
+// UpdateDataTransfer adds traffic of OpenVPN client, counted since its previous report, to its session.
+// Bytes received from client are counted as download, bytes sent to client - as upload.
+// Counters start over, when client reconnects, so traffic of earlier connections stays in the session.
+func (v *Validator) UpdateDataTransfer(clientID int, bytesIn, bytesOut uint64) error {
+	sessionID, found := v.clientMap.FindClientSessionID(clientID)
+	if !found {
+		return errors.New("no session found for client: " + strconv.Itoa(clientID))
+	}
+
+	current := session.DataTransfer{Up: bytesOut, Down: bytesIn}
+	v.transferLock.Lock()
+	previous := v.clientTransfers[clientID]
+	v.clientTransfers[clientID] = current
+	v.transferLock.Unlock()
+
+	v.clientMap.sessions.AddDataTransfer(sessionID, transferredSince(previous, current))
+	return nil
+}
+
+func (v *Validator) forgetClientTransfer(clientID int) {
+	v.transferLock.Lock()
+	defer v.transferLock.Unlock()
+
+	delete(v.clientTransfers, clientID)
+}
+
+// transferredSince returns traffic counted after the previous counters were reported.
+// Counters lower than previous ones mean that they were restarted.
+func transferredSince(previous, current session.DataTransfer) session.DataTransfer {
+	if current.Up < previous.Up || current.Down < previous.Down {
+		return current
+	}
+	return session.DataTransfer{Up: current.Up - previous.Up, Down: current.Down - previous.Down}
 }
 
 // Cleanup removes session from underlying session managers
 func (v *Validator) Cleanup(sessionString string) error {
 	sessionID := session.ID(sessionString)
 
+	if clientID, found := v.clientMap.FindSessionClientID(sessionID); found {
+		v.forgetClientTransfer(clientID)
+		v.clientCleanup(clientID)
+	}
 	return v.clientMap.RemoveSession(sessionID)
 }
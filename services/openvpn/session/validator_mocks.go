@@ -28,10 +28,10 @@ func mockValidator(identityToExtract identity.Identity) *Validator {
 		nil,
 	}
 	mockSessions := &mockSessions{
-		session.Session{},
-		false,
+		OnFindReturnSession: session.Session{},
+		OnFindReturnSuccess: false,
 	}
-	return NewValidator(mockSessions, mockExtractor)
+	return NewValidator(mockSessions, mockExtractor, func(int) {}, func(int) error { return nil })
 }
 
 func mockValidatorWithSession(identityToExtract identity.Identity, sessionInstance session.Session) *Validator {
@@ -40,10 +40,10 @@ func mockValidatorWithSession(identityToExtract identity.Identity, sessionInstan
 		nil,
 	}
 	mockSessions := &mockSessions{
-		sessionInstance,
-		true,
+		OnFindReturnSession: sessionInstance,
+		OnFindReturnSuccess: true,
 	}
-	return NewValidator(mockSessions, mockExtractor)
+	return NewValidator(mockSessions, mockExtractor, func(int) {}, func(int) error { return nil })
 }
 
 // mockIdentityExtractor mocked identity extractor
@@ -60,6 +60,8 @@ func (extractor *mockIdentityExtractor) Extract(message []byte, signature identi
 type mockSessions struct {
 	OnFindReturnSession session.Session
 	OnFindReturnSuccess bool
+	MarkedConnected     bool
+	DataTransfer        session.DataTransfer
 }
 
 func (sessions *mockSessions) Add(sessionInstance session.Session) {
@@ -69,6 +71,15 @@ func (sessions *mockSessions) Find(session.ID) (session.Session, bool) {
 	return sessions.OnFindReturnSession, sessions.OnFindReturnSuccess
 }
 
+func (sessions *mockSessions) MarkConnected(session.ID) {
+	sessions.MarkedConnected = true
+}
+
+func (sessions *mockSessions) AddDataTransfer(id session.ID, dataTransfer session.DataTransfer) {
+	sessions.DataTransfer.Up += dataTransfer.Up
+	sessions.DataTransfer.Down += dataTransfer.Down
+}
+
 func (sessions *mockSessions) Remove(session.ID) {
 	sessions.OnFindReturnSession = session.Session{}
 	sessions.OnFindReturnSuccess = false
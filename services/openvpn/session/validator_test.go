@@ -54,6 +54,7 @@ func TestValidateReturnsFalseWhenSignatureIsInvalid(t *testing.T) {
 
 	assert.NoError(t, err)
 	assert.False(t, authenticated)
+	assert.False(t, validator.clientMap.sessions.(*mockSessions).MarkedConnected)
 }
 
 func TestValidateReturnsTrueWhenSessionExistsAndSignatureIsValid(t *testing.T) {
@@ -63,6 +64,7 @@ func TestValidateReturnsTrueWhenSessionExistsAndSignatureIsValid(t *testing.T) {
 
 	assert.NoError(t, err)
 	assert.True(t, authenticated)
+	assert.True(t, validator.clientMap.sessions.(*mockSessions).MarkedConnected)
 }
 
 func TestValidateReturnsFalseWhenSessionExistsAndSignatureIsValidAndClientIDDiffers(t *testing.T) {
@@ -96,6 +98,23 @@ func TestCleanupReturnsNoErrorIfSessionIsCleared(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestCleanupReleasesClientOfSession(t *testing.T) {
+	mockSessions := &mockSessions{OnFindReturnSession: sessionExisting, OnFindReturnSuccess: true}
+	mockExtractor := &mockIdentityExtractor{identityExisting, nil}
+	var cleanedClientIDs []int
+	validator := NewValidator(mockSessions, mockExtractor, func(clientID int) {
+		cleanedClientIDs = append(cleanedClientIDs, clientID)
+	}, func(int) error { return nil })
+
+	validator.Validate(7, sessionExistingString, "not important")
+	err := validator.Cleanup(sessionExistingString)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{7}, cleanedClientIDs)
+
+	validator.Cleanup(sessionExistingString)
+	assert.Equal(t, []int{7}, cleanedClientIDs, "client is released once")
+}
+
 func TestCleanupReturnsErrorIfSessionNotExists(t *testing.T) {
 	validator := mockValidator(identityExisting)
 
@@ -103,3 +122,48 @@ func TestCleanupReturnsErrorIfSessionNotExists(t *testing.T) {
 
 	assert.Errorf(t, err, "no underlying session exists: nonexistent_session")
 }
+
+func TestUpdateDataTransferSavesCountersToClientSession(t *testing.T) {
+	mockSessions := &mockSessions{OnFindReturnSession: sessionExisting, OnFindReturnSuccess: true}
+	validator := NewValidator(mockSessions, &mockIdentityExtractor{identityExisting, nil}, func(int) {}, func(int) error { return nil })
+
+	validator.Validate(1, sessionExistingString, "not important")
+	err := validator.UpdateDataTransfer(1, 100, 200)
+
+	assert.NoError(t, err)
+	assert.Equal(t, session.DataTransfer{Up: 200, Down: 100}, mockSessions.DataTransfer)
+}
+
+func TestUpdateDataTransferAddsTrafficSincePreviousCounters(t *testing.T) {
+	mockSessions := &mockSessions{OnFindReturnSession: sessionExisting, OnFindReturnSuccess: true}
+	validator := NewValidator(mockSessions, &mockIdentityExtractor{identityExisting, nil}, func(int) {}, func(int) error { return nil })
+
+	validator.Validate(1, sessionExistingString, "not important")
+	assert.NoError(t, validator.UpdateDataTransfer(1, 100, 200))
+	assert.NoError(t, validator.UpdateDataTransfer(1, 150, 300))
+	assert.Equal(t, session.DataTransfer{Up: 300, Down: 150}, mockSessions.DataTransfer)
+
+	assert.NoError(t, validator.UpdateDataTransfer(1, 10, 20))
+	assert.Equal(t, session.DataTransfer{Up: 320, Down: 160}, mockSessions.DataTransfer)
+}
+
+func TestUpdateDataTransferKeepsTrafficOfReconnectedClient(t *testing.T) {
+	mockSessions := &mockSessions{OnFindReturnSession: sessionExisting, OnFindReturnSuccess: true}
+	validator := NewValidator(mockSessions, &mockIdentityExtractor{identityExisting, nil}, func(int) {}, func(int) error { return nil })
+
+	validator.Validate(1, sessionExistingString, "not important")
+	assert.NoError(t, validator.UpdateDataTransfer(1, 100, 200))
+
+	validator.clientMap.RemoveClient(sessionExisting.ID)
+	validator.Validate(2, sessionExistingString, "not important")
+	assert.NoError(t, validator.UpdateDataTransfer(2, 10, 20))
+	assert.Equal(t, session.DataTransfer{Up: 220, Down: 110}, mockSessions.DataTransfer)
+}
+
+func TestUpdateDataTransferReturnsErrorForUnknownClient(t *testing.T) {
+	validator := mockValidatorWithSession(identityExisting, sessionExisting)
+
+	err := validator.UpdateDataTransfer(2, 100, 200)
+
+	assert.EqualError(t, err, "no session found for client: 2")
+}
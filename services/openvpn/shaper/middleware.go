@@ -0,0 +1,68 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package shaper
+
+import (
+	"regexp"
+	"strconv"
+
+	log "github.com/cihub/seelog"
+	"github.com/mysteriumnetwork/go-openvpn/openvpn/management"
+)
+
+// addressLine is sent by OpenVPN management interface when VPN address is assigned to client, i.e.
+// ">CLIENT:ADDRESS,{CID},{ADDR},{PRI}"
+var addressLine = regexp.MustCompile(`^>CLIENT:ADDRESS,(\d+),([^,]+),`)
+
+// ClientLimiter limits bandwidth of OpenVPN client by its VPN address
+type ClientLimiter interface {
+	Limit(clientID int, address string) error
+}
+
+type middleware struct {
+	limiter ClientLimiter
+}
+
+// NewMiddleware creates management middleware, which limits bandwidth of every client once it gets VPN address
+func NewMiddleware(limiter ClientLimiter) management.Middleware {
+	return &middleware{limiter: limiter}
+}
+
+func (m *middleware) Start(connection management.Connection) error {
+	return nil
+}
+
+func (m *middleware) Stop(connection management.Connection) error {
+	return nil
+}
+
+func (m *middleware) ConsumeLine(line string) (bool, error) {
+	match := addressLine.FindStringSubmatch(line)
+	if len(match) == 0 {
+		return false, nil
+	}
+
+	clientID, err := strconv.Atoi(match[1])
+	if err != nil {
+		return true, err
+	}
+	if err := m.limiter.Limit(clientID, match[2]); err != nil {
+		log.Error(logPrefix, "Failed to limit bandwidth of client ", clientID, ": ", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,232 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package shaper
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+
+	log "github.com/cihub/seelog"
+	"github.com/mysteriumnetwork/node/datasize"
+)
+
+const logPrefix = "[openvpn-shaper] "
+
+// ingressHandle is the handle of ingress qdisc, which polices traffic uploaded by clients
+const ingressHandle = "ffff:"
+
+// maxClass is the biggest htb class minor number and filter priority accepted by tc
+const maxClass = 0xffff
+
+// ErrNoFreeClass is returned when every htb class number is taken by limited clients
+var ErrNoFreeClass = errors.New("no free traffic class left for client")
+
+// commandExecutor runs system command with given arguments
+type commandExecutor func(name string, args ...string) error
+
+// Shaper limits bandwidth of every OpenVPN client to the same rate using tc on server tun device.
+// Traffic downloaded by client is shaped by htb class, traffic uploaded by client is policed on ingress.
+type Shaper struct {
+	bandwidth datasize.BitSize
+	execute   commandExecutor
+
+	lock    sync.Mutex
+	device  string
+	ready   bool
+	clients map[int]shapedClient
+}
+
+type shapedClient struct {
+	address string
+	// class is used as htb class minor number and as priority of filters, so they can be deleted together.
+	// Numbers of unlimited clients are reused, so they stay within range accepted by tc.
+	class int
+}
+
+// NewShaper creates shaper, which limits clients to given bandwidth. Clients are not limited if bandwidth is 0
+func NewShaper(bandwidth datasize.BitSize) *Shaper {
+	return newShaper(bandwidth, sudoTC)
+}
+
+func newShaper(bandwidth datasize.BitSize, executor commandExecutor) *Shaper {
+	return &Shaper{
+		bandwidth: bandwidth,
+		execute:   executor,
+		clients:   make(map[int]shapedClient),
+	}
+}
+
+// Start assigns tun device of the server, qdiscs are created with the first limited client,
+// as device appears only after server starts
+func (shaper *Shaper) Start(device string) {
+	shaper.lock.Lock()
+	defer shaper.lock.Unlock()
+
+	shaper.device = device
+}
+
+// Stop removes all limits from the device
+func (shaper *Shaper) Stop() {
+	shaper.lock.Lock()
+	defer shaper.lock.Unlock()
+
+	if shaper.ready {
+		if err := shaper.tc("qdisc", "del", "dev", shaper.device, "root"); err != nil {
+			log.Warn(logPrefix, "Failed to remove qdisc: ", err)
+		}
+		if err := shaper.tc("qdisc", "del", "dev", shaper.device, "handle", ingressHandle, "ingress"); err != nil {
+			log.Warn(logPrefix, "Failed to remove ingress qdisc: ", err)
+		}
+	}
+	shaper.ready = false
+	shaper.clients = make(map[int]shapedClient)
+}
+
+// Limit limits bandwidth of client, which was assigned given VPN address
+func (shaper *Shaper) Limit(clientID int, address string) error {
+	shaper.lock.Lock()
+	defer shaper.lock.Unlock()
+
+	if shaper.bandwidth == 0 || shaper.device == "" {
+		return nil
+	}
+	if client, found := shaper.clients[clientID]; found {
+		if client.address == address {
+			return nil
+		}
+		shaper.unlimit(clientID, client)
+	}
+
+	if !shaper.ready {
+		if err := shaper.setup(); err != nil {
+			return err
+		}
+	}
+
+	classNumber, err := shaper.freeClass()
+	if err != nil {
+		return err
+	}
+	client := shapedClient{address: address, class: classNumber}
+	classID := classID(client.class)
+	priority := strconv.Itoa(client.class)
+	rate := fmt.Sprintf("%dbit", shaper.bandwidth.Bits())
+	// police needs burst to accommodate traffic of 100ms
+	burst := fmt.Sprintf("%db", maxInt(int(shaper.bandwidth.Bytes()/10), 16*1024))
+
+	commands := [][]string{
+		{"class", "add", "dev", shaper.device, "parent", "1:", "classid", classID, "htb", "rate", rate},
+		{
+			"filter", "add", "dev", shaper.device, "parent", "1:", "protocol", "ip", "prio", priority,
+			"u32", "match", "ip", "dst", address + "/32", "flowid", classID,
+		},
+		{
+			"filter", "add", "dev", shaper.device, "parent", ingressHandle, "protocol", "ip", "prio", priority,
+			"u32", "match", "ip", "src", address + "/32", "police", "rate", rate, "burst", burst, "drop", "flowid", ":1",
+		},
+	}
+	for _, command := range commands {
+		if err := shaper.tc(command...); err != nil {
+			shaper.unlimit(clientID, client)
+			return err
+		}
+	}
+
+	shaper.clients[clientID] = client
+	log.Info(logPrefix, "Client ", clientID, " with address ", address, " limited to ", shaper.bandwidth, "/s")
+	return nil
+}
+
+// Unlimit removes limits of given client, does nothing if client is not limited
+func (shaper *Shaper) Unlimit(clientID int) {
+	shaper.lock.Lock()
+	defer shaper.lock.Unlock()
+
+	if client, found := shaper.clients[clientID]; found {
+		shaper.unlimit(clientID, client)
+	}
+}
+
+func (shaper *Shaper) setup() error {
+	if err := shaper.tc("qdisc", "add", "dev", shaper.device, "root", "handle", "1:", "htb"); err != nil {
+		return err
+	}
+	if err := shaper.tc("qdisc", "add", "dev", shaper.device, "handle", ingressHandle, "ingress"); err != nil {
+		shaper.tc("qdisc", "del", "dev", shaper.device, "root")
+		return err
+	}
+	shaper.ready = true
+	return nil
+}
+
+// freeClass returns the lowest class number, which is not used by limited clients
+func (shaper *Shaper) freeClass() (int, error) {
+	used := make(map[int]bool, len(shaper.clients))
+	for _, client := range shaper.clients {
+		used[client.class] = true
+	}
+	for class := 1; class <= maxClass; class++ {
+		if !used[class] {
+			return class, nil
+		}
+	}
+	return 0, ErrNoFreeClass
+}
+
+func (shaper *Shaper) unlimit(clientID int, client shapedClient) {
+	priority := strconv.Itoa(client.class)
+	commands := [][]string{
+		{"filter", "del", "dev", shaper.device, "parent", "1:", "prio", priority},
+		{"filter", "del", "dev", shaper.device, "parent", ingressHandle, "prio", priority},
+		{"class", "del", "dev", shaper.device, "classid", classID(client.class)},
+	}
+	for _, command := range commands {
+		if err := shaper.tc(command...); err != nil {
+			log.Warn(logPrefix, "Failed to remove limit of client ", clientID, ": ", err)
+		}
+	}
+	delete(shaper.clients, clientID)
+}
+
+func (shaper *Shaper) tc(args ...string) error {
+	return shaper.execute("tc", args...)
+}
+
+// classID formats htb class id, tc parses class minor number as hexadecimal
+func classID(class int) string {
+	return fmt.Sprintf("1:%x", class)
+}
+
+func sudoTC(name string, args ...string) error {
+	cmd := exec.Command("sudo", append([]string{name}, args...)...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return errors.New(name + " " + strings.Join(args, " ") + " failed: " + err.Error() + " " + strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
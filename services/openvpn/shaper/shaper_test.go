@@ -0,0 +1,174 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package shaper
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mysteriumnetwork/node/datasize"
+	"github.com/stretchr/testify/assert"
+)
+
+type executorFake struct {
+	commands []string
+	failOn   string
+}
+
+func (executor *executorFake) execute(name string, args ...string) error {
+	command := strings.Join(append([]string{name}, args...), " ")
+	executor.commands = append(executor.commands, command)
+	if executor.failOn != "" && strings.HasPrefix(command, executor.failOn) {
+		return errors.New("command failed")
+	}
+	return nil
+}
+
+func TestShaper_LimitAndUnlimit(t *testing.T) {
+	executor := &executorFake{}
+	shaper := newShaper(datasize.MB, executor.execute)
+	shaper.Start("mysttun0")
+
+	assert.NoError(t, shaper.Limit(3, "10.8.0.2"))
+	assert.NoError(t, shaper.Limit(3, "10.8.0.2"), "client is limited once")
+	shaper.Unlimit(3)
+	shaper.Unlimit(3)
+
+	assert.Equal(
+		t,
+		[]string{
+			"tc qdisc add dev mysttun0 root handle 1: htb",
+			"tc qdisc add dev mysttun0 handle ffff: ingress",
+			"tc class add dev mysttun0 parent 1: classid 1:1 htb rate 8388608bit",
+			"tc filter add dev mysttun0 parent 1: protocol ip prio 1 u32 match ip dst 10.8.0.2/32 flowid 1:1",
+			"tc filter add dev mysttun0 parent ffff: protocol ip prio 1 u32 match ip src 10.8.0.2/32 police rate 8388608bit burst 104857b drop flowid :1",
+			"tc filter del dev mysttun0 parent 1: prio 1",
+			"tc filter del dev mysttun0 parent ffff: prio 1",
+			"tc class del dev mysttun0 classid 1:1",
+		},
+		executor.commands,
+	)
+}
+
+func TestShaper_LimitSeveralClients(t *testing.T) {
+	executor := &executorFake{}
+	shaper := newShaper(datasize.MB, executor.execute)
+	shaper.Start("mysttun0")
+
+	assert.NoError(t, shaper.Limit(1, "10.8.0.2"))
+	assert.NoError(t, shaper.Limit(2, "10.8.0.3"))
+	assert.Len(t, executor.commands, 8, "qdiscs are created once")
+	assert.Equal(t, "tc class add dev mysttun0 parent 1: classid 1:2 htb rate 8388608bit", executor.commands[5])
+
+	executor.commands = nil
+	shaper.Stop()
+	assert.Equal(
+		t,
+		[]string{
+			"tc qdisc del dev mysttun0 root",
+			"tc qdisc del dev mysttun0 handle ffff: ingress",
+		},
+		executor.commands,
+	)
+}
+
+func TestShaper_ClassesAreReusedAndFormattedAsHex(t *testing.T) {
+	executor := &executorFake{}
+	shaper := newShaper(datasize.MB, executor.execute)
+	shaper.Start("mysttun0")
+
+	for clientID := 1; clientID <= 10; clientID++ {
+		assert.NoError(t, shaper.Limit(clientID, fmt.Sprintf("10.8.0.%d", clientID+1)))
+	}
+	assert.Equal(
+		t,
+		[]string{
+			"tc class add dev mysttun0 parent 1: classid 1:a htb rate 8388608bit",
+			"tc filter add dev mysttun0 parent 1: protocol ip prio 10 u32 match ip dst 10.8.0.11/32 flowid 1:a",
+			"tc filter add dev mysttun0 parent ffff: protocol ip prio 10 u32 match ip src 10.8.0.11/32 police rate 8388608bit burst 104857b drop flowid :1",
+		},
+		executor.commands[len(executor.commands)-3:],
+	)
+
+	shaper.Unlimit(2)
+	executor.commands = nil
+	assert.NoError(t, shaper.Limit(11, "10.8.0.12"))
+	assert.Equal(t, "tc class add dev mysttun0 parent 1: classid 1:2 htb rate 8388608bit", executor.commands[0])
+}
+
+func TestShaper_LimitFailsWithoutFreeClass(t *testing.T) {
+	executor := &executorFake{}
+	shaper := newShaper(datasize.MB, executor.execute)
+	shaper.Start("mysttun0")
+	shaper.ready = true
+	for class := 1; class <= maxClass; class++ {
+		shaper.clients[class] = shapedClient{address: "10.8.0.2", class: class}
+	}
+
+	assert.Exactly(t, ErrNoFreeClass, shaper.Limit(0, "10.8.0.3"))
+	assert.Empty(t, executor.commands)
+}
+
+func TestShaper_LimitFailure(t *testing.T) {
+	executor := &executorFake{failOn: "tc filter add dev mysttun0 parent ffff:"}
+	shaper := newShaper(datasize.MB, executor.execute)
+	shaper.Start("mysttun0")
+
+	assert.EqualError(t, shaper.Limit(1, "10.8.0.2"), "command failed")
+	assert.Equal(t, "tc class del dev mysttun0 classid 1:1", executor.commands[len(executor.commands)-1])
+	assert.Empty(t, shaper.clients)
+}
+
+func TestShaper_Unlimited(t *testing.T) {
+	executor := &executorFake{}
+	shaper := newShaper(0, executor.execute)
+	shaper.Start("mysttun0")
+
+	assert.NoError(t, shaper.Limit(1, "10.8.0.2"))
+	shaper.Unlimit(1)
+	shaper.Stop()
+	assert.Empty(t, executor.commands)
+}
+
+type limiterFake struct {
+	clientID int
+	address  string
+}
+
+func (fake *limiterFake) Limit(clientID int, address string) error {
+	fake.clientID = clientID
+	fake.address = address
+	return nil
+}
+
+func TestMiddleware_ConsumeLine(t *testing.T) {
+	limiter := &limiterFake{}
+	middleware := NewMiddleware(limiter)
+
+	consumed, err := middleware.ConsumeLine(">CLIENT:ADDRESS,115,10.8.0.2,1")
+	assert.NoError(t, err)
+	assert.True(t, consumed)
+	assert.Equal(t, &limiterFake{clientID: 115, address: "10.8.0.2"}, limiter)
+
+	consumed, err = middleware.ConsumeLine(">CLIENT:CONNECT,116,1")
+	assert.NoError(t, err)
+	assert.False(t, consumed)
+	assert.Equal(t, 115, limiter.clientID)
+}
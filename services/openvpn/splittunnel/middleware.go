@@ -0,0 +1,145 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package splittunnel
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/cihub/seelog"
+	"github.com/mysteriumnetwork/go-openvpn/openvpn/management"
+)
+
+// Domains are destinations, which are resolved again periodically and routed the same way as some known address
+type Domains struct {
+	Names []string
+	// RoutedLike is an address, which is already routed the way resolved addresses should be
+	RoutedLike string
+	// Routed are networks, which are routed by connection config already
+	Routed []string
+}
+
+// Middleware keeps routes of domains up to date while OpenVPN client is connected
+type Middleware struct {
+	table    RouteTable
+	lookup   LookupFunc
+	interval time.Duration
+	domains  []Domains
+
+	connected chan struct{}
+	stop      chan struct{}
+	stopped   sync.WaitGroup
+	added     []string
+}
+
+// NewMiddleware creates management middleware, which resolves given domains every interval and routes new addresses of them
+func NewMiddleware(table RouteTable, lookup LookupFunc, interval time.Duration, domains ...Domains) *Middleware {
+	return &Middleware{
+		table:     table,
+		lookup:    lookup,
+		interval:  interval,
+		domains:   domains,
+		connected: make(chan struct{}, 1),
+	}
+}
+
+// Start starts refreshing routes, first refresh happens once client is connected
+func (m *Middleware) Start(connection management.Connection) error {
+	m.stop = make(chan struct{})
+	m.stopped.Add(1)
+	go m.refreshRoutes(m.stop)
+	return nil
+}
+
+// Stop stops refreshing routes and removes routes added during refreshes
+func (m *Middleware) Stop(connection management.Connection) error {
+	if m.stop != nil {
+		close(m.stop)
+		m.stopped.Wait()
+		m.stop = nil
+	}
+	return nil
+}
+
+// ConsumeLine watches for connected state of client, the line is left for other middlewares
+func (m *Middleware) ConsumeLine(line string) (bool, error) {
+	if strings.HasPrefix(line, ">STATE:") && strings.Contains(line, ",CONNECTED,") {
+		select {
+		case m.connected <- struct{}{}:
+		default:
+		}
+	}
+	return false, nil
+}
+
+func (m *Middleware) refreshRoutes(stop <-chan struct{}) {
+	defer m.stopped.Done()
+
+	var ticker *time.Ticker
+	var tick <-chan time.Time
+	defer func() {
+		if ticker != nil {
+			ticker.Stop()
+		}
+	}()
+
+	for {
+		select {
+		case <-stop:
+			m.removeRoutes()
+			return
+		case <-m.connected:
+			// tunnel may be recreated during reconnect, so routes of tunnel are added again
+			m.added = nil
+			if ticker == nil {
+				ticker = time.NewTicker(m.interval)
+				tick = ticker.C
+			}
+			m.addRoutes()
+		case <-tick:
+			m.addRoutes()
+		}
+	}
+}
+
+func (m *Middleware) addRoutes() {
+	for _, domains := range m.domains {
+		for _, cidr := range ResolveCIDRs(domains.Names, m.lookup) {
+			if contains(domains.Routed, cidr) || contains(m.added, cidr) {
+				continue
+			}
+			if err := m.table.AddLike(cidr, domains.RoutedLike); err != nil {
+				log.Error(logPrefix, "Failed to route ", cidr, ": ", err)
+				continue
+			}
+			log.Info(logPrefix, "Routed new address ", cidr, " like ", domains.RoutedLike)
+			m.added = append(m.added, cidr)
+		}
+	}
+}
+
+func (m *Middleware) removeRoutes() {
+	for _, cidr := range m.added {
+		// routes through tunnel are removed together with tunnel device
+		if err := m.table.Delete(cidr); err != nil {
+			log.Debug(logPrefix, "Failed to remove route ", cidr, ": ", err)
+		}
+	}
+	m.added = nil
+}
@@ -0,0 +1,142 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package splittunnel
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mysteriumnetwork/go-openvpn/openvpn/management"
+	"github.com/stretchr/testify/assert"
+)
+
+type routeTableFake struct {
+	lock    sync.Mutex
+	routes  map[string]string
+	deleted []string
+}
+
+func (table *routeTableFake) AddLike(network string, like string) error {
+	table.lock.Lock()
+	defer table.lock.Unlock()
+	table.routes[network] = like
+	return nil
+}
+
+func (table *routeTableFake) Delete(network string) error {
+	table.lock.Lock()
+	defer table.lock.Unlock()
+	table.deleted = append(table.deleted, network)
+	return nil
+}
+
+func (table *routeTableFake) routesCopy() map[string]string {
+	table.lock.Lock()
+	defer table.lock.Unlock()
+	routes := make(map[string]string)
+	for network, like := range table.routes {
+		routes[network] = like
+	}
+	return routes
+}
+
+type lookupFake struct {
+	lock  sync.Mutex
+	hosts map[string][]net.IP
+}
+
+func (lookup *lookupFake) set(host string, ips ...string) {
+	lookup.lock.Lock()
+	defer lookup.lock.Unlock()
+	lookup.hosts[host] = nil
+	for _, ip := range ips {
+		lookup.hosts[host] = append(lookup.hosts[host], net.ParseIP(ip))
+	}
+}
+
+func (lookup *lookupFake) Lookup(host string) ([]net.IP, error) {
+	lookup.lock.Lock()
+	defer lookup.lock.Unlock()
+	ips, found := lookup.hosts[host]
+	if !found {
+		return nil, errors.New("no such host")
+	}
+	return ips, nil
+}
+
+func TestResolveCIDRs(t *testing.T) {
+	lookup := &lookupFake{hosts: make(map[string][]net.IP)}
+	lookup.set("mysterium.network", "1.2.3.4", "2001:db8::1", "1.2.3.4")
+	lookup.set("example.com", "5.6.7.8")
+
+	assert.Equal(
+		t,
+		[]string{"1.2.3.4/32", "5.6.7.8/32"},
+		ResolveCIDRs([]string{"mysterium.network", "unknown.host", "example.com"}, lookup.Lookup),
+	)
+	assert.Equal(t, []string{}, ResolveCIDRs(nil, lookup.Lookup))
+}
+
+func TestMiddleware_RoutesNewAddressesWhenConnected(t *testing.T) {
+	lookup := &lookupFake{hosts: make(map[string][]net.IP)}
+	lookup.set("included.com", "1.1.1.1")
+	lookup.set("excluded.com", "2.2.2.2")
+	table := &routeTableFake{routes: make(map[string]string)}
+
+	middleware := NewMiddleware(
+		table,
+		lookup.Lookup,
+		10*time.Millisecond,
+		Domains{Names: []string{"included.com"}, RoutedLike: "1.1.1.1", Routed: []string{"1.1.1.1/32"}},
+		Domains{Names: []string{"excluded.com"}, RoutedLike: "9.9.9.9", Routed: []string{"2.2.2.2/32"}},
+	)
+	connection := &management.MockConnection{}
+	assert.NoError(t, middleware.Start(connection))
+
+	lookup.set("included.com", "1.1.1.1", "1.1.1.2")
+	lookup.set("excluded.com", "2.2.2.3")
+	time.Sleep(30 * time.Millisecond)
+	assert.Empty(t, table.routesCopy(), "routes are not refreshed until connected")
+
+	consumed, err := middleware.ConsumeLine(">STATE:1522855903,CONNECTED,SUCCESS,10.8.0.2,1.2.3.4,1194,,")
+	assert.NoError(t, err)
+	assert.False(t, consumed)
+	assert.NoError(t, waitFor(func() bool { return len(table.routesCopy()) == 2 }))
+	assert.Equal(t, map[string]string{"1.1.1.2/32": "1.1.1.1", "2.2.2.3/32": "9.9.9.9"}, table.routesCopy())
+
+	lookup.set("excluded.com", "2.2.2.4")
+	assert.NoError(t, waitFor(func() bool { return len(table.routesCopy()) == 3 }))
+
+	assert.NoError(t, middleware.Stop(connection))
+	assert.ElementsMatch(t, []string{"1.1.1.2/32", "2.2.2.3/32", "2.2.2.4/32"}, table.deleted)
+}
+
+func waitFor(f func() bool) error {
+	timeout := time.Now().Add(time.Second)
+	for time.Now().Before(timeout) {
+		if f() {
+			return nil
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return fmt.Errorf("Failed to wait for expected result")
+}
@@ -0,0 +1,61 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package splittunnel
+
+import (
+	"net"
+	"strings"
+
+	log "github.com/cihub/seelog"
+)
+
+const logPrefix = "[split-tunnel] "
+
+// LookupFunc resolves addresses of given host
+type LookupFunc func(host string) ([]net.IP, error)
+
+// ResolveCIDRs resolves IPv4 addresses of given domains into host networks, domains which fail to resolve are skipped
+func ResolveCIDRs(domains []string, lookup LookupFunc) []string {
+	cidrs := make([]string, 0)
+	for _, domain := range domains {
+		ips, err := lookup(strings.TrimSpace(domain))
+		if err != nil {
+			log.Warn(logPrefix, "Failed to resolve ", domain, ": ", err)
+			continue
+		}
+		for _, ip := range ips {
+			if ip.To4() == nil {
+				continue
+			}
+			cidr := ip.To4().String() + "/32"
+			if !contains(cidrs, cidr) {
+				cidrs = append(cidrs, cidr)
+			}
+		}
+	}
+	return cidrs
+}
+
+func contains(values []string, value string) bool {
+	for _, listed := range values {
+		if listed == value {
+			return true
+		}
+	}
+	return false
+}
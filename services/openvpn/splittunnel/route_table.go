@@ -0,0 +1,54 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package splittunnel
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+)
+
+// RouteTable manages routes of operating system
+type RouteTable interface {
+	// AddLike routes network the same way as given address is routed currently
+	AddLike(network string, like string) error
+	// Delete removes route of network
+	Delete(network string) error
+}
+
+type executor func(arguments ...string) (string, error)
+
+func sudoExecutor(arguments ...string) (string, error) {
+	cmd := exec.Command("sudo", arguments...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", errors.New(strings.Join(arguments, " ") + " failed: " + err.Error() + " " + strings.TrimSpace(string(output)))
+	}
+	return string(output), nil
+}
+
+// fieldAfter returns value following given key in whitespace separated output
+func fieldAfter(output string, key string) string {
+	fields := strings.Fields(output)
+	for i := 0; i < len(fields)-1; i++ {
+		if fields[i] == key {
+			return fields[i+1]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,53 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package splittunnel
+
+import "errors"
+
+// NewRouteTable returns darwin route table managed with route tool
+func NewRouteTable() RouteTable {
+	return &bsdRouteTable{execute: sudoExecutor}
+}
+
+type bsdRouteTable struct {
+	execute executor
+}
+
+func (table *bsdRouteTable) AddLike(network string, like string) error {
+	output, err := table.execute("/sbin/route", "-n", "get", like)
+	if err != nil {
+		return err
+	}
+
+	arguments := []string{"/sbin/route", "-n", "add", "-net", network}
+	if gateway := fieldAfter(output, "gateway:"); gateway != "" {
+		arguments = append(arguments, gateway)
+	} else if device := fieldAfter(output, "interface:"); device != "" {
+		arguments = append(arguments, "-interface", device)
+	} else {
+		return errors.New("no route to " + like)
+	}
+
+	_, err = table.execute(arguments...)
+	return err
+}
+
+func (table *bsdRouteTable) Delete(network string) error {
+	_, err := table.execute("/sbin/route", "-n", "delete", "-net", network)
+	return err
+}
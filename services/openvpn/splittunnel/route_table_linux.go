@@ -0,0 +1,54 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package splittunnel
+
+import "errors"
+
+// NewRouteTable returns linux route table managed with ip tool
+func NewRouteTable() RouteTable {
+	return &ipRouteTable{execute: sudoExecutor}
+}
+
+type ipRouteTable struct {
+	execute executor
+}
+
+func (table *ipRouteTable) AddLike(network string, like string) error {
+	output, err := table.execute("/sbin/ip", "route", "get", like)
+	if err != nil {
+		return err
+	}
+
+	arguments := []string{"/sbin/ip", "route", "replace", network}
+	if gateway := fieldAfter(output, "via"); gateway != "" {
+		arguments = append(arguments, "via", gateway)
+	}
+	device := fieldAfter(output, "dev")
+	if device == "" {
+		return errors.New("no route to " + like)
+	}
+	arguments = append(arguments, "dev", device)
+
+	_, err = table.execute(arguments...)
+	return err
+}
+
+func (table *ipRouteTable) Delete(network string) error {
+	_, err := table.execute("/sbin/ip", "route", "del", network)
+	return err
+}
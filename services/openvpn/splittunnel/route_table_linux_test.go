@@ -0,0 +1,53 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package splittunnel
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIPRouteTable_AddLike(t *testing.T) {
+	var commands []string
+	table := &ipRouteTable{
+		execute: func(arguments ...string) (string, error) {
+			commands = append(commands, strings.Join(arguments, " "))
+			if arguments[2] == "get" && arguments[3] == "1.2.3.4" {
+				return "1.2.3.4 via 192.168.1.1 dev eth0 src 192.168.1.5 uid 0\n    cache\n", nil
+			}
+			return "10.0.0.1 dev tun0 src 10.8.0.2 uid 0\n    cache\n", nil
+		},
+	}
+
+	assert.NoError(t, table.AddLike("5.6.7.8/32", "1.2.3.4"))
+	assert.NoError(t, table.AddLike("10.1.0.0/16", "10.0.0.1"))
+	assert.NoError(t, table.Delete("5.6.7.8/32"))
+	assert.Equal(
+		t,
+		[]string{
+			"/sbin/ip route get 1.2.3.4",
+			"/sbin/ip route replace 5.6.7.8/32 via 192.168.1.1 dev eth0",
+			"/sbin/ip route get 10.0.0.1",
+			"/sbin/ip route replace 10.1.0.0/16 dev tun0",
+			"/sbin/ip route del 5.6.7.8/32",
+		},
+		commands,
+	)
+}
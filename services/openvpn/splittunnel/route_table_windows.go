@@ -0,0 +1,35 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package splittunnel
+
+import "errors"
+
+// NewRouteTable returns route table, which does not support refreshing routes yet
+func NewRouteTable() RouteTable {
+	return &unsupportedRouteTable{}
+}
+
+type unsupportedRouteTable struct{}
+
+func (table *unsupportedRouteTable) AddLike(network string, like string) error {
+	return errors.New("routes can not be refreshed on this platform")
+}
+
+func (table *unsupportedRouteTable) Delete(network string) error {
+	return nil
+}
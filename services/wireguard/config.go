@@ -0,0 +1,38 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package wireguard
+
+// ServiceConfig represents WireGuard tunnel configuration, which provider passes to consumer within session
+type ServiceConfig struct {
+	Provider ProviderConfig `json:"provider"`
+	Consumer ConsumerConfig `json:"consumer"`
+}
+
+// ProviderConfig describes provider side of the tunnel
+type ProviderConfig struct {
+	PublicKey    Key    `json:"public_key"`
+	EndpointIP   string `json:"endpoint_ip"`
+	EndpointPort int    `json:"endpoint_port"`
+}
+
+// ConsumerConfig describes consumer side of the tunnel
+type ConsumerConfig struct {
+	PrivateKey Key `json:"private_key"`
+	// IPAddress of consumer in tunnel subnet, i.e. 10.182.0.2/24
+	IPAddress string `json:"ip_address"`
+}
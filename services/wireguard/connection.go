@@ -0,0 +1,320 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package wireguard
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/cihub/seelog"
+	"github.com/mysteriumnetwork/node/client/stats"
+	"github.com/mysteriumnetwork/node/core/connection"
+	"github.com/mysteriumnetwork/node/core/events"
+	"github.com/mysteriumnetwork/node/firewall"
+)
+
+const connectionLogPrefix = "[wireguard-connection] "
+
+const (
+	// keepAliveInterval keeps NAT mappings of consumer alive, value recommended by WireGuard
+	keepAliveInterval = 25
+	statsInterval     = time.Second
+
+	// handshakeTimeout limits waiting for the first handshake with provider
+	handshakeTimeout      = 30 * time.Second
+	handshakePollInterval = 100 * time.Millisecond
+	// handshakeStaleAfter is the age of the latest handshake, after which provider is considered gone.
+	// Peers re-handshake every 2 minutes while keep alive packets flow, WireGuard rejects the session after 3 minutes.
+	handshakeStaleAfter = 3 * time.Minute
+)
+
+var (
+	errHandshakeTimeout = errors.New("no handshake with provider")
+	errHandshakeStale   = errors.New("provider stopped responding to handshakes")
+	errStopped          = errors.New("connection stopped")
+)
+
+// routesThroughTunnel override default route without removing it, same as wg-quick does
+var routesThroughTunnel = []string{"0.0.0.0/1", "128.0.0.0/1"}
+
+// DNSConfigurator takes over DNS of the system for the duration of connection
+type DNSConfigurator interface {
+	Apply(device string, servers []string) error
+	Restore(device string) error
+}
+
+// Connection is WireGuard tunnel from consumer to provider.
+// Provider does not push resolvers, DNS of the system is taken over only if consumer asks for resolvers.
+type Connection struct {
+	interfaceName  string
+	config         ServiceConfig
+	device         Device
+	execute        CommandExecutor
+	stateChannel   connection.StateChannel
+	statsKeeper    stats.SessionStatsKeeper
+	eventPublisher events.Publisher
+
+	dnsConfigurator DNSConfigurator
+	dns             []string
+	dnsApplied      bool
+	dnsLock         sync.Mutex
+
+	// includeCIDRs are the only networks routed through tunnel, all traffic is routed if empty
+	includeCIDRs []string
+	// excludeCIDRs are networks, which traffic bypasses tunnel
+	excludeCIDRs []string
+
+	bypassRoutes [][]string
+	stopChannel  chan struct{}
+	failChannel  chan error
+	stopOnce     sync.Once
+
+	handshakeTimeout time.Duration
+}
+
+// Start implements the connection.Connection interface
+func (c *Connection) Start() (err error) {
+	c.stateChannel <- connection.Connecting
+
+	err = c.device.Up(DeviceConfig{
+		Name:       c.interfaceName,
+		PrivateKey: c.config.Consumer.PrivateKey,
+		IPAddress:  c.config.Consumer.IPAddress,
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			c.cleanup()
+		}
+	}()
+
+	err = c.device.AddPeer(Peer{
+		PublicKey:         c.config.Provider.PublicKey,
+		Endpoint:          net.JoinHostPort(c.config.Provider.EndpointIP, strconv.Itoa(c.config.Provider.EndpointPort)),
+		AllowedIPs:        []string{"0.0.0.0/0"},
+		KeepAliveInterval: keepAliveInterval,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = c.waitForHandshake(); err != nil {
+		return err
+	}
+	if err = c.routeThroughTunnel(); err != nil {
+		return err
+	}
+	if err = c.applyDNS(); err != nil {
+		return err
+	}
+
+	c.stateChannel <- connection.Connected
+	go c.monitor()
+	return nil
+}
+
+// Wait implements the connection.Connection interface.
+// Error is returned, if connection was stopped because provider stopped responding.
+func (c *Connection) Wait() error {
+	<-c.stopChannel
+	select {
+	case err := <-c.failChannel:
+		return err
+	default:
+		return nil
+	}
+}
+
+// Stop implements the connection.Connection interface
+func (c *Connection) Stop() {
+	c.stopOnce.Do(func() {
+		c.stateChannel <- connection.Disconnecting
+		close(c.stopChannel)
+		c.cleanup()
+		c.stateChannel <- connection.NotConnected
+		close(c.stateChannel)
+	})
+}
+
+// DNS returns resolvers of the system, which are applied by the connection
+func (c *Connection) DNS() []string {
+	c.dnsLock.Lock()
+	defer c.dnsLock.Unlock()
+
+	if !c.dnsApplied {
+		return nil
+	}
+	return c.dns
+}
+
+// ServerAddress returns address of WireGuard endpoint which consumer connects to
+func (c *Connection) ServerAddress() firewall.ServerAddress {
+	return firewall.ServerAddress{
+		IP:       c.config.Provider.EndpointIP,
+		Port:     c.config.Provider.EndpointPort,
+		Protocol: "udp",
+	}
+}
+
+// routeThroughTunnel routes included traffic through tunnel, or all traffic if nothing is included.
+// Traffic to the provider endpoint and to excluded networks bypasses tunnel.
+func (c *Connection) routeThroughTunnel() error {
+	output, err := c.execute("ip", "route", "get", c.config.Provider.EndpointIP)
+	if err != nil {
+		return err
+	}
+	gateway, device, err := parseRoute(output)
+	if err != nil {
+		return err
+	}
+
+	bypassed := append([]string{c.config.Provider.EndpointIP + "/32"}, c.excludeCIDRs...)
+	for _, cidr := range bypassed {
+		route := []string{cidr, "dev", device}
+		if gateway != "" {
+			route = append(route, "via", gateway)
+		}
+		if _, err = c.execute("ip", append([]string{"route", "add"}, route...)...); err != nil {
+			return err
+		}
+		c.bypassRoutes = append(c.bypassRoutes, route)
+	}
+
+	routed := routesThroughTunnel
+	if len(c.includeCIDRs) > 0 {
+		routed = c.includeCIDRs
+	}
+	for _, cidr := range routed {
+		if _, err = c.execute("ip", "route", "add", cidr, "dev", c.interfaceName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyDNS points DNS of the system to resolvers requested by consumer, queries are sent through tunnel
+func (c *Connection) applyDNS() error {
+	if len(c.dns) == 0 {
+		return nil
+	}
+
+	c.dnsLock.Lock()
+	defer c.dnsLock.Unlock()
+
+	if err := c.dnsConfigurator.Apply(c.interfaceName, c.dns); err != nil {
+		return err
+	}
+	c.dnsApplied = true
+	return nil
+}
+
+// cleanup restores DNS of the system, removes interface, together with routes through it, and routes bypassing tunnel
+func (c *Connection) cleanup() {
+	c.dnsLock.Lock()
+	if c.dnsApplied {
+		if err := c.dnsConfigurator.Restore(c.interfaceName); err != nil {
+			log.Warn(connectionLogPrefix, "Failed to restore DNS of the system: ", err)
+		}
+		c.dnsApplied = false
+	}
+	c.dnsLock.Unlock()
+
+	if err := c.device.Down(); err != nil {
+		log.Warn(connectionLogPrefix, "Failed to remove interface: ", err)
+	}
+	for _, route := range c.bypassRoutes {
+		if _, err := c.execute("ip", append([]string{"route", "delete"}, route...)...); err != nil {
+			log.Warn(connectionLogPrefix, "Failed to remove route bypassing tunnel: ", err)
+		}
+	}
+	c.bypassRoutes = nil
+}
+
+// waitForHandshake waits until provider responds through the tunnel, so connection is not reported before it works
+func (c *Connection) waitForHandshake() error {
+	timeout := time.After(c.handshakeTimeout)
+	for {
+		peerStats, err := c.device.Stats()
+		if err != nil {
+			return err
+		}
+		if !peerStats[c.config.Provider.PublicKey].LastHandshake.IsZero() {
+			return nil
+		}
+
+		select {
+		case <-c.stopChannel:
+			return errStopped
+		case <-timeout:
+			return errHandshakeTimeout
+		case <-time.After(handshakePollInterval):
+		}
+	}
+}
+
+// monitor collects tunnel statistics and stops the connection, when handshakes with provider go stale
+func (c *Connection) monitor() {
+	for {
+		select {
+		case <-c.stopChannel:
+			return
+		case <-time.After(statsInterval):
+		}
+
+		peerStats, err := c.device.Stats()
+		if err != nil {
+			log.Warn(connectionLogPrefix, "Failed to read tunnel statistics: ", err)
+			continue
+		}
+		providerStats := peerStats[c.config.Provider.PublicKey]
+		if time.Since(providerStats.LastHandshake) > handshakeStaleAfter {
+			log.Warn(connectionLogPrefix, "Latest handshake with provider was at ", providerStats.LastHandshake, ", stopping connection")
+			c.failChannel <- errHandshakeStale
+			c.Stop()
+			return
+		}
+
+		sessionStats := stats.SessionStats{BytesSent: providerStats.BytesSent, BytesReceived: providerStats.BytesReceived}
+		c.statsKeeper.Save(sessionStats)
+		c.eventPublisher.Publish(stats.SessionStatsEventTopic, sessionStats)
+	}
+}
+
+// parseRoute parses gateway and device from output of `ip route get <ip>`, i.e.
+// "1.2.3.4 via 192.168.1.1 dev eth0 src 192.168.1.10 uid 0"
+func parseRoute(output string) (gateway, device string, err error) {
+	fields := strings.Fields(output)
+	for i := 0; i < len(fields)-1; i++ {
+		switch fields[i] {
+		case "via":
+			gateway = fields[i+1]
+		case "dev":
+			device = fields[i+1]
+		}
+	}
+	if device == "" {
+		return "", "", errors.New("failed to parse route: " + output)
+	}
+	return gateway, device, nil
+}
@@ -0,0 +1,87 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package wireguard
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/mysteriumnetwork/node/core/connection"
+	"github.com/mysteriumnetwork/node/core/events"
+)
+
+// errSplitTunnelDomains is returned, as routes of WireGuard connection are not refreshed while domain addresses change
+var errSplitTunnelDomains = errors.New("split tunnelling of domains is not supported by WireGuard connection")
+
+// NewConnectionCreator creates WireGuard connections, every connection manages its own interface
+func NewConnectionCreator(eventPublisher events.Publisher, dnsConfigurator DNSConfigurator) connection.ConnectionCreator {
+	newDevice := func() Device {
+		return NewDevice(false)
+	}
+	return newConnectionCreator(newDevice, SudoExecutor, eventPublisher, dnsConfigurator)
+}
+
+func newConnectionCreator(
+	newDevice func() Device,
+	executor CommandExecutor,
+	eventPublisher events.Publisher,
+	dnsConfigurator DNSConfigurator,
+) connection.ConnectionCreator {
+	return func(options connection.ConnectOptions, stateChannel connection.StateChannel) (connection.Connection, error) {
+		splitTunnel := options.Params.SplitTunnel
+		if len(splitTunnel.IncludeDomains)+len(splitTunnel.ExcludeDomains) > 0 {
+			return nil, errSplitTunnelDomains
+		}
+
+		var config ServiceConfig
+		if err := json.Unmarshal(options.SessionConfig, &config); err != nil {
+			return nil, err
+		}
+
+		return &Connection{
+			interfaceName:  consumerInterfaceName(string(options.SessionID)),
+			config:         config,
+			includeCIDRs:   splitTunnel.IncludeCIDRs,
+			excludeCIDRs:   splitTunnel.ExcludeCIDRs,
+			device:         newDevice(),
+			execute:        executor,
+			stateChannel:   stateChannel,
+			statsKeeper:    options.StatsKeeper,
+			eventPublisher: eventPublisher,
+
+			dnsConfigurator: dnsConfigurator,
+			dns:             options.Params.DNS,
+
+			stopChannel: make(chan struct{}),
+			failChannel: make(chan error, 1),
+
+			handshakeTimeout: handshakeTimeout,
+		}, nil
+	}
+}
+
+// consumerInterfaceName derives interface name from session, so concurrent connections do not clash
+func consumerInterfaceName(sessionID string) string {
+	const maxSuffixLength = 8
+	suffix := strings.Replace(sessionID, "-", "", -1)
+	if len(suffix) > maxSuffixLength {
+		suffix = suffix[:maxSuffixLength]
+	}
+	return InterfacePrefix + suffix
+}
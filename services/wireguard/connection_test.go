@@ -0,0 +1,331 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package wireguard
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mysteriumnetwork/node/client/stats"
+	"github.com/mysteriumnetwork/node/core/connection"
+	"github.com/mysteriumnetwork/node/core/events"
+	"github.com/mysteriumnetwork/node/firewall"
+	"github.com/stretchr/testify/assert"
+)
+
+type deviceFake struct {
+	config    DeviceConfig
+	peers     []Peer
+	up        bool
+	handshake time.Time
+
+	lock sync.Mutex
+}
+
+func (fake *deviceFake) Up(config DeviceConfig) error {
+	fake.lock.Lock()
+	defer fake.lock.Unlock()
+	fake.config = config
+	fake.up = true
+	return nil
+}
+
+func (fake *deviceFake) AddPeer(peer Peer) error {
+	fake.lock.Lock()
+	defer fake.lock.Unlock()
+	fake.peers = append(fake.peers, peer)
+	return nil
+}
+
+func (fake *deviceFake) RemovePeer(key Key) error {
+	return nil
+}
+
+func (fake *deviceFake) Stats() (map[Key]PeerStats, error) {
+	fake.lock.Lock()
+	defer fake.lock.Unlock()
+	return map[Key]PeerStats{testPeerKey: {LastHandshake: fake.handshake}}, nil
+}
+
+func (fake *deviceFake) Down() error {
+	fake.lock.Lock()
+	defer fake.lock.Unlock()
+	fake.up = false
+	return nil
+}
+
+type dnsConfiguratorFake struct {
+	applied map[string][]string
+	err     error
+}
+
+func (fake *dnsConfiguratorFake) Apply(device string, servers []string) error {
+	if fake.err != nil {
+		return fake.err
+	}
+	if fake.applied == nil {
+		fake.applied = make(map[string][]string)
+	}
+	fake.applied[device] = servers
+	return nil
+}
+
+func (fake *dnsConfiguratorFake) Restore(device string) error {
+	delete(fake.applied, device)
+	return nil
+}
+
+var testServiceConfig = ServiceConfig{
+	Provider: ProviderConfig{PublicKey: testPeerKey, EndpointIP: "1.2.3.4", EndpointPort: 51820},
+	Consumer: ConsumerConfig{PrivateKey: testPrivateKey, IPAddress: "10.182.0.2/24"},
+}
+
+func newTestConnection(device Device, executor *executorFake) (connection.Connection, connection.StateChannel) {
+	conn, stateChannel, _ := newTestConnectionWithParams(device, executor, connection.ConnectParams{})
+	return conn, stateChannel
+}
+
+func newTestConnectionWithParams(
+	device Device,
+	executor *executorFake,
+	params connection.ConnectParams,
+) (connection.Connection, connection.StateChannel, error) {
+	return newTestConnectionWithDNS(device, executor, params, &dnsConfiguratorFake{})
+}
+
+func newTestConnectionWithDNS(
+	device Device,
+	executor *executorFake,
+	params connection.ConnectParams,
+	dnsConfigurator DNSConfigurator,
+) (connection.Connection, connection.StateChannel, error) {
+	sessionConfig, _ := json.Marshal(testServiceConfig)
+	stateChannel := make(connection.StateChannel, 10)
+	newDevice := func() Device {
+		return device
+	}
+	createConnection := newConnectionCreator(newDevice, executor.execute, events.NewPublisherFake(), dnsConfigurator)
+	conn, err := createConnection(
+		connection.ConnectOptions{
+			SessionID:     "d3f8c2a4-3a7e-4d1b-9e0c-0123456789ab",
+			SessionConfig: sessionConfig,
+			StatsKeeper:   stats.NewSessionStatsKeeper(nil),
+			Params:        params,
+		},
+		stateChannel,
+	)
+	return conn, stateChannel, err
+}
+
+func TestConnection_StartAndStop(t *testing.T) {
+	device := &deviceFake{handshake: time.Now()}
+	executor := &executorFake{outputs: map[string]string{
+		"ip route get 1.2.3.4": "1.2.3.4 via 192.168.1.1 dev eth0 src 192.168.1.10 uid 0",
+	}}
+	conn, stateChannel := newTestConnection(device, executor)
+
+	assert.NoError(t, conn.Start())
+	assert.Equal(t, "mystwgd3f8c2a4", device.config.Name)
+	assert.Equal(t, "10.182.0.2/24", device.config.IPAddress)
+	assert.Equal(
+		t,
+		[]Peer{{PublicKey: testPeerKey, Endpoint: "1.2.3.4:51820", AllowedIPs: []string{"0.0.0.0/0"}, KeepAliveInterval: 25}},
+		device.peers,
+	)
+	assert.Equal(
+		t,
+		[]string{
+			"ip route get 1.2.3.4",
+			"ip route add 1.2.3.4/32 dev eth0 via 192.168.1.1",
+			"ip route add 0.0.0.0/1 dev mystwgd3f8c2a4",
+			"ip route add 128.0.0.0/1 dev mystwgd3f8c2a4",
+		},
+		executor.commands,
+	)
+
+	conn.Stop()
+	assert.NoError(t, conn.Wait())
+	assert.False(t, device.up)
+	assert.Equal(t, "ip route delete 1.2.3.4/32 dev eth0 via 192.168.1.1", executor.commands[len(executor.commands)-1])
+
+	var states []connection.State
+	for state := range stateChannel {
+		states = append(states, state)
+	}
+	assert.Equal(
+		t,
+		[]connection.State{connection.Connecting, connection.Connected, connection.Disconnecting, connection.NotConnected},
+		states,
+	)
+}
+
+func TestConnection_StartRoutesOnlyIncludedNetworks(t *testing.T) {
+	executor := &executorFake{outputs: map[string]string{
+		"ip route get 1.2.3.4": "1.2.3.4 via 192.168.1.1 dev eth0 src 192.168.1.10 uid 0",
+	}}
+	params := connection.ConnectParams{SplitTunnel: connection.SplitTunnelParams{IncludeCIDRs: []string{"10.0.0.0/8"}}}
+	conn, _, err := newTestConnectionWithParams(&deviceFake{handshake: time.Now()}, executor, params)
+	assert.NoError(t, err)
+
+	assert.NoError(t, conn.Start())
+	defer conn.Stop()
+	assert.Equal(
+		t,
+		[]string{
+			"ip route get 1.2.3.4",
+			"ip route add 1.2.3.4/32 dev eth0 via 192.168.1.1",
+			"ip route add 10.0.0.0/8 dev mystwgd3f8c2a4",
+		},
+		executor.commands,
+	)
+}
+
+func TestConnection_StartRoutesExcludedNetworksOutsideTunnel(t *testing.T) {
+	executor := &executorFake{outputs: map[string]string{
+		"ip route get 1.2.3.4": "1.2.3.4 via 192.168.1.1 dev eth0 src 192.168.1.10 uid 0",
+	}}
+	params := connection.ConnectParams{SplitTunnel: connection.SplitTunnelParams{ExcludeCIDRs: []string{"172.16.0.0/12"}}}
+	conn, _, err := newTestConnectionWithParams(&deviceFake{handshake: time.Now()}, executor, params)
+	assert.NoError(t, err)
+
+	assert.NoError(t, conn.Start())
+	assert.Equal(
+		t,
+		[]string{
+			"ip route get 1.2.3.4",
+			"ip route add 1.2.3.4/32 dev eth0 via 192.168.1.1",
+			"ip route add 172.16.0.0/12 dev eth0 via 192.168.1.1",
+			"ip route add 0.0.0.0/1 dev mystwgd3f8c2a4",
+			"ip route add 128.0.0.0/1 dev mystwgd3f8c2a4",
+		},
+		executor.commands,
+	)
+
+	conn.Stop()
+	assert.Equal(
+		t,
+		[]string{
+			"ip route delete 1.2.3.4/32 dev eth0 via 192.168.1.1",
+			"ip route delete 172.16.0.0/12 dev eth0 via 192.168.1.1",
+		},
+		executor.commands[5:],
+	)
+}
+
+func TestConnection_CreateRejectsSplitTunnelOfDomains(t *testing.T) {
+	params := connection.ConnectParams{SplitTunnel: connection.SplitTunnelParams{IncludeDomains: []string{"example.com"}}}
+	_, _, err := newTestConnectionWithParams(&deviceFake{}, &executorFake{}, params)
+	assert.Equal(t, errSplitTunnelDomains, err)
+}
+
+func TestConnection_StartAppliesRequestedDNS(t *testing.T) {
+	executor := &executorFake{outputs: map[string]string{
+		"ip route get 1.2.3.4": "1.2.3.4 dev eth0 src 192.168.1.10 uid 0",
+	}}
+	dnsConfigurator := &dnsConfiguratorFake{}
+	params := connection.ConnectParams{DNS: []string{"1.1.1.1"}}
+	conn, _, err := newTestConnectionWithDNS(&deviceFake{handshake: time.Now()}, executor, params, dnsConfigurator)
+	assert.NoError(t, err)
+	assert.Nil(t, conn.(connection.DNSProvider).DNS())
+
+	assert.NoError(t, conn.Start())
+	assert.Equal(t, map[string][]string{"mystwgd3f8c2a4": {"1.1.1.1"}}, dnsConfigurator.applied)
+	assert.Equal(t, []string{"1.1.1.1"}, conn.(connection.DNSProvider).DNS())
+
+	conn.Stop()
+	assert.Len(t, dnsConfigurator.applied, 0)
+	assert.Nil(t, conn.(connection.DNSProvider).DNS())
+}
+
+func TestConnection_StartFailsWhenDNSIsNotApplied(t *testing.T) {
+	device := &deviceFake{handshake: time.Now()}
+	executor := &executorFake{outputs: map[string]string{
+		"ip route get 1.2.3.4": "1.2.3.4 dev eth0 src 192.168.1.10 uid 0",
+	}}
+	dnsConfigurator := &dnsConfiguratorFake{err: errors.New("resolvconf failed")}
+	params := connection.ConnectParams{DNS: []string{"1.1.1.1"}}
+	conn, _, err := newTestConnectionWithDNS(device, executor, params, dnsConfigurator)
+	assert.NoError(t, err)
+
+	assert.EqualError(t, conn.Start(), "resolvconf failed")
+	assert.False(t, device.up)
+}
+
+func TestConnection_StartCleansUpOnFailure(t *testing.T) {
+	device := &deviceFake{handshake: time.Now()}
+	executor := &executorFake{failOn: "ip route get"}
+	conn, _ := newTestConnection(device, executor)
+
+	assert.EqualError(t, conn.Start(), "command failed")
+	assert.False(t, device.up)
+}
+
+func TestConnection_StartFailsWithoutHandshake(t *testing.T) {
+	device := &deviceFake{}
+	executor := &executorFake{}
+	conn, stateChannel := newTestConnection(device, executor)
+	conn.(*Connection).handshakeTimeout = 10 * time.Millisecond
+
+	assert.Equal(t, errHandshakeTimeout, conn.Start())
+	assert.False(t, device.up)
+	assert.Len(t, executor.commands, 0)
+	assert.Equal(t, connection.Connecting, <-stateChannel)
+	assert.Len(t, stateChannel, 0)
+}
+
+func TestConnection_WaitReturnsErrorWhenHandshakeIsStale(t *testing.T) {
+	device := &deviceFake{handshake: time.Now().Add(-handshakeStaleAfter - time.Minute)}
+	executor := &executorFake{outputs: map[string]string{
+		"ip route get 1.2.3.4": "1.2.3.4 dev eth0 src 192.168.1.10 uid 0",
+	}}
+	conn, stateChannel := newTestConnection(device, executor)
+
+	assert.NoError(t, conn.Start())
+	assert.Equal(t, errHandshakeStale, conn.Wait())
+
+	var states []connection.State
+	for state := range stateChannel {
+		states = append(states, state)
+	}
+	assert.Equal(
+		t,
+		[]connection.State{connection.Connecting, connection.Connected, connection.Disconnecting, connection.NotConnected},
+		states,
+	)
+}
+
+func TestConnection_ServerAddress(t *testing.T) {
+	conn, _ := newTestConnection(&deviceFake{}, &executorFake{})
+
+	addressProvider, ok := conn.(connection.ServerAddressProvider)
+	assert.True(t, ok)
+	assert.Equal(t, firewall.ServerAddress{IP: "1.2.3.4", Port: 51820, Protocol: "udp"}, addressProvider.ServerAddress())
+}
+
+func TestParseRoute(t *testing.T) {
+	gateway, device, err := parseRoute("10.0.0.5 dev eth1 src 10.0.0.1 uid 0\n    cache")
+	assert.NoError(t, err)
+	assert.Equal(t, "", gateway)
+	assert.Equal(t, "eth1", device)
+
+	_, _, err = parseRoute("RTNETLINK answers: Network is unreachable")
+	assert.Error(t, err)
+}
@@ -0,0 +1,244 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package wireguard
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/cihub/seelog"
+)
+
+const deviceLogPrefix = "[wireguard-device] "
+
+// InterfacePrefix is common prefix of WireGuard interfaces created by node, kill switch relies on it
+const InterfacePrefix = "mystwg"
+
+// DeviceConfig describes local side of WireGuard tunnel
+type DeviceConfig struct {
+	Name       string
+	PrivateKey Key
+	// IPAddress of the interface in tunnel subnet, i.e. 10.182.0.1/24
+	IPAddress string
+	// ListenPort is UDP port for incoming connections, random port is used if 0
+	ListenPort int
+}
+
+// Peer describes remote side of WireGuard tunnel
+type Peer struct {
+	PublicKey Key
+	// Endpoint is address of the peer, i.e. 1.2.3.4:51820. Empty if peer connects to us
+	Endpoint   string
+	AllowedIPs []string
+	// KeepAliveInterval in seconds, keep alive packets are not sent if 0
+	KeepAliveInterval int
+}
+
+// PeerStats describes traffic of the peer
+type PeerStats struct {
+	LastHandshake time.Time
+	BytesReceived uint64
+	BytesSent     uint64
+}
+
+// Device manages WireGuard network interface
+type Device interface {
+	Up(config DeviceConfig) error
+	AddPeer(peer Peer) error
+	RemovePeer(publicKey Key) error
+	Stats() (map[Key]PeerStats, error)
+	Down() error
+}
+
+// CommandExecutor runs system command and returns its combined output
+type CommandExecutor func(name string, args ...string) (string, error)
+
+// NewDevice creates WireGuard device managed with ip and wg tools.
+// Userspace device is run by wireguard-go, so it works without kernel module, i.e. inside network namespace of container.
+// Kernel device falls back to userspace one, if kernel module is not available.
+func NewDevice(userspace bool) Device {
+	return newDevice(userspace, SudoExecutor)
+}
+
+func newDevice(userspace bool, executor CommandExecutor) *device {
+	return &device{
+		userspace: userspace,
+		execute:   executor,
+	}
+}
+
+type device struct {
+	userspace bool
+	execute   CommandExecutor
+	name      string
+}
+
+// Up creates and configures WireGuard interface
+func (device *device) Up(config DeviceConfig) (err error) {
+	device.name = config.Name
+	if err = device.create(config.Name); err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if errDown := device.Down(); errDown != nil {
+				log.Warn(deviceLogPrefix, "Failed to remove half configured interface: ", errDown)
+			}
+		}
+	}()
+
+	if err = device.setPrivateKey(config); err != nil {
+		return err
+	}
+	if _, err = device.execute("ip", "address", "add", config.IPAddress, "dev", config.Name); err != nil {
+		return err
+	}
+	if _, err = device.execute("ip", "link", "set", "up", "dev", config.Name); err != nil {
+		return err
+	}
+
+	log.Info(deviceLogPrefix, "Interface ", config.Name, " is up with address ", config.IPAddress)
+	return nil
+}
+
+// AddPeer adds peer allowed to communicate through the interface
+func (device *device) AddPeer(peer Peer) error {
+	args := []string{"set", device.name, "peer", peer.PublicKey.String()}
+	if peer.Endpoint != "" {
+		args = append(args, "endpoint", peer.Endpoint)
+	}
+	args = append(args, "allowed-ips", strings.Join(peer.AllowedIPs, ","))
+	if peer.KeepAliveInterval > 0 {
+		args = append(args, "persistent-keepalive", strconv.Itoa(peer.KeepAliveInterval))
+	}
+
+	_, err := device.execute("wg", args...)
+	return err
+}
+
+// RemovePeer removes peer from the interface
+func (device *device) RemovePeer(publicKey Key) error {
+	_, err := device.execute("wg", "set", device.name, "peer", publicKey.String(), "remove")
+	return err
+}
+
+// Stats returns traffic statistics of every peer of the interface
+func (device *device) Stats() (map[Key]PeerStats, error) {
+	output, err := device.execute("wg", "show", device.name, "dump")
+	if err != nil {
+		return nil, err
+	}
+	return parseDump(output)
+}
+
+// Down removes the interface
+func (device *device) Down() error {
+	_, err := device.execute("ip", "link", "delete", "dev", device.name)
+	return err
+}
+
+func (device *device) create(name string) error {
+	if !device.userspace {
+		_, err := device.execute("ip", "link", "add", "dev", name, "type", "wireguard")
+		if err == nil {
+			return nil
+		}
+		log.Warn(deviceLogPrefix, "Failed to create kernel interface, falling back to userspace: ", err)
+	}
+
+	_, err := device.execute("wireguard-go", name)
+	return err
+}
+
+func (device *device) setPrivateKey(config DeviceConfig) error {
+	// wg tool accepts private key only from file, so it does not appear in process list
+	keyFile, err := ioutil.TempFile("", "wg-key")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(keyFile.Name())
+
+	_, err = keyFile.WriteString(config.PrivateKey.String())
+	if errClose := keyFile.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		return err
+	}
+
+	args := []string{"set", config.Name, "private-key", keyFile.Name()}
+	if config.ListenPort > 0 {
+		args = append(args, "listen-port", strconv.Itoa(config.ListenPort))
+	}
+	_, err = device.execute("wg", args...)
+	return err
+}
+
+// parseDump parses peers from output of `wg show <interface> dump`.
+// First line describes interface, every next line describes peer with tab separated fields:
+// public-key, preshared-key, endpoint, allowed-ips, latest-handshake, transfer-rx, transfer-tx, persistent-keepalive
+func parseDump(output string) (map[Key]PeerStats, error) {
+	stats := make(map[Key]PeerStats)
+
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	for _, line := range lines[1:] {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 8 {
+			return nil, errors.New("unexpected wg dump line: " + line)
+		}
+
+		publicKey, err := ParseKey(fields[0])
+		if err != nil {
+			return nil, err
+		}
+		handshake, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		received, err := strconv.ParseUint(fields[5], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		sent, err := strconv.ParseUint(fields[6], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		peerStats := PeerStats{BytesReceived: received, BytesSent: sent}
+		if handshake > 0 {
+			peerStats.LastHandshake = time.Unix(handshake, 0)
+		}
+		stats[publicKey] = peerStats
+	}
+	return stats, nil
+}
+
+// SudoExecutor runs command with root privileges
+func SudoExecutor(name string, args ...string) (string, error) {
+	cmd := exec.Command("sudo", append([]string{name}, args...)...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", errors.New(name + " " + strings.Join(args, " ") + " failed: " + err.Error() + " " + strings.TrimSpace(string(output)))
+	}
+	return string(output), nil
+}
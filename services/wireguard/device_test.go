@@ -0,0 +1,152 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package wireguard
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type executorFake struct {
+	commands []string
+	outputs  map[string]string
+	failOn   string
+}
+
+func (executor *executorFake) execute(name string, args ...string) (string, error) {
+	command := strings.Join(append([]string{name}, args...), " ")
+	executor.commands = append(executor.commands, command)
+	if executor.failOn != "" && strings.HasPrefix(command, executor.failOn) {
+		return "", errors.New("command failed")
+	}
+	return executor.outputs[command], nil
+}
+
+var (
+	testPrivateKey, _ = ParseKey("dwdtCnMYpX08FsFyUbJmRd9ML4frwJkqsXf7pR25LCo=")
+	testPeerKey, _    = ParseKey("hSDwCYkwp1R0i33ctD73Wg2/Og0mOBr066SpjqqbTmo=")
+)
+
+func TestDevice_UpKernel(t *testing.T) {
+	executor := &executorFake{}
+	device := newDevice(false, executor.execute)
+
+	err := device.Up(DeviceConfig{Name: "wg0", PrivateKey: testPrivateKey, IPAddress: "10.182.0.1/24", ListenPort: 51820})
+	assert.NoError(t, err)
+
+	assert.Len(t, executor.commands, 4)
+	assert.Equal(t, "ip link add dev wg0 type wireguard", executor.commands[0])
+	assert.Regexp(t, "^wg set wg0 private-key .+ listen-port 51820$", executor.commands[1])
+	assert.Equal(t, "ip address add 10.182.0.1/24 dev wg0", executor.commands[2])
+	assert.Equal(t, "ip link set up dev wg0", executor.commands[3])
+}
+
+func TestDevice_UpUserspace(t *testing.T) {
+	executor := &executorFake{}
+	device := newDevice(true, executor.execute)
+
+	err := device.Up(DeviceConfig{Name: "wg0", PrivateKey: testPrivateKey, IPAddress: "10.182.0.2/24"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "wireguard-go wg0", executor.commands[0])
+	assert.Regexp(t, "^wg set wg0 private-key [^ ]+$", executor.commands[1])
+}
+
+func TestDevice_UpFallsBackToUserspace(t *testing.T) {
+	executor := &executorFake{failOn: "ip link add"}
+	device := newDevice(false, executor.execute)
+
+	err := device.Up(DeviceConfig{Name: "wg0", PrivateKey: testPrivateKey, IPAddress: "10.182.0.2/24"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "ip link add dev wg0 type wireguard", executor.commands[0])
+	assert.Equal(t, "wireguard-go wg0", executor.commands[1])
+}
+
+func TestDevice_UpRemovesInterfaceOnFailure(t *testing.T) {
+	executor := &executorFake{failOn: "ip address add"}
+	device := newDevice(false, executor.execute)
+
+	err := device.Up(DeviceConfig{Name: "wg0", PrivateKey: testPrivateKey, IPAddress: "10.182.0.1/24"})
+	assert.EqualError(t, err, "command failed")
+	assert.Equal(t, "ip link delete dev wg0", executor.commands[len(executor.commands)-1])
+}
+
+func TestDevice_Peers(t *testing.T) {
+	executor := &executorFake{}
+	device := newDevice(false, executor.execute)
+	device.name = "wg0"
+
+	assert.NoError(t, device.AddPeer(Peer{
+		PublicKey:         testPeerKey,
+		Endpoint:          "1.2.3.4:51820",
+		AllowedIPs:        []string{"0.0.0.0/0", "::/0"},
+		KeepAliveInterval: 25,
+	}))
+	assert.NoError(t, device.AddPeer(Peer{PublicKey: testPeerKey, AllowedIPs: []string{"10.182.0.2/32"}}))
+	assert.NoError(t, device.RemovePeer(testPeerKey))
+
+	assert.Equal(
+		t,
+		[]string{
+			"wg set wg0 peer hSDwCYkwp1R0i33ctD73Wg2/Og0mOBr066SpjqqbTmo= endpoint 1.2.3.4:51820 allowed-ips 0.0.0.0/0,::/0 persistent-keepalive 25",
+			"wg set wg0 peer hSDwCYkwp1R0i33ctD73Wg2/Og0mOBr066SpjqqbTmo= allowed-ips 10.182.0.2/32",
+			"wg set wg0 peer hSDwCYkwp1R0i33ctD73Wg2/Og0mOBr066SpjqqbTmo= remove",
+		},
+		executor.commands,
+	)
+}
+
+func TestDevice_Stats(t *testing.T) {
+	executor := &executorFake{outputs: map[string]string{
+		"wg show wg0 dump": "cFNOGFrdmQ3BM4ABGhR4ugjYkU7q7H+12xEYlPk3FFc=\thSDwCYkwp1R0i33ctD73Wg2/Og0mOBr066SpjqqbTmo=\t51820\toff\n" +
+			"hSDwCYkwp1R0i33ctD73Wg2/Og0mOBr066SpjqqbTmo=\t(none)\t1.2.3.4:40000\t10.182.0.2/32\t1541066400\t1024\t2048\toff\n" +
+			"cFNOGFrdmQ3BM4ABGhR4ugjYkU7q7H+12xEYlPk3FFc=\t(none)\t(none)\t10.182.0.3/32\t0\t0\t0\toff\n",
+	}}
+	device := newDevice(false, executor.execute)
+	device.name = "wg0"
+
+	stats, err := device.Stats()
+	assert.NoError(t, err)
+
+	otherKey, _ := ParseKey("cFNOGFrdmQ3BM4ABGhR4ugjYkU7q7H+12xEYlPk3FFc=")
+	assert.Equal(
+		t,
+		map[Key]PeerStats{
+			testPeerKey: {LastHandshake: time.Unix(1541066400, 0), BytesReceived: 1024, BytesSent: 2048},
+			otherKey:    {},
+		},
+		stats,
+	)
+}
+
+func TestDevice_StatsWithoutPeers(t *testing.T) {
+	executor := &executorFake{outputs: map[string]string{
+		"wg show wg0 dump": "cFNOGFrdmQ3BM4ABGhR4ugjYkU7q7H+12xEYlPk3FFc=\thSDwCYkwp1R0i33ctD73Wg2/Og0mOBr066SpjqqbTmo=\t51820\toff\n",
+	}}
+	device := newDevice(false, executor.execute)
+	device.name = "wg0"
+
+	stats, err := device.Stats()
+	assert.NoError(t, err)
+	assert.Empty(t, stats)
+}
@@ -0,0 +1,62 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package wireguard
+
+import (
+	"time"
+
+	"github.com/mysteriumnetwork/node/money"
+	"github.com/mysteriumnetwork/node/nat"
+	dto_discovery "github.com/mysteriumnetwork/node/service_discovery/dto"
+	dto_openvpn "github.com/mysteriumnetwork/node/services/openvpn/discovery/dto"
+)
+
+// ServiceDefinition structure represents "wireguard" service parameters
+type ServiceDefinition struct {
+	// Approximate information on location where the service is provided from
+	Location dto_discovery.Location `json:"location"`
+
+	// Approximate information on location where the tunnelled traffic will originate from
+	LocationOriginate dto_discovery.Location `json:"location_originate"`
+
+	// Destinations, which consumers may reach through the service
+	TrafficPolicy *nat.Policy `json:"traffic_policy,omitempty"`
+}
+
+// GetLocation returns geographic location of service definition provider
+func (service ServiceDefinition) GetLocation() dto_discovery.Location {
+	return service.Location
+}
+
+// NewServiceProposalWithLocation creates service proposal description for wireguard service
+func NewServiceProposalWithLocation(serviceLocation dto_discovery.Location, trafficPolicy nat.Policy) dto_discovery.ServiceProposal {
+	return dto_discovery.ServiceProposal{
+		ServiceType: ServiceType,
+		ServiceDefinition: ServiceDefinition{
+			Location:          serviceLocation,
+			LocationOriginate: serviceLocation,
+			TrafficPolicy:     &trafficPolicy,
+		},
+		PaymentMethodType: dto_openvpn.PaymentMethodPerTime,
+		PaymentMethod: dto_openvpn.PaymentPerTime{
+			// same price as for openvpn service
+			Price:    money.NewMoney(0.125, money.CURRENCY_MYST),
+			Duration: 1 * time.Hour,
+		},
+	}
+}
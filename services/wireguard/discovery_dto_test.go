@@ -0,0 +1,51 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package wireguard
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mysteriumnetwork/node/nat"
+	dto_discovery "github.com/mysteriumnetwork/node/service_discovery/dto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServiceDefinition_Serialize(t *testing.T) {
+	definition := ServiceDefinition{
+		Location:          dto_discovery.Location{Country: "LT"},
+		LocationOriginate: dto_discovery.Location{Country: "LT"},
+	}
+
+	jsonBytes, err := json.Marshal(definition)
+	assert.NoError(t, err)
+	assert.JSONEq(
+		t,
+		`{"location": {"country": "LT"}, "location_originate": {"country": "LT"}}`,
+		string(jsonBytes),
+	)
+}
+
+func TestNewServiceProposalWithLocation(t *testing.T) {
+	proposal := NewServiceProposalWithLocation(dto_discovery.Location{Country: "LT"}, nat.Policy{AllowLocalNetworks: true})
+
+	assert.Equal(t, ServiceType, proposal.ServiceType)
+	assert.Equal(t, dto_discovery.Location{Country: "LT"}, proposal.ServiceDefinition.GetLocation())
+	assert.Equal(t, "PER_TIME", proposal.PaymentMethodType)
+	assert.Equal(t, &nat.Policy{AllowLocalNetworks: true}, proposal.ServiceDefinition.(ServiceDefinition).TrafficPolicy)
+}
@@ -0,0 +1,88 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package wireguard
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+
+	"golang.org/x/crypto/curve25519"
+)
+
+// KeyLength is the length of WireGuard keys in bytes
+const KeyLength = 32
+
+// Key is Curve25519 key used by WireGuard
+type Key [KeyLength]byte
+
+// GeneratePrivateKey generates new random private key
+func GeneratePrivateKey() (Key, error) {
+	var key Key
+	if _, err := rand.Read(key[:]); err != nil {
+		return Key{}, err
+	}
+
+	// clamping as described in https://cr.yp.to/ecdh.html
+	key[0] &= 248
+	key[31] &= 127
+	key[31] |= 64
+	return key, nil
+}
+
+// ParseKey parses base64 encoded key
+func ParseKey(encoded string) (Key, error) {
+	var key Key
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return Key{}, err
+	}
+	if len(decoded) != KeyLength {
+		return Key{}, errors.New("invalid key length")
+	}
+	copy(key[:], decoded)
+	return key, nil
+}
+
+// PublicKey derives public key from the private key
+func (key Key) PublicKey() Key {
+	var public [KeyLength]byte
+	private := [KeyLength]byte(key)
+	curve25519.ScalarBaseMult(&public, &private)
+	return Key(public)
+}
+
+// String returns key encoded to base64, as expected by wg tool
+func (key Key) String() string {
+	return base64.StdEncoding.EncodeToString(key[:])
+}
+
+// MarshalText serializes key to base64
+func (key Key) MarshalText() ([]byte, error) {
+	return []byte(key.String()), nil
+}
+
+// UnmarshalText unserializes key from base64
+func (key *Key) UnmarshalText(text []byte) error {
+	parsed, err := ParseKey(string(text))
+	if err != nil {
+		return err
+	}
+	*key = parsed
+	return nil
+}
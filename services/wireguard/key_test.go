@@ -0,0 +1,65 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package wireguard
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKey_PublicKey(t *testing.T) {
+	// test vector from RFC 7748, section 6.1
+	private, err := ParseKey("dwdtCnMYpX08FsFyUbJmRd9ML4frwJkqsXf7pR25LCo=")
+	assert.NoError(t, err)
+
+	assert.Equal(t, "hSDwCYkwp1R0i33ctD73Wg2/Og0mOBr066SpjqqbTmo=", private.PublicKey().String())
+}
+
+func TestGeneratePrivateKey(t *testing.T) {
+	first, err := GeneratePrivateKey()
+	assert.NoError(t, err)
+	second, err := GeneratePrivateKey()
+	assert.NoError(t, err)
+
+	assert.NotEqual(t, first, second)
+	assert.Equal(t, byte(0), first[0]&7)
+	assert.Equal(t, byte(64), first[31]&192)
+}
+
+func TestParseKey_Invalid(t *testing.T) {
+	_, err := ParseKey("not base64")
+	assert.Error(t, err)
+
+	_, err = ParseKey("c2hvcnQ=")
+	assert.EqualError(t, err, "invalid key length")
+}
+
+func TestKey_JSON(t *testing.T) {
+	key, err := ParseKey("hSDwCYkwp1R0i33ctD73Wg2/Og0mOBr066SpjqqbTmo=")
+	assert.NoError(t, err)
+
+	jsonBytes, err := json.Marshal(struct{ Key Key }{key})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"Key": "hSDwCYkwp1R0i33ctD73Wg2/Og0mOBr066SpjqqbTmo="}`, string(jsonBytes))
+
+	var decoded struct{ Key Key }
+	assert.NoError(t, json.Unmarshal(jsonBytes, &decoded))
+	assert.Equal(t, key, decoded.Key)
+}
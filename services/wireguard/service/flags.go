@@ -0,0 +1,76 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package service
+
+import (
+	"encoding/json"
+
+	"github.com/urfave/cli"
+)
+
+// Options describes options which are required to start WireGuard service
+type Options struct {
+	Port      int    `json:"port"`
+	Subnet    string `json:"subnet"`
+	Userspace bool   `json:"userspace"`
+}
+
+var (
+	portFlag = cli.IntFlag{
+		Name:  "wireguard.port",
+		Usage: "WireGuard UDP port to use. Default 51820",
+		Value: 51820,
+	}
+	subnetFlag = cli.StringFlag{
+		Name:  "wireguard.subnet",
+		Usage: "WireGuard subnet of peers, or pool to pick free /24 subnet from. Default 10.182.0.0/16",
+		Value: "10.182.0.0/16",
+	}
+	userspaceFlag = cli.BoolFlag{
+		Name:  "wireguard.userspace",
+		Usage: "Use userspace WireGuard implementation (wireguard-go) instead of kernel module",
+	}
+)
+
+// RegisterFlags function register WireGuard flags to flag list
+func RegisterFlags(flags *[]cli.Flag) {
+	*flags = append(*flags, portFlag, subnetFlag, userspaceFlag)
+}
+
+// ParseFlags function fills in WireGuard options from CLI context
+func ParseFlags(ctx *cli.Context) Options {
+	return Options{
+		Port:      ctx.Int(portFlag.Name),
+		Subnet:    ctx.String(subnetFlag.Name),
+		Userspace: ctx.Bool(userspaceFlag.Name),
+	}
+}
+
+// ParseJSONOptions function fills in WireGuard options from JSON request, absent options take default flag values
+func ParseJSONOptions(request *json.RawMessage) (Options, error) {
+	options := Options{
+		Port:   portFlag.Value,
+		Subnet: subnetFlag.Value,
+	}
+	if request == nil || len(*request) == 0 {
+		return options, nil
+	}
+
+	err := json.Unmarshal(*request, &options)
+	return options, err
+}
@@ -0,0 +1,327 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package service
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/cihub/seelog"
+	"github.com/mysteriumnetwork/node/core/ip"
+	"github.com/mysteriumnetwork/node/core/location"
+	"github.com/mysteriumnetwork/node/core/service"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/nat"
+	dto_discovery "github.com/mysteriumnetwork/node/service_discovery/dto"
+	"github.com/mysteriumnetwork/node/services/wireguard"
+	"github.com/mysteriumnetwork/node/session"
+)
+
+const logPrefix = "[service-wireguard] "
+
+const (
+	serverHost    = 1
+	firstPeerHost = 2
+
+	// peerGracePeriod is the time given for the consumer to create session with the issued config
+	peerGracePeriod   = time.Minute
+	reconcileInterval = 30 * time.Second
+)
+
+// ErrNoFreeAddress is returned when all addresses of the tunnel subnet are taken by peers
+var ErrNoFreeAddress = errors.New("no free address left in WireGuard subnet")
+
+// SubnetAllocator hands out subnets, which do not collide with local networks and other services
+type SubnetAllocator interface {
+	Allocate(pool string) (*net.IPNet, error)
+	Release(subnet *net.IPNet)
+}
+
+// SessionStorage provides sessions served by provider, so peers of destroyed sessions can be removed
+type SessionStorage interface {
+	GetAll() []session.Session
+	MarkConnected(id session.ID)
+}
+
+type peer struct {
+	host    int
+	addedAt time.Time
+}
+
+// Manager represents entrypoint for WireGuard service
+type Manager struct {
+	options          Options
+	trafficPolicy    nat.Policy
+	ipResolver       ip.Resolver
+	locationResolver location.Resolver
+	natService       nat.NATService
+	natRule          *nat.RuleForwarding
+	subnetAllocator  SubnetAllocator
+	subnet           *net.IPNet
+	sessionStorage   SessionStorage
+	device           wireguard.Device
+	generateKey      func() (wireguard.Key, error)
+
+	publicKey wireguard.Key
+	publicIP  string
+	peers     map[wireguard.Key]peer
+	peersLock sync.Mutex
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewManager creates new instance of WireGuard service
+func NewManager(
+	serviceOptions Options,
+	trafficPolicy nat.Policy,
+	ipResolver ip.Resolver,
+	locationResolver location.Resolver,
+	sessionStorage SessionStorage,
+	natService nat.NATService,
+	subnetAllocator SubnetAllocator,
+) *Manager {
+	return &Manager{
+		options:          serviceOptions,
+		trafficPolicy:    trafficPolicy,
+		ipResolver:       ipResolver,
+		locationResolver: locationResolver,
+		natService:       natService,
+		subnetAllocator:  subnetAllocator,
+		sessionStorage:   sessionStorage,
+		device:           wireguard.NewDevice(serviceOptions.Userspace),
+		generateKey:      wireguard.GeneratePrivateKey,
+		peers:            make(map[wireguard.Key]peer),
+		stop:             make(chan struct{}),
+	}
+}
+
+// Start starts service - does not block
+func (manager *Manager) Start(providerID identity.Identity) (
+	proposal dto_discovery.ServiceProposal,
+	sessionConfigProvider session.ConfigProvider,
+	err error,
+) {
+	manager.publicIP, err = manager.ipResolver.GetPublicIP()
+	if err != nil {
+		return
+	}
+
+	outboundIP, err := manager.ipResolver.GetOutboundIP()
+	if err != nil {
+		return
+	}
+
+	// every service gets its own subnet, so NAT rules and peer addresses of services do not collide
+	manager.subnet, err = manager.subnetAllocator.Allocate(manager.options.Subnet)
+	if err != nil {
+		log.Error(logPrefix, "Failed to allocate subnet from pool ", manager.options.Subnet, ": ", err)
+		return
+	}
+	log.Info(logPrefix, "Using subnet: ", manager.subnet)
+
+	natRule := nat.RuleForwarding{
+		SourceAddress: manager.subnet.String(),
+		TargetIP:      outboundIP,
+		Policy:        manager.trafficPolicy,
+	}
+	// traffic policy is enforced by NAT rules, so service must not run without them
+	if err = manager.natService.Add(natRule); err != nil {
+		log.Error(logPrefix, "Failed to add NAT rule: ", err)
+		manager.subnetAllocator.Release(manager.subnet)
+		manager.subnet = nil
+		return
+	}
+	manager.natRule = &natRule
+
+	currentCountry, err := manager.locationResolver.ResolveCountry(manager.publicIP)
+	if err != nil {
+		log.Warn(logPrefix, "Failed to detect service country. ", err)
+		err = service.ErrorLocation
+		return
+	}
+	log.Info(logPrefix, "Country detected: ", currentCountry)
+
+	privateKey, err := manager.generateKey()
+	if err != nil {
+		return
+	}
+	manager.publicKey = privateKey.PublicKey()
+
+	err = manager.device.Up(wireguard.DeviceConfig{
+		Name:       wireguard.InterfacePrefix + strconv.Itoa(manager.options.Port),
+		PrivateKey: privateKey,
+		IPAddress:  manager.hostAddress(serverHost) + manager.subnetMask(),
+		ListenPort: manager.options.Port,
+	})
+	if err != nil {
+		return
+	}
+	go manager.reconcilePeers()
+
+	proposal = wireguard.NewServiceProposalWithLocation(dto_discovery.Location{Country: currentCountry}, manager.trafficPolicy)
+	sessionConfigProvider = manager.provideConfig
+	return
+}
+
+// Wait blocks until service is stopped
+func (manager *Manager) Wait() error {
+	<-manager.stop
+	return nil
+}
+
+// Stop stops service
+func (manager *Manager) Stop() error {
+	manager.stopOnce.Do(func() {
+		close(manager.stop)
+		if manager.natRule != nil {
+			if err := manager.natService.Del(*manager.natRule); err != nil {
+				log.Warn(logPrefix, "Failed to remove NAT rule: ", err)
+			}
+		}
+		if manager.subnet != nil {
+			manager.subnetAllocator.Release(manager.subnet)
+		}
+		if err := manager.device.Down(); err != nil {
+			log.Warn(logPrefix, "Failed to remove interface: ", err)
+		}
+	})
+	return nil
+}
+
+// provideConfig adds new peer with fresh keys to the interface and returns its config for the consumer
+func (manager *Manager) provideConfig() (session.ServiceConfiguration, error) {
+	manager.peersLock.Lock()
+	defer manager.peersLock.Unlock()
+
+	host, err := manager.freeHost()
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, err := manager.generateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	err = manager.device.AddPeer(wireguard.Peer{
+		PublicKey:  privateKey.PublicKey(),
+		AllowedIPs: []string{manager.hostAddress(host) + "/32"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	manager.peers[privateKey.PublicKey()] = peer{host: host, addedAt: time.Now()}
+
+	return &wireguard.ServiceConfig{
+		Provider: wireguard.ProviderConfig{
+			PublicKey:    manager.publicKey,
+			EndpointIP:   manager.publicIP,
+			EndpointPort: manager.options.Port,
+		},
+		Consumer: wireguard.ConsumerConfig{
+			PrivateKey: privateKey,
+			IPAddress:  manager.hostAddress(host) + manager.subnetMask(),
+		},
+	}, nil
+}
+
+func (manager *Manager) freeHost() (int, error) {
+	taken := make(map[int]bool, len(manager.peers))
+	for _, existing := range manager.peers {
+		taken[existing.host] = true
+	}
+
+	for host := firstPeerHost; host <= manager.lastPeerHost(); host++ {
+		if !taken[host] {
+			return host, nil
+		}
+	}
+	return 0, ErrNoFreeAddress
+}
+
+func (manager *Manager) reconcilePeers() {
+	for {
+		select {
+		case <-manager.stop:
+			return
+		case <-time.After(reconcileInterval):
+			manager.reconcile(time.Now())
+		}
+	}
+}
+
+// reconcile marks sessions connected once their peers complete handshake and
+// removes peers, whose sessions were destroyed or never created
+func (manager *Manager) reconcile(now time.Time) {
+	peerStats, err := manager.device.Stats()
+	if err != nil {
+		log.Warn(logPrefix, "Failed to read peer statistics: ", err)
+		return
+	}
+
+	sessions := make(map[wireguard.Key]session.Session)
+	for _, sessionInstance := range manager.sessionStorage.GetAll() {
+		if config, ok := sessionInstance.Config.(*wireguard.ServiceConfig); ok {
+			sessions[config.Consumer.PrivateKey.PublicKey()] = sessionInstance
+		}
+	}
+
+	manager.peersLock.Lock()
+	defer manager.peersLock.Unlock()
+
+	for key, existing := range manager.peers {
+		sessionInstance, found := sessions[key]
+		if found {
+			if !sessionInstance.Connected && !peerStats[key].LastHandshake.IsZero() {
+				manager.sessionStorage.MarkConnected(sessionInstance.ID)
+			}
+			continue
+		}
+		if now.Sub(existing.addedAt) < peerGracePeriod {
+			continue
+		}
+
+		if err := manager.device.RemovePeer(key); err != nil {
+			log.Warn(logPrefix, "Failed to remove peer ", key, ": ", err)
+			continue
+		}
+		delete(manager.peers, key)
+	}
+}
+
+func (manager *Manager) hostAddress(host int) string {
+	address := make(net.IP, net.IPv4len)
+	binary.BigEndian.PutUint32(address, binary.BigEndian.Uint32(manager.subnet.IP.To4())+uint32(host))
+	return address.String()
+}
+
+func (manager *Manager) subnetMask() string {
+	ones, _ := manager.subnet.Mask.Size()
+	return "/" + strconv.Itoa(ones)
+}
+
+// lastPeerHost is the last host of the subnet before its broadcast address
+func (manager *Manager) lastPeerHost() int {
+	ones, bits := manager.subnet.Mask.Size()
+	return 1<<uint(bits-ones) - 2
+}
@@ -0,0 +1,258 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package service
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/mysteriumnetwork/node/core/ip"
+	"github.com/mysteriumnetwork/node/core/location"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/nat"
+	"github.com/mysteriumnetwork/node/services/wireguard"
+	"github.com/mysteriumnetwork/node/session"
+	"github.com/stretchr/testify/assert"
+)
+
+type natFake struct {
+	rules   []nat.RuleForwarding
+	failAdd error
+}
+
+func (fake *natFake) Add(rule nat.RuleForwarding) error {
+	if fake.failAdd != nil {
+		return fake.failAdd
+	}
+	fake.rules = append(fake.rules, rule)
+	return nil
+}
+
+func (fake *natFake) Del(rule nat.RuleForwarding) error {
+	for i, existing := range fake.rules {
+		if existing.SourceAddress == rule.SourceAddress {
+			fake.rules = append(fake.rules[:i], fake.rules[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (fake *natFake) ClearStale() error {
+	return nil
+}
+
+func (fake *natFake) Disable() {
+}
+
+type deviceFake struct {
+	config wireguard.DeviceConfig
+	peers  map[wireguard.Key]wireguard.Peer
+	stats  map[wireguard.Key]wireguard.PeerStats
+	up     bool
+}
+
+func (fake *deviceFake) Up(config wireguard.DeviceConfig) error {
+	fake.config = config
+	fake.up = true
+	return nil
+}
+
+func (fake *deviceFake) AddPeer(peer wireguard.Peer) error {
+	fake.peers[peer.PublicKey] = peer
+	return nil
+}
+
+func (fake *deviceFake) RemovePeer(key wireguard.Key) error {
+	delete(fake.peers, key)
+	return nil
+}
+
+func (fake *deviceFake) Stats() (map[wireguard.Key]wireguard.PeerStats, error) {
+	return fake.stats, nil
+}
+
+func (fake *deviceFake) Down() error {
+	fake.up = false
+	return nil
+}
+
+type sessionStorageFake struct {
+	sessions  []session.Session
+	connected []session.ID
+}
+
+func (fake *sessionStorageFake) GetAll() []session.Session {
+	return fake.sessions
+}
+
+func (fake *sessionStorageFake) MarkConnected(id session.ID) {
+	fake.connected = append(fake.connected, id)
+}
+
+func noLocalNetworks() ([]*net.IPNet, error) {
+	return nil, nil
+}
+
+func newTestManager(device *deviceFake, storage *sessionStorageFake) *Manager {
+	return newTestManagerWithAllocator(device, storage, ip.NewSubnetAllocatorWithNetworks(noLocalNetworks))
+}
+
+func newTestManagerWithAllocator(device *deviceFake, storage *sessionStorageFake, allocator SubnetAllocator) *Manager {
+	manager := NewManager(
+		Options{Port: 51820, Subnet: "10.182.0.0/16"},
+		nat.Policy{DeniedPorts: []int{25}},
+		ip.NewResolverFake("1.2.3.4"),
+		location.NewStaticResolver("LT"),
+		storage,
+		&natFake{},
+		allocator,
+	)
+	manager.device = device
+	return manager
+}
+
+func TestManager_StartAndStop(t *testing.T) {
+	device := &deviceFake{peers: make(map[wireguard.Key]wireguard.Peer)}
+	manager := newTestManager(device, &sessionStorageFake{})
+
+	proposal, _, err := manager.Start(identity.FromAddress("0x1"))
+	assert.NoError(t, err)
+	assert.Equal(t, wireguard.ServiceType, proposal.ServiceType)
+	assert.Equal(t, "LT", proposal.ServiceDefinition.GetLocation().Country)
+	assert.Equal(t, "mystwg51820", device.config.Name)
+	assert.Equal(t, "10.182.0.1/24", device.config.IPAddress)
+	assert.Equal(t, 51820, device.config.ListenPort)
+	assert.Equal(
+		t,
+		[]nat.RuleForwarding{{SourceAddress: "10.182.0.0/24", TargetIP: "1.2.3.4", Policy: nat.Policy{DeniedPorts: []int{25}}}},
+		manager.natService.(*natFake).rules,
+	)
+
+	assert.NoError(t, manager.Stop())
+	assert.NoError(t, manager.Wait())
+	assert.False(t, device.up)
+	assert.Empty(t, manager.natService.(*natFake).rules)
+}
+
+func TestManager_ServicesGetSeparateSubnets(t *testing.T) {
+	allocator := ip.NewSubnetAllocatorWithNetworks(noLocalNetworks)
+	firstDevice := &deviceFake{peers: make(map[wireguard.Key]wireguard.Peer)}
+	first := newTestManagerWithAllocator(firstDevice, &sessionStorageFake{}, allocator)
+	secondDevice := &deviceFake{peers: make(map[wireguard.Key]wireguard.Peer)}
+	second := newTestManagerWithAllocator(secondDevice, &sessionStorageFake{}, allocator)
+
+	_, _, err := first.Start(identity.FromAddress("0x1"))
+	assert.NoError(t, err)
+	_, secondConfigProvider, err := second.Start(identity.FromAddress("0x1"))
+	assert.NoError(t, err)
+
+	assert.Equal(t, "10.182.0.1/24", firstDevice.config.IPAddress)
+	assert.Equal(t, "10.182.1.1/24", secondDevice.config.IPAddress)
+	assert.Equal(t, "10.182.1.0/24", second.natService.(*natFake).rules[0].SourceAddress)
+	config, err := secondConfigProvider()
+	assert.NoError(t, err)
+	assert.Equal(t, "10.182.1.2/24", config.(*wireguard.ServiceConfig).Consumer.IPAddress)
+
+	assert.NoError(t, first.Stop())
+	third := newTestManagerWithAllocator(&deviceFake{peers: make(map[wireguard.Key]wireguard.Peer)}, &sessionStorageFake{}, allocator)
+	_, _, err = third.Start(identity.FromAddress("0x1"))
+	assert.NoError(t, err)
+	assert.Equal(t, "10.182.0.0/24", third.subnet.String(), "subnet of stopped service is reused")
+
+	assert.NoError(t, second.Stop())
+	assert.NoError(t, third.Stop())
+}
+
+func TestManager_StartFailsWithoutLocation(t *testing.T) {
+	manager := newTestManager(&deviceFake{}, &sessionStorageFake{})
+	manager.locationResolver = location.NewFailingResolver(errors.New("no location"))
+
+	_, _, err := manager.Start(identity.FromAddress("0x1"))
+	assert.Error(t, err)
+}
+
+func TestManager_StartFailsWithoutNATRule(t *testing.T) {
+	manager := newTestManager(&deviceFake{}, &sessionStorageFake{})
+	manager.natService = &natFake{failAdd: errors.New("pfctl failed")}
+
+	_, _, err := manager.Start(identity.FromAddress("0x1"))
+	assert.EqualError(t, err, "pfctl failed")
+	assert.Nil(t, manager.natRule)
+}
+
+func TestManager_ConfigProviderAddsPeers(t *testing.T) {
+	device := &deviceFake{peers: make(map[wireguard.Key]wireguard.Peer)}
+	manager := newTestManager(device, &sessionStorageFake{})
+	_, configProvider, err := manager.Start(identity.FromAddress("0x1"))
+	assert.NoError(t, err)
+	defer manager.Stop()
+
+	first, err := configProvider()
+	assert.NoError(t, err)
+	second, err := configProvider()
+	assert.NoError(t, err)
+
+	firstConfig := first.(*wireguard.ServiceConfig)
+	assert.Equal(t, device.config.PrivateKey.PublicKey(), firstConfig.Provider.PublicKey)
+	assert.Equal(t, "1.2.3.4", firstConfig.Provider.EndpointIP)
+	assert.Equal(t, 51820, firstConfig.Provider.EndpointPort)
+	assert.Equal(t, "10.182.0.2/24", firstConfig.Consumer.IPAddress)
+	assert.Equal(t, "10.182.0.3/24", second.(*wireguard.ServiceConfig).Consumer.IPAddress)
+
+	assert.Equal(
+		t,
+		[]string{"10.182.0.2/32"},
+		device.peers[firstConfig.Consumer.PrivateKey.PublicKey()].AllowedIPs,
+	)
+	assert.Len(t, device.peers, 2)
+}
+
+func TestManager_ReconcilePeers(t *testing.T) {
+	device := &deviceFake{peers: make(map[wireguard.Key]wireguard.Peer)}
+	storage := &sessionStorageFake{}
+	manager := newTestManager(device, storage)
+	_, configProvider, _ := manager.Start(identity.FromAddress("0x1"))
+	defer manager.Stop()
+
+	active, _ := configProvider()
+	abandoned, _ := configProvider()
+	activeKey := active.(*wireguard.ServiceConfig).Consumer.PrivateKey.PublicKey()
+	abandonedKey := abandoned.(*wireguard.ServiceConfig).Consumer.PrivateKey.PublicKey()
+
+	storage.sessions = []session.Session{{ID: "session1", Config: active}}
+	device.stats = map[wireguard.Key]wireguard.PeerStats{activeKey: {LastHandshake: time.Now()}}
+
+	manager.reconcile(time.Now())
+	assert.Len(t, device.peers, 2, "peers are kept during grace period")
+	assert.Equal(t, []session.ID{"session1"}, storage.connected)
+
+	manager.reconcile(time.Now().Add(peerGracePeriod))
+	assert.Contains(t, device.peers, activeKey)
+	assert.NotContains(t, device.peers, abandonedKey)
+
+	storage.sessions = nil
+	manager.reconcile(time.Now().Add(peerGracePeriod))
+	assert.Empty(t, device.peers)
+
+	next, err := configProvider()
+	assert.NoError(t, err)
+	assert.Equal(t, "10.182.0.2/24", next.(*wireguard.ServiceConfig).Consumer.IPAddress, "released address is reused")
+}
@@ -0,0 +1,156 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package session
+
+import (
+	"errors"
+
+	log "github.com/cihub/seelog"
+	"github.com/mysteriumnetwork/node/identity"
+)
+
+const admissionLogPrefix = "[session-admission] "
+
+var (
+	// ErrorConsumerNotAllowed is returned when consumer is denied or is not in the list of allowed consumers
+	ErrorConsumerNotAllowed = errors.New("consumer is not allowed")
+	// ErrorSessionLimitReached is returned when provider already serves maximum number of sessions
+	ErrorSessionLimitReached = errors.New("session limit reached")
+	// ErrorConsumerSessionLimitReached is returned when consumer already has maximum number of sessions
+	ErrorConsumerSessionLimitReached = errors.New("consumer session limit reached")
+	// ErrorInsufficientBalance is returned when consumer balance is lower than required
+	ErrorInsufficientBalance = errors.New("insufficient consumer balance")
+)
+
+// AdmissionPolicy defines which consumers are granted new sessions
+type AdmissionPolicy struct {
+	// MaxSessions limits concurrent sessions served by node, zero means unlimited
+	MaxSessions int
+	// MaxSessionsPerConsumer limits concurrent sessions of single consumer, zero means unlimited
+	MaxSessionsPerConsumer int
+	// AllowedConsumers when not empty, only listed consumers are granted sessions
+	AllowedConsumers []identity.Identity
+	// DeniedConsumers are never granted sessions
+	DeniedConsumers []identity.Identity
+	// MinBalance is minimal consumer balance in the blockchain, zero means balance is not checked
+	MinBalance uint64
+}
+
+// Admission decides whether consumer is granted a new session and whether consumer may keep granted sessions
+type Admission interface {
+	Admit(consumerID identity.Identity) error
+	CheckLimits(consumerID identity.Identity, activeSessions []Session) error
+	Verify(consumerID identity.Identity) error
+}
+
+// NewAdmission returns admission which enforces given policy.
+// Balance is queried only when policy requires minimal balance.
+func NewAdmission(policy AdmissionPolicy, balance identity.Balance) *admission {
+	return &admission{
+		policy:  policy,
+		balance: balance,
+	}
+}
+
+type admission struct {
+	policy  AdmissionPolicy
+	balance identity.Balance
+}
+
+// Admit returns nil if consumer is allowed and has sufficient balance for a new session, otherwise the reason of rejection.
+// Session limits are checked separately by CheckLimits, while the new session is being stored.
+func (admission *admission) Admit(consumerID identity.Identity) error {
+	if err := admission.checkLists(consumerID); err != nil {
+		return err
+	}
+	return admission.checkBalance(consumerID)
+}
+
+// CheckLimits returns nil if one more session of consumer fits into session limits, otherwise the limit reached
+func (admission *admission) CheckLimits(consumerID identity.Identity, activeSessions []Session) error {
+	if admission.policy.MaxSessions > 0 && len(activeSessions) >= admission.policy.MaxSessions {
+		return ErrorSessionLimitReached
+	}
+	if admission.policy.MaxSessionsPerConsumer > 0 && countConsumerSessions(activeSessions, consumerID) >= admission.policy.MaxSessionsPerConsumer {
+		return ErrorConsumerSessionLimitReached
+	}
+	return nil
+}
+
+// Verify returns nil if consumer still may be served within granted sessions, otherwise the violation of policy.
+// Session limits are not verified, as they were checked when sessions were granted.
+// Failure to query balance is not a violation, so that consumers are not disconnected when blockchain is unreachable.
+func (admission *admission) Verify(consumerID identity.Identity) error {
+	if err := admission.checkLists(consumerID); err != nil {
+		return err
+	}
+
+	err := admission.checkBalance(consumerID)
+	if err != nil && err != ErrorInsufficientBalance {
+		log.Warn(admissionLogPrefix, "Failed to verify balance of consumer ", consumerID.Address, ": ", err)
+		return nil
+	}
+	return err
+}
+
+func (admission *admission) checkLists(consumerID identity.Identity) error {
+	if containsIdentity(admission.policy.DeniedConsumers, consumerID) {
+		return ErrorConsumerNotAllowed
+	}
+	if len(admission.policy.AllowedConsumers) > 0 && !containsIdentity(admission.policy.AllowedConsumers, consumerID) {
+		return ErrorConsumerNotAllowed
+	}
+	return nil
+}
+
+func (admission *admission) checkBalance(consumerID identity.Identity) error {
+	if admission.policy.MinBalance == 0 {
+		return nil
+	}
+
+	balance, err := admission.balance(consumerID)
+	if err != nil {
+		return err
+	}
+	if balance < admission.policy.MinBalance {
+		return ErrorInsufficientBalance
+	}
+	return nil
+}
+
+func containsIdentity(identities []identity.Identity, id identity.Identity) bool {
+	for _, listed := range identities {
+		if sameIdentity(listed, id) {
+			return true
+		}
+	}
+	return false
+}
+
+func countConsumerSessions(sessions []Session, consumerID identity.Identity) (count int) {
+	for _, sessionInstance := range sessions {
+		if sameIdentity(sessionInstance.ConsumerID, consumerID) {
+			count++
+		}
+	}
+	return
+}
+
+func sameIdentity(a, b identity.Identity) bool {
+	return identity.FromAddress(a.Address) == identity.FromAddress(b.Address)
+}
@@ -0,0 +1,110 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package session
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	consumerA = identity.FromAddress("0x000000000000000000000000000000000000000a")
+	consumerB = identity.FromAddress("0x000000000000000000000000000000000000000b")
+)
+
+func balanceOf(amount uint64, err error) identity.Balance {
+	return func(identity.Identity) (uint64, error) {
+		return amount, err
+	}
+}
+
+func TestAdmission_AdmitsAnyoneWithEmptyPolicy(t *testing.T) {
+	admission := NewAdmission(AdmissionPolicy{}, nil)
+
+	assert.NoError(t, admission.Admit(consumerA))
+	assert.NoError(t, admission.CheckLimits(consumerA, []Session{{ConsumerID: consumerA}, {ConsumerID: consumerB}}))
+}
+
+func TestAdmission_Lists(t *testing.T) {
+	tests := []struct {
+		policy      AdmissionPolicy
+		consumerID  identity.Identity
+		expectedErr error
+	}{
+		{AdmissionPolicy{DeniedConsumers: []identity.Identity{consumerA}}, consumerA, ErrorConsumerNotAllowed},
+		{AdmissionPolicy{DeniedConsumers: []identity.Identity{consumerA}}, consumerB, nil},
+		{AdmissionPolicy{AllowedConsumers: []identity.Identity{consumerA}}, consumerA, nil},
+		{AdmissionPolicy{AllowedConsumers: []identity.Identity{consumerA}}, consumerB, ErrorConsumerNotAllowed},
+		{AdmissionPolicy{AllowedConsumers: []identity.Identity{{Address: "0x000000000000000000000000000000000000000A"}}}, consumerA, nil},
+		{
+			AdmissionPolicy{AllowedConsumers: []identity.Identity{consumerA}, DeniedConsumers: []identity.Identity{consumerA}},
+			consumerA,
+			ErrorConsumerNotAllowed,
+		},
+	}
+
+	for _, test := range tests {
+		admission := NewAdmission(test.policy, nil)
+		assert.Exactly(t, test.expectedErr, admission.Admit(test.consumerID))
+	}
+}
+
+func TestAdmission_SessionLimits(t *testing.T) {
+	activeSessions := []Session{{ConsumerID: consumerA}, {ConsumerID: consumerA}, {ConsumerID: consumerB}}
+
+	admission := NewAdmission(AdmissionPolicy{MaxSessions: 3}, nil)
+	assert.Exactly(t, ErrorSessionLimitReached, admission.CheckLimits(consumerB, activeSessions))
+
+	admission = NewAdmission(AdmissionPolicy{MaxSessions: 4, MaxSessionsPerConsumer: 2}, nil)
+	assert.Exactly(t, ErrorConsumerSessionLimitReached, admission.CheckLimits(consumerA, activeSessions))
+	assert.NoError(t, admission.CheckLimits(consumerB, activeSessions))
+}
+
+func TestAdmission_ConsumerSessionLimitIgnoresAddressCase(t *testing.T) {
+	activeSessions := []Session{{ConsumerID: identity.Identity{Address: "0x000000000000000000000000000000000000000A"}}}
+
+	admission := NewAdmission(AdmissionPolicy{MaxSessionsPerConsumer: 1}, nil)
+	assert.Exactly(t, ErrorConsumerSessionLimitReached, admission.CheckLimits(consumerA, activeSessions))
+}
+
+func TestAdmission_MinBalance(t *testing.T) {
+	admission := NewAdmission(AdmissionPolicy{MinBalance: 100}, balanceOf(99, nil))
+	assert.Exactly(t, ErrorInsufficientBalance, admission.Admit(consumerA))
+
+	admission = NewAdmission(AdmissionPolicy{MinBalance: 100}, balanceOf(100, nil))
+	assert.NoError(t, admission.Admit(consumerA))
+
+	balanceErr := errors.New("node unreachable")
+	admission = NewAdmission(AdmissionPolicy{MinBalance: 100}, balanceOf(0, balanceErr))
+	assert.Exactly(t, balanceErr, admission.Admit(consumerA))
+}
+
+func TestAdmission_Verify(t *testing.T) {
+	admission := NewAdmission(AdmissionPolicy{MaxSessionsPerConsumer: 1, DeniedConsumers: []identity.Identity{consumerB}}, nil)
+	assert.NoError(t, admission.Verify(consumerA))
+	assert.Exactly(t, ErrorConsumerNotAllowed, admission.Verify(consumerB))
+
+	admission = NewAdmission(AdmissionPolicy{MinBalance: 100}, balanceOf(99, nil))
+	assert.Exactly(t, ErrorInsufficientBalance, admission.Verify(consumerA))
+
+	admission = NewAdmission(AdmissionPolicy{MinBalance: 100}, balanceOf(0, errors.New("node unreachable")))
+	assert.NoError(t, admission.Verify(consumerA))
+}
@@ -27,6 +27,7 @@ import (
 // Manager defines methods for session management
 type Manager interface {
 	Create(consumerID identity.Identity, proposalID int) (Session, error)
+	Destroy(consumerID identity.Identity, sessionID ID) error
 }
 
 // createConsumer processes session create requests from communication channel.
@@ -56,6 +57,14 @@ func (consumer *createConsumer) Consume(requestPtr interface{}) (response interf
 		return responseWithSession(sessionInstance), nil
 	case ErrorInvalidProposal:
 		return responseInvalidProposal, nil
+	case ErrorConsumerNotAllowed:
+		return responseConsumerNotAllowed, nil
+	case ErrorSessionLimitReached:
+		return responseSessionLimitReached, nil
+	case ErrorConsumerSessionLimitReached:
+		return responseConsumerSessionLimitReached, nil
+	case ErrorInsufficientBalance:
+		return responseInsufficientBalance, nil
 	default:
 		return responseInternalError, nil
 	}
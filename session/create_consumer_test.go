@@ -28,9 +28,9 @@ import (
 func TestConsumer_Success(t *testing.T) {
 	mockManager := &managerFake{
 		returnSession: Session{
-			"new-id",
-			fakeSessionConfig{"string-param", 123},
-			identity.FromAddress("123"),
+			ID:         "new-id",
+			Config:     fakeSessionConfig{"string-param", 123},
+			ConsumerID: identity.FromAddress("123"),
 		},
 	}
 	consumer := createConsumer{
@@ -71,6 +71,28 @@ func TestConsumer_ErrorInvalidProposal(t *testing.T) {
 	assert.Exactly(t, responseInvalidProposal, sessionResponse)
 }
 
+func TestConsumer_ErrorAdmission(t *testing.T) {
+	tests := []struct {
+		managerErr       error
+		expectedResponse CreateResponse
+	}{
+		{ErrorConsumerNotAllowed, responseConsumerNotAllowed},
+		{ErrorSessionLimitReached, responseSessionLimitReached},
+		{ErrorConsumerSessionLimitReached, responseConsumerSessionLimitReached},
+		{ErrorInsufficientBalance, responseInsufficientBalance},
+	}
+
+	for _, test := range tests {
+		consumer := createConsumer{SessionManager: &managerFake{returnError: test.managerErr}}
+
+		request := consumer.NewRequest().(*CreateRequest)
+		sessionResponse, err := consumer.Consume(request)
+
+		assert.NoError(t, err)
+		assert.Exactly(t, test.expectedResponse, sessionResponse)
+	}
+}
+
 func TestConsumer_ErrorFatal(t *testing.T) {
 	mockManager := &managerFake{
 		returnError: errors.New("fatality"),
@@ -88,6 +110,7 @@ func TestConsumer_ErrorFatal(t *testing.T) {
 type managerFake struct {
 	lastConsumerID identity.Identity
 	lastProposalID int
+	lastSessionID  ID
 	returnSession  Session
 	returnError    error
 }
@@ -98,3 +121,10 @@ func (manager *managerFake) Create(consumerID identity.Identity, proposalID int)
 	manager.lastProposalID = proposalID
 	return manager.returnSession, manager.returnError
 }
+
+// Destroy function records destroyed session
+func (manager *managerFake) Destroy(consumerID identity.Identity, sessionID ID) error {
+	manager.lastConsumerID = consumerID
+	manager.lastSessionID = sessionID
+	return manager.returnError
+}
@@ -25,9 +25,31 @@ import (
 
 const endpointSessionCreate = communication.RequestEndpoint("session-create")
 
+// ErrorCode identifies the reason why provider refused to create session
+type ErrorCode string
+
+const (
+	// ErrorCodeInvalidProposal is returned when requested proposal is not served by provider
+	ErrorCodeInvalidProposal = ErrorCode("invalid_proposal")
+	// ErrorCodeInternal is returned when provider failed to create session
+	ErrorCodeInternal = ErrorCode("internal_error")
+	// ErrorCodeConsumerNotAllowed is returned when consumer is denied by provider
+	ErrorCodeConsumerNotAllowed = ErrorCode("consumer_not_allowed")
+	// ErrorCodeSessionLimitReached is returned when provider serves maximum number of sessions
+	ErrorCodeSessionLimitReached = ErrorCode("session_limit_reached")
+	// ErrorCodeConsumerSessionLimitReached is returned when consumer has maximum number of sessions
+	ErrorCodeConsumerSessionLimitReached = ErrorCode("consumer_session_limit_reached")
+	// ErrorCodeInsufficientBalance is returned when consumer balance is lower than required by provider
+	ErrorCodeInsufficientBalance = ErrorCode("insufficient_balance")
+)
+
 var (
-	responseInvalidProposal = CreateResponse{Success: false, Message: "Invalid Proposal"}
-	responseInternalError   = CreateResponse{Success: false, Message: "Internal Error"}
+	responseInvalidProposal             = CreateResponse{Success: false, ErrorCode: ErrorCodeInvalidProposal, Message: "Invalid Proposal"}
+	responseInternalError               = CreateResponse{Success: false, ErrorCode: ErrorCodeInternal, Message: "Internal Error"}
+	responseConsumerNotAllowed          = CreateResponse{Success: false, ErrorCode: ErrorCodeConsumerNotAllowed, Message: "Consumer Not Allowed"}
+	responseSessionLimitReached         = CreateResponse{Success: false, ErrorCode: ErrorCodeSessionLimitReached, Message: "Session Limit Reached"}
+	responseConsumerSessionLimitReached = CreateResponse{Success: false, ErrorCode: ErrorCodeConsumerSessionLimitReached, Message: "Consumer Session Limit Reached"}
+	responseInsufficientBalance         = CreateResponse{Success: false, ErrorCode: ErrorCodeInsufficientBalance, Message: "Insufficient Balance"}
 )
 
 // CreateRequest structure represents message from service consumer to initiate session for given proposal id
@@ -37,9 +59,10 @@ type CreateRequest struct {
 
 // CreateResponse structure represents service provider response to given session request from consumer
 type CreateResponse struct {
-	Success bool       `json:"success"`
-	Message string     `json:"message"`
-	Session SessionDto `json:"session"`
+	Success   bool       `json:"success"`
+	ErrorCode ErrorCode  `json:"error_code,omitempty"`
+	Message   string     `json:"message"`
+	Session   SessionDto `json:"session"`
 }
 
 // SessionDto structure represents session information data within session creation response (session id and configuration options for underlaying service type)
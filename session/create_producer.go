@@ -19,11 +19,20 @@ package session
 
 import (
 	"encoding/json"
-	"errors"
 
 	"github.com/mysteriumnetwork/node/communication"
 )
 
+// CreateError is returned when provider refuses to create session
+type CreateError struct {
+	Code    ErrorCode
+	Message string
+}
+
+func (err *CreateError) Error() string {
+	return "Session create failed. " + err.Message
+}
+
 type createProducer struct {
 	ProposalID int
 }
@@ -53,7 +62,7 @@ func RequestSessionCreate(sender communication.Sender, proposalID int) (sessionI
 
 	response := responsePtr.(*CreateResponse)
 	if !response.Success {
-		err = errors.New("Session create failed. " + response.Message)
+		err = &CreateError{Code: response.ErrorCode, Message: response.Message}
 		return
 	}
 
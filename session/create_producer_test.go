@@ -51,8 +51,20 @@ func TestProducer_RequestSessionCreate(t *testing.T) {
 	assert.Exactly(t, succesfullSessionConfig, config)
 }
 
+func TestProducer_RequestSessionCreateRefused(t *testing.T) {
+	sender := &fakeSender{response: &CreateResponse{
+		Success:   false,
+		ErrorCode: ErrorCodeSessionLimitReached,
+		Message:   "Session Limit Reached",
+	}}
+	_, _, err := RequestSessionCreate(sender, 123)
+	assert.Exactly(t, &CreateError{Code: ErrorCodeSessionLimitReached, Message: "Session Limit Reached"}, err)
+	assert.EqualError(t, err, "Session create failed. Session Limit Reached")
+}
+
 type fakeSender struct {
 	lastRequest communication.RequestProducer
+	response    *CreateResponse
 }
 
 func (sender *fakeSender) Send(producer communication.MessageProducer) error {
@@ -61,6 +73,9 @@ func (sender *fakeSender) Send(producer communication.MessageProducer) error {
 
 func (sender *fakeSender) Request(producer communication.RequestProducer) (responsePtr interface{}, err error) {
 	sender.lastRequest = producer
+	if sender.response != nil {
+		return sender.response, nil
+	}
 	return &CreateResponse{
 		Success: true,
 		Message: "Everything is great!",
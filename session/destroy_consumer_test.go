@@ -0,0 +1,70 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package session
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDestroyConsumer_Success(t *testing.T) {
+	mockManager := &managerFake{}
+	consumer := destroyConsumer{
+		SessionManager: mockManager,
+		PeerID:         identity.FromAddress("peer-id"),
+	}
+
+	request := consumer.NewRequest().(*DestroyRequest)
+	request.SessionID = "session-id"
+	response, err := consumer.Consume(request)
+
+	assert.NoError(t, err)
+	assert.Exactly(t, identity.FromAddress("peer-id"), mockManager.lastConsumerID)
+	assert.Exactly(t, ID("session-id"), mockManager.lastSessionID)
+	assert.Exactly(t, responseDestroySuccess, response)
+}
+
+func TestDestroyConsumer_ErrorSessionNotExists(t *testing.T) {
+	consumer := destroyConsumer{SessionManager: &managerFake{returnError: ErrorSessionNotExists}}
+
+	response, err := consumer.Consume(consumer.NewRequest())
+
+	assert.NoError(t, err)
+	assert.Exactly(t, responseDestroyNotFound, response)
+}
+
+func TestDestroyConsumer_ErrorWrongSessionOwner(t *testing.T) {
+	consumer := destroyConsumer{SessionManager: &managerFake{returnError: ErrorWrongSessionOwner}}
+
+	response, err := consumer.Consume(consumer.NewRequest())
+
+	assert.NoError(t, err)
+	assert.Exactly(t, responseDestroyNotFound, response)
+}
+
+func TestDestroyConsumer_ErrorFatal(t *testing.T) {
+	consumer := destroyConsumer{SessionManager: &managerFake{returnError: errors.New("fatality")}}
+
+	response, err := consumer.Consume(consumer.NewRequest())
+
+	assert.NoError(t, err)
+	assert.Exactly(t, responseDestroyInternalError, response)
+}
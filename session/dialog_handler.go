@@ -41,9 +41,21 @@ func (handler *handler) Handle(dialog communication.Dialog) error {
 }
 
 func (handler *handler) subscribeSessionRequests(dialog communication.Dialog) error {
-	return dialog.Respond(
+	sessionManager := handler.sessionManagerFactory(dialog)
+
+	err := dialog.Respond(
 		&createConsumer{
-			SessionManager: handler.sessionManagerFactory(dialog),
+			SessionManager: sessionManager,
+			PeerID:         dialog.PeerID(),
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	return dialog.Respond(
+		&destroyConsumer{
+			SessionManager: sessionManager,
 			PeerID:         dialog.PeerID(),
 		},
 	)
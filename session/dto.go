@@ -17,7 +17,11 @@
 
 package session
 
-import "github.com/mysteriumnetwork/node/identity"
+import (
+	"time"
+
+	"github.com/mysteriumnetwork/node/identity"
+)
 
 // ID represents session id type
 type ID string
@@ -27,6 +31,18 @@ type Session struct {
 	ID         ID
 	Config     ServiceConfiguration
 	ConsumerID identity.Identity
+	CreatedAt  time.Time
+	// Connected is set once consumer connects to the service using this session
+	Connected    bool
+	DataTransfer DataTransfer
+	// Done is closed when session is destroyed
+	Done chan struct{}
+}
+
+// DataTransfer counts traffic of the session from the provider's point of view
+type DataTransfer struct {
+	Up   uint64
+	Down uint64
 }
 
 // ServiceConfiguration defines service configuration from underlying transport mechanism to be passed to remote party
@@ -20,14 +20,25 @@ package session
 import (
 	"errors"
 	"sync"
+	"time"
 
+	log "github.com/cihub/seelog"
 	"github.com/mysteriumnetwork/node/identity"
 	discovery_dto "github.com/mysteriumnetwork/node/service_discovery/dto"
 )
 
+const managerLogPrefix = "[session-manager] "
+
+// admissionCheckInterval is how often consumers of active sessions are verified against admission policy
+const admissionCheckInterval = 5 * time.Minute
+
 var (
 	// ErrorInvalidProposal is validation error then invalid proposal requested for session creation
 	ErrorInvalidProposal = errors.New("proposal does not exist")
+	// ErrorSessionNotExists returned when consumer tries to destroy session that does not exists
+	ErrorSessionNotExists = errors.New("session does not exists")
+	// ErrorWrongSessionOwner returned when consumer tries to destroy session that does not belongs to him
+	ErrorWrongSessionOwner = errors.New("wrong session owner")
 )
 
 // IDGenerator defines method for session id generation
@@ -36,31 +47,46 @@ type IDGenerator func() (ID, error)
 // ConfigProvider provides session config for remote client
 type ConfigProvider func() (ServiceConfiguration, error)
 
-// SaveCallback stores newly started sessions
-type SaveCallback func(Session)
+// Storage keeps sessions, which are currently served by provider
+type Storage interface {
+	Add(Session)
+	AddIfAdmitted(sessionInstance Session, admit func(activeSessions []Session) error) error
+	Find(ID) (Session, bool)
+	GetAll() []Session
+	Remove(ID)
+}
 
 // PromiseProcessor processes promises at provider side.
 // Provider checks promises from consumer and signs them also.
 // Provider clears promises from consumer.
+// Processor calls terminate, when consumer stops paying for the session.
 type PromiseProcessor interface {
-	Start(discovery_dto.ServiceProposal) error
+	Start(proposal discovery_dto.ServiceProposal, sessionID ID, terminate func()) error
 	Stop() error
 }
 
+// PromiseProcessorFactory creates promise processor for a session
+type PromiseProcessorFactory func() PromiseProcessor
+
 // NewManager returns new session manager
 func NewManager(
 	currentProposal discovery_dto.ServiceProposal,
 	idGenerator IDGenerator,
 	configProvider ConfigProvider,
-	saveCallback SaveCallback,
-	promiseProcessor PromiseProcessor,
+	sessionStorage Storage,
+	admission Admission,
+	promiseProcessorFactory PromiseProcessorFactory,
 ) *manager {
 	return &manager{
-		currentProposal:  currentProposal,
-		generateID:       idGenerator,
-		provideConfig:    configProvider,
-		saveSession:      saveCallback,
-		promiseProcessor: promiseProcessor,
+		currentProposal:     currentProposal,
+		generateID:          idGenerator,
+		provideConfig:       configProvider,
+		sessionStorage:      sessionStorage,
+		admission:           admission,
+		newPromiseProcessor: promiseProcessorFactory,
+		timeGetter:          time.Now,
+
+		admissionCheckInterval: admissionCheckInterval,
 
 		creationLock: sync.Mutex{},
 	}
@@ -68,11 +94,15 @@ func NewManager(
 
 // manager knows how to start and provision session
 type manager struct {
-	currentProposal  discovery_dto.ServiceProposal
-	generateID       IDGenerator
-	provideConfig    ConfigProvider
-	saveSession      SaveCallback
-	promiseProcessor PromiseProcessor
+	currentProposal     discovery_dto.ServiceProposal
+	generateID          IDGenerator
+	provideConfig       ConfigProvider
+	sessionStorage      Storage
+	admission           Admission
+	newPromiseProcessor PromiseProcessorFactory
+	timeGetter          func() time.Time
+
+	admissionCheckInterval time.Duration
 
 	creationLock sync.Mutex
 }
@@ -87,26 +117,83 @@ func (manager *manager) Create(consumerID identity.Identity, proposalID int) (se
 		return
 	}
 
+	if err = manager.admission.Admit(consumerID); err != nil {
+		log.Info(managerLogPrefix, "Session refused for consumer ", consumerID.Address, ": ", err)
+		return
+	}
+
 	sessionInstance, err = manager.createSession(consumerID)
 	if err != nil {
 		return
 	}
 
-	err = manager.promiseProcessor.Start(manager.currentProposal)
+	// storage is shared by dialogs of all services, so limits are checked by it while session is being added
+	err = manager.sessionStorage.AddIfAdmitted(sessionInstance, func(activeSessions []Session) error {
+		return manager.admission.CheckLimits(consumerID, activeSessions)
+	})
 	if err != nil {
-		return
+		log.Info(managerLogPrefix, "Session refused for consumer ", consumerID.Address, ": ", err)
+		return Session{}, err
 	}
 
-	manager.saveSession(sessionInstance)
+	sessionID := sessionInstance.ID
+	promiseProcessor := manager.newPromiseProcessor()
+	err = promiseProcessor.Start(manager.currentProposal, sessionID, func() { manager.terminate(sessionID) })
+	if err != nil {
+		manager.sessionStorage.Remove(sessionID)
+		return Session{}, err
+	}
+
+	go manager.superviseSession(sessionInstance, promiseProcessor)
 	return sessionInstance, nil
 }
 
+// Destroy destroys session of the consumer, promise processing of the session is stopped
+func (manager *manager) Destroy(consumerID identity.Identity, sessionID ID) error {
+	sessionInstance, found := manager.sessionStorage.Find(sessionID)
+	if !found {
+		return ErrorSessionNotExists
+	}
+	if sessionInstance.ConsumerID != consumerID {
+		return ErrorWrongSessionOwner
+	}
+
+	manager.sessionStorage.Remove(sessionID)
+	return nil
+}
+
+func (manager *manager) terminate(sessionID ID) {
+	log.Warn(managerLogPrefix, "Terminating unpaid session: ", sessionID)
+	manager.sessionStorage.Remove(sessionID)
+}
+
+// superviseSession terminates session, when its consumer violates admission policy, and stops promise processing of the session,
+// however session was destroyed - by consumer, by expiration, by provider or by service
+func (manager *manager) superviseSession(sessionInstance Session, promiseProcessor PromiseProcessor) {
+	for {
+		select {
+		case <-sessionInstance.Done:
+			if err := promiseProcessor.Stop(); err != nil {
+				log.Warn(managerLogPrefix, "Failed to stop promise processor: ", err)
+			}
+			return
+		case <-time.After(manager.admissionCheckInterval):
+			if err := manager.admission.Verify(sessionInstance.ConsumerID); err != nil {
+				log.Warn(managerLogPrefix, "Terminating session ", sessionInstance.ID, " violating admission policy: ", err)
+				manager.sessionStorage.Remove(sessionInstance.ID)
+			}
+		}
+	}
+}
+
 func (manager *manager) createSession(consumerID identity.Identity) (sessionInstance Session, err error) {
 	sessionInstance.ID, err = manager.generateID()
 	if err != nil {
 		return
 	}
 	sessionInstance.ConsumerID = consumerID
+	sessionInstance.CreatedAt = manager.timeGetter()
+	sessionInstance.Done = make(chan struct{})
 	sessionInstance.Config, err = manager.provideConfig()
 	return
 }
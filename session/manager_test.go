@@ -18,8 +18,12 @@
 package session
 
 import (
+	"fmt"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/mysteriumnetwork/node/core/events"
 	"github.com/mysteriumnetwork/node/identity"
 	discovery_dto "github.com/mysteriumnetwork/node/service_discovery/dto"
 	"github.com/stretchr/testify/assert"
@@ -30,13 +34,14 @@ var (
 	currentProposal   = discovery_dto.ServiceProposal{
 		ID: currentProposalID,
 	}
-	expectedID      = ID("mocked-id")
-	expectedSession = Session{
+	expectedID        = ID("mocked-id")
+	expectedCreatedAt = time.Date(2018, 11, 1, 10, 0, 0, 0, time.UTC)
+	expectedSession   = Session{
 		ID:         expectedID,
 		Config:     expectedSessionConfig,
 		ConsumerID: identity.FromAddress("deadbeef"),
+		CreatedAt:  expectedCreatedAt,
 	}
-	lastSession Session
 )
 
 const expectedSessionConfig = "config_string"
@@ -49,49 +54,251 @@ func generateSessionID() (ID, error) {
 	return expectedID, nil
 }
 
-func saveSession(sessionInstance Session) {
-	lastSession = sessionInstance
-}
-
 type fakePromiseProcessor struct {
-	started  bool
-	proposal discovery_dto.ServiceProposal
+	started   bool
+	proposal  discovery_dto.ServiceProposal
+	sessionID ID
+	terminate func()
+	stopped   chan struct{}
 }
 
-func (processor *fakePromiseProcessor) Start(proposal discovery_dto.ServiceProposal) error {
+func (processor *fakePromiseProcessor) Start(proposal discovery_dto.ServiceProposal, sessionID ID, terminate func()) error {
 	processor.started = true
 	processor.proposal = proposal
+	processor.sessionID = sessionID
+	processor.terminate = terminate
+	processor.stopped = make(chan struct{})
 	return nil
 }
 
 func (processor *fakePromiseProcessor) Stop() error {
-	processor.started = false
+	close(processor.stopped)
+	return nil
+}
+
+func (processor *fakePromiseProcessor) waitStopped(t *testing.T) {
+	select {
+	case <-processor.stopped:
+	case <-time.After(time.Second):
+		assert.Fail(t, "promise processor was not stopped")
+	}
+}
+
+type admissionFake struct {
+	lock      sync.Mutex
+	verifyErr error
+}
+
+func (admission *admissionFake) Admit(identity.Identity) error {
 	return nil
 }
 
+func (admission *admissionFake) CheckLimits(identity.Identity, []Session) error {
+	return nil
+}
+
+func (admission *admissionFake) Verify(identity.Identity) error {
+	admission.lock.Lock()
+	defer admission.lock.Unlock()
+
+	return admission.verifyErr
+}
+
+func (admission *admissionFake) setVerifyErr(err error) {
+	admission.lock.Lock()
+	defer admission.lock.Unlock()
+
+	admission.verifyErr = err
+}
+
+func newTestManager(storage Storage, promiseProcessor PromiseProcessor) *manager {
+	return newTestManagerWithAdmission(storage, NewAdmission(AdmissionPolicy{}, nil), promiseProcessor)
+}
+
+func newTestManagerWithAdmission(storage Storage, admission Admission, promiseProcessor PromiseProcessor) *manager {
+	newPromiseProcessor := func() PromiseProcessor {
+		return promiseProcessor
+	}
+	manager := NewManager(currentProposal, generateSessionID, mockedConfigProvider, storage, admission, newPromiseProcessor)
+	manager.timeGetter = func() time.Time { return expectedCreatedAt }
+	return manager
+}
+
+func assertSession(t *testing.T, expected, actual Session) {
+	assert.NotNil(t, actual.Done)
+	actual.Done = nil
+	assert.Exactly(t, expected, actual)
+}
+
 func TestManager_Create_StoresSession(t *testing.T) {
-	manager := NewManager(currentProposal, generateSessionID, mockedConfigProvider, saveSession, &fakePromiseProcessor{})
+	storage := NewStorageMemory(events.NewPublisherFake())
+	manager := newTestManager(storage, &fakePromiseProcessor{})
 
 	sessionInstance, err := manager.Create(identity.FromAddress("deadbeef"), currentProposalID)
 	assert.NoError(t, err)
-	assert.Exactly(t, expectedSession, sessionInstance)
-	assert.Exactly(t, expectedSession, lastSession)
+	assertSession(t, expectedSession, sessionInstance)
+
+	storedSession, found := storage.Find(expectedID)
+	assert.True(t, found)
+	assertSession(t, expectedSession, storedSession)
 }
 
 func TestManager_Create_RejectsUnknownProposal(t *testing.T) {
-	manager := NewManager(currentProposal, generateSessionID, mockedConfigProvider, saveSession, &fakePromiseProcessor{})
+	manager := newTestManager(NewStorageMemory(events.NewPublisherFake()), &fakePromiseProcessor{})
 
 	sessionInstance, err := manager.Create(identity.FromAddress("deadbeef"), 69)
 	assert.Exactly(t, err, ErrorInvalidProposal)
 	assert.Exactly(t, Session{}, sessionInstance)
 }
 
+func TestManager_Create_RejectsNotAdmittedConsumer(t *testing.T) {
+	storage := NewStorageMemory(events.NewPublisherFake())
+	storage.Add(Session{ID: "existing-id", ConsumerID: identity.FromAddress("deadbeef")})
+	promiseProcessor := &fakePromiseProcessor{}
+	manager := newTestManagerWithAdmission(storage, NewAdmission(AdmissionPolicy{MaxSessionsPerConsumer: 1}, nil), promiseProcessor)
+
+	sessionInstance, err := manager.Create(identity.FromAddress("deadbeef"), currentProposalID)
+	assert.Exactly(t, ErrorConsumerSessionLimitReached, err)
+	assert.Exactly(t, Session{}, sessionInstance)
+	assert.False(t, promiseProcessor.started)
+	_, found := storage.Find(expectedID)
+	assert.False(t, found)
+}
+
+func TestManager_Create_LimitsSessionsAcrossManagersOfSharedStorage(t *testing.T) {
+	storage := NewStorageMemory(events.NewPublisherFake())
+	admission := NewAdmission(AdmissionPolicy{MaxSessions: 1}, nil)
+	managers := make([]*manager, 10)
+	for i := range managers {
+		sessionID := ID(fmt.Sprintf("session-%d", i))
+		managers[i] = newTestManagerWithAdmission(storage, admission, &fakePromiseProcessor{})
+		managers[i].generateID = func() (ID, error) { return sessionID, nil }
+	}
+
+	var wg sync.WaitGroup
+	for _, sessionManager := range managers {
+		wg.Add(1)
+		go func(sessionManager *manager) {
+			defer wg.Done()
+			sessionManager.Create(identity.FromAddress("deadbeef"), currentProposalID)
+		}(sessionManager)
+	}
+	wg.Wait()
+
+	assert.Len(t, storage.GetAll(), 1)
+}
+
 func TestManager_Create_StartsPromiseProcessor(t *testing.T) {
 	promiseProcessor := &fakePromiseProcessor{}
-	manager := NewManager(currentProposal, generateSessionID, mockedConfigProvider, saveSession, promiseProcessor)
+	manager := newTestManager(NewStorageMemory(events.NewPublisherFake()), promiseProcessor)
 
 	_, err := manager.Create(identity.FromAddress("deadbeef"), currentProposalID)
 	assert.NoError(t, err)
 	assert.True(t, promiseProcessor.started)
 	assert.Exactly(t, currentProposal, promiseProcessor.proposal)
+	assert.Exactly(t, expectedID, promiseProcessor.sessionID)
+}
+
+func TestManager_Create_StartsPromiseProcessorPerSession(t *testing.T) {
+	storage := NewStorageMemory(events.NewPublisherFake())
+	var promiseProcessors []*fakePromiseProcessor
+	manager := NewManager(
+		currentProposal,
+		generateSessionID,
+		mockedConfigProvider,
+		storage,
+		NewAdmission(AdmissionPolicy{}, nil),
+		func() PromiseProcessor {
+			promiseProcessor := &fakePromiseProcessor{}
+			promiseProcessors = append(promiseProcessors, promiseProcessor)
+			return promiseProcessor
+		},
+	)
+	sessionIDs := []ID{"session-1", "session-2"}
+	manager.generateID = func() (ID, error) {
+		sessionID := sessionIDs[0]
+		sessionIDs = sessionIDs[1:]
+		return sessionID, nil
+	}
+
+	_, err := manager.Create(identity.FromAddress("deadbeef"), currentProposalID)
+	assert.NoError(t, err)
+	_, err = manager.Create(identity.FromAddress("deadbeef"), currentProposalID)
+	assert.NoError(t, err)
+	assert.Len(t, promiseProcessors, 2)
+	assert.Exactly(t, ID("session-1"), promiseProcessors[0].sessionID)
+	assert.Exactly(t, ID("session-2"), promiseProcessors[1].sessionID)
+
+	assert.NoError(t, manager.Destroy(identity.FromAddress("deadbeef"), "session-1"))
+	promiseProcessors[0].waitStopped(t)
+	select {
+	case <-promiseProcessors[1].stopped:
+		assert.Fail(t, "promise processor of other session was stopped")
+	default:
+	}
+}
+
+func TestManager_Create_TerminatesUnpaidSession(t *testing.T) {
+	storage := NewStorageMemory(events.NewPublisherFake())
+	promiseProcessor := &fakePromiseProcessor{}
+	manager := newTestManager(storage, promiseProcessor)
+
+	_, err := manager.Create(identity.FromAddress("deadbeef"), currentProposalID)
+	assert.NoError(t, err)
+
+	promiseProcessor.terminate()
+	promiseProcessor.waitStopped(t)
+	_, found := storage.Find(expectedID)
+	assert.False(t, found)
+}
+
+func TestManager_Create_TerminatesSessionViolatingAdmission(t *testing.T) {
+	storage := NewStorageMemory(events.NewPublisherFake())
+	promiseProcessor := &fakePromiseProcessor{}
+	admission := &admissionFake{}
+	manager := newTestManagerWithAdmission(storage, admission, promiseProcessor)
+	manager.admissionCheckInterval = time.Millisecond
+
+	_, err := manager.Create(identity.FromAddress("deadbeef"), currentProposalID)
+	assert.NoError(t, err)
+
+	admission.setVerifyErr(ErrorInsufficientBalance)
+	promiseProcessor.waitStopped(t)
+	_, found := storage.Find(expectedID)
+	assert.False(t, found)
+}
+
+func TestManager_Destroy_RemovesSessionAndStopsPromiseProcessor(t *testing.T) {
+	storage := NewStorageMemory(events.NewPublisherFake())
+	promiseProcessor := &fakePromiseProcessor{}
+	manager := newTestManager(storage, promiseProcessor)
+
+	_, err := manager.Create(identity.FromAddress("deadbeef"), currentProposalID)
+	assert.NoError(t, err)
+
+	err = manager.Destroy(identity.FromAddress("deadbeef"), expectedID)
+	assert.NoError(t, err)
+	promiseProcessor.waitStopped(t)
+	_, found := storage.Find(expectedID)
+	assert.False(t, found)
+}
+
+func TestManager_Destroy_RejectsUnknownSession(t *testing.T) {
+	manager := newTestManager(NewStorageMemory(events.NewPublisherFake()), &fakePromiseProcessor{})
+
+	err := manager.Destroy(identity.FromAddress("deadbeef"), expectedID)
+	assert.Exactly(t, ErrorSessionNotExists, err)
+}
+
+func TestManager_Destroy_RejectsForeignSession(t *testing.T) {
+	storage := NewStorageMemory(events.NewPublisherFake())
+	manager := newTestManager(storage, &fakePromiseProcessor{})
+
+	_, err := manager.Create(identity.FromAddress("deadbeef"), currentProposalID)
+	assert.NoError(t, err)
+
+	err = manager.Destroy(identity.FromAddress("other-consumer"), expectedID)
+	assert.Exactly(t, ErrorWrongSessionOwner, err)
+	_, found := storage.Find(expectedID)
+	assert.True(t, found)
 }
@@ -18,21 +18,46 @@
 package session
 
 import (
+	"sort"
 	"sync"
+	"time"
+
+	log "github.com/cihub/seelog"
+	"github.com/mysteriumnetwork/node/core/events"
+	"github.com/mysteriumnetwork/node/identity"
+)
+
+const storageLogPrefix = "[session-storage] "
+
+const (
+	// CreatedEventTopic is the topic of events published when service session is created
+	CreatedEventTopic = "service-session-created"
+	// DestroyedEventTopic is the topic of events published when service session is destroyed
+	DestroyedEventTopic = "service-session-destroyed"
 )
 
+// Event is the payload of service session lifecycle events
+type Event struct {
+	ID         ID     `json:"id"`
+	ConsumerID string `json:"consumerId"`
+}
+
 // NewStorageMemory initiates new session storage
-func NewStorageMemory() *StorageMemory {
+func NewStorageMemory(eventPublisher events.Publisher) *StorageMemory {
 	return &StorageMemory{
-		sessionMap: make(map[ID]Session),
-		lock:       sync.Mutex{},
+		sessionMap:     make(map[ID]Session),
+		eventPublisher: eventPublisher,
+		timeGetter:     time.Now,
+		lock:           sync.Mutex{},
 	}
 }
 
 // StorageMemory maintains a map of session id -> session
 type StorageMemory struct {
-	sessionMap map[ID]Session
-	lock       sync.Mutex
+	sessionMap     map[ID]Session
+	eventPublisher events.Publisher
+	timeGetter     func() time.Time
+	lock           sync.Mutex
 }
 
 // Add puts given session to storage. Multiple sessions per peerID is possible in case different services are used
@@ -41,18 +66,152 @@ func (storage *StorageMemory) Add(sessionInstance Session) {
 	defer storage.lock.Unlock()
 
 	storage.sessionMap[sessionInstance.ID] = sessionInstance
+	storage.eventPublisher.Publish(CreatedEventTopic, newEvent(sessionInstance))
+}
+
+// AddIfAdmitted puts given session to storage, unless admit rejects it.
+// Admit is given sessions stored at the moment and is called atomically with adding, so concurrently created
+// sessions can not exceed the limits checked by admit.
+func (storage *StorageMemory) AddIfAdmitted(sessionInstance Session, admit func(activeSessions []Session) error) error {
+	storage.lock.Lock()
+	defer storage.lock.Unlock()
+
+	if err := admit(storage.sessions()); err != nil {
+		return err
+	}
+	storage.sessionMap[sessionInstance.ID] = sessionInstance
+	storage.eventPublisher.Publish(CreatedEventTopic, newEvent(sessionInstance))
+	return nil
 }
 
 // Find returns underlying session instance
 func (storage *StorageMemory) Find(id ID) (Session, bool) {
+	storage.lock.Lock()
+	defer storage.lock.Unlock()
+
 	sessionInstance, found := storage.sessionMap[id]
 	return sessionInstance, found
 }
 
+// GetAll returns all sessions ordered by creation time
+func (storage *StorageMemory) GetAll() []Session {
+	storage.lock.Lock()
+	defer storage.lock.Unlock()
+
+	return storage.sessions()
+}
+
+func (storage *StorageMemory) sessions() []Session {
+	sessions := make([]Session, 0, len(storage.sessionMap))
+	for _, sessionInstance := range storage.sessionMap {
+		sessions = append(sessions, sessionInstance)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		if sessions[i].CreatedAt.Equal(sessions[j].CreatedAt) {
+			return sessions[i].ID < sessions[j].ID
+		}
+		return sessions[i].CreatedAt.Before(sessions[j].CreatedAt)
+	})
+	return sessions
+}
+
+// MarkConnected marks session as used by consumer's connection, so it is not expired while idle
+func (storage *StorageMemory) MarkConnected(id ID) {
+	storage.lock.Lock()
+	defer storage.lock.Unlock()
+
+	if sessionInstance, found := storage.sessionMap[id]; found {
+		sessionInstance.Connected = true
+		storage.sessionMap[id] = sessionInstance
+	}
+}
+
+// AddDataTransfer adds traffic to the amount transferred during the session
+func (storage *StorageMemory) AddDataTransfer(id ID, dataTransfer DataTransfer) {
+	storage.lock.Lock()
+	defer storage.lock.Unlock()
+
+	if sessionInstance, found := storage.sessionMap[id]; found {
+		sessionInstance.DataTransfer.Up += dataTransfer.Up
+		sessionInstance.DataTransfer.Down += dataTransfer.Down
+		storage.sessionMap[id] = sessionInstance
+	}
+}
+
 // Remove removes given session from underlying storage
 func (storage *StorageMemory) Remove(id ID) {
 	storage.lock.Lock()
 	defer storage.lock.Unlock()
 
+	storage.remove(id)
+}
+
+// RemoveConsumer removes all sessions of given consumer
+func (storage *StorageMemory) RemoveConsumer(consumerID identity.Identity) []ID {
+	storage.lock.Lock()
+	defer storage.lock.Unlock()
+
+	removed := make([]ID, 0)
+	for id, sessionInstance := range storage.sessionMap {
+		if identity.FromAddress(sessionInstance.ConsumerID.Address) == identity.FromAddress(consumerID.Address) {
+			storage.remove(id)
+			removed = append(removed, id)
+		}
+	}
+	return removed
+}
+
+// RemoveIdle removes sessions, which were not connected within given time to live
+func (storage *StorageMemory) RemoveIdle(ttl time.Duration) []ID {
+	storage.lock.Lock()
+	defer storage.lock.Unlock()
+
+	expired := make([]ID, 0)
+	deadline := storage.timeGetter().Add(-ttl)
+	for id, sessionInstance := range storage.sessionMap {
+		if !sessionInstance.Connected && sessionInstance.CreatedAt.Before(deadline) {
+			storage.remove(id)
+			expired = append(expired, id)
+		}
+	}
+	return expired
+}
+
+// ExpireIdle periodically removes sessions, which were not connected within given time to live.
+// Returned function stops the expiration.
+func (storage *StorageMemory) ExpireIdle(ttl, checkInterval time.Duration) (stop func()) {
+	stopChannel := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-stopChannel:
+				return
+			case <-time.After(checkInterval):
+				for _, id := range storage.RemoveIdle(ttl) {
+					log.Info(storageLogPrefix, "Idle session expired: ", id)
+				}
+			}
+		}
+	}()
+
+	var stopOnce sync.Once
+	return func() {
+		stopOnce.Do(func() { close(stopChannel) })
+	}
+}
+
+func (storage *StorageMemory) remove(id ID) {
+	sessionInstance, found := storage.sessionMap[id]
+	if !found {
+		return
+	}
 	delete(storage.sessionMap, id)
+	if sessionInstance.Done != nil {
+		close(sessionInstance.Done)
+	}
+	storage.eventPublisher.Publish(DestroyedEventTopic, newEvent(sessionInstance))
+}
+
+func newEvent(sessionInstance Session) Event {
+	return Event{ID: sessionInstance.ID, ConsumerID: sessionInstance.ConsumerID.Address}
 }
@@ -19,13 +19,17 @@ package session
 
 import (
 	"testing"
+	"time"
 
+	"github.com/mysteriumnetwork/node/core/events"
+	"github.com/mysteriumnetwork/node/identity"
 	"github.com/stretchr/testify/assert"
 )
 
 var (
 	sessionExisting = Session{
-		ID: ID("mocked-id"),
+		ID:         ID("mocked-id"),
+		ConsumerID: identity.FromAddress("consumer"),
 	}
 )
 
@@ -54,6 +58,58 @@ func TestStorage_Add(t *testing.T) {
 	storage.Add(sessionNew)
 	assert.Len(t, storage.sessionMap, 2)
 	assert.Exactly(t, sessionNew, storage.sessionMap[sessionNew.ID])
+	assert.Equal(
+		t,
+		[]events.Event{{Topic: CreatedEventTopic, Payload: Event{ID: "new-id"}}},
+		storage.eventPublisher.(*events.PublisherFake).Published(),
+	)
+}
+
+func TestStorage_AddIfAdmitted(t *testing.T) {
+	storage := mockStorage(sessionExisting)
+	sessionNew := Session{
+		ID: ID("new-id"),
+	}
+
+	var activeSessions []Session
+	err := storage.AddIfAdmitted(sessionNew, func(sessions []Session) error {
+		activeSessions = sessions
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []Session{sessionExisting}, activeSessions)
+	assert.Exactly(t, sessionNew, storage.sessionMap[sessionNew.ID])
+	assert.Equal(
+		t,
+		[]events.Event{{Topic: CreatedEventTopic, Payload: Event{ID: "new-id"}}},
+		storage.eventPublisher.(*events.PublisherFake).Published(),
+	)
+}
+
+func TestStorage_AddIfAdmitted_Rejected(t *testing.T) {
+	storage := mockStorage(sessionExisting)
+	sessionNew := Session{
+		ID: ID("new-id"),
+	}
+
+	err := storage.AddIfAdmitted(sessionNew, func([]Session) error {
+		return ErrorSessionLimitReached
+	})
+	assert.Exactly(t, ErrorSessionLimitReached, err)
+	assert.Len(t, storage.sessionMap, 1)
+	assert.Empty(t, storage.eventPublisher.(*events.PublisherFake).Published())
+}
+
+func TestStorage_AddDataTransfer(t *testing.T) {
+	storage := mockStorage(sessionExisting)
+
+	storage.AddDataTransfer(sessionExisting.ID, DataTransfer{Up: 10, Down: 20})
+	storage.AddDataTransfer(sessionExisting.ID, DataTransfer{Up: 5, Down: 1})
+	storage.AddDataTransfer(ID("unknown-id"), DataTransfer{Up: 1, Down: 1})
+
+	sessionInstance, _ := storage.Find(sessionExisting.ID)
+	assert.Equal(t, DataTransfer{Up: 15, Down: 21}, sessionInstance.DataTransfer)
+	assert.Len(t, storage.sessionMap, 1)
 }
 
 func TestStorage_Remove(t *testing.T) {
@@ -61,6 +117,80 @@ func TestStorage_Remove(t *testing.T) {
 
 	storage.Remove(sessionExisting.ID)
 	assert.Len(t, storage.sessionMap, 0)
+	assert.Equal(
+		t,
+		[]events.Event{{Topic: DestroyedEventTopic, Payload: Event{ID: "mocked-id", ConsumerID: "consumer"}}},
+		storage.eventPublisher.(*events.PublisherFake).Published(),
+	)
+}
+
+func TestStorage_RemoveUnknown(t *testing.T) {
+	storage := mockStorage(sessionExisting)
+
+	storage.Remove(ID("unknown-id"))
+	assert.Len(t, storage.sessionMap, 1)
+	assert.Len(t, storage.eventPublisher.(*events.PublisherFake).Published(), 0)
+}
+
+func TestStorage_RemoveConsumer(t *testing.T) {
+	storage := mockStorage(sessionExisting)
+	storage.Add(Session{ID: "other-id", ConsumerID: identity.FromAddress("other-consumer")})
+
+	removed := storage.RemoveConsumer(identity.FromAddress("CONSUMER"))
+	assert.Equal(t, []ID{sessionExisting.ID}, removed)
+	assert.Len(t, storage.sessionMap, 1)
+
+	removed = storage.RemoveConsumer(identity.FromAddress("consumer"))
+	assert.Empty(t, removed)
+}
+
+func TestStorage_RemoveClosesDone(t *testing.T) {
+	sessionInstance := sessionExisting
+	sessionInstance.Done = make(chan struct{})
+	storage := mockStorage(sessionInstance)
+
+	storage.Remove(sessionInstance.ID)
+	_, open := <-sessionInstance.Done
+	assert.False(t, open)
+}
+
+func TestStorage_GetAll(t *testing.T) {
+	now := time.Now()
+	storage := mockStorage(Session{ID: "second", CreatedAt: now})
+	storage.Add(Session{ID: "first", CreatedAt: now.Add(-time.Minute)})
+
+	sessions := storage.GetAll()
+	assert.Len(t, sessions, 2)
+	assert.Equal(t, ID("first"), sessions[0].ID)
+	assert.Equal(t, ID("second"), sessions[1].ID)
+}
+
+func TestStorage_RemoveIdle(t *testing.T) {
+	now := time.Now()
+	storage := mockStorage(Session{ID: "fresh", CreatedAt: now})
+	storage.Add(Session{ID: "idle", CreatedAt: now.Add(-time.Hour)})
+	storage.Add(Session{ID: "connected", CreatedAt: now.Add(-time.Hour)})
+	storage.MarkConnected("connected")
+	storage.timeGetter = func() time.Time { return now }
+
+	expired := storage.RemoveIdle(time.Minute)
+	assert.Equal(t, []ID{"idle"}, expired)
+	assert.Len(t, storage.GetAll(), 2)
+}
+
+func TestStorage_ExpireIdle(t *testing.T) {
+	storage := mockStorage(Session{ID: "idle", CreatedAt: time.Now().Add(-time.Hour)})
+
+	stop := storage.ExpireIdle(time.Minute, time.Millisecond)
+	defer stop()
+
+	for i := 0; i < 100; i++ {
+		if _, found := storage.Find("idle"); !found {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	assert.Fail(t, "idle session was not expired")
 }
 
 func mockStorage(sessionInstance Session) *StorageMemory {
@@ -68,5 +198,7 @@ func mockStorage(sessionInstance Session) *StorageMemory {
 		sessionMap: map[ID]Session{
 			sessionInstance.ID: sessionInstance,
 		},
+		eventPublisher: events.NewPublisherFake(),
+		timeGetter:     time.Now,
 	}
 }
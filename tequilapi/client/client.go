@@ -101,17 +101,37 @@ func (client *Client) IdentityRegistrationStatus(address string) (RegistrationDa
 	return status, err
 }
 
-// Connect initiates a new connection to a host identified by providerID
-func (client *Client) Connect(consumerID, providerID string, options endpoints.ConnectOptions) (status StatusDTO, err error) {
+// Connect initiates a new connection to a host identified by providerID, using provider's proposal chosen by selector
+func (client *Client) Connect(consumerID, providerID string, selector ProposalSelector, options endpoints.ConnectOptions) (status StatusDTO, err error) {
 	payload := struct {
-		Identity   string                   `json:"consumerId"`
-		ProviderID string                   `json:"providerId"`
-		Options    endpoints.ConnectOptions `json:"connectOptions"`
+		Identity   string `json:"consumerId"`
+		ProviderID string `json:"providerId"`
+		ProposalSelector
+		Options endpoints.ConnectOptions `json:"connectOptions"`
 	}{
 		consumerID,
 		providerID,
+		selector,
 		options,
 	}
+	return client.createConnection(payload)
+}
+
+// ConnectAuto initiates a new connection to the best reachable provider matching the filter
+func (client *Client) ConnectAuto(consumerID string, filter endpoints.ProviderFilterOptions, options endpoints.ConnectOptions) (status StatusDTO, err error) {
+	payload := struct {
+		Identity string                          `json:"consumerId"`
+		Filter   endpoints.ProviderFilterOptions `json:"providerFilter"`
+		Options  endpoints.ConnectOptions        `json:"connectOptions"`
+	}{
+		consumerID,
+		filter,
+		options,
+	}
+	return client.createConnection(payload)
+}
+
+func (client *Client) createConnection(payload interface{}) (status StatusDTO, err error) {
 	response, err := client.http.Put("connection", payload)
 
 	var errorMessage struct {
@@ -184,7 +204,12 @@ func (client *Client) Healthcheck() (healthcheck HealthcheckDTO, err error) {
 
 // Proposals returns all available proposals for services
 func (client *Client) Proposals() ([]ProposalDTO, error) {
-	response, err := client.http.Get("proposals", url.Values{})
+	return client.FilteredProposals(url.Values{})
+}
+
+// FilteredProposals returns proposals matching filters of the query, sorted and paged as requested by it
+func (client *Client) FilteredProposals(query url.Values) ([]ProposalDTO, error) {
+	response, err := client.http.Get("proposals", query)
 	if err != nil {
 		return []ProposalDTO{}, err
 	}
@@ -21,8 +21,9 @@ import "fmt"
 
 // StatusDTO holds connection status and session id
 type StatusDTO struct {
-	Status    string `json:"status"`
-	SessionID string `json:"sessionId"`
+	Status     string `json:"status"`
+	SessionID  string `json:"sessionId"`
+	ProviderID string `json:"providerId"`
 }
 
 // StatisticsDTO holds statistics about connection
@@ -102,3 +103,9 @@ type SignatureDTO struct {
 	S string `json:"s"`
 	V uint8  `json:"v"`
 }
+
+// ProposalSelector chooses one of provider's proposals to connect to, provider's first proposal is used when it is empty
+type ProposalSelector struct {
+	ProposalID  int    `json:"proposalId,omitempty"`
+	ServiceType string `json:"serviceType,omitempty"`
+}
@@ -0,0 +1,143 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package endpoints
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/mysteriumnetwork/node/core/promise/clearing"
+	"github.com/mysteriumnetwork/node/money"
+	"github.com/mysteriumnetwork/node/tequilapi/utils"
+)
+
+// swagger:model ClearingTransactionsDTO
+type clearingTransactionsDTO struct {
+	Transactions []clearingTransactionDTO `json:"transactions"`
+}
+
+// swagger:model ClearingTransactionDTO
+type clearingTransactionDTO struct {
+	// example: 0x7e9f4c1d4f0e2d1fb4b1e0e1c9c6c5a8f0e3d2c1b0a9f8e7d6c5b4a3f2e1d0c9
+	Hash string `json:"hash"`
+
+	// example: 0x0000000000000000000000000000000000000001
+	IssuerID string `json:"issuerId"`
+
+	// example: 0x0000000000000000000000000000000000000002
+	BenefiterID string `json:"benefiterId"`
+
+	// example: 3
+	SerialNumber int `json:"serialNumber"`
+
+	Amount money.Money `json:"amount"`
+
+	// example: Pending
+	Status string `json:"status"`
+
+	// example: 2018-11-01T10:00:00Z
+	SubmittedAt time.Time `json:"submittedAt"`
+
+	// example: 2018-11-01T10:05:00Z
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type promiseClearer interface {
+	Clear() ([]clearing.Transaction, error)
+	Transactions() ([]clearing.Transaction, error)
+}
+
+type clearingEndpoint struct {
+	clearer promiseClearer
+}
+
+// NewClearingEndpoint creates and returns promise clearing endpoint
+func NewClearingEndpoint(clearer promiseClearer) *clearingEndpoint {
+	return &clearingEndpoint{
+		clearer: clearer,
+	}
+}
+
+// swagger:operation GET /clearing Clearing listClearingTransactions
+// ---
+// summary: Returns promise clearing transactions
+// description: Returns all transactions, which were submitted to the payments contract to clear received promises
+// responses:
+//   200:
+//     description: List of clearing transactions
+//     schema:
+//       "$ref": "#/definitions/ClearingTransactionsDTO"
+//   500:
+//     description: Internal server error
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+func (endpoint *clearingEndpoint) List(resp http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	transactions, err := endpoint.clearer.Transactions()
+	if err != nil {
+		utils.SendError(resp, err, http.StatusInternalServerError)
+		return
+	}
+	utils.WriteAsJSON(clearingTransactionsDTO{Transactions: mapClearingTransactions(transactions)}, resp)
+}
+
+// swagger:operation POST /clearing Clearing clearPromises
+// ---
+// summary: Clears received promises
+// description: Submits the highest not yet cleared promise of every issuer to the payments contract
+// responses:
+//   200:
+//     description: Transactions submitted during this request
+//     schema:
+//       "$ref": "#/definitions/ClearingTransactionsDTO"
+//   500:
+//     description: Internal server error
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+func (endpoint *clearingEndpoint) Clear(resp http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	transactions, err := endpoint.clearer.Clear()
+	if err != nil {
+		utils.SendError(resp, err, http.StatusInternalServerError)
+		return
+	}
+	utils.WriteAsJSON(clearingTransactionsDTO{Transactions: mapClearingTransactions(transactions)}, resp)
+}
+
+// AddRoutesForClearing attaches promise clearing endpoints to router
+func AddRoutesForClearing(router *httprouter.Router, clearer promiseClearer) {
+	clearingEndpoint := NewClearingEndpoint(clearer)
+	router.GET("/clearing", clearingEndpoint.List)
+	router.POST("/clearing", clearingEndpoint.Clear)
+}
+
+func mapClearingTransactions(transactions []clearing.Transaction) []clearingTransactionDTO {
+	dtoArray := make([]clearingTransactionDTO, len(transactions))
+	for i, transaction := range transactions {
+		dtoArray[i] = clearingTransactionDTO{
+			Hash:         transaction.Hash,
+			IssuerID:     transaction.IssuerID,
+			BenefiterID:  transaction.BenefiterID,
+			SerialNumber: transaction.SerialNumber,
+			Amount:       transaction.Amount,
+			Status:       string(transaction.Status),
+			SubmittedAt:  transaction.Submitted.UTC(),
+			UpdatedAt:    transaction.Updated.UTC(),
+		}
+	}
+	return dtoArray
+}
@@ -0,0 +1,101 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package endpoints
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/mysteriumnetwork/node/core/promise/clearing"
+	"github.com/mysteriumnetwork/node/money"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeClearer struct {
+	transactions []clearing.Transaction
+	clearCalled  bool
+	err          error
+}
+
+func (clearer *fakeClearer) Clear() ([]clearing.Transaction, error) {
+	clearer.clearCalled = true
+	return clearer.transactions, clearer.err
+}
+
+func (clearer *fakeClearer) Transactions() ([]clearing.Transaction, error) {
+	return clearer.transactions, clearer.err
+}
+
+var clearingTransactionJSON = `{
+	"transactions": [{
+		"hash": "0x1234",
+		"issuerId": "0x1",
+		"benefiterId": "0x2",
+		"serialNumber": 3,
+		"amount": {"amount": 300, "currency": "MYST"},
+		"status": "Pending",
+		"submittedAt": "2018-11-01T10:00:00Z",
+		"updatedAt": "2018-11-01T10:00:00Z"
+	}]
+}`
+
+func TestAddRoutesForClearingAddsRoutes(t *testing.T) {
+	submitted := time.Date(2018, 11, 1, 10, 0, 0, 0, time.UTC)
+	clearer := &fakeClearer{
+		transactions: []clearing.Transaction{
+			{
+				Hash:         "0x1234",
+				IssuerID:     "0x1",
+				BenefiterID:  "0x2",
+				SerialNumber: 3,
+				Amount:       money.Money{Amount: 300, Currency: money.CURRENCY_MYST},
+				Status:       clearing.TxPending,
+				Submitted:    submitted,
+				Updated:      submitted,
+			},
+		},
+	}
+	router := httprouter.New()
+	AddRoutesForClearing(router, clearer)
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/clearing", nil))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, clearingTransactionJSON, resp.Body.String())
+	assert.False(t, clearer.clearCalled)
+
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/clearing", nil))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, clearingTransactionJSON, resp.Body.String())
+	assert.True(t, clearer.clearCalled)
+}
+
+func TestClearingClearWhenClearerFails(t *testing.T) {
+	endpoint := NewClearingEndpoint(&fakeClearer{err: errors.New("ledger unavailable")})
+	resp := httptest.NewRecorder()
+
+	endpoint.Clear(resp, nil, nil)
+
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	assert.JSONEq(t, `{"message": "ledger unavailable"}`, resp.Body.String())
+}
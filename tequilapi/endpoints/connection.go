@@ -19,7 +19,10 @@ package endpoints
 
 import (
 	"encoding/json"
+	"net"
 	"net/http"
+	"strings"
+	"time"
 
 	log "github.com/cihub/seelog"
 	"github.com/julienschmidt/httprouter"
@@ -35,13 +38,78 @@ import (
 // operations, custom client error code is defined. Maybe in later times a better idea will come how to handle these situations
 const statusConnectCancelled = 499
 
+// minReconnectBackoffSeconds prevents consumer from flooding provider with reconnection attempts
+const minReconnectBackoffSeconds = 1
+
 // ConnectOptions holds tequilapi connect options
 // swagger:model ConnectOptionsDTO
 type ConnectOptions struct {
-	// kill switch option restricting communication only through VPN
+	// kill switch option restricting communication only through VPN, it has to be disabled when split tunnelling is used
 	// required: false
 	// example: true
 	DisableKillSwitch bool `json:"killSwitch"`
+	// automatic reconnection policy, reconnection is disabled if not given
+	// required: false
+	Reconnect ReconnectOptions `json:"reconnect"`
+	// split tunnelling, all traffic is routed through VPN if not given. Kill switch has to be disabled to use it
+	// required: false
+	SplitTunnel SplitTunnelOptions `json:"splitTunnel"`
+}
+
+// SplitTunnelOptions holds tequilapi split tunnelling options
+// swagger:model SplitTunnelOptionsDTO
+type SplitTunnelOptions struct {
+	// the only IPv4 networks routed through VPN
+	// required: false
+	// example: ["10.0.0.0/8"]
+	IncludeCIDRs []string `json:"includeCidrs"`
+	// IPv4 networks bypassing VPN
+	// required: false
+	// example: ["192.168.0.0/16"]
+	ExcludeCIDRs []string `json:"excludeCidrs"`
+	// the only domains routed through VPN, their addresses are resolved periodically
+	// required: false
+	// example: ["example.com"]
+	IncludeDomains []string `json:"includeDomains"`
+	// domains bypassing VPN, their addresses are resolved periodically
+	// required: false
+	// example: ["intranet.example.com"]
+	ExcludeDomains []string `json:"excludeDomains"`
+}
+
+// ProviderFilterOptions holds tequilapi options of automatic provider selection
+// swagger:model ProviderFilterDTO
+type ProviderFilterOptions struct {
+	// country code of provider location
+	// required: false
+	// example: NL
+	Country string `json:"country"`
+	// type of service
+	// required: false
+	// example: openvpn
+	ServiceType string `json:"serviceType"`
+	// transport protocol of service
+	// required: false
+	// example: udp
+	Protocol string `json:"protocol"`
+}
+
+// ReconnectOptions holds tequilapi automatic reconnection options
+// swagger:model ReconnectOptionsDTO
+type ReconnectOptions struct {
+	// number of reconnection attempts after connection drops, 0 disables reconnection
+	// required: false
+	// example: 3
+	MaxAttempts int `json:"maxAttempts"`
+	// delay in seconds before first reconnection attempt, it doubles after each failed attempt up to 5 minutes.
+	// At least 1 second is required, when reconnection is enabled
+	// required: false
+	// example: 2
+	BackoffSeconds int `json:"backoffSeconds"`
+	// request new session from provider instead of reusing the dropped one
+	// required: false
+	// example: true
+	NewSession bool `json:"newSession"`
 }
 
 // swagger:model ConnectionRequestDTO
@@ -51,11 +119,25 @@ type connectionRequest struct {
 	// example: 0x0000000000000000000000000000000000000001
 	ConsumerID string `json:"consumerId"`
 
-	// provider identity
-	// required: true
+	// provider identity, required unless provider filter is given
+	// required: false
 	// example: 0x0000000000000000000000000000000000000002
 	ProviderID string `json:"providerId"`
 
+	// proposal of the provider to connect to, provider's first proposal matching service type is used if not given
+	// required: false
+	// example: 1
+	ProposalID int `json:"proposalId,omitempty"`
+
+	// service type of provider's proposal to connect to, any service type is accepted if not given
+	// required: false
+	// example: openvpn
+	ServiceType string `json:"serviceType,omitempty"`
+
+	// filter of providers, the best reachable provider matching it is connected to when provider identity is not given
+	// required: false
+	ProviderFilter *ProviderFilterOptions `json:"providerFilter,omitempty"`
+
 	// connect options
 	// required: false
 	ConnectOptions ConnectOptions `json:"connectOptions,omitempty"`
@@ -68,6 +150,14 @@ type statusResponse struct {
 
 	// example: 4cfb0324-daf6-4ad8-448b-e61fe0a1f918
 	SessionID string `json:"sessionId,omitempty"`
+
+	// provider selected automatically, present only in response to connection request with provider filter
+	// example: 0x0000000000000000000000000000000000000002
+	ProviderID string `json:"providerId,omitempty"`
+
+	// resolvers of the system while connected, absent when DNS of the system is not taken over
+	// example: ["208.67.222.222","208.67.220.220"]
+	DNS []string `json:"dns,omitempty"`
 }
 
 // swagger:model IPDTO
@@ -90,21 +180,28 @@ type statisticsResponse struct {
 	Duration int `json:"duration"`
 }
 
+// AutoConnector connects to the best provider matching the filter
+type AutoConnector interface {
+	Connect(consumerID identity.Identity, filter connection.ProposalFilter, params connection.ConnectParams) (identity.Identity, error)
+}
+
 // ConnectionEndpoint struct represents /connection resource and it's subresources
 type ConnectionEndpoint struct {
-	manager     connection.Manager
-	ipResolver  ip.Resolver
-	statsKeeper stats.SessionStatsKeeper
+	manager       connection.Manager
+	ipResolver    ip.Resolver
+	statsKeeper   stats.SessionStatsKeeper
+	autoConnector AutoConnector
 }
 
 const connectionLogPrefix = "[Connection] "
 
 // NewConnectionEndpoint creates and returns connection endpoint
-func NewConnectionEndpoint(manager connection.Manager, ipResolver ip.Resolver, statsKeeper stats.SessionStatsKeeper) *ConnectionEndpoint {
+func NewConnectionEndpoint(manager connection.Manager, ipResolver ip.Resolver, statsKeeper stats.SessionStatsKeeper, autoConnector AutoConnector) *ConnectionEndpoint {
 	return &ConnectionEndpoint{
-		manager:     manager,
-		ipResolver:  ipResolver,
-		statsKeeper: statsKeeper,
+		manager:       manager,
+		ipResolver:    ipResolver,
+		statsKeeper:   statsKeeper,
+		autoConnector: autoConnector,
 	}
 }
 
@@ -131,11 +228,11 @@ func (ce *ConnectionEndpoint) Status(resp http.ResponseWriter, _ *http.Request,
 // swagger:operation PUT /connection Connection createConnection
 // ---
 // summary: Starts new connection
-// description: Consumer opens connection to provider
+// description: Consumer opens connection to provider. When provider filter is given instead of provider, reachable providers matching it are probed and the best one is connected to
 // parameters:
 //   - in: body
 //     name: body
-//     description: Parameters in body (consumerId, providerId) required for creating new connection
+//     description: Parameters in body (consumerId, providerId or providerFilter) required for creating new connection
 //     schema:
 //       $ref: "#/definitions/ConnectionRequestDTO"
 // responses:
@@ -147,6 +244,10 @@ func (ce *ConnectionEndpoint) Status(resp http.ResponseWriter, _ *http.Request,
 //     description: Bad request
 //     schema:
 //       "$ref": "#/definitions/ErrorMessageDTO"
+//   404:
+//     description: No reachable provider matches the filter
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
 //   409:
 //     description: Conflict. Connection already exists
 //     schema:
@@ -177,22 +278,43 @@ func (ce *ConnectionEndpoint) Create(resp http.ResponseWriter, req *http.Request
 	}
 
 	connectOptions := getConnectOptions(cr)
-	err = ce.manager.Connect(identity.FromAddress(cr.ConsumerID), identity.FromAddress(cr.ProviderID), connectOptions)
+	consumerID := identity.FromAddress(cr.ConsumerID)
+	if cr.ProviderID != "" {
+		err = ce.manager.Connect(consumerID, identity.FromAddress(cr.ProviderID), connectOptions)
+		if err != nil {
+			sendConnectError(resp, err)
+			return
+		}
+		resp.WriteHeader(http.StatusCreated)
+		ce.Status(resp, req, params)
+		return
+	}
 
+	providerID, err := ce.autoConnector.Connect(consumerID, getProposalFilter(cr.ProviderFilter), connectOptions)
 	if err != nil {
-		switch err {
-		case connection.ErrAlreadyExists:
-			utils.SendError(resp, err, http.StatusConflict)
-		case connection.ErrConnectionCancelled:
-			utils.SendError(resp, err, statusConnectCancelled)
-		default:
-			log.Error(connectionLogPrefix, err)
-			utils.SendError(resp, err, http.StatusInternalServerError)
-		}
+		sendConnectError(resp, err)
 		return
 	}
+	statusResponse := toStatusResponse(ce.manager.Status())
+	statusResponse.ProviderID = providerID.Address
 	resp.WriteHeader(http.StatusCreated)
-	ce.Status(resp, req, params)
+	utils.WriteAsJSON(statusResponse, resp)
+}
+
+func sendConnectError(resp http.ResponseWriter, err error) {
+	switch err {
+	case connection.ErrAlreadyExists:
+		utils.SendError(resp, err, http.StatusConflict)
+	case connection.ErrKillSwitchWithSplitTunnel:
+		utils.SendError(resp, err, http.StatusBadRequest)
+	case connection.ErrConnectionCancelled:
+		utils.SendError(resp, err, statusConnectCancelled)
+	case connection.ErrNoProviders:
+		utils.SendError(resp, err, http.StatusNotFound)
+	default:
+		log.Error(connectionLogPrefix, err)
+		utils.SendError(resp, err, http.StatusInternalServerError)
+	}
 }
 
 // Kill stops connection
@@ -287,8 +409,8 @@ func (ce *ConnectionEndpoint) GetStatistics(writer http.ResponseWriter, request
 
 // AddRoutesForConnection adds connections routes to given router
 func AddRoutesForConnection(router *httprouter.Router, manager connection.Manager, ipResolver ip.Resolver,
-	statsKeeper stats.SessionStatsKeeper) {
-	connectionEndpoint := NewConnectionEndpoint(manager, ipResolver, statsKeeper)
+	statsKeeper stats.SessionStatsKeeper, autoConnector AutoConnector) {
+	connectionEndpoint := NewConnectionEndpoint(manager, ipResolver, statsKeeper, autoConnector)
 	router.GET("/connection", connectionEndpoint.Status)
 	router.PUT("/connection", connectionEndpoint.Create)
 	router.DELETE("/connection", connectionEndpoint.Kill)
@@ -306,7 +428,32 @@ func toConnectionRequest(req *http.Request) (*connectionRequest, error) {
 }
 
 func getConnectOptions(cr *connectionRequest) connection.ConnectParams {
-	return connection.ConnectParams{DisableKillSwitch: cr.ConnectOptions.DisableKillSwitch}
+	reconnect := cr.ConnectOptions.Reconnect
+	splitTunnel := cr.ConnectOptions.SplitTunnel
+	return connection.ConnectParams{
+		ProposalID:        cr.ProposalID,
+		ServiceType:       cr.ServiceType,
+		DisableKillSwitch: cr.ConnectOptions.DisableKillSwitch,
+		Reconnect: connection.ReconnectParams{
+			MaxAttempts: reconnect.MaxAttempts,
+			Backoff:     time.Duration(reconnect.BackoffSeconds) * time.Second,
+			NewSession:  reconnect.NewSession,
+		},
+		SplitTunnel: connection.SplitTunnelParams{
+			IncludeCIDRs:   splitTunnel.IncludeCIDRs,
+			ExcludeCIDRs:   splitTunnel.ExcludeCIDRs,
+			IncludeDomains: splitTunnel.IncludeDomains,
+			ExcludeDomains: splitTunnel.ExcludeDomains,
+		},
+	}
+}
+
+func getProposalFilter(filter *ProviderFilterOptions) connection.ProposalFilter {
+	return connection.ProposalFilter{
+		Country:     filter.Country,
+		ServiceType: filter.ServiceType,
+		Protocol:    filter.Protocol,
+	}
 }
 
 func validateConnectionRequest(cr *connectionRequest) *validation.FieldErrorMap {
@@ -314,15 +461,58 @@ func validateConnectionRequest(cr *connectionRequest) *validation.FieldErrorMap
 	if len(cr.ConsumerID) == 0 {
 		errors.ForField("consumerId").AddError("required", "Field is required")
 	}
-	if len(cr.ProviderID) == 0 {
+	if len(cr.ProviderID) == 0 && cr.ProviderFilter == nil {
 		errors.ForField("providerId").AddError("required", "Field is required")
 	}
+	if len(cr.ProviderID) != 0 && cr.ProviderFilter != nil {
+		errors.ForField("providerFilter").AddError("invalid", "Cannot be combined with providerId")
+	}
+	if cr.ProposalID < 0 {
+		errors.ForField("proposalId").AddError("invalid", "Cannot be negative")
+	}
+	if cr.ProviderFilter != nil && cr.ProposalID != 0 {
+		errors.ForField("proposalId").AddError("invalid", "Cannot be combined with providerFilter")
+	}
+	if cr.ProviderFilter != nil && cr.ServiceType != "" {
+		errors.ForField("serviceType").AddError("invalid", "Cannot be combined with providerFilter, use its serviceType")
+	}
+	if cr.ConnectOptions.Reconnect.MaxAttempts < 0 {
+		errors.ForField("connectOptions.reconnect.maxAttempts").AddError("invalid", "Cannot be negative")
+	}
+	if cr.ConnectOptions.Reconnect.BackoffSeconds < 0 {
+		errors.ForField("connectOptions.reconnect.backoffSeconds").AddError("invalid", "Cannot be negative")
+	} else if cr.ConnectOptions.Reconnect.MaxAttempts > 0 && cr.ConnectOptions.Reconnect.BackoffSeconds < minReconnectBackoffSeconds {
+		errors.ForField("connectOptions.reconnect.backoffSeconds").AddError("invalid", "Must be at least 1 second, when reconnection is enabled")
+	}
+	splitTunnel := cr.ConnectOptions.SplitTunnel
+	validateCIDRs(errors, "connectOptions.splitTunnel.includeCidrs", splitTunnel.IncludeCIDRs)
+	validateCIDRs(errors, "connectOptions.splitTunnel.excludeCidrs", splitTunnel.ExcludeCIDRs)
+	validateDomains(errors, "connectOptions.splitTunnel.includeDomains", splitTunnel.IncludeDomains)
+	validateDomains(errors, "connectOptions.splitTunnel.excludeDomains", splitTunnel.ExcludeDomains)
 	return errors
 }
 
+func validateCIDRs(errors *validation.FieldErrorMap, field string, cidrs []string) {
+	for _, cidr := range cidrs {
+		ip, _, err := net.ParseCIDR(cidr)
+		if err != nil || ip.To4() == nil {
+			errors.ForField(field).AddError("invalid", "IPv4 network is expected: "+cidr)
+		}
+	}
+}
+
+func validateDomains(errors *validation.FieldErrorMap, field string, domains []string) {
+	for _, domain := range domains {
+		if len(strings.TrimSpace(domain)) == 0 || strings.ContainsAny(domain, " /:") {
+			errors.ForField(field).AddError("invalid", "Domain name is expected: "+domain)
+		}
+	}
+}
+
 func toStatusResponse(status connection.ConnectionStatus) statusResponse {
 	return statusResponse{
 		Status:    string(status.State),
 		SessionID: string(status.SessionID),
+		DNS:       status.DNS,
 	}
 }
@@ -41,11 +41,13 @@ type fakeManager struct {
 	disconnectCount    int
 	requestedConsumer  identity.Identity
 	requestedProvider  identity.Identity
+	requestedParams    connection.ConnectParams
 }
 
 func (fm *fakeManager) Connect(consumerID identity.Identity, providerID identity.Identity, options connection.ConnectParams) error {
 	fm.requestedConsumer = consumerID
 	fm.requestedProvider = providerID
+	fm.requestedParams = options
 	return fm.onConnectReturn
 }
 
@@ -74,7 +76,7 @@ func TestAddRoutesForConnectionAddsRoutes(t *testing.T) {
 	statsKeeper.MarkSessionStart()
 	settableClock.SetTime(sessionStart.Add(time.Minute))
 
-	AddRoutesForConnection(router, &fakeManager, ipResolver, statsKeeper)
+	AddRoutesForConnection(router, &fakeManager, ipResolver, statsKeeper, nil)
 
 	tests := []struct {
 		method         string
@@ -129,7 +131,7 @@ func TestDisconnectingState(t *testing.T) {
 		SessionID: "",
 	}
 
-	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil)
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, nil)
 	req := httptest.NewRequest(http.MethodGet, "/irrelevant", nil)
 	resp := httptest.NewRecorder()
 
@@ -151,7 +153,7 @@ func TestNotConnectedStateIsReturnedWhenNoConnection(t *testing.T) {
 		SessionID: "",
 	}
 
-	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil)
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, nil)
 	req := httptest.NewRequest(http.MethodGet, "/irrelevant", nil)
 	resp := httptest.NewRecorder()
 
@@ -173,7 +175,7 @@ func TestStateConnectingIsReturnedWhenIsConnectionInProgress(t *testing.T) {
 		State: connection.Connecting,
 	}
 
-	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil)
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, nil)
 	req := httptest.NewRequest(http.MethodGet, "/irrelevant", nil)
 	resp := httptest.NewRecorder()
 
@@ -196,7 +198,7 @@ func TestConnectedStateAndSessionIdIsReturnedWhenIsConnected(t *testing.T) {
 		SessionID: "My-super-session",
 	}
 
-	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil)
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, nil)
 	req := httptest.NewRequest(http.MethodGet, "/irrelevant", nil)
 	resp := httptest.NewRecorder()
 
@@ -213,10 +215,35 @@ func TestConnectedStateAndSessionIdIsReturnedWhenIsConnected(t *testing.T) {
 
 }
 
+func TestConnectedStatusReportsDNS(t *testing.T) {
+	var fakeManager = fakeManager{}
+	fakeManager.onStatusReturn = connection.ConnectionStatus{
+		State:     connection.Connected,
+		SessionID: "My-super-session",
+		DNS:       []string{"10.8.0.1"},
+	}
+
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, nil)
+	req := httptest.NewRequest(http.MethodGet, "/irrelevant", nil)
+	resp := httptest.NewRecorder()
+
+	connEndpoint.Status(resp, req, httprouter.Params{})
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(
+		t,
+		`{
+			"status" : "Connected",
+			"sessionId" : "My-super-session",
+			"dns" : [ "10.8.0.1" ]
+		}`,
+		resp.Body.String())
+}
+
 func TestPutReturns400ErrorIfRequestBodyIsNotJSON(t *testing.T) {
 	fakeManager := fakeManager{}
 
-	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil)
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, nil)
 	req := httptest.NewRequest(http.MethodPut, "/irrelevant", strings.NewReader("a"))
 	resp := httptest.NewRecorder()
 
@@ -235,7 +262,7 @@ func TestPutReturns400ErrorIfRequestBodyIsNotJSON(t *testing.T) {
 func TestPutReturns422ErrorIfRequestBodyIsMissingFieldValues(t *testing.T) {
 	fakeManager := fakeManager{}
 
-	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil)
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, nil)
 	req := httptest.NewRequest(http.MethodPut, "/irrelevant", strings.NewReader("{}"))
 	resp := httptest.NewRecorder()
 
@@ -257,7 +284,7 @@ func TestPutReturns422ErrorIfRequestBodyIsMissingFieldValues(t *testing.T) {
 func TestPutWithValidBodyCreatesConnection(t *testing.T) {
 	fakeManager := fakeManager{}
 
-	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil)
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, nil)
 	req := httptest.NewRequest(
 		http.MethodPut,
 		"/irrelevant",
@@ -277,10 +304,169 @@ func TestPutWithValidBodyCreatesConnection(t *testing.T) {
 
 }
 
+func TestPutPassesReconnectOptionsToManager(t *testing.T) {
+	fakeManager := fakeManager{}
+
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, nil)
+	req := httptest.NewRequest(
+		http.MethodPut,
+		"/irrelevant",
+		strings.NewReader(
+			`{
+				"consumerId" : "my-identity",
+				"providerId" : "required-node",
+				"connectOptions" : {
+					"reconnect" : { "maxAttempts" : 3, "backoffSeconds" : 2, "newSession" : true }
+				}
+			}`))
+	resp := httptest.NewRecorder()
+
+	connEndpoint.Create(resp, req, httprouter.Params{})
+
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	assert.Equal(
+		t,
+		connection.ReconnectParams{MaxAttempts: 3, Backoff: 2 * time.Second, NewSession: true},
+		fakeManager.requestedParams.Reconnect,
+	)
+}
+
+func TestPutReturns422ErrorIfReconnectOptionsAreNegative(t *testing.T) {
+	fakeManager := fakeManager{}
+
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, nil)
+	req := httptest.NewRequest(
+		http.MethodPut,
+		"/irrelevant",
+		strings.NewReader(
+			`{
+				"consumerId" : "my-identity",
+				"providerId" : "required-node",
+				"connectOptions" : {
+					"reconnect" : { "maxAttempts" : -1, "backoffSeconds" : -2 }
+				}
+			}`))
+	resp := httptest.NewRecorder()
+
+	connEndpoint.Create(resp, req, httprouter.Params{})
+
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	assert.JSONEq(
+		t,
+		`{
+			"message" : "validation_error",
+			"errors" : {
+				"connectOptions.reconnect.maxAttempts" : [ { "code" : "invalid" , "message" : "Cannot be negative" } ],
+				"connectOptions.reconnect.backoffSeconds" : [ { "code" : "invalid" , "message" : "Cannot be negative" } ]
+			}
+		}`, resp.Body.String())
+}
+
+func TestPutReturns422ErrorIfReconnectBackoffIsTooShort(t *testing.T) {
+	fakeManager := fakeManager{}
+
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, nil)
+	req := httptest.NewRequest(
+		http.MethodPut,
+		"/irrelevant",
+		strings.NewReader(
+			`{
+				"consumerId" : "my-identity",
+				"providerId" : "required-node",
+				"connectOptions" : {
+					"reconnect" : { "maxAttempts" : 3, "backoffSeconds" : 0 }
+				}
+			}`))
+	resp := httptest.NewRecorder()
+
+	connEndpoint.Create(resp, req, httprouter.Params{})
+
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	assert.JSONEq(
+		t,
+		`{
+			"message" : "validation_error",
+			"errors" : {
+				"connectOptions.reconnect.backoffSeconds" : [ { "code" : "invalid" , "message" : "Must be at least 1 second, when reconnection is enabled" } ]
+			}
+		}`, resp.Body.String())
+}
+
+func TestPutPassesSplitTunnelOptionsToManager(t *testing.T) {
+	fakeManager := fakeManager{}
+
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, nil)
+	req := httptest.NewRequest(
+		http.MethodPut,
+		"/irrelevant",
+		strings.NewReader(
+			`{
+				"consumerId" : "my-identity",
+				"providerId" : "required-node",
+				"connectOptions" : {
+					"splitTunnel" : {
+						"includeCidrs" : [ "10.0.0.0/8" ],
+						"excludeCidrs" : [ "10.1.0.0/16" ],
+						"includeDomains" : [ "example.com" ],
+						"excludeDomains" : [ "intranet.example.com" ]
+					}
+				}
+			}`))
+	resp := httptest.NewRecorder()
+
+	connEndpoint.Create(resp, req, httprouter.Params{})
+
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	assert.Equal(
+		t,
+		connection.SplitTunnelParams{
+			IncludeCIDRs:   []string{"10.0.0.0/8"},
+			ExcludeCIDRs:   []string{"10.1.0.0/16"},
+			IncludeDomains: []string{"example.com"},
+			ExcludeDomains: []string{"intranet.example.com"},
+		},
+		fakeManager.requestedParams.SplitTunnel,
+	)
+}
+
+func TestPutReturns422ErrorIfSplitTunnelOptionsAreInvalid(t *testing.T) {
+	fakeManager := fakeManager{}
+
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, nil)
+	req := httptest.NewRequest(
+		http.MethodPut,
+		"/irrelevant",
+		strings.NewReader(
+			`{
+				"consumerId" : "my-identity",
+				"providerId" : "required-node",
+				"connectOptions" : {
+					"splitTunnel" : {
+						"includeCidrs" : [ "10.0.0.0" ],
+						"excludeDomains" : [ "http://example.com" ]
+					}
+				}
+			}`))
+	resp := httptest.NewRecorder()
+
+	connEndpoint.Create(resp, req, httprouter.Params{})
+
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	assert.JSONEq(
+		t,
+		`{
+			"message" : "validation_error",
+			"errors" : {
+				"connectOptions.splitTunnel.includeCidrs" : [ { "code" : "invalid" , "message" : "IPv4 network is expected: 10.0.0.0" } ],
+				"connectOptions.splitTunnel.excludeDomains" : [ { "code" : "invalid" , "message" : "Domain name is expected: http://example.com" } ]
+			}
+		}`, resp.Body.String())
+}
+
 func TestDeleteCallsDisconnect(t *testing.T) {
 	fakeManager := fakeManager{}
 
-	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil)
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, nil)
 	req := httptest.NewRequest(http.MethodDelete, "/irrelevant", nil)
 	resp := httptest.NewRecorder()
 
@@ -294,7 +480,7 @@ func TestDeleteCallsDisconnect(t *testing.T) {
 func TestGetIPEndpointSucceeds(t *testing.T) {
 	manager := fakeManager{}
 	ipResolver := ip.NewResolverFake("123.123.123.123")
-	connEndpoint := NewConnectionEndpoint(&manager, ipResolver, nil)
+	connEndpoint := NewConnectionEndpoint(&manager, ipResolver, nil, nil)
 	resp := httptest.NewRecorder()
 
 	connEndpoint.GetIP(resp, nil, nil)
@@ -312,7 +498,7 @@ func TestGetIPEndpointSucceeds(t *testing.T) {
 func TestGetIPEndpointReturnsErrorWhenIPDetectionFails(t *testing.T) {
 	manager := fakeManager{}
 	ipResolver := ip.NewResolverFakeFailing(errors.New("fake error"))
-	connEndpoint := NewConnectionEndpoint(&manager, ipResolver, nil)
+	connEndpoint := NewConnectionEndpoint(&manager, ipResolver, nil, nil)
 	resp := httptest.NewRecorder()
 
 	connEndpoint.GetIP(resp, nil, nil)
@@ -339,7 +525,7 @@ func TestGetStatisticsEndpointReturnsStatistics(t *testing.T) {
 	settableClock.SetTime(sessionStart.Add(time.Minute))
 
 	manager := fakeManager{}
-	connEndpoint := NewConnectionEndpoint(&manager, nil, statsKeeper)
+	connEndpoint := NewConnectionEndpoint(&manager, nil, statsKeeper, nil)
 
 	resp := httptest.NewRecorder()
 	connEndpoint.GetStatistics(resp, nil, nil)
@@ -361,7 +547,7 @@ func TestGetStatisticsEndpointReturnsStatisticsWhenSessionIsNotStarted(t *testin
 	statsKeeper.Save(st)
 
 	manager := fakeManager{}
-	connEndpoint := NewConnectionEndpoint(&manager, nil, statsKeeper)
+	connEndpoint := NewConnectionEndpoint(&manager, nil, statsKeeper, nil)
 
 	resp := httptest.NewRecorder()
 	connEndpoint.GetStatistics(resp, nil, nil)
@@ -380,7 +566,7 @@ func TestEndpointReturnsConflictStatusIfConnectionAlreadyExists(t *testing.T) {
 	manager := fakeManager{}
 	manager.onConnectReturn = connection.ErrAlreadyExists
 
-	connectionEndpoint := NewConnectionEndpoint(&manager, nil, nil)
+	connectionEndpoint := NewConnectionEndpoint(&manager, nil, nil, nil)
 
 	req := httptest.NewRequest(
 		http.MethodPut,
@@ -404,11 +590,39 @@ func TestEndpointReturnsConflictStatusIfConnectionAlreadyExists(t *testing.T) {
 	)
 }
 
+func TestConnectReturnsBadRequestWhenKillSwitchIsCombinedWithSplitTunnel(t *testing.T) {
+	manager := fakeManager{}
+	manager.onConnectReturn = connection.ErrKillSwitchWithSplitTunnel
+
+	connectionEndpoint := NewConnectionEndpoint(&manager, nil, nil, nil)
+	req := httptest.NewRequest(
+		http.MethodPut,
+		"/irrelevant",
+		strings.NewReader(
+			`{
+				"consumerId" : "my-identity",
+				"providerId" : "required-node",
+				"connectOptions": {"splitTunnel": {"excludeCidrs": ["192.168.0.0/16"]}}
+			}`))
+	resp := httptest.NewRecorder()
+
+	connectionEndpoint.Create(resp, req, nil)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.JSONEq(
+		t,
+		`{
+			"message" : "kill switch can not be combined with split tunnelling"
+		}`,
+		resp.Body.String(),
+	)
+}
+
 func TestDisconnectReturnsConflictStatusIfConnectionDoesNotExist(t *testing.T) {
 	manager := fakeManager{}
 	manager.onDisconnectReturn = connection.ErrNoConnection
 
-	connectionEndpoint := NewConnectionEndpoint(&manager, nil, nil)
+	connectionEndpoint := NewConnectionEndpoint(&manager, nil, nil, nil)
 
 	req := httptest.NewRequest(
 		http.MethodDelete,
@@ -433,7 +647,7 @@ func TestConnectReturnsConnectCancelledStatusWhenErrConnectionCancelledIsEncount
 	manager := fakeManager{}
 	manager.onConnectReturn = connection.ErrConnectionCancelled
 
-	connectionEndpoint := NewConnectionEndpoint(&manager, nil, nil)
+	connectionEndpoint := NewConnectionEndpoint(&manager, nil, nil, nil)
 	req := httptest.NewRequest(
 		http.MethodPut,
 		"/irrelevant",
@@ -455,3 +669,158 @@ func TestConnectReturnsConnectCancelledStatusWhenErrConnectionCancelledIsEncount
 		resp.Body.String(),
 	)
 }
+
+type fakeAutoConnector struct {
+	onConnectProvider identity.Identity
+	onConnectReturn   error
+	requestedConsumer identity.Identity
+	requestedFilter   connection.ProposalFilter
+	requestedParams   connection.ConnectParams
+}
+
+func (connector *fakeAutoConnector) Connect(consumerID identity.Identity, filter connection.ProposalFilter, params connection.ConnectParams) (identity.Identity, error) {
+	connector.requestedConsumer = consumerID
+	connector.requestedFilter = filter
+	connector.requestedParams = params
+	return connector.onConnectProvider, connector.onConnectReturn
+}
+
+func TestPutWithProviderFilterConnectsToSelectedProvider(t *testing.T) {
+	manager := fakeManager{onStatusReturn: connection.ConnectionStatus{State: connection.Connected, SessionID: "my-session"}}
+	autoConnector := fakeAutoConnector{onConnectProvider: identity.FromAddress("best-node")}
+
+	connectionEndpoint := NewConnectionEndpoint(&manager, nil, nil, &autoConnector)
+	req := httptest.NewRequest(
+		http.MethodPut,
+		"/irrelevant",
+		strings.NewReader(
+			`{
+				"consumerId" : "my-identity",
+				"providerFilter" : { "country" : "NL", "serviceType" : "openvpn", "protocol" : "udp" },
+				"connectOptions" : { "killSwitch" : true }
+			}`))
+	resp := httptest.NewRecorder()
+
+	connectionEndpoint.Create(resp, req, nil)
+
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	assert.Equal(t, identity.FromAddress("my-identity"), autoConnector.requestedConsumer)
+	assert.Equal(t, connection.ProposalFilter{Country: "NL", ServiceType: "openvpn", Protocol: "udp"}, autoConnector.requestedFilter)
+	assert.True(t, autoConnector.requestedParams.DisableKillSwitch)
+	assert.JSONEq(
+		t,
+		`{
+			"status" : "Connected",
+			"sessionId" : "my-session",
+			"providerId" : "best-node"
+		}`,
+		resp.Body.String(),
+	)
+}
+
+func TestPutWithProviderFilterReturnsNotFoundWhenNoProviderIsReachable(t *testing.T) {
+	autoConnector := fakeAutoConnector{onConnectReturn: connection.ErrNoProviders}
+
+	connectionEndpoint := NewConnectionEndpoint(&fakeManager{}, nil, nil, &autoConnector)
+	req := httptest.NewRequest(
+		http.MethodPut,
+		"/irrelevant",
+		strings.NewReader(
+			`{
+				"consumerId" : "my-identity",
+				"providerFilter" : {}
+			}`))
+	resp := httptest.NewRecorder()
+
+	connectionEndpoint.Create(resp, req, nil)
+
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	assert.JSONEq(
+		t,
+		`{
+			"message" : "no reachable provider matches the filter"
+		}`,
+		resp.Body.String(),
+	)
+}
+
+func TestPutWithProviderAndProviderFilterReturnsValidationError(t *testing.T) {
+	connectionEndpoint := NewConnectionEndpoint(&fakeManager{}, nil, nil, &fakeAutoConnector{})
+	req := httptest.NewRequest(
+		http.MethodPut,
+		"/irrelevant",
+		strings.NewReader(
+			`{
+				"consumerId" : "my-identity",
+				"providerId" : "required-node",
+				"providerFilter" : { "country" : "NL" }
+			}`))
+	resp := httptest.NewRecorder()
+
+	connectionEndpoint.Create(resp, req, nil)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	assert.JSONEq(
+		t,
+		`{
+			"message" : "validation_error",
+			"errors" : {
+				"providerFilter" : [ { "code" : "invalid" , "message" : "Cannot be combined with providerId" } ]
+			}
+		}`,
+		resp.Body.String(),
+	)
+}
+
+func TestPutPassesSelectedProposalToManager(t *testing.T) {
+	fakeManager := fakeManager{}
+
+	connEndpoint := NewConnectionEndpoint(&fakeManager, nil, nil, nil)
+	req := httptest.NewRequest(
+		http.MethodPut,
+		"/irrelevant",
+		strings.NewReader(
+			`{
+				"consumerId" : "my-identity",
+				"providerId" : "required-node",
+				"proposalId" : 2,
+				"serviceType" : "openvpn"
+			}`))
+	resp := httptest.NewRecorder()
+
+	connEndpoint.Create(resp, req, httprouter.Params{})
+
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	assert.Equal(t, 2, fakeManager.requestedParams.ProposalID)
+	assert.Equal(t, "openvpn", fakeManager.requestedParams.ServiceType)
+}
+
+func TestPutWithProviderFilterAndSelectedProposalReturnsValidationError(t *testing.T) {
+	connectionEndpoint := NewConnectionEndpoint(&fakeManager{}, nil, nil, &fakeAutoConnector{})
+	req := httptest.NewRequest(
+		http.MethodPut,
+		"/irrelevant",
+		strings.NewReader(
+			`{
+				"consumerId" : "my-identity",
+				"proposalId" : 2,
+				"serviceType" : "openvpn",
+				"providerFilter" : { "country" : "NL" }
+			}`))
+	resp := httptest.NewRecorder()
+
+	connectionEndpoint.Create(resp, req, nil)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	assert.JSONEq(
+		t,
+		`{
+			"message" : "validation_error",
+			"errors" : {
+				"proposalId" : [ { "code" : "invalid" , "message" : "Cannot be combined with providerFilter" } ],
+				"serviceType" : [ { "code" : "invalid" , "message" : "Cannot be combined with providerFilter, use its serviceType" } ]
+			}
+		}`,
+		resp.Body.String(),
+	)
+}
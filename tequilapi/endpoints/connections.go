@@ -0,0 +1,249 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package endpoints
+
+import (
+	"net/http"
+
+	log "github.com/cihub/seelog"
+	"github.com/julienschmidt/httprouter"
+	"github.com/mysteriumnetwork/node/core/connection"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/session"
+	"github.com/mysteriumnetwork/node/tequilapi/utils"
+)
+
+// swagger:model ConnectionDTO
+type connectionInfoResponse struct {
+	// example: Connected
+	Status string `json:"status"`
+
+	// example: 4cfb0324-daf6-4ad8-448b-e61fe0a1f918
+	SessionID string `json:"sessionId,omitempty"`
+
+	// true for connection which is managed through /connection endpoints
+	// example: false
+	Primary bool `json:"primary"`
+
+	Statistics statisticsResponse `json:"statistics"`
+}
+
+// swagger:model ConnectionListDTO
+type connectionListResponse struct {
+	Connections []connectionInfoResponse `json:"connections"`
+}
+
+type connectionPool interface {
+	Connect(consumerID, providerID identity.Identity, params connection.ConnectParams) (session.ID, error)
+	List() []connection.ConnectionInfo
+	Get(sessionID session.ID) (connection.ConnectionInfo, error)
+	Disconnect(sessionID session.ID) error
+}
+
+// ConnectionsEndpoint struct represents /connections resource, which manages concurrent connections
+type ConnectionsEndpoint struct {
+	pool connectionPool
+}
+
+// NewConnectionsEndpoint creates and returns connections endpoint
+func NewConnectionsEndpoint(pool connectionPool) *ConnectionsEndpoint {
+	return &ConnectionsEndpoint{pool: pool}
+}
+
+// List returns all active connections
+// swagger:operation GET /connections Connection listConnections
+// ---
+// summary: Returns all connections
+// description: Returns primary connection (if any) and all additional connections
+// responses:
+//   200:
+//     description: List of connections
+//     schema:
+//       "$ref": "#/definitions/ConnectionListDTO"
+func (ce *ConnectionsEndpoint) List(resp http.ResponseWriter, req *http.Request, params httprouter.Params) {
+	response := connectionListResponse{Connections: []connectionInfoResponse{}}
+	for _, info := range ce.pool.List() {
+		response.Connections = append(response.Connections, toConnectionInfoResponse(info))
+	}
+	utils.WriteAsJSON(response, resp)
+}
+
+// Create starts additional connection
+// swagger:operation POST /connections Connection createConnection
+// ---
+// summary: Starts additional connection
+// description: Starts new connection to given provider, which runs alongside primary connection and routes only included networks or domains. Kill switch is not supported.
+// parameters:
+//   - in: body
+//     name: body
+//     description: Parameters in body (consumerId, providerId) required for creating new connection
+//     schema:
+//       $ref: "#/definitions/ConnectionRequestDTO"
+// responses:
+//   201:
+//     description: Connection started
+//     schema:
+//       "$ref": "#/definitions/ConnectionDTO"
+//   400:
+//     description: Bad request
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+//   422:
+//     description: Parameters validation error
+//     schema:
+//       "$ref": "#/definitions/ValidationErrorDTO"
+//   409:
+//     description: Conflict. Kill switch of primary connection is enabled
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+//   500:
+//     description: Internal server error
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+func (ce *ConnectionsEndpoint) Create(resp http.ResponseWriter, req *http.Request, params httprouter.Params) {
+	cr, err := toConnectionRequest(req)
+	if err != nil {
+		utils.SendError(resp, err, http.StatusBadRequest)
+		return
+	}
+
+	errorMap := validateConnectionRequest(cr)
+	if cr.ProviderFilter != nil {
+		errorMap.ForField("providerFilter").AddError("unsupported", "Additional connections require providerId")
+	}
+	splitTunnel := cr.ConnectOptions.SplitTunnel
+	if len(splitTunnel.IncludeCIDRs)+len(splitTunnel.IncludeDomains) == 0 {
+		errorMap.ForField("connectOptions.splitTunnel").AddError("required", "Additional connections route only included networks or domains")
+	}
+	if errorMap.HasErrors() {
+		utils.SendValidationErrorMessage(resp, errorMap)
+		return
+	}
+
+	sessionID, err := ce.pool.Connect(identity.FromAddress(cr.ConsumerID), identity.FromAddress(cr.ProviderID), getConnectOptions(cr))
+	if err == connection.ErrAdditionalWithKillSwitch {
+		utils.SendError(resp, err, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Error(connectionLogPrefix, err)
+		utils.SendError(resp, err, http.StatusInternalServerError)
+		return
+	}
+
+	info, err := ce.pool.Get(sessionID)
+	if err != nil {
+		utils.SendError(resp, err, http.StatusInternalServerError)
+		return
+	}
+	resp.WriteHeader(http.StatusCreated)
+	utils.WriteAsJSON(toConnectionInfoResponse(info), resp)
+}
+
+// Get returns connection with given session id
+// swagger:operation GET /connections/{id} Connection getConnection
+// ---
+// summary: Returns connection
+// description: Returns status and statistics of connection with given session id
+// parameters:
+// - name: id
+//   in: path
+//   description: session id of connection
+//   type: string
+//   required: true
+// responses:
+//   200:
+//     description: Connection
+//     schema:
+//       "$ref": "#/definitions/ConnectionDTO"
+//   404:
+//     description: Connection not found
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+func (ce *ConnectionsEndpoint) Get(resp http.ResponseWriter, req *http.Request, params httprouter.Params) {
+	info, err := ce.pool.Get(session.ID(params.ByName("id")))
+	if err != nil {
+		utils.SendError(resp, err, http.StatusNotFound)
+		return
+	}
+	utils.WriteAsJSON(toConnectionInfoResponse(info), resp)
+}
+
+// Kill stops connection with given session id
+// swagger:operation DELETE /connections/{id} Connection killConnectionByID
+// ---
+// summary: Stops connection
+// description: Stops connection with given session id
+// parameters:
+// - name: id
+//   in: path
+//   description: session id of connection
+//   type: string
+//   required: true
+// responses:
+//   202:
+//     description: Connection stopped
+//   404:
+//     description: Connection not found
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+//   409:
+//     description: Conflict. Connection is already closed
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+//   500:
+//     description: Internal server error
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+func (ce *ConnectionsEndpoint) Kill(resp http.ResponseWriter, req *http.Request, params httprouter.Params) {
+	err := ce.pool.Disconnect(session.ID(params.ByName("id")))
+	if err != nil {
+		switch err {
+		case connection.ErrConnectionNotFound:
+			utils.SendError(resp, err, http.StatusNotFound)
+		case connection.ErrNoConnection:
+			utils.SendError(resp, err, http.StatusConflict)
+		default:
+			utils.SendError(resp, err, http.StatusInternalServerError)
+		}
+		return
+	}
+	resp.WriteHeader(http.StatusAccepted)
+}
+
+// AddRoutesForConnections adds connections routes to given router
+func AddRoutesForConnections(router *httprouter.Router, pool connectionPool) {
+	connectionsEndpoint := NewConnectionsEndpoint(pool)
+	router.GET("/connections", connectionsEndpoint.List)
+	router.POST("/connections", connectionsEndpoint.Create)
+	router.GET("/connections/:id", connectionsEndpoint.Get)
+	router.DELETE("/connections/:id", connectionsEndpoint.Kill)
+}
+
+func toConnectionInfoResponse(info connection.ConnectionInfo) connectionInfoResponse {
+	return connectionInfoResponse{
+		Status:    string(info.Status.State),
+		SessionID: string(info.Status.SessionID),
+		Primary:   info.Primary,
+		Statistics: statisticsResponse{
+			BytesSent:     info.Statistics.BytesSent,
+			BytesReceived: info.Statistics.BytesReceived,
+			Duration:      int(info.Duration.Seconds()),
+		},
+	}
+}
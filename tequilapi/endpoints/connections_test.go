@@ -0,0 +1,208 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package endpoints
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/mysteriumnetwork/node/client/stats"
+	"github.com/mysteriumnetwork/node/core/connection"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/session"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeConnectionPool struct {
+	connections       map[session.ID]connection.ConnectionInfo
+	onConnectID       session.ID
+	onConnectError    error
+	onDisconnectErr   error
+	requestedParams   connection.ConnectParams
+	disconnectedID    session.ID
+	requestedProvider identity.Identity
+}
+
+func (pool *fakeConnectionPool) Connect(consumerID, providerID identity.Identity, params connection.ConnectParams) (session.ID, error) {
+	pool.requestedParams = params
+	pool.requestedProvider = providerID
+	return pool.onConnectID, pool.onConnectError
+}
+
+func (pool *fakeConnectionPool) List() []connection.ConnectionInfo {
+	var list []connection.ConnectionInfo
+	for _, info := range pool.connections {
+		list = append(list, info)
+	}
+	return list
+}
+
+func (pool *fakeConnectionPool) Get(sessionID session.ID) (connection.ConnectionInfo, error) {
+	info, found := pool.connections[sessionID]
+	if !found {
+		return info, connection.ErrConnectionNotFound
+	}
+	return info, nil
+}
+
+func (pool *fakeConnectionPool) Disconnect(sessionID session.ID) error {
+	pool.disconnectedID = sessionID
+	return pool.onDisconnectErr
+}
+
+var probeConnection = connection.ConnectionInfo{
+	Status:     connection.ConnectionStatus{State: connection.Connected, SessionID: "probe-session"},
+	Statistics: stats.SessionStats{BytesSent: 1, BytesReceived: 2},
+	Duration:   time.Minute,
+}
+
+func TestConnectionsListIsEmptyWithoutConnections(t *testing.T) {
+	endpoint := NewConnectionsEndpoint(&fakeConnectionPool{})
+	resp := httptest.NewRecorder()
+
+	endpoint.List(resp, httptest.NewRequest(http.MethodGet, "/connections", nil), nil)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `{"connections": []}`, resp.Body.String())
+}
+
+func TestConnectionsGetReturnsConnection(t *testing.T) {
+	pool := &fakeConnectionPool{connections: map[session.ID]connection.ConnectionInfo{"probe-session": probeConnection}}
+	endpoint := NewConnectionsEndpoint(pool)
+	resp := httptest.NewRecorder()
+
+	endpoint.Get(
+		resp,
+		httptest.NewRequest(http.MethodGet, "/connections/probe-session", nil),
+		httprouter.Params{{Key: "id", Value: "probe-session"}},
+	)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(
+		t,
+		`{
+			"status": "Connected",
+			"sessionId": "probe-session",
+			"primary": false,
+			"statistics": {"bytesSent": 1, "bytesReceived": 2, "duration": 60}
+		}`,
+		resp.Body.String(),
+	)
+}
+
+func TestConnectionsGetReturnsNotFoundForUnknownSession(t *testing.T) {
+	endpoint := NewConnectionsEndpoint(&fakeConnectionPool{})
+	resp := httptest.NewRecorder()
+
+	endpoint.Get(
+		resp,
+		httptest.NewRequest(http.MethodGet, "/connections/unknown", nil),
+		httprouter.Params{{Key: "id", Value: "unknown"}},
+	)
+
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	assert.JSONEq(t, `{"message": "connection not found"}`, resp.Body.String())
+}
+
+func TestConnectionsCreateStartsAdditionalConnection(t *testing.T) {
+	pool := &fakeConnectionPool{
+		connections: map[session.ID]connection.ConnectionInfo{"probe-session": probeConnection},
+		onConnectID: "probe-session",
+	}
+	endpoint := NewConnectionsEndpoint(pool)
+	req := httptest.NewRequest(
+		http.MethodPost,
+		"/connections",
+		strings.NewReader(`{"consumerId": "my-identity", "providerId": "required-node", "connectOptions": {"splitTunnel": {"includeCidrs": ["10.0.0.0/8"]}}}`),
+	)
+	resp := httptest.NewRecorder()
+
+	endpoint.Create(resp, req, nil)
+
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	assert.Equal(t, identity.FromAddress("required-node"), pool.requestedProvider)
+	assert.Contains(t, resp.Body.String(), `"sessionId":"probe-session"`)
+}
+
+func TestConnectionsCreateValidatesRequest(t *testing.T) {
+	endpoint := NewConnectionsEndpoint(&fakeConnectionPool{})
+	resp := httptest.NewRecorder()
+
+	endpoint.Create(resp, httptest.NewRequest(http.MethodPost, "/connections", strings.NewReader("{}")), nil)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+}
+
+func TestConnectionsCreateRequiresIncludedNetworks(t *testing.T) {
+	endpoint := NewConnectionsEndpoint(&fakeConnectionPool{})
+	req := httptest.NewRequest(
+		http.MethodPost,
+		"/connections",
+		strings.NewReader(`{"consumerId": "my-identity", "providerId": "required-node", "connectOptions": {"splitTunnel": {"excludeCidrs": ["10.0.0.0/8"]}}}`),
+	)
+	resp := httptest.NewRecorder()
+
+	endpoint.Create(resp, req, nil)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	assert.Contains(t, resp.Body.String(), "connectOptions.splitTunnel")
+}
+
+func TestConnectionsCreateIsRefusedWhileKillSwitchIsEnabled(t *testing.T) {
+	endpoint := NewConnectionsEndpoint(&fakeConnectionPool{onConnectError: connection.ErrAdditionalWithKillSwitch})
+	req := httptest.NewRequest(
+		http.MethodPost,
+		"/connections",
+		strings.NewReader(`{"consumerId": "my-identity", "providerId": "required-node", "connectOptions": {"splitTunnel": {"includeDomains": ["example.com"]}}}`),
+	)
+	resp := httptest.NewRecorder()
+
+	endpoint.Create(resp, req, nil)
+
+	assert.Equal(t, http.StatusConflict, resp.Code)
+}
+
+func TestConnectionsKillMapsErrors(t *testing.T) {
+	var tests = []struct {
+		err          error
+		expectedCode int
+	}{
+		{nil, http.StatusAccepted},
+		{connection.ErrConnectionNotFound, http.StatusNotFound},
+		{connection.ErrNoConnection, http.StatusConflict},
+	}
+
+	for _, test := range tests {
+		pool := &fakeConnectionPool{onDisconnectErr: test.err}
+		endpoint := NewConnectionsEndpoint(pool)
+		resp := httptest.NewRecorder()
+
+		endpoint.Kill(
+			resp,
+			httptest.NewRequest(http.MethodDelete, "/connections/probe-session", nil),
+			httprouter.Params{{Key: "id", Value: "probe-session"}},
+		)
+
+		assert.Equal(t, test.expectedCode, resp.Code)
+		assert.Equal(t, session.ID("probe-session"), pool.disconnectedID)
+	}
+}
@@ -0,0 +1,114 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package endpoints
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	log "github.com/cihub/seelog"
+	"github.com/julienschmidt/httprouter"
+	"github.com/mysteriumnetwork/node/core/events"
+	"github.com/mysteriumnetwork/node/tequilapi/utils"
+)
+
+const eventsLogPrefix = "[tequilapi-events] "
+
+// eventsKeepAliveInterval defines how often comment line is sent to idle stream, so that proxies do not drop it
+const eventsKeepAliveInterval = 30 * time.Second
+
+var errStreamingUnsupported = errors.New("streaming is not supported")
+
+// EventsEndpoint streams node events to clients
+type EventsEndpoint struct {
+	subscriber        events.Subscriber
+	keepAliveInterval time.Duration
+}
+
+// NewEventsEndpoint creates and returns events endpoint
+func NewEventsEndpoint(subscriber events.Subscriber) *EventsEndpoint {
+	return &EventsEndpoint{
+		subscriber:        subscriber,
+		keepAliveInterval: eventsKeepAliveInterval,
+	}
+}
+
+// Stream streams node events until client disconnects
+// swagger:operation GET /events Events streamEvents
+// ---
+// summary: Streams node events
+// description: |
+//   Streams events as Server-Sent Events, until client closes the connection.
+//   Event name is the topic: connection-state, connection-statistics, service-session-created,
+//   service-session-destroyed or discovery-status. Event data is JSON with topic, time and topic specific payload.
+// produces:
+//   - text/event-stream
+// responses:
+//   200:
+//     description: Stream of events
+//   500:
+//     description: Internal server error
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+func (endpoint *EventsEndpoint) Stream(resp http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	flusher, ok := resp.(http.Flusher)
+	if !ok {
+		utils.SendError(resp, errStreamingUnsupported, http.StatusInternalServerError)
+		return
+	}
+
+	eventChannel, unsubscribe := endpoint.subscriber.Subscribe()
+	defer unsubscribe()
+
+	resp.Header().Set("Content-Type", "text/event-stream")
+	resp.Header().Set("Connection", "keep-alive")
+	resp.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(endpoint.keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(resp, ": keep-alive\n\n")
+		case event, more := <-eventChannel:
+			if !more {
+				return
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				log.Error(eventsLogPrefix, "Failed to serialize event: ", err)
+				continue
+			}
+			fmt.Fprintf(resp, "event: %s\ndata: %s\n\n", event.Topic, data)
+		}
+		flusher.Flush()
+	}
+}
+
+// AddRoutesForEvents attaches events streaming endpoint to router
+func AddRoutesForEvents(router *httprouter.Router, subscriber events.Subscriber) {
+	endpoint := NewEventsEndpoint(subscriber)
+	router.GET("/events", endpoint.Stream)
+}
@@ -0,0 +1,119 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package endpoints
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mysteriumnetwork/node/core/events"
+	"github.com/stretchr/testify/assert"
+)
+
+type streamRecorder struct {
+	*httptest.ResponseRecorder
+	flushed chan string
+}
+
+func (recorder *streamRecorder) Flush() {
+	select {
+	case recorder.flushed <- recorder.Body.String():
+	default:
+	}
+}
+
+func newStreamRecorder() *streamRecorder {
+	return &streamRecorder{httptest.NewRecorder(), make(chan string, 10)}
+}
+
+func TestEventsEndpointStreamsPublishedEvents(t *testing.T) {
+	bus := events.NewBus(10)
+	endpoint := NewEventsEndpoint(bus)
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodGet, "/events", nil).WithContext(ctx)
+	resp := newStreamRecorder()
+
+	streamDone := make(chan struct{})
+	go func() {
+		endpoint.Stream(resp, req, nil)
+		close(streamDone)
+	}()
+	<-resp.flushed
+
+	bus.Publish("connection-state", map[string]string{"state": "Connected"})
+	body := <-resp.flushed
+	cancel()
+	<-streamDone
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "text/event-stream", resp.Header().Get("Content-Type"))
+	assert.True(t, strings.HasPrefix(body, "event: connection-state\ndata: {\"topic\":\"connection-state\""))
+	assert.Contains(t, body, `"payload":{"state":"Connected"}`)
+	assert.True(t, strings.HasSuffix(body, "\n\n"))
+}
+
+func TestEventsEndpointSendsKeepAlive(t *testing.T) {
+	endpoint := NewEventsEndpoint(events.NewBus(10))
+	endpoint.keepAliveInterval = time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodGet, "/events", nil).WithContext(ctx)
+	resp := newStreamRecorder()
+
+	streamDone := make(chan struct{})
+	go func() {
+		endpoint.Stream(resp, req, nil)
+		close(streamDone)
+	}()
+	<-resp.flushed
+	body := <-resp.flushed
+	cancel()
+	<-streamDone
+
+	assert.Equal(t, ": keep-alive\n\n", body)
+}
+
+func TestEventsEndpointFailsWhenStreamingIsNotSupported(t *testing.T) {
+	endpoint := NewEventsEndpoint(events.NewBus(10))
+	req := httptest.NewRequest(http.MethodGet, "/events", nil)
+	recorder := httptest.NewRecorder()
+
+	endpoint.Stream(&nonFlushingWriter{recorder}, req, nil)
+
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	assert.JSONEq(t, `{"message": "streaming is not supported"}`, recorder.Body.String())
+}
+
+type nonFlushingWriter struct {
+	recorder *httptest.ResponseRecorder
+}
+
+func (writer *nonFlushingWriter) Header() http.Header {
+	return writer.recorder.Header()
+}
+
+func (writer *nonFlushingWriter) Write(data []byte) (int, error) {
+	return writer.recorder.Write(data)
+}
+
+func (writer *nonFlushingWriter) WriteHeader(code int) {
+	writer.recorder.WriteHeader(code)
+}
@@ -0,0 +1,152 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package endpoints
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/mysteriumnetwork/node/core/promise"
+	"github.com/mysteriumnetwork/node/money"
+	"github.com/mysteriumnetwork/node/tequilapi/utils"
+)
+
+// swagger:model PromisesDTO
+type promisesDTO struct {
+	Promises []promiseDTO `json:"promises"`
+}
+
+// swagger:model PromiseDTO
+type promiseDTO struct {
+	// example: 0x0000000000000000000000000000000000000001
+	IssuerID string `json:"issuerId"`
+
+	// example: 0x0000000000000000000000000000000000000002
+	BenefiterID string `json:"benefiterId"`
+
+	// session in which the promise was received
+	// example: 4cfb0324-daf6-4ad8-448b-e61fe0a1f918
+	SessionID string `json:"sessionId"`
+
+	// example: 3
+	SerialNumber int `json:"serialNumber"`
+
+	// cumulative amount promised by the issuer
+	Amount money.Money `json:"amount"`
+
+	// example: sXeJmBrjjMXkGraD8ItqNEI+0IommozG4dF24FvgbWQdgHJi10EvJOLt0F2AS5Y0MBe8hlDo3B0vlH2hW5uHSQE=
+	Signature string `json:"signature"`
+
+	// example: 2018-11-01T10:00:00Z
+	FirstReceivedAt time.Time `json:"firstReceivedAt"`
+
+	// example: 2018-11-01T10:05:00Z
+	LastReceivedAt time.Time `json:"lastReceivedAt"`
+}
+
+type promiseLedger interface {
+	List() ([]promise.LedgerEntry, error)
+	ListByIssuer(issuerID string) ([]promise.LedgerEntry, error)
+}
+
+type promisesEndpoint struct {
+	ledger promiseLedger
+}
+
+// NewPromisesEndpoint creates and returns promises endpoint
+func NewPromisesEndpoint(ledger promiseLedger) *promisesEndpoint {
+	return &promisesEndpoint{
+		ledger: ledger,
+	}
+}
+
+// swagger:operation GET /promises Promise listPromises
+// ---
+// summary: Returns received promises
+// description: Returns the latest promise received from every issuer in every session
+// responses:
+//   200:
+//     description: List of promises
+//     schema:
+//       "$ref": "#/definitions/PromisesDTO"
+//   500:
+//     description: Internal server error
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+func (endpoint *promisesEndpoint) List(resp http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	entries, err := endpoint.ledger.List()
+	if err != nil {
+		utils.SendError(resp, err, http.StatusInternalServerError)
+		return
+	}
+	utils.WriteAsJSON(promisesDTO{Promises: mapPromises(entries)}, resp)
+}
+
+// swagger:operation GET /promises/{issuer} Promise listIssuerPromises
+// ---
+// summary: Returns promises received from the issuer
+// description: Returns the latest promises received from the given issuer in every session
+// parameters:
+// - name: issuer
+//   in: path
+//   description: Issuer identity
+//   example: 0x0000000000000000000000000000000000000001
+//   type: string
+//   required: true
+// responses:
+//   200:
+//     description: List of promises
+//     schema:
+//       "$ref": "#/definitions/PromisesDTO"
+//   500:
+//     description: Internal server error
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+func (endpoint *promisesEndpoint) ListByIssuer(resp http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	entries, err := endpoint.ledger.ListByIssuer(params.ByName("issuer"))
+	if err != nil {
+		utils.SendError(resp, err, http.StatusInternalServerError)
+		return
+	}
+	utils.WriteAsJSON(promisesDTO{Promises: mapPromises(entries)}, resp)
+}
+
+// AddRoutesForPromises attaches promises endpoints to router
+func AddRoutesForPromises(router *httprouter.Router, ledger promiseLedger) {
+	promisesEndpoint := NewPromisesEndpoint(ledger)
+	router.GET("/promises", promisesEndpoint.List)
+	router.GET("/promises/:issuer", promisesEndpoint.ListByIssuer)
+}
+
+func mapPromises(entries []promise.LedgerEntry) []promiseDTO {
+	dtoArray := make([]promiseDTO, len(entries))
+	for i, entry := range entries {
+		dtoArray[i] = promiseDTO{
+			IssuerID:        entry.IssuerID,
+			BenefiterID:     entry.BenefiterID,
+			SessionID:       entry.SessionID,
+			SerialNumber:    entry.SignedPromise.Promise.SerialNumber,
+			Amount:          entry.SignedPromise.Promise.Amount,
+			Signature:       string(entry.SignedPromise.IssuerSignature),
+			FirstReceivedAt: entry.FirstReceived.UTC(),
+			LastReceivedAt:  entry.LastReceived.UTC(),
+		}
+	}
+	return dtoArray
+}
@@ -0,0 +1,124 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package endpoints
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/mysteriumnetwork/node/core/promise"
+	"github.com/mysteriumnetwork/node/money"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakePromiseLedger struct {
+	entries []promise.LedgerEntry
+	err     error
+}
+
+func (ledger *fakePromiseLedger) List() ([]promise.LedgerEntry, error) {
+	return ledger.entries, ledger.err
+}
+
+func (ledger *fakePromiseLedger) ListByIssuer(issuerID string) ([]promise.LedgerEntry, error) {
+	issuerEntries := make([]promise.LedgerEntry, 0)
+	for _, entry := range ledger.entries {
+		if entry.IssuerID == issuerID {
+			issuerEntries = append(issuerEntries, entry)
+		}
+	}
+	return issuerEntries, ledger.err
+}
+
+func TestAddRoutesForPromisesAddsRoutes(t *testing.T) {
+	received := time.Date(2018, 11, 1, 10, 0, 0, 0, time.UTC)
+	ledger := &fakePromiseLedger{
+		entries: []promise.LedgerEntry{
+			{
+				IssuerID:    "0x1",
+				BenefiterID: "0x2",
+				SessionID:   "session-1",
+				SignedPromise: promise.SignedPromise{
+					Promise: promise.Promise{
+						SerialNumber: 3,
+						IssuerID:     "0x1",
+						BenefiterID:  "0x2",
+						Amount:       money.Money{Amount: 300, Currency: money.CURRENCY_MYST},
+					},
+					IssuerSignature: "signature",
+				},
+				FirstReceived: received,
+				LastReceived:  received.Add(time.Minute),
+			},
+		},
+	}
+	router := httprouter.New()
+	AddRoutesForPromises(router, ledger)
+
+	tests := []struct {
+		method         string
+		path           string
+		expectedStatus int
+		expectedJSON   string
+	}{
+		{
+			http.MethodGet, "/promises",
+			http.StatusOK,
+			`{
+				"promises": [{
+					"issuerId": "0x1",
+					"benefiterId": "0x2",
+					"sessionId": "session-1",
+					"serialNumber": 3,
+					"amount": {"amount": 300, "currency": "MYST"},
+					"signature": "signature",
+					"firstReceivedAt": "2018-11-01T10:00:00Z",
+					"lastReceivedAt": "2018-11-01T10:01:00Z"
+				}]
+			}`,
+		},
+		{
+			http.MethodGet, "/promises/0x3",
+			http.StatusOK,
+			`{"promises": []}`,
+		},
+	}
+
+	for _, test := range tests {
+		resp := httptest.NewRecorder()
+		req := httptest.NewRequest(test.method, test.path, strings.NewReader(""))
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, test.expectedStatus, resp.Code)
+		assert.JSONEq(t, test.expectedJSON, resp.Body.String())
+	}
+}
+
+func TestPromisesListWhenLedgerFails(t *testing.T) {
+	endpoint := NewPromisesEndpoint(&fakePromiseLedger{err: errors.New("storage failure")})
+	resp := httptest.NewRecorder()
+
+	endpoint.List(resp, nil, nil)
+
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	assert.JSONEq(t, `{"message": "storage failure"}`, resp.Body.String())
+}
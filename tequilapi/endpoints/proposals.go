@@ -20,17 +20,53 @@ package endpoints
 import (
 	"encoding/json"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
+	"github.com/mysteriumnetwork/node/core/connection"
+	"github.com/mysteriumnetwork/node/money"
 	"github.com/mysteriumnetwork/node/server"
 	"github.com/mysteriumnetwork/node/server/metrics"
 	dto_discovery "github.com/mysteriumnetwork/node/service_discovery/dto"
 	"github.com/mysteriumnetwork/node/tequilapi/utils"
+	"github.com/mysteriumnetwork/node/tequilapi/validation"
+)
+
+const (
+	sortByPrice   = "price"
+	sortByQuality = "quality"
 )
 
 // swagger:model ProposalsList
 type proposalsRes struct {
 	Proposals []proposalRes `json:"proposals"`
+
+	// present only when page size is requested
+	Paging *pagingRes `json:"paging,omitempty"`
+}
+
+// swagger:model PagingDTO
+type pagingRes struct {
+	// example: 1
+	Page int `json:"page"`
+
+	// example: 10
+	PageSize int `json:"pageSize"`
+
+	// number of proposals matching the filters
+	// example: 25
+	Total int `json:"total"`
+}
+
+// swagger:model PaymentMethodDTO
+type paymentMethodRes struct {
+	// example: PER_TIME
+	Type string `json:"type"`
+
+	Price money.Money `json:"price"`
 }
 
 // swagger:model ServiceLocationDTO
@@ -68,12 +104,19 @@ type proposalRes struct {
 	// qualitative service definition
 	ServiceDefinition serviceDefinitionRes `json:"serviceDefinition"`
 
+	// payment method of the service, absent when provider does not announce it
+	PaymentMethod *paymentMethodRes `json:"paymentMethod,omitempty"`
+
 	// Metrics of the service
 	Metrics json.RawMessage `json:"metrics,omitempty"`
+
+	// seconds since proposal was fetched from discovery, present when proposals are served from local cache
+	// example: 30
+	AgeSeconds *int `json:"ageSeconds,omitempty"`
 }
 
 func proposalToRes(p dto_discovery.ServiceProposal) proposalRes {
-	return proposalRes{
+	res := proposalRes{
 		ID:          p.ID,
 		ProviderID:  p.ProviderID,
 		ServiceType: p.ServiceType,
@@ -85,6 +128,10 @@ func proposalToRes(p dto_discovery.ServiceProposal) proposalRes {
 			},
 		},
 	}
+	if p.PaymentMethod != nil {
+		res.PaymentMethod = &paymentMethodRes{Type: p.PaymentMethodType, Price: p.PaymentMethod.GetPrice()}
+	}
+	return res
 }
 
 func mapProposalsToRes(
@@ -99,6 +146,11 @@ func mapProposalsToRes(
 	return proposalsResArry
 }
 
+// proposalsCache is implemented by clients, which keep fetched proposals in local cache
+type proposalsCache interface {
+	CachedProposals(filter server.ProposalsFilter) ([]server.CachedProposal, error)
+}
+
 type proposalsEndpoint struct {
 	mysteriumClient      server.Client
 	mysteriumMorqaClient metrics.QualityOracle
@@ -112,40 +164,315 @@ func NewProposalsEndpoint(mc server.Client, morqaClient metrics.QualityOracle) *
 // swagger:operation GET /proposals Proposal listProposals
 // ---
 // summary: Returns proposals
-// description: Returns list of proposals filtered by provider id
+// description: Returns list of proposals matching the filters, sorted and paged if requested. Proposals may be served from local cache, their age is given then
 // parameters:
 //   - in: query
 //     name: providerId
 //     description: id of provider proposals
 //     example: "0x0000000000000000000000000000000000000001"
 //     type: string
+//   - in: query
+//     name: serviceType
+//     description: type of service
+//     example: openvpn
+//     type: string
+//   - in: query
+//     name: country
+//     description: country code of service location
+//     example: NL
+//     type: string
+//   - in: query
+//     name: city
+//     description: city of service location
+//     example: Amsterdam
+//     type: string
+//   - in: query
+//     name: asn
+//     description: autonomous system number of service location
+//     example: AS00001
+//     type: string
+//   - in: query
+//     name: protocol
+//     description: transport protocol of service
+//     example: udp
+//     type: string
+//   - in: query
+//     name: paymentMethod
+//     description: type of payment method
+//     example: PER_TIME
+//     type: string
+//   - in: query
+//     name: maxPrice
+//     description: maximum price amount, proposals without announced price are excluded
+//     example: 12500000
+//     type: integer
+//   - in: query
+//     name: fetchConnectCounts
+//     description: include connection metrics of proposals
+//     example: true
+//     type: boolean
+//   - in: query
+//     name: sortBy
+//     description: sort by price (the cheapest first) or by quality (the highest share of successful connections first)
+//     enum: [price, quality]
+//     type: string
+//   - in: query
+//     name: page
+//     description: number of page, starting from 1
+//     example: 1
+//     type: integer
+//   - in: query
+//     name: pageSize
+//     description: number of proposals in page, all proposals are returned if not given
+//     example: 10
+//     type: integer
 // responses:
 //   200:
 //     description: List of proposals
 //     schema:
 //       "$ref": "#/definitions/ProposalsList"
+//   422:
+//     description: Parameters validation error
+//     schema:
+//       "$ref": "#/definitions/ValidationErrorDTO"
 //   500:
 //     description: Internal server error
 //     schema:
 //       "$ref": "#/definitions/ErrorMessageDTO"
 func (pe *proposalsEndpoint) List(resp http.ResponseWriter, req *http.Request, params httprouter.Params) {
-	providerID := req.URL.Query().Get("providerId")
-	fetchConnectCounts := req.URL.Query().Get("fetchConnectCounts")
-	proposals, err := pe.mysteriumClient.FindProposals(providerID)
+	query, errorMap := toProposalsQuery(req)
+	if errorMap.HasErrors() {
+		utils.SendValidationErrorMessage(resp, errorMap)
+		return
+	}
+
+	proposals, toRes, err := pe.findProposals(server.ProposalsFilter{
+		ProviderID:  query.providerID,
+		ServiceType: query.serviceType,
+	})
 	if err != nil {
 		utils.SendError(resp, err, http.StatusInternalServerError)
 		return
 	}
+	proposals = filterProposals(proposals, query)
+
+	var receivedMetrics []json.RawMessage
+	if query.fetchConnectCounts || query.sortBy == sortByQuality {
+		receivedMetrics = pe.mysteriumMorqaClient.ProposalsMetrics()
+	}
+	switch query.sortBy {
+	case sortByPrice:
+		sortProposalsByPrice(proposals)
+	case sortByQuality:
+		sortProposalsByQuality(proposals, metrics.ParseConnectCounts(receivedMetrics))
+	}
+
+	var paging *pagingRes
+	if query.pageSize > 0 {
+		paging = &pagingRes{Page: query.page, PageSize: query.pageSize, Total: len(proposals)}
+		proposals = pageOfProposals(proposals, query.page, query.pageSize)
+	}
 
 	addMetricsToRes := noMetrics
-	if fetchConnectCounts == "true" {
-		addMetricsToRes = addMetrics(pe.mysteriumMorqaClient)
+	if query.fetchConnectCounts {
+		addMetricsToRes = addMetrics(receivedMetrics)
 	}
 
-	proposalsRes := proposalsRes{mapProposalsToRes(proposals, proposalToRes, addMetricsToRes)}
+	proposalsRes := proposalsRes{
+		Proposals: mapProposalsToRes(proposals, toRes, addMetricsToRes),
+		Paging:    paging,
+	}
 	utils.WriteAsJSON(proposalsRes, resp)
 }
 
+// findProposals fetches proposals from cache if client supports it, returned mapper adds age to cached proposals
+func (pe *proposalsEndpoint) findProposals(filter server.ProposalsFilter) ([]dto_discovery.ServiceProposal, func(dto_discovery.ServiceProposal) proposalRes, error) {
+	cache, ok := pe.mysteriumClient.(proposalsCache)
+	if !ok {
+		proposals, err := pe.mysteriumClient.FindProposals(filter)
+		return proposals, proposalToRes, err
+	}
+
+	cached, err := cache.CachedProposals(filter)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	proposals := make([]dto_discovery.ServiceProposal, len(cached))
+	ages := make(map[string]int, len(cached))
+	for i, entry := range cached {
+		proposals[i] = entry.Proposal
+		ages[server.ProposalCacheKey(entry.Proposal)] = int(entry.Age(now).Seconds())
+	}
+	toRes := func(p dto_discovery.ServiceProposal) proposalRes {
+		res := proposalToRes(p)
+		if age, ok := ages[server.ProposalCacheKey(p)]; ok {
+			res.AgeSeconds = &age
+		}
+		return res
+	}
+	return proposals, toRes, nil
+}
+
+type proposalsQuery struct {
+	providerID         string
+	serviceType        string
+	country            string
+	city               string
+	asn                string
+	protocol           string
+	paymentMethod      string
+	maxPrice           *uint64
+	fetchConnectCounts bool
+	sortBy             string
+	page               int
+	pageSize           int
+}
+
+func toProposalsQuery(req *http.Request) (proposalsQuery, *validation.FieldErrorMap) {
+	values := req.URL.Query()
+	errors := validation.NewErrorMap()
+	query := proposalsQuery{
+		providerID:         values.Get("providerId"),
+		serviceType:        values.Get("serviceType"),
+		country:            values.Get("country"),
+		city:               values.Get("city"),
+		asn:                values.Get("asn"),
+		protocol:           values.Get("protocol"),
+		paymentMethod:      values.Get("paymentMethod"),
+		fetchConnectCounts: values.Get("fetchConnectCounts") == "true",
+		sortBy:             values.Get("sortBy"),
+		page:               1,
+	}
+
+	if maxPrice := values.Get("maxPrice"); maxPrice != "" {
+		amount, err := strconv.ParseUint(maxPrice, 10, 64)
+		if err != nil {
+			errors.ForField("maxPrice").AddError("invalid", "Non negative integer is expected")
+		}
+		query.maxPrice = &amount
+	}
+	if query.sortBy != "" && query.sortBy != sortByPrice && query.sortBy != sortByQuality {
+		errors.ForField("sortBy").AddError("invalid", "Expected one of: price, quality")
+	}
+	if page := values.Get("page"); page != "" {
+		var err error
+		query.page, err = strconv.Atoi(page)
+		if err != nil || query.page < 1 {
+			errors.ForField("page").AddError("invalid", "Positive integer is expected")
+		}
+	}
+	if pageSize := values.Get("pageSize"); pageSize != "" {
+		var err error
+		query.pageSize, err = strconv.Atoi(pageSize)
+		if err != nil || query.pageSize < 1 {
+			errors.ForField("pageSize").AddError("invalid", "Positive integer is expected")
+		}
+	}
+	return query, errors
+}
+
+// filterProposals applies filters, which are not supported by discovery
+func filterProposals(proposals []dto_discovery.ServiceProposal, query proposalsQuery) []dto_discovery.ServiceProposal {
+	filtered := make([]dto_discovery.ServiceProposal, 0, len(proposals))
+	for _, proposal := range proposals {
+		if proposalMatches(proposal, query) {
+			filtered = append(filtered, proposal)
+		}
+	}
+	return filtered
+}
+
+func proposalMatches(proposal dto_discovery.ServiceProposal, query proposalsQuery) bool {
+	filter := connection.ProposalFilter{Country: query.country, ServiceType: query.serviceType, Protocol: query.protocol}
+	if !filter.Matches(proposal) {
+		return false
+	}
+	if query.paymentMethod != "" && !strings.EqualFold(query.paymentMethod, proposal.PaymentMethodType) {
+		return false
+	}
+	if query.maxPrice != nil && (proposal.PaymentMethod == nil || proposal.PaymentMethod.GetPrice().Amount > *query.maxPrice) {
+		return false
+	}
+
+	var location dto_discovery.Location
+	if proposal.ServiceDefinition != nil {
+		location = proposal.ServiceDefinition.GetLocation()
+	}
+	if query.city != "" && !strings.EqualFold(query.city, location.City) {
+		return false
+	}
+	if query.asn != "" && !strings.EqualFold(query.asn, location.ASN) {
+		return false
+	}
+	return true
+}
+
+// sortProposalsByPrice puts the cheapest proposals first, proposals without announced price go last.
+// Prices are comparable only within the same payment method type and currency, so proposals are grouped by them
+// in order of the first proposal of each group, and sorted by price within the group.
+func sortProposalsByPrice(proposals []dto_discovery.ServiceProposal) {
+	type priceGroup struct {
+		paymentMethodType string
+		currency          money.Currency
+	}
+	groupOf := func(proposal dto_discovery.ServiceProposal) priceGroup {
+		return priceGroup{proposal.PaymentMethodType, proposal.PaymentMethod.GetPrice().Currency}
+	}
+
+	groupOrder := make(map[priceGroup]int)
+	for _, proposal := range proposals {
+		if proposal.PaymentMethod == nil {
+			continue
+		}
+		if _, found := groupOrder[groupOf(proposal)]; !found {
+			groupOrder[groupOf(proposal)] = len(groupOrder)
+		}
+	}
+
+	sort.SliceStable(proposals, func(i, j int) bool {
+		if proposals[j].PaymentMethod == nil {
+			return proposals[i].PaymentMethod != nil
+		}
+		if proposals[i].PaymentMethod == nil {
+			return false
+		}
+		groupI, groupJ := groupOrder[groupOf(proposals[i])], groupOrder[groupOf(proposals[j])]
+		if groupI != groupJ {
+			return groupI < groupJ
+		}
+		return proposals[i].PaymentMethod.GetPrice().Amount < proposals[j].PaymentMethod.GetPrice().Amount
+	})
+}
+
+// sortProposalsByQuality puts proposals with the highest share of successful connections first,
+// proposals without connection statistics go last
+func sortProposalsByQuality(proposals []dto_discovery.ServiceProposal, connectCounts map[metrics.ProposalKey]metrics.ConnectCount) {
+	quality := func(proposal dto_discovery.ServiceProposal) float64 {
+		rate, ok := connectCounts[metrics.ProposalKey{ProviderID: proposal.ProviderID, ServiceType: proposal.ServiceType}].SuccessRate()
+		if !ok {
+			return -1
+		}
+		return rate
+	}
+	sort.SliceStable(proposals, func(i, j int) bool {
+		return quality(proposals[i]) > quality(proposals[j])
+	})
+}
+
+func pageOfProposals(proposals []dto_discovery.ServiceProposal, page, pageSize int) []dto_discovery.ServiceProposal {
+	start := (page - 1) * pageSize
+	if start >= len(proposals) {
+		return []dto_discovery.ServiceProposal{}
+	}
+	end := start + pageSize
+	if end > len(proposals) {
+		end = len(proposals)
+	}
+	return proposals[start:end]
+}
+
 // AddRoutesForProposals attaches proposals endpoints to router
 func AddRoutesForProposals(router *httprouter.Router, mc server.Client, morqaClient metrics.QualityOracle) {
 	pe := NewProposalsEndpoint(mc, morqaClient)
@@ -154,8 +481,7 @@ func AddRoutesForProposals(router *httprouter.Router, mc server.Client, morqaCli
 
 func noMetrics(p proposalRes) proposalRes { return p }
 
-func addMetrics(mc metrics.QualityOracle) func(p proposalRes) proposalRes {
-	receivedMetrics := mc.ProposalsMetrics()
+func addMetrics(receivedMetrics []json.RawMessage) func(p proposalRes) proposalRes {
 	proposalsMetrics := make(map[string]json.RawMessage, len(receivedMetrics))
 	var proposal struct{ ProposalID proposalRes }
 
@@ -22,7 +22,9 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/mysteriumnetwork/node/money"
 	"github.com/mysteriumnetwork/node/server"
 	dto_discovery "github.com/mysteriumnetwork/node/service_discovery/dto"
 	"github.com/stretchr/testify/assert"
@@ -221,3 +223,248 @@ func (m *mysteriumMorqaFake) ProposalsMetrics() []json.RawMessage {
 	}
 	return nil
 }
+
+type testFilteredServiceDefinition struct {
+	location dto_discovery.Location
+	protocol string
+}
+
+func (service testFilteredServiceDefinition) GetLocation() dto_discovery.Location {
+	return service.location
+}
+
+func (service testFilteredServiceDefinition) GetProtocol() string {
+	return service.protocol
+}
+
+type testPaymentMethod struct {
+	amount   uint64
+	currency money.Currency
+}
+
+func (method testPaymentMethod) GetPrice() money.Money {
+	if method.currency == "" {
+		return money.Money{Amount: method.amount, Currency: money.CURRENCY_MYST}
+	}
+	return money.Money{Amount: method.amount, Currency: method.currency}
+}
+
+type qualityOracleFake struct {
+	metrics []json.RawMessage
+}
+
+func (oracle *qualityOracleFake) ProposalsMetrics() []json.RawMessage {
+	return oracle.metrics
+}
+
+func filteredProposal(providerID, serviceType, country, protocol string, price *uint64) dto_discovery.ServiceProposal {
+	proposal := dto_discovery.ServiceProposal{
+		ID:          1,
+		ProviderID:  providerID,
+		ServiceType: serviceType,
+		ServiceDefinition: testFilteredServiceDefinition{
+			location: dto_discovery.Location{ASN: "AS00001", Country: country, City: "Capital"},
+			protocol: protocol,
+		},
+	}
+	if price != nil {
+		proposal.PaymentMethodType = "PER_TIME"
+		proposal.PaymentMethod = testPaymentMethod{amount: *price}
+	}
+	return proposal
+}
+
+func price(amount uint64) *uint64 {
+	return &amount
+}
+
+func listProposals(t *testing.T, oracle *qualityOracleFake, query string, proposals ...dto_discovery.ServiceProposal) *httptest.ResponseRecorder {
+	discoveryAPI := server.NewClientFake()
+	for _, proposal := range proposals {
+		discoveryAPI.RegisterProposal(proposal, nil)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "/irrelevant?"+query, nil)
+	assert.NoError(t, err)
+
+	resp := httptest.NewRecorder()
+	NewProposalsEndpoint(discoveryAPI, oracle).List(resp, req, nil)
+	return resp
+}
+
+func listedProviders(t *testing.T, resp *httptest.ResponseRecorder) []string {
+	var list proposalsRes
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &list))
+
+	providers := make([]string, len(list.Proposals))
+	for i, proposal := range list.Proposals {
+		providers[i] = proposal.ProviderID
+	}
+	return providers
+}
+
+func TestProposalsEndpointListFiltersProposals(t *testing.T) {
+	filteredProposals := []dto_discovery.ServiceProposal{
+		filteredProposal("nl-udp-cheap", "openvpn", "NL", "udp", price(10)),
+		filteredProposal("nl-tcp-cheap", "openvpn", "NL", "tcp", price(10)),
+		filteredProposal("nl-udp-expensive", "openvpn", "NL", "udp", price(100)),
+		filteredProposal("nl-udp-free", "openvpn", "NL", "udp", nil),
+		filteredProposal("de-udp-cheap", "openvpn", "DE", "udp", price(10)),
+		filteredProposal("nl-noop", "noop", "NL", "", price(10)),
+	}
+
+	resp := listProposals(t, &qualityOracleFake{}, "serviceType=openvpn&country=nl&protocol=udp&maxPrice=50", filteredProposals...)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, []string{"nl-udp-cheap"}, listedProviders(t, resp))
+
+	resp = listProposals(t, &qualityOracleFake{}, "city=capital&asn=AS00001&paymentMethod=PER_TIME&serviceType=noop", filteredProposals...)
+	assert.Equal(t, []string{"nl-noop"}, listedProviders(t, resp))
+}
+
+func TestProposalsEndpointListSortsByPrice(t *testing.T) {
+	resp := listProposals(
+		t,
+		&qualityOracleFake{},
+		"sortBy=price",
+		filteredProposal("free", "openvpn", "NL", "udp", nil),
+		filteredProposal("expensive", "openvpn", "NL", "udp", price(100)),
+		filteredProposal("cheap", "openvpn", "NL", "udp", price(10)),
+	)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, []string{"cheap", "expensive", "free"}, listedProviders(t, resp))
+	assert.Contains(t, resp.Body.String(), `"paymentMethod":{"type":"PER_TIME","price":{"amount":10,"currency":"MYST"}}`)
+}
+
+func TestProposalsEndpointListSortsByPriceWithinPaymentMethodAndCurrency(t *testing.T) {
+	perBytes := filteredProposal("per-bytes", "openvpn", "NL", "udp", price(1))
+	perBytes.PaymentMethodType = "PER_BYTES"
+	otherCurrency := filteredProposal("other-currency", "openvpn", "NL", "udp", nil)
+	otherCurrency.PaymentMethodType = "PER_TIME"
+	otherCurrency.PaymentMethod = testPaymentMethod{amount: 2, currency: money.Currency("ETH")}
+
+	resp := listProposals(
+		t,
+		&qualityOracleFake{},
+		"sortBy=price",
+		filteredProposal("per-time-expensive", "openvpn", "NL", "udp", price(100)),
+		perBytes,
+		filteredProposal("free", "openvpn", "NL", "udp", nil),
+		otherCurrency,
+		filteredProposal("per-time-cheap", "openvpn", "NL", "udp", price(10)),
+	)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(
+		t,
+		[]string{"per-time-cheap", "per-time-expensive", "per-bytes", "other-currency", "free"},
+		listedProviders(t, resp),
+	)
+}
+
+func TestProposalsEndpointListSortsByQuality(t *testing.T) {
+	oracle := &qualityOracleFake{metrics: []json.RawMessage{
+		json.RawMessage(`{"proposalId": {"providerId": "unreliable", "serviceType": "openvpn"}, "connectCount": {"success": 1, "fail": 3}}`),
+		json.RawMessage(`{"proposalId": {"providerId": "reliable", "serviceType": "openvpn"}, "connectCount": {"success": 9, "timeout": 1}}`),
+	}}
+
+	resp := listProposals(
+		t,
+		oracle,
+		"sortBy=quality",
+		filteredProposal("unknown", "openvpn", "NL", "udp", nil),
+		filteredProposal("unreliable", "openvpn", "NL", "udp", nil),
+		filteredProposal("reliable", "openvpn", "NL", "udp", nil),
+	)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, []string{"reliable", "unreliable", "unknown"}, listedProviders(t, resp))
+	assert.NotContains(t, resp.Body.String(), "metrics")
+}
+
+func TestProposalsEndpointListPagesProposals(t *testing.T) {
+	pagedProposals := []dto_discovery.ServiceProposal{
+		filteredProposal("first", "openvpn", "NL", "udp", nil),
+		filteredProposal("second", "openvpn", "NL", "udp", nil),
+		filteredProposal("third", "openvpn", "NL", "udp", nil),
+	}
+
+	resp := listProposals(t, &qualityOracleFake{}, "page=2&pageSize=2", pagedProposals...)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, []string{"third"}, listedProviders(t, resp))
+	assert.Contains(t, resp.Body.String(), `"paging":{"page":2,"pageSize":2,"total":3}`)
+
+	resp = listProposals(t, &qualityOracleFake{}, "page=3&pageSize=2", pagedProposals...)
+	assert.Equal(t, []string{}, listedProviders(t, resp))
+}
+
+func TestProposalsEndpointListValidatesQuery(t *testing.T) {
+	resp := listProposals(t, &qualityOracleFake{}, "sortBy=name&maxPrice=-1&page=0&pageSize=x")
+
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	assert.JSONEq(
+		t,
+		`{
+			"message": "validation_error",
+			"errors": {
+				"sortBy": [ { "code": "invalid", "message": "Expected one of: price, quality" } ],
+				"maxPrice": [ { "code": "invalid", "message": "Non negative integer is expected" } ],
+				"page": [ { "code": "invalid", "message": "Positive integer is expected" } ],
+				"pageSize": [ { "code": "invalid", "message": "Positive integer is expected" } ]
+			}
+		}`,
+		resp.Body.String(),
+	)
+}
+
+type proposalsCacheFake struct {
+	server.Client
+	cached []server.CachedProposal
+}
+
+func (cache *proposalsCacheFake) CachedProposals(filter server.ProposalsFilter) ([]server.CachedProposal, error) {
+	return cache.cached, nil
+}
+
+func TestProposalsEndpointListServesCachedProposalsWithAge(t *testing.T) {
+	cache := &proposalsCacheFake{
+		Client: server.NewClientFake(),
+		cached: []server.CachedProposal{
+			{Proposal: filteredProposal("cached", "openvpn", "NL", "udp", nil), Fetched: time.Now().Add(-90 * time.Second)},
+		},
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "/irrelevant", nil)
+	assert.NoError(t, err)
+
+	resp := httptest.NewRecorder()
+	NewProposalsEndpoint(cache, &qualityOracleFake{}).List(resp, req, nil)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, []string{"cached"}, listedProviders(t, resp))
+	assert.Contains(t, resp.Body.String(), `"ageSeconds":90`)
+}
+
+func TestProposalsEndpointListGivesAgeOfEachProposalOfProvider(t *testing.T) {
+	olderProposal := filteredProposal("cached", "openvpn", "NL", "udp", nil)
+	newerProposal := filteredProposal("cached", "openvpn", "NL", "tcp", nil)
+	newerProposal.ID = 2
+	cache := &proposalsCacheFake{
+		Client: server.NewClientFake(),
+		cached: []server.CachedProposal{
+			{Proposal: olderProposal, Fetched: time.Now().Add(-90 * time.Second)},
+			{Proposal: newerProposal, Fetched: time.Now().Add(-30 * time.Second)},
+		},
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "/irrelevant", nil)
+	assert.NoError(t, err)
+
+	resp := httptest.NewRecorder()
+	NewProposalsEndpoint(cache, &qualityOracleFake{}).List(resp, req, nil)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, []string{"cached", "cached"}, listedProviders(t, resp))
+	assert.Contains(t, resp.Body.String(), `"ageSeconds":90`)
+	assert.Contains(t, resp.Body.String(), `"ageSeconds":30`)
+}
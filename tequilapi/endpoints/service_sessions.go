@@ -0,0 +1,185 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package endpoints
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/session"
+	"github.com/mysteriumnetwork/node/tequilapi/utils"
+)
+
+var errConsumerIDMissing = errors.New("consumerId is missing")
+
+// swagger:model ServiceSessionsDTO
+type serviceSessionsDTO struct {
+	Sessions []serviceSessionDTO `json:"sessions"`
+}
+
+// swagger:model ServiceSessionDTO
+type serviceSessionDTO struct {
+	// example: 4cfb0324-daf6-4ad8-448b-e61fe0a1f918
+	ID string `json:"id"`
+
+	// example: 0x0000000000000000000000000000000000000001
+	ConsumerID string `json:"consumerId"`
+
+	// example: 2018-11-01T10:00:00Z
+	CreatedAt time.Time `json:"createdAt"`
+
+	// whether consumer has connected to the service using this session
+	// example: true
+	Connected bool `json:"connected"`
+
+	// bytes sent to the consumer
+	// example: 1024
+	BytesUp uint64 `json:"bytesUp"`
+
+	// bytes received from the consumer
+	// example: 2048
+	BytesDown uint64 `json:"bytesDown"`
+}
+
+// swagger:model ServiceSessionsRemovedDTO
+type serviceSessionsRemovedDTO struct {
+	// ids of terminated sessions
+	// example: ["4cfb0324-daf6-4ad8-448b-e61fe0a1f918"]
+	Sessions []string `json:"sessions"`
+}
+
+type serviceSessionStorage interface {
+	GetAll() []session.Session
+	Find(session.ID) (session.Session, bool)
+	Remove(session.ID)
+	RemoveConsumer(consumerID identity.Identity) []session.ID
+}
+
+type serviceSessionsEndpoint struct {
+	storage serviceSessionStorage
+}
+
+// NewServiceSessionsEndpoint creates and returns service sessions endpoint
+func NewServiceSessionsEndpoint(storage serviceSessionStorage) *serviceSessionsEndpoint {
+	return &serviceSessionsEndpoint{
+		storage: storage,
+	}
+}
+
+// swagger:operation GET /service-sessions ServiceSession listServiceSessions
+// ---
+// summary: Returns service sessions
+// description: Returns sessions which consumers have created with services running on this node
+// responses:
+//   200:
+//     description: List of service sessions
+//     schema:
+//       "$ref": "#/definitions/ServiceSessionsDTO"
+func (endpoint *serviceSessionsEndpoint) List(resp http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	utils.WriteAsJSON(serviceSessionsDTO{Sessions: mapServiceSessions(endpoint.storage.GetAll())}, resp)
+}
+
+// Kill terminates service session with given id
+// swagger:operation DELETE /service-sessions/{id} ServiceSession killServiceSession
+// ---
+// summary: Terminates service session
+// description: Disconnects consumer of the session and revokes the session, so that it can not be used again
+// parameters:
+// - name: id
+//   in: path
+//   description: id of service session
+//   type: string
+//   required: true
+// responses:
+//   202:
+//     description: Service session terminated
+//   404:
+//     description: Service session not found
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+func (endpoint *serviceSessionsEndpoint) Kill(resp http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	sessionID := session.ID(params.ByName("id"))
+	if _, found := endpoint.storage.Find(sessionID); !found {
+		utils.SendError(resp, session.ErrorSessionNotExists, http.StatusNotFound)
+		return
+	}
+
+	endpoint.storage.Remove(sessionID)
+	resp.WriteHeader(http.StatusAccepted)
+}
+
+// KillConsumer terminates all service sessions of given consumer
+// swagger:operation DELETE /service-sessions ServiceSession killConsumerServiceSessions
+// ---
+// summary: Terminates service sessions of consumer
+// description: Disconnects consumer and revokes all its sessions, so that they can not be used again
+// parameters:
+// - name: consumerId
+//   in: query
+//   description: identity of consumer
+//   type: string
+//   required: true
+// responses:
+//   200:
+//     description: Terminated service sessions
+//     schema:
+//       "$ref": "#/definitions/ServiceSessionsRemovedDTO"
+//   400:
+//     description: Consumer identity is missing
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+func (endpoint *serviceSessionsEndpoint) KillConsumer(resp http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	consumerID := request.URL.Query().Get("consumerId")
+	if consumerID == "" {
+		utils.SendError(resp, errConsumerIDMissing, http.StatusBadRequest)
+		return
+	}
+
+	removed := endpoint.storage.RemoveConsumer(identity.FromAddress(consumerID))
+	sessions := make([]string, len(removed))
+	for i, sessionID := range removed {
+		sessions[i] = string(sessionID)
+	}
+	utils.WriteAsJSON(serviceSessionsRemovedDTO{Sessions: sessions}, resp)
+}
+
+// AddRoutesForServiceSessions attaches service sessions endpoints to router
+func AddRoutesForServiceSessions(router *httprouter.Router, storage serviceSessionStorage) {
+	serviceSessionsEndpoint := NewServiceSessionsEndpoint(storage)
+	router.GET("/service-sessions", serviceSessionsEndpoint.List)
+	router.DELETE("/service-sessions", serviceSessionsEndpoint.KillConsumer)
+	router.DELETE("/service-sessions/:id", serviceSessionsEndpoint.Kill)
+}
+
+func mapServiceSessions(sessions []session.Session) []serviceSessionDTO {
+	dtoArray := make([]serviceSessionDTO, len(sessions))
+	for i, se := range sessions {
+		dtoArray[i] = serviceSessionDTO{
+			ID:         string(se.ID),
+			ConsumerID: se.ConsumerID.Address,
+			CreatedAt:  se.CreatedAt.UTC(),
+			Connected:  se.Connected,
+			BytesUp:    se.DataTransfer.Up,
+			BytesDown:  se.DataTransfer.Down,
+		}
+	}
+	return dtoArray
+}
@@ -0,0 +1,157 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package endpoints
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/session"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeServiceSessionStorage struct {
+	sessions []session.Session
+}
+
+func (storage *fakeServiceSessionStorage) GetAll() []session.Session {
+	return storage.sessions
+}
+
+func (storage *fakeServiceSessionStorage) Find(id session.ID) (session.Session, bool) {
+	for _, sessionInstance := range storage.sessions {
+		if sessionInstance.ID == id {
+			return sessionInstance, true
+		}
+	}
+	return session.Session{}, false
+}
+
+func (storage *fakeServiceSessionStorage) Remove(id session.ID) {
+	storage.removeWhere(func(sessionInstance session.Session) bool { return sessionInstance.ID == id })
+}
+
+func (storage *fakeServiceSessionStorage) RemoveConsumer(consumerID identity.Identity) []session.ID {
+	return storage.removeWhere(func(sessionInstance session.Session) bool { return sessionInstance.ConsumerID == consumerID })
+}
+
+func (storage *fakeServiceSessionStorage) removeWhere(match func(session.Session) bool) []session.ID {
+	removed := make([]session.ID, 0)
+	kept := make([]session.Session, 0)
+	for _, sessionInstance := range storage.sessions {
+		if match(sessionInstance) {
+			removed = append(removed, sessionInstance.ID)
+		} else {
+			kept = append(kept, sessionInstance)
+		}
+	}
+	storage.sessions = kept
+	return removed
+}
+
+func TestAddRoutesForServiceSessionsAddsRoutes(t *testing.T) {
+	created := time.Date(2018, 11, 1, 10, 0, 0, 0, time.UTC)
+	storage := &fakeServiceSessionStorage{
+		sessions: []session.Session{
+			{
+				ID:           session.ID("session-1"),
+				ConsumerID:   identity.FromAddress("0x1"),
+				CreatedAt:    created,
+				Connected:    true,
+				DataTransfer: session.DataTransfer{Up: 10, Down: 20},
+			},
+		},
+	}
+	router := httprouter.New()
+	AddRoutesForServiceSessions(router, storage)
+
+	resp := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/service-sessions", strings.NewReader(""))
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(
+		t,
+		`{
+			"sessions": [{
+				"id": "session-1",
+				"consumerId": "0x1",
+				"createdAt": "2018-11-01T10:00:00Z",
+				"connected": true,
+				"bytesUp": 10,
+				"bytesDown": 20
+			}]
+		}`,
+		resp.Body.String(),
+	)
+}
+
+func TestServiceSessionsListWhenEmpty(t *testing.T) {
+	endpoint := NewServiceSessionsEndpoint(&fakeServiceSessionStorage{})
+	resp := httptest.NewRecorder()
+
+	endpoint.List(resp, nil, nil)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `{"sessions": []}`, resp.Body.String())
+}
+
+func TestServiceSessionsKill(t *testing.T) {
+	storage := &fakeServiceSessionStorage{
+		sessions: []session.Session{{ID: "session-1"}, {ID: "session-2"}},
+	}
+	router := httprouter.New()
+	AddRoutesForServiceSessions(router, storage)
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodDelete, "/service-sessions/session-1", nil))
+	assert.Equal(t, http.StatusAccepted, resp.Code)
+	assert.Equal(t, []session.Session{{ID: "session-2"}}, storage.sessions)
+
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodDelete, "/service-sessions/session-1", nil))
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	assert.JSONEq(t, `{"message": "session does not exists"}`, resp.Body.String())
+}
+
+func TestServiceSessionsKillConsumer(t *testing.T) {
+	storage := &fakeServiceSessionStorage{
+		sessions: []session.Session{
+			{ID: "session-1", ConsumerID: identity.FromAddress("0x1")},
+			{ID: "session-2", ConsumerID: identity.FromAddress("0x2")},
+			{ID: "session-3", ConsumerID: identity.FromAddress("0x1")},
+		},
+	}
+	router := httprouter.New()
+	AddRoutesForServiceSessions(router, storage)
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodDelete, "/service-sessions?consumerId=0x1", nil))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `{"sessions": ["session-1", "session-3"]}`, resp.Body.String())
+	assert.Len(t, storage.sessions, 1)
+
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodDelete, "/service-sessions", nil))
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
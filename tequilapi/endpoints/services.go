@@ -0,0 +1,372 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package endpoints
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/julienschmidt/httprouter"
+	"github.com/mysteriumnetwork/node/core/service"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/nat"
+	"github.com/mysteriumnetwork/node/session"
+	"github.com/mysteriumnetwork/node/tequilapi/utils"
+	"github.com/mysteriumnetwork/node/tequilapi/validation"
+)
+
+// swagger:model ServiceRequestDTO
+type serviceRequest struct {
+	// type of service to start
+	// example: openvpn
+	Type string `json:"type"`
+
+	// provider identity, last used or new identity is taken if not given
+	// example: 0x0000000000000000000000000000000000000001
+	ProviderID string `json:"providerId"`
+
+	// passphrase to unlock provider identity
+	Passphrase string `json:"passphrase"`
+
+	// transport options of the service, i.e. {"protocol": "tcp", "port": 443} for openvpn
+	Options *json.RawMessage `json:"options"`
+
+	// policy deciding which consumers are granted sessions, sessions are not restricted if not given
+	Admission *serviceAdmissionRequest `json:"admission,omitempty"`
+
+	// policy restricting destinations reachable by consumers, local networks of provider are denied if not given
+	TrafficPolicy *serviceTrafficPolicyRequest `json:"trafficPolicy,omitempty"`
+}
+
+// swagger:model ServiceAdmissionDTO
+type serviceAdmissionRequest struct {
+	// maximum number of concurrent sessions, zero means unlimited
+	// example: 100
+	MaxSessions int `json:"maxSessions"`
+
+	// maximum number of concurrent sessions of single consumer, zero means unlimited
+	// example: 2
+	MaxSessionsPerConsumer int `json:"maxSessionsPerConsumer"`
+
+	// only listed consumers are granted sessions, if not empty
+	// example: ["0x0000000000000000000000000000000000000001"]
+	AllowedConsumers []string `json:"allowedConsumers,omitempty"`
+
+	// consumers which are never granted sessions
+	// example: ["0x0000000000000000000000000000000000000002"]
+	DeniedConsumers []string `json:"deniedConsumers,omitempty"`
+
+	// minimal consumer balance in the blockchain, zero means balance is not checked
+	// example: 1000
+	MinBalance uint64 `json:"minBalance"`
+}
+
+// swagger:model ServiceTrafficPolicyDTO
+type serviceTrafficPolicyRequest struct {
+	// permits traffic to local networks of the provider
+	// example: false
+	AllowLocalNetworks bool `json:"allowLocalNetworks"`
+
+	// destination subnets, traffic to which is dropped
+	// example: ["192.0.2.0/24"]
+	DeniedNetworks []string `json:"deniedNetworks,omitempty"`
+
+	// destination ports, TCP and UDP traffic to which is dropped
+	// example: [25]
+	DeniedPorts []int `json:"deniedPorts,omitempty"`
+
+	// restricts traffic to given destination subnets only, if not empty
+	// example: ["198.51.100.0/24"]
+	AllowedNetworks []string `json:"allowedNetworks,omitempty"`
+}
+
+// swagger:model ServiceListDTO
+type serviceListResponse struct {
+	Services []serviceResponse `json:"services"`
+}
+
+// swagger:model ServiceDTO
+type serviceResponse struct {
+	// example: 1
+	ID int `json:"id"`
+
+	// example: openvpn
+	Type string `json:"type"`
+
+	// example: Running
+	State string `json:"state"`
+
+	// stage of proposal registration in discovery
+	// example: PingProposal
+	DiscoveryStatus string `json:"discoveryStatus"`
+
+	// proposal announced by the service
+	Proposal proposalRes `json:"proposal"`
+}
+
+type serviceManager interface {
+	Start(options service.Options) (int, error)
+	Stop(id int) error
+	Get(id int) (*service.Instance, bool)
+	List() []*service.Instance
+}
+
+type serviceOptionsParser interface {
+	ParseOptions(serviceType string, data *json.RawMessage) (service.TransportOptions, error)
+}
+
+type servicesEndpoint struct {
+	manager       serviceManager
+	optionsParser serviceOptionsParser
+}
+
+// NewServicesEndpoint creates and returns services endpoint
+func NewServicesEndpoint(manager serviceManager, optionsParser serviceOptionsParser) *servicesEndpoint {
+	return &servicesEndpoint{
+		manager:       manager,
+		optionsParser: optionsParser,
+	}
+}
+
+// swagger:operation GET /services Service listServices
+// ---
+// summary: Returns services
+// description: Returns services provided by the node
+// responses:
+//   200:
+//     description: List of services
+//     schema:
+//       "$ref": "#/definitions/ServiceListDTO"
+func (endpoint *servicesEndpoint) List(resp http.ResponseWriter, req *http.Request, params httprouter.Params) {
+	instances := endpoint.manager.List()
+
+	services := make([]serviceResponse, len(instances))
+	for i, instance := range instances {
+		services[i] = toServiceResponse(instance)
+	}
+	utils.WriteAsJSON(serviceListResponse{Services: services}, resp)
+}
+
+// swagger:operation POST /services Service startService
+// ---
+// summary: Starts service
+// description: Starts service and announces its proposal to the network
+// parameters:
+//   - in: body
+//     name: body
+//     description: Parameters in body (type, providerId, options, admission, trafficPolicy) required for starting service
+//     schema:
+//       $ref: "#/definitions/ServiceRequestDTO"
+// responses:
+//   201:
+//     description: Service started
+//     schema:
+//       "$ref": "#/definitions/ServiceDTO"
+//   400:
+//     description: Bad request
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+//   422:
+//     description: Parameters validation error
+//     schema:
+//       "$ref": "#/definitions/ValidationErrorDTO"
+//   500:
+//     description: Internal server error
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+func (endpoint *servicesEndpoint) Create(resp http.ResponseWriter, req *http.Request, params httprouter.Params) {
+	sr, err := toServiceRequest(req)
+	if err != nil {
+		utils.SendError(resp, err, http.StatusBadRequest)
+		return
+	}
+
+	errorMap := validateServiceRequest(sr)
+	if errorMap.HasErrors() {
+		utils.SendValidationErrorMessage(resp, errorMap)
+		return
+	}
+
+	transportOptions, err := endpoint.optionsParser.ParseOptions(sr.Type, sr.Options)
+	if err == service.ErrUnsupportedServiceType {
+		errorMap.ForField("type").AddError("invalid", "Unsupported service type")
+		utils.SendValidationErrorMessage(resp, errorMap)
+		return
+	}
+	if err != nil {
+		utils.SendError(resp, err, http.StatusBadRequest)
+		return
+	}
+
+	id, err := endpoint.manager.Start(service.Options{
+		Identity:      sr.ProviderID,
+		Passphrase:    sr.Passphrase,
+		Type:          sr.Type,
+		Options:       transportOptions,
+		Admission:     toAdmissionPolicy(sr.Admission),
+		TrafficPolicy: toTrafficPolicy(sr.TrafficPolicy),
+	})
+	if err != nil {
+		utils.SendError(resp, err, http.StatusInternalServerError)
+		return
+	}
+
+	instance, found := endpoint.manager.Get(id)
+	if !found {
+		utils.SendError(resp, errors.New("service stopped while starting"), http.StatusInternalServerError)
+		return
+	}
+	resp.WriteHeader(http.StatusCreated)
+	utils.WriteAsJSON(toServiceResponse(instance), resp)
+}
+
+// swagger:operation DELETE /services/{id} Service stopService
+// ---
+// summary: Stops service
+// description: Stops service and unregisters its proposal
+// parameters:
+// - name: id
+//   in: path
+//   description: id of the service
+//   type: integer
+//   required: true
+// responses:
+//   202:
+//     description: Service stopped
+//   404:
+//     description: Service not found
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+//   500:
+//     description: Internal server error
+//     schema:
+//       "$ref": "#/definitions/ErrorMessageDTO"
+func (endpoint *servicesEndpoint) Kill(resp http.ResponseWriter, req *http.Request, params httprouter.Params) {
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil {
+		utils.SendError(resp, service.ErrorServiceNotFound, http.StatusNotFound)
+		return
+	}
+
+	err = endpoint.manager.Stop(id)
+	switch err {
+	case nil:
+		resp.WriteHeader(http.StatusAccepted)
+	case service.ErrorServiceNotFound:
+		utils.SendError(resp, err, http.StatusNotFound)
+	default:
+		utils.SendError(resp, err, http.StatusInternalServerError)
+	}
+}
+
+// AddRoutesForServices attaches services endpoints to router
+func AddRoutesForServices(router *httprouter.Router, manager serviceManager, optionsParser serviceOptionsParser) {
+	servicesEndpoint := NewServicesEndpoint(manager, optionsParser)
+	router.GET("/services", servicesEndpoint.List)
+	router.POST("/services", servicesEndpoint.Create)
+	router.DELETE("/services/:id", servicesEndpoint.Kill)
+}
+
+func toServiceRequest(req *http.Request) (*serviceRequest, error) {
+	var sr = &serviceRequest{}
+	if err := json.NewDecoder(req.Body).Decode(sr); err != nil {
+		return nil, err
+	}
+	return sr, nil
+}
+
+func validateServiceRequest(sr *serviceRequest) *validation.FieldErrorMap {
+	errors := validation.NewErrorMap()
+	if len(sr.Type) == 0 {
+		errors.ForField("type").AddError("required", "Field is required")
+	}
+	if sr.Admission != nil {
+		validateAdmission(sr.Admission, errors)
+	}
+	if sr.TrafficPolicy != nil {
+		if err := toTrafficPolicy(sr.TrafficPolicy).Validate(); err != nil {
+			errors.ForField("trafficPolicy").AddError("invalid", err.Error())
+		}
+	}
+	return errors
+}
+
+func validateAdmission(admission *serviceAdmissionRequest, errors *validation.FieldErrorMap) {
+	if admission.MaxSessions < 0 {
+		errors.ForField("admission.maxSessions").AddError("invalid", "Field can not be negative")
+	}
+	if admission.MaxSessionsPerConsumer < 0 {
+		errors.ForField("admission.maxSessionsPerConsumer").AddError("invalid", "Field can not be negative")
+	}
+	for _, address := range admission.AllowedConsumers {
+		if !common.IsHexAddress(address) {
+			errors.ForField("admission.allowedConsumers").AddError("invalid", "Invalid consumer address: "+address)
+		}
+	}
+	for _, address := range admission.DeniedConsumers {
+		if !common.IsHexAddress(address) {
+			errors.ForField("admission.deniedConsumers").AddError("invalid", "Invalid consumer address: "+address)
+		}
+	}
+}
+
+func toAdmissionPolicy(admission *serviceAdmissionRequest) session.AdmissionPolicy {
+	if admission == nil {
+		return session.AdmissionPolicy{}
+	}
+	return session.AdmissionPolicy{
+		MaxSessions:            admission.MaxSessions,
+		MaxSessionsPerConsumer: admission.MaxSessionsPerConsumer,
+		AllowedConsumers:       toIdentities(admission.AllowedConsumers),
+		DeniedConsumers:        toIdentities(admission.DeniedConsumers),
+		MinBalance:             admission.MinBalance,
+	}
+}
+
+func toTrafficPolicy(policy *serviceTrafficPolicyRequest) nat.Policy {
+	if policy == nil {
+		return nat.Policy{}
+	}
+	return nat.Policy{
+		AllowLocalNetworks: policy.AllowLocalNetworks,
+		DeniedNetworks:     policy.DeniedNetworks,
+		DeniedPorts:        policy.DeniedPorts,
+		AllowedNetworks:    policy.AllowedNetworks,
+	}
+}
+
+func toIdentities(addresses []string) []identity.Identity {
+	identities := make([]identity.Identity, len(addresses))
+	for i, address := range addresses {
+		identities[i] = identity.FromAddress(address)
+	}
+	return identities
+}
+
+func toServiceResponse(instance *service.Instance) serviceResponse {
+	return serviceResponse{
+		ID:              instance.ID(),
+		Type:            instance.Options().Type,
+		State:           string(instance.State()),
+		DiscoveryStatus: instance.DiscoveryStatus().String(),
+		Proposal:        proposalToRes(instance.Proposal()),
+	}
+}
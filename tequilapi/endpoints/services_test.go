@@ -0,0 +1,351 @@
+/*
+ * Copyright (C) 2018 The "MysteriumNetwork/node" Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package endpoints
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/mysteriumnetwork/node/communication"
+	"github.com/mysteriumnetwork/node/core/service"
+	"github.com/mysteriumnetwork/node/discovery"
+	"github.com/mysteriumnetwork/node/identity"
+	"github.com/mysteriumnetwork/node/nat"
+	dto_discovery "github.com/mysteriumnetwork/node/service_discovery/dto"
+	"github.com/mysteriumnetwork/node/session"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeServiceIdentityHandler struct{}
+
+func (handler *fakeServiceIdentityHandler) UseExisting(address, passphrase string) (identity.Identity, error) {
+	return identity.FromAddress(address), nil
+}
+
+func (handler *fakeServiceIdentityHandler) UseLast(passphrase string) (identity.Identity, error) {
+	return identity.FromAddress("0x1"), nil
+}
+
+func (handler *fakeServiceIdentityHandler) UseNew(passphrase string) (identity.Identity, error) {
+	return identity.FromAddress("0x1"), nil
+}
+
+type fakeService struct {
+	options service.Options
+	stopped chan struct{}
+}
+
+func (fake *fakeService) Start(providerID identity.Identity) (dto_discovery.ServiceProposal, session.ConfigProvider, error) {
+	return dto_discovery.ServiceProposal{
+		ServiceType:       fake.options.Type,
+		ServiceDefinition: TestServiceDefinition{},
+	}, nil, nil
+}
+
+func (fake *fakeService) Wait() error {
+	<-fake.stopped
+	return nil
+}
+
+func (fake *fakeService) Stop() error {
+	close(fake.stopped)
+	return nil
+}
+
+type fakeServiceDialogWaiter struct{}
+
+func (waiter *fakeServiceDialogWaiter) Start() (dto_discovery.Contact, error) {
+	return dto_discovery.Contact{}, nil
+}
+
+func (waiter *fakeServiceDialogWaiter) Stop() error {
+	return nil
+}
+
+func (waiter *fakeServiceDialogWaiter) ServeDialogs(communication.DialogHandler) error {
+	return nil
+}
+
+type fakeServiceDiscovery struct{}
+
+func (fake *fakeServiceDiscovery) Start(identity.Identity, dto_discovery.ServiceProposal) {}
+
+func (fake *fakeServiceDiscovery) Status() discovery.Status {
+	return discovery.PingProposal
+}
+
+func (fake *fakeServiceDiscovery) Wait() {}
+
+func (fake *fakeServiceDiscovery) Stop() {}
+
+type fakeServiceOptionsParser struct{}
+
+func (parser *fakeServiceOptionsParser) ParseOptions(serviceType string, data *json.RawMessage) (service.TransportOptions, error) {
+	switch serviceType {
+	case "fake":
+		return data, nil
+	case "broken":
+		return nil, errors.New("invalid options")
+	default:
+		return nil, service.ErrUnsupportedServiceType
+	}
+}
+
+func newTestServiceManager() *service.Manager {
+	return service.NewManager(
+		&fakeServiceIdentityHandler{},
+		func(options service.Options) (service.Service, error) {
+			return &fakeService{options: options, stopped: make(chan struct{})}, nil
+		},
+		func(identity.Identity, int) communication.DialogWaiter {
+			return &fakeServiceDialogWaiter{}
+		},
+		func(dto_discovery.ServiceProposal, session.ConfigProvider, session.AdmissionPolicy) communication.DialogHandler {
+			return nil
+		},
+		func() service.Discovery {
+			return &fakeServiceDiscovery{}
+		},
+	)
+}
+
+func TestAddRoutesForServicesAddsRoutes(t *testing.T) {
+	router := httprouter.New()
+	AddRoutesForServices(router, newTestServiceManager(), &fakeServiceOptionsParser{})
+
+	tests := []struct {
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+		expectedJSON   string
+	}{
+		{
+			http.MethodGet, "/services", "",
+			http.StatusOK,
+			`{"services": []}`,
+		},
+		{
+			http.MethodPost, "/services", `{"type": "fake", "options": {"port": 1}}`,
+			http.StatusCreated,
+			`{
+				"id": 755209391,
+				"type": "fake",
+				"state": "Running",
+				"discoveryStatus": "PingProposal",
+				"proposal": {
+					"id": 755209391,
+					"providerId": "0x1",
+					"serviceType": "fake",
+					"serviceDefinition": {
+						"locationOriginate": {"asn": "LT", "country": "Lithuania", "city": "Vilnius"}
+					}
+				}
+			}`,
+		},
+		{
+			http.MethodGet, "/services", "",
+			http.StatusOK,
+			`{
+				"services": [{
+					"id": 755209391,
+					"type": "fake",
+					"state": "Running",
+					"discoveryStatus": "PingProposal",
+					"proposal": {
+						"id": 755209391,
+						"providerId": "0x1",
+						"serviceType": "fake",
+						"serviceDefinition": {
+							"locationOriginate": {"asn": "LT", "country": "Lithuania", "city": "Vilnius"}
+						}
+					}
+				}]
+			}`,
+		},
+		{
+			http.MethodDelete, "/services/755209391", "",
+			http.StatusAccepted,
+			"",
+		},
+		{
+			http.MethodDelete, "/services/755209391", "",
+			http.StatusNotFound,
+			`{"message": "service not found"}`,
+		},
+		{
+			http.MethodGet, "/services", "",
+			http.StatusOK,
+			`{"services": []}`,
+		},
+	}
+
+	for _, test := range tests {
+		resp := httptest.NewRecorder()
+		req := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, test.expectedStatus, resp.Code, test.method+" "+test.path)
+		if test.expectedJSON != "" {
+			assert.JSONEq(t, test.expectedJSON, resp.Body.String(), test.method+" "+test.path)
+		}
+	}
+}
+
+func TestServicesCreateValidatesRequest(t *testing.T) {
+	endpoint := NewServicesEndpoint(newTestServiceManager(), &fakeServiceOptionsParser{})
+
+	tests := []struct {
+		body           string
+		expectedStatus int
+		expectedJSON   string
+	}{
+		{
+			`{}`,
+			http.StatusUnprocessableEntity,
+			`{"message": "validation_error", "errors": {"type": [{"code": "required", "message": "Field is required"}]}}`,
+		},
+		{
+			`{"type": "wireguard"}`,
+			http.StatusUnprocessableEntity,
+			`{"message": "validation_error", "errors": {"type": [{"code": "invalid", "message": "Unsupported service type"}]}}`,
+		},
+		{
+			`{"type": "broken"}`,
+			http.StatusBadRequest,
+			`{"message": "invalid options"}`,
+		},
+		{
+			`{`,
+			http.StatusBadRequest,
+			`{"message": "unexpected EOF"}`,
+		},
+	}
+
+	for _, test := range tests {
+		resp := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/services", strings.NewReader(test.body))
+		endpoint.Create(resp, req, nil)
+		assert.Equal(t, test.expectedStatus, resp.Code, test.body)
+		assert.JSONEq(t, test.expectedJSON, resp.Body.String(), test.body)
+	}
+}
+
+func TestServicesCreateValidatesPolicies(t *testing.T) {
+	endpoint := NewServicesEndpoint(newTestServiceManager(), &fakeServiceOptionsParser{})
+
+	tests := []struct {
+		body         string
+		expectedJSON string
+	}{
+		{
+			`{"type": "fake", "admission": {"maxSessions": -1, "maxSessionsPerConsumer": -1}}`,
+			`{
+				"message": "validation_error",
+				"errors": {
+					"admission.maxSessions": [{"code": "invalid", "message": "Field can not be negative"}],
+					"admission.maxSessionsPerConsumer": [{"code": "invalid", "message": "Field can not be negative"}]
+				}
+			}`,
+		},
+		{
+			`{"type": "fake", "admission": {"allowedConsumers": ["0x1"], "deniedConsumers": ["consumer"]}}`,
+			`{
+				"message": "validation_error",
+				"errors": {
+					"admission.allowedConsumers": [{"code": "invalid", "message": "Invalid consumer address: 0x1"}],
+					"admission.deniedConsumers": [{"code": "invalid", "message": "Invalid consumer address: consumer"}]
+				}
+			}`,
+		},
+		{
+			`{"type": "fake", "trafficPolicy": {"deniedNetworks": ["10.0.0.0"]}}`,
+			`{
+				"message": "validation_error",
+				"errors": {
+					"trafficPolicy": [{"code": "invalid", "message": "invalid network in traffic policy: 10.0.0.0"}]
+				}
+			}`,
+		},
+	}
+
+	for _, test := range tests {
+		resp := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/services", strings.NewReader(test.body))
+		endpoint.Create(resp, req, nil)
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.Code, test.body)
+		assert.JSONEq(t, test.expectedJSON, resp.Body.String(), test.body)
+	}
+}
+
+func TestServicesCreatePassesPoliciesToService(t *testing.T) {
+	manager := newTestServiceManager()
+	endpoint := NewServicesEndpoint(manager, &fakeServiceOptionsParser{})
+
+	resp := httptest.NewRecorder()
+	req := httptest.NewRequest(
+		http.MethodPost,
+		"/services",
+		strings.NewReader(`{
+			"type": "fake",
+			"admission": {
+				"maxSessions": 10,
+				"maxSessionsPerConsumer": 2,
+				"allowedConsumers": ["0x000000000000000000000000000000000000000A"],
+				"deniedConsumers": ["0x000000000000000000000000000000000000000b"],
+				"minBalance": 100
+			},
+			"trafficPolicy": {
+				"allowLocalNetworks": true,
+				"deniedNetworks": ["192.0.2.0/24"],
+				"deniedPorts": [25],
+				"allowedNetworks": ["198.51.100.0/24"]
+			}
+		}`),
+	)
+	endpoint.Create(resp, req, nil)
+	assert.Equal(t, http.StatusCreated, resp.Code)
+
+	instances := manager.List()
+	assert.Len(t, instances, 1)
+	assert.Equal(
+		t,
+		session.AdmissionPolicy{
+			MaxSessions:            10,
+			MaxSessionsPerConsumer: 2,
+			AllowedConsumers:       []identity.Identity{identity.FromAddress("0x000000000000000000000000000000000000000a")},
+			DeniedConsumers:        []identity.Identity{identity.FromAddress("0x000000000000000000000000000000000000000b")},
+			MinBalance:             100,
+		},
+		instances[0].Options().Admission,
+	)
+	assert.Equal(
+		t,
+		nat.Policy{
+			AllowLocalNetworks: true,
+			DeniedNetworks:     []string{"192.0.2.0/24"},
+			DeniedPorts:        []int{25},
+			AllowedNetworks:    []string{"198.51.100.0/24"},
+		},
+		instances[0].Options().TrafficPolicy,
+	)
+}